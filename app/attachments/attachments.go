@@ -0,0 +1,130 @@
+// Package attachments: simpan file upload (lampiran chat) di storage privat (bukan /uploads publik).
+// Jenis file ditentukan dari isinya (bukan nama / header dari browser) dan gambar dibuatkan thumbnail.
+package attachments
+
+import (
+	"context"
+	"errors"
+	"image/color"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/alirogz/goshop/app/imaging"
+	"github.com/alirogz/goshop/app/storage"
+	"github.com/google/uuid"
+)
+
+// ThumbMaxSize: sisi terpanjang thumbnail (px)
+const ThumbMaxSize = 320
+
+var (
+	ErrEmpty    = errors.New("file kosong")
+	ErrTooLarge = errors.New("file terlalu besar")
+	ErrType     = errors.New("jenis file tidak didukung (hanya JPG, PNG, GIF, WEBP atau PDF)")
+)
+
+// jenis file yang boleh diunggah → ekstensi file yang disimpan
+var allowedTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+// Store: penyimpanan lampiran
+type Store struct {
+	Blob    storage.Blob // privat, file hanya dibuka lewat SignedURL dari handler yang cek akses
+	MaxSize int64        // byte per file
+}
+
+// File: hasil simpan. Path & ThumbPath = key di Store.Blob (ThumbPath kosong kalau tidak ada thumbnail).
+type File struct {
+	Path        string
+	ThumbPath   string
+	ContentType string
+	Size        int64
+	Width       int
+	Height      int
+}
+
+// IsImage: jenis file gambar (ditampilkan inline)
+func IsImage(contentType string) bool {
+	return strings.HasPrefix(contentType, "image/")
+}
+
+// Save: baca file (maks MaxSize), cek jenisnya dari isi file, simpan dengan nama acak & buat thumbnail
+func (s *Store) Save(ctx context.Context, src io.Reader) (*File, error) {
+	data, err := io.ReadAll(io.LimitReader(src, s.MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, ErrEmpty
+	}
+	if int64(len(data)) > s.MaxSize {
+		return nil, ErrTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	ext, ok := allowedTypes[contentType]
+	if !ok {
+		return nil, ErrType
+	}
+
+	name := uuid.NewString()
+	dir := time.Now().Format("2006/01")
+	file := &File{
+		Path:        path.Join(dir, name+ext),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+	}
+
+	if err := storage.PutBytes(ctx, s.Blob, file.Path, data, contentType); err != nil {
+		return nil, err
+	}
+
+	// thumbnail hanya untuk gambar yang bisa di-decode library standar (webp & pdf tidak)
+	if thumb, w, h, err := thumbnail(data); err == nil {
+		file.Width, file.Height = w, h
+		file.ThumbPath = path.Join(dir, name+"_thumb.jpg")
+		if err := storage.PutBytes(ctx, s.Blob, file.ThumbPath, thumb, "image/jpeg"); err != nil {
+			s.Delete(ctx, file)
+			return nil, err
+		}
+	}
+
+	return file, nil
+}
+
+// Open: buka file tersimpan untuk dibaca
+func (s *Store) Open(ctx context.Context, rel string) (io.ReadCloser, error) {
+	return s.Blob.Get(ctx, rel)
+}
+
+// Delete: hapus file & thumbnail-nya (dipakai kalau simpan ke DB gagal)
+func (s *Store) Delete(ctx context.Context, f *File) {
+	for _, rel := range []string{f.Path, f.ThumbPath} {
+		if rel != "" {
+			s.Blob.Delete(ctx, rel)
+		}
+	}
+}
+
+// thumbnail: JPEG dengan sisi terpanjang ThumbMaxSize, plus ukuran asli gambar
+func thumbnail(data []byte) ([]byte, int, int, error) {
+	img, err := imaging.Decode(data)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	b := img.Bounds()
+	thumb, err := imaging.EncodeJPEG(imaging.Fit(imaging.Flatten(img, color.White), ThumbMaxSize), 80)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return thumb, b.Dx(), b.Dy(), nil
+}
@@ -0,0 +1,27 @@
+package consts
+
+// Status batch import mutasi bank (kolom bank_import_batches.status)
+const (
+	BankImportStatusPreview    = "preview"     // sudah dibaca, belum disimpan ke bank_transactions
+	BankImportStatusCommitted  = "committed"   // mutasi sudah disimpan
+	BankImportStatusRolledBack = "rolled_back" // mutasi batch dihapus lagi
+)
+
+// Status 1 baris di batch import (kolom bank_import_rows.status)
+const (
+	BankImportRowNew        = "new"
+	BankImportRowDuplicate  = "duplicate" // fingerprint sudah ada di bank_transactions
+	BankImportRowImported   = "imported"
+	BankImportRowError      = "error"
+	BankImportRowRolledBack = "rolled_back"
+)
+
+// Keputusan rekonsiliasi mutasi ↔ order (kolom reconciliation_logs.action)
+const (
+	ReconcileAuto    = "auto"
+	ReconcileConfirm = "confirm"
+	ReconcileManual  = "manual"
+	ReconcileReject  = "reject"
+	ReconcileUnmatch = "unmatch"
+	ReconcileShort   = "short" // dipasangkan walaupun nominalnya kurang, order tidak ditandai lunas
+)
@@ -0,0 +1,16 @@
+package consts
+
+// Status percakapan chat (kolom chats.status)
+const (
+	ChatStatusOpen     = "open"     // menunggu balasan CS
+	ChatStatusPending  = "pending"  // sudah dibalas CS, menunggu pembeli
+	ChatStatusResolved = "resolved" // selesai; pesan baru dari pembeli membuka lagi
+)
+
+// Filter daftar chat admin (?filter=)
+const (
+	ChatFilterActive     = ""           // semua yang belum selesai
+	ChatFilterUnassigned = "unassigned" // belum dipegang CS siapa pun
+	ChatFilterMine       = "mine"       // dipegang admin yang sedang login
+	ChatFilterResolved   = "resolved"
+)
@@ -0,0 +1,9 @@
+package consts
+
+// Alasan perubahan stok (kolom inventory_movements.reason)
+const (
+	InventoryReasonInitial        = "initial"
+	InventoryReasonAdjustment     = "adjustment"
+	InventoryReasonOrderPlaced    = "order_placed"
+	InventoryReasonOrderCancelled = "order_cancelled"
+)
@@ -0,0 +1,23 @@
+package consts
+
+// Jenis email notifikasi (kolom notifications.kind); nama file template di app/notifications/templates
+const (
+	NotificationOrderPlaced     = "order_placed"
+	NotificationPaymentApproved = "payment_approved"
+	NotificationPaymentRejected = "payment_rejected"
+	NotificationOrderStatus     = "order_status"
+	NotificationChatMessage     = "chat_message"
+)
+
+// Status pengiriman di outbox (kolom notifications.status)
+const (
+	NotificationStatusPending = "pending"
+	NotificationStatusSent    = "sent"
+	NotificationStatusFailed  = "failed"
+)
+
+// Bahasa email (kolom users.locale)
+const (
+	LocaleID = "id"
+	LocaleEN = "en"
+)
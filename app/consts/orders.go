@@ -1,19 +1,64 @@
 package consts
 
 const (
-	OrderPaymentStatusUnpaid = "UNPAID"
-	OrderPaymentStatusPaid   = "PAID"
+	OrderPaymentStatusUnpaid        = "UNPAID"
+	OrderPaymentStatusPaid          = "PAID"
+	OrderPaymentStatusWaitingReview = "waiting_review"
+	OrderPaymentStatusRejected      = "rejected"
 )
 
+// Status review 1 bukti bayar (kolom payment_proofs.status)
 const (
-	OrderStatusPending   = 0
-	OrderStatusReceived  = 1
-	OrderStatusDelivered = 2
-	OrderStatusCancelled = 3
+	PaymentProofPending  = "pending"
+	PaymentProofApproved = "approved"
+	PaymentProofRejected = "rejected"
 )
 
+// Asal bukti bayar (kolom payment_proofs.source)
 const (
-	PaymentStatusCapture    = "capture"
-	FraudStatusAccept       = "accept"
-	PaymentStatusSettlement = "settlement"
+	PaymentProofSourceUpload = "upload" // diunggah pembeli di halaman pesanan
+	PaymentProofSourceChat   = "chat"   // lampiran chat yang dijadikan bukti oleh CS
+	PaymentProofSourceLegacy = "legacy" // dipindah dari kolom orders.payment_proof lama
+)
+
+// Metode pembayaran order (kolom orders.payment_method)
+const OrderPaymentMethodBankTransfer = "Transfer Bank"
+
+// Status pesanan (kolom orders.status).
+// Nilai 0–3 sama dengan data lama (pending, diproses, dikirim, selesai),
+// cancelled & refunded memakai nilai baru supaya tidak bentrok.
+const (
+	OrderStatusPending    = 0
+	OrderStatusProcessing = 1
+	OrderStatusShipped    = 2
+	OrderStatusCompleted  = 3
+	OrderStatusCancelled  = 4
+	OrderStatusRefunded   = 5
+)
+
+// Jenis event yang dicatat di order_status_histories
+const (
+	OrderEventStatus          = "status"
+	OrderEventPaid            = "paid"
+	OrderEventPaymentRejected = "payment_rejected"
+	OrderEventPaymentReverted = "payment_reverted"
+)
+
+// Actor untuk perubahan yang dilakukan sistem (bukan user/admin)
+const OrderActorSystem = "system"
+
+// Status transaksi & fraud dari notifikasi Midtrans
+const (
+	PaymentStatusCapture       = "capture"
+	PaymentStatusSettlement    = "settlement"
+	PaymentStatusPending       = "pending"
+	PaymentStatusDeny          = "deny"
+	PaymentStatusCancel        = "cancel"
+	PaymentStatusExpire        = "expire"
+	PaymentStatusFailure       = "failure"
+	PaymentStatusRefund        = "refund"
+	PaymentStatusPartialRefund = "partial_refund"
+
+	FraudStatusAccept    = "accept"
+	FraudStatusChallenge = "challenge"
 )
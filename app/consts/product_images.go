@@ -0,0 +1,18 @@
+package consts
+
+// Ukuran turunan gambar produk (sisi terpanjang dalam px), kolom product_images.*
+const (
+	ProductImageExtraLarge = 1200 // zoom di halaman produk
+	ProductImageLarge      = 800  // gambar utama halaman produk
+	ProductImageMedium     = 480  // kartu produk di katalog & home
+	ProductImageSmall      = 160  // keranjang, pesanan, thumbnail galeri
+)
+
+// Nama ukuran untuk ProductImage.URL / Product.ImageURL di template
+const (
+	ImageSizeOriginal   = "original"
+	ImageSizeExtraLarge = "xl"
+	ImageSizeLarge      = "large"
+	ImageSizeMedium     = "medium"
+	ImageSizeSmall      = "small"
+)
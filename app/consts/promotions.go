@@ -0,0 +1,14 @@
+package consts
+
+// Jenis potongan promo (kolom promotions.type)
+const (
+	PromotionTypePercent      = "percent"
+	PromotionTypeFixed        = "fixed"
+	PromotionTypeFreeShipping = "free_shipping"
+)
+
+// Status pemakaian promo (kolom promotion_redemptions.status)
+const (
+	RedemptionStatusApplied  = "applied"
+	RedemptionStatusReleased = "released"
+)
@@ -0,0 +1,21 @@
+package consts
+
+// Role bawaan staff (kolom roles.name). Role tambahan bisa dibuat langsung di DB.
+const (
+	RoleOwner        = "owner"
+	RoleOrderStaff   = "order-staff"
+	RoleCatalogStaff = "catalog-staff"
+	RoleCSAgent      = "cs-agent"
+	RoleFinance      = "finance"
+)
+
+// Hak akses halaman admin (kolom role_permissions.permission)
+const (
+	PermOrdersView       = "orders.view"
+	PermOrdersManage     = "orders.manage" // ubah status & pengiriman
+	PermPaymentsManage   = "payments.manage"
+	PermCatalogManage    = "catalog.manage"
+	PermPromotionsManage = "promotions.manage"
+	PermChatsManage      = "chats.manage"
+	PermSettingsManage   = "settings.manage"
+)
@@ -0,0 +1,10 @@
+package consts
+
+// Urutan hasil pencarian / daftar produk (?sort=)
+const (
+	ProductSortRelevance   = "relevance" // default kalau ada kata pencarian
+	ProductSortNewest      = "newest"    // default tanpa kata pencarian
+	ProductSortPriceAsc    = "price_asc"
+	ProductSortPriceDesc   = "price_desc"
+	ProductSortBestSelling = "best_selling"
+)
@@ -0,0 +1,16 @@
+package consts
+
+// Jenis dokumen yang punya nomor urut (kolom sequence_counters.name)
+const (
+	SequenceOrder    = "order"
+	SequencePayment  = "payment"
+	SequenceInvoice  = "invoice"
+	SequenceShipment = "shipment"
+)
+
+// Kapan nomor urut kembali ke 1
+const (
+	SequenceResetMonthly = "monthly"
+	SequenceResetYearly  = "yearly"
+	SequenceResetNever   = "never"
+)
@@ -0,0 +1,16 @@
+package consts
+
+// Kelas pajak produk (kolom products.tax_class)
+const (
+	TaxClassTaxable = "taxable"
+	TaxClassExempt  = "exempt"
+)
+
+// Key pengaturan toko (tabel settings)
+const (
+	SettingTaxRate          = "tax.rate"
+	SettingPricesIncludeTax = "tax.prices_include_tax"
+)
+
+// DefaultTaxRate: tarif PPN (persen) kalau belum diatur di admin
+const DefaultTaxRate = "11"
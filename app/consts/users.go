@@ -0,0 +1,7 @@
+package consts
+
+// Kegunaan token sekali pakai (kolom user_tokens.purpose)
+const (
+	UserTokenPasswordReset     = "password_reset"
+	UserTokenEmailVerification = "email_verification"
+)
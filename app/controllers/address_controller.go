@@ -9,7 +9,7 @@ import (
 )
 
 func (server *Server) AddressesIndex(w http.ResponseWriter, r *http.Request) {
-	ren := userRender()
+	ren := userRender(r)
 
 	user := server.CurrentUser(w, r)
 	if user == nil {
@@ -36,7 +36,7 @@ func (server *Server) AddressesIndex(w http.ResponseWriter, r *http.Request) {
 }
 
 func (server *Server) AddressNew(w http.ResponseWriter, r *http.Request) {
-	ren := userRender()
+	ren := userRender(r)
 
 	user := server.CurrentUser(w, r)
 	if user == nil {
@@ -103,7 +103,7 @@ func (server *Server) AddressCreate(w http.ResponseWriter, r *http.Request) {
 }
 
 func (server *Server) AddressEdit(w http.ResponseWriter, r *http.Request) {
-	ren := userRender()
+	ren := userRender(r)
 
 	user := server.CurrentUser(w, r)
 	if user == nil {
@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/alirogz/goshop/app/models"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// GET /admin/payments/imports/{id}
+// preview batch sebelum disimpan, atau ringkasan batch yang sudah disimpan
+func (server *Server) AdminBankImportShow(w http.ResponseWriter, r *http.Request) {
+	admin := server.CurrentUser(w, r)
+
+	batch, err := models.FindBankImportBatch(server.DB, mux.Vars(r)["id"])
+	if err != nil {
+		SetFlash(w, r, "error", "Batch import tidak ditemukan")
+		http.Redirect(w, r, "/admin/payments/import", http.StatusSeeOther)
+		return
+	}
+
+	ren := adminRender(r)
+	_ = ren.HTML(w, http.StatusOK, "admin_payment_import_batch", map[string]interface{}{
+		"batch":     batch,
+		"previous":  batch.PreviousImport(server.DB),
+		"matched":   batch.MatchedCount(server.DB),
+		"user":      admin,
+		"cartCount": server.GetCartCount(w, r),
+		"isAdmin":   IsAdminUser(admin),
+		"success":   GetFlash(w, r, "success"),
+		"error":     GetFlash(w, r, "error"),
+	})
+}
+
+// POST /admin/payments/imports/{id}/commit
+func (server *Server) AdminBankImportCommit(w http.ResponseWriter, r *http.Request) {
+	admin := server.CurrentUser(w, r)
+
+	id := mux.Vars(r)["id"]
+	batch := &models.BankImportBatch{ID: id}
+	if err := batch.Commit(server.DB, admin.ID); err != nil {
+		if !errors.Is(err, models.ErrImportBatchNotPreview) && !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Println("BankImportBatch.Commit error:", err)
+		}
+		SetFlash(w, r, "error", "Gagal menyimpan import: "+err.Error())
+		http.Redirect(w, r, "/admin/payments/imports/"+id, http.StatusSeeOther)
+		return
+	}
+
+	msg := strconv.Itoa(batch.ImportedRows) + " mutasi berhasil disimpan"
+	if batch.DuplicateRows > 0 {
+		msg += ", " + strconv.Itoa(batch.DuplicateRows) + " duplikat dilewati"
+	}
+	SetFlash(w, r, "success", msg)
+	http.Redirect(w, r, "/admin/payments/imports/"+id, http.StatusSeeOther)
+}
+
+// POST /admin/payments/imports/{id}/rollback
+// hanya bisa kalau belum ada mutasi dari batch ini yang dipasangkan dengan order
+func (server *Server) AdminBankImportRollback(w http.ResponseWriter, r *http.Request) {
+	admin := server.CurrentUser(w, r)
+
+	id := mux.Vars(r)["id"]
+	batch := &models.BankImportBatch{ID: id}
+	if err := batch.Rollback(server.DB, admin.ID); err != nil {
+		if !errors.Is(err, models.ErrImportBatchMatched) && !errors.Is(err, models.ErrImportBatchNotCommitted) {
+			log.Println("BankImportBatch.Rollback error:", err)
+		}
+		SetFlash(w, r, "error", "Gagal membatalkan import: "+err.Error())
+		http.Redirect(w, r, "/admin/payments/imports/"+id, http.StatusSeeOther)
+		return
+	}
+
+	SetFlash(w, r, "success", "Import dibatalkan, semua mutasi dari batch ini sudah dihapus")
+	http.Redirect(w, r, "/admin/payments/imports/"+id, http.StatusSeeOther)
+}
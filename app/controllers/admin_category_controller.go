@@ -0,0 +1,254 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/alirogz/goshop/app/models"
+	"github.com/gorilla/mux"
+)
+
+// GET /admin/categories: daftar section + tree kategori
+func (server *Server) AdminCategoriesIndex(w http.ResponseWriter, r *http.Request) {
+	admin := server.CurrentUser(w, r)
+
+	categories, sections, err := server.catalogTree()
+	if err != nil {
+		SetFlash(w, r, "error", "Gagal mengambil kategori: "+err.Error())
+	}
+
+	counts, err := models.CategoryProductCounts(server.DB)
+	if err != nil {
+		log.Println("CategoryProductCounts error:", err)
+	}
+
+	tree := models.BuildSectionTree(sections, categories)
+	rows := map[string][]*models.CategoryNode{}
+	for _, s := range tree {
+		rows[s.ID] = models.FlattenCategoryTree(s.Categories)
+	}
+
+	ren := adminRender(r)
+	_ = ren.HTML(w, http.StatusOK, "admin_categories", map[string]interface{}{
+		"sections":  tree,
+		"rows":      rows,
+		"counts":    counts,
+		"user":      admin,
+		"cartCount": server.GetCartCount(w, r),
+		"isAdmin":   IsAdminUser(admin),
+		"success":   GetFlash(w, r, "success"),
+		"error":     GetFlash(w, r, "error"),
+	})
+}
+
+// GET /admin/categories/new (?parent_id= / ?section_id= untuk isi awal)
+func (server *Server) AdminCategoriesNew(w http.ResponseWriter, r *http.Request) {
+	admin := server.CurrentUser(w, r)
+
+	category := models.Category{
+		ParentID:  r.URL.Query().Get("parent_id"),
+		SectionID: r.URL.Query().Get("section_id"),
+	}
+	server.renderCategoryForm(w, r, admin, category, false)
+}
+
+// POST /admin/categories
+func (server *Server) AdminCategoriesCreate(w http.ResponseWriter, r *http.Request) {
+	category := models.Category{}
+	categoryFromForm(r, &category)
+
+	if err := models.SaveCategory(server.DB, &category); err != nil {
+		SetFlash(w, r, "error", "Gagal menyimpan kategori: "+err.Error())
+		http.Redirect(w, r, "/admin/categories/new", http.StatusSeeOther)
+		return
+	}
+
+	SetFlash(w, r, "success", "Kategori berhasil dibuat")
+	http.Redirect(w, r, "/admin/categories", http.StatusSeeOther)
+}
+
+// GET /admin/categories/{id}/edit
+func (server *Server) AdminCategoriesEdit(w http.ResponseWriter, r *http.Request) {
+	admin := server.CurrentUser(w, r)
+
+	var category models.Category
+	if err := server.DB.Where("id = ?", mux.Vars(r)["id"]).First(&category).Error; err != nil {
+		SetFlash(w, r, "error", "Kategori tidak ditemukan")
+		http.Redirect(w, r, "/admin/categories", http.StatusSeeOther)
+		return
+	}
+
+	server.renderCategoryForm(w, r, admin, category, true)
+}
+
+// POST /admin/categories/{id}
+func (server *Server) AdminCategoriesUpdate(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var category models.Category
+	if err := server.DB.Where("id = ?", id).First(&category).Error; err != nil {
+		SetFlash(w, r, "error", "Kategori tidak ditemukan")
+		http.Redirect(w, r, "/admin/categories", http.StatusSeeOther)
+		return
+	}
+
+	categoryFromForm(r, &category)
+	if err := models.SaveCategory(server.DB, &category); err != nil {
+		SetFlash(w, r, "error", "Gagal mengubah kategori: "+err.Error())
+		http.Redirect(w, r, "/admin/categories/"+id+"/edit", http.StatusSeeOther)
+		return
+	}
+
+	SetFlash(w, r, "success", "Kategori berhasil diubah")
+	http.Redirect(w, r, "/admin/categories", http.StatusSeeOther)
+}
+
+// POST /admin/categories/{id}/delete
+func (server *Server) AdminCategoriesDelete(w http.ResponseWriter, r *http.Request) {
+	if err := models.DeleteCategory(server.DB, mux.Vars(r)["id"]); err != nil {
+		SetFlash(w, r, "error", "Gagal menghapus kategori: "+err.Error())
+	} else {
+		SetFlash(w, r, "success", "Kategori berhasil dihapus")
+	}
+
+	http.Redirect(w, r, "/admin/categories", http.StatusSeeOther)
+}
+
+// POST /admin/sections (form tambah section ada di halaman kategori)
+func (server *Server) AdminSectionsCreate(w http.ResponseWriter, r *http.Request) {
+	section := models.Section{Name: strings.TrimSpace(r.FormValue("name"))}
+
+	if err := models.SaveSection(server.DB, &section); err != nil {
+		SetFlash(w, r, "error", "Gagal menyimpan section: "+err.Error())
+	} else {
+		SetFlash(w, r, "success", "Section berhasil dibuat")
+	}
+
+	http.Redirect(w, r, "/admin/categories", http.StatusSeeOther)
+}
+
+// GET /admin/sections/{id}/edit
+func (server *Server) AdminSectionsEdit(w http.ResponseWriter, r *http.Request) {
+	admin := server.CurrentUser(w, r)
+
+	var section models.Section
+	if err := server.DB.Where("id = ?", mux.Vars(r)["id"]).First(&section).Error; err != nil {
+		SetFlash(w, r, "error", "Section tidak ditemukan")
+		http.Redirect(w, r, "/admin/categories", http.StatusSeeOther)
+		return
+	}
+
+	ren := adminRender(r)
+	_ = ren.HTML(w, http.StatusOK, "admin_section_form", map[string]interface{}{
+		"section":   section,
+		"user":      admin,
+		"cartCount": server.GetCartCount(w, r),
+		"isAdmin":   IsAdminUser(admin),
+		"error":     GetFlash(w, r, "error"),
+	})
+}
+
+// POST /admin/sections/{id}
+func (server *Server) AdminSectionsUpdate(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var section models.Section
+	if err := server.DB.Where("id = ?", id).First(&section).Error; err != nil {
+		SetFlash(w, r, "error", "Section tidak ditemukan")
+		http.Redirect(w, r, "/admin/categories", http.StatusSeeOther)
+		return
+	}
+
+	section.Name = strings.TrimSpace(r.FormValue("name"))
+	if err := models.SaveSection(server.DB, &section); err != nil {
+		SetFlash(w, r, "error", "Gagal mengubah section: "+err.Error())
+		http.Redirect(w, r, "/admin/sections/"+id+"/edit", http.StatusSeeOther)
+		return
+	}
+
+	SetFlash(w, r, "success", "Section berhasil diubah")
+	http.Redirect(w, r, "/admin/categories", http.StatusSeeOther)
+}
+
+// POST /admin/sections/{id}/delete
+func (server *Server) AdminSectionsDelete(w http.ResponseWriter, r *http.Request) {
+	if err := models.DeleteSection(server.DB, mux.Vars(r)["id"]); err != nil {
+		SetFlash(w, r, "error", "Gagal menghapus section: "+err.Error())
+	} else {
+		SetFlash(w, r, "success", "Section berhasil dihapus")
+	}
+
+	http.Redirect(w, r, "/admin/categories", http.StatusSeeOther)
+}
+
+func (server *Server) renderCategoryForm(w http.ResponseWriter, r *http.Request, admin *models.User, category models.Category, isEdit bool) {
+	categories, sections, err := server.catalogTree()
+	if err != nil {
+		log.Println("catalogTree error:", err)
+	}
+
+	// pilihan induk: semua kategori kecuali kategori ini & turunannya
+	excluded := map[string]bool{}
+	if category.ID != "" {
+		for _, id := range models.CategoryDescendantIDs(categories, category.ID) {
+			excluded[id] = true
+		}
+	}
+	var parents []*models.CategoryNode
+	for _, s := range models.BuildSectionTree(sections, categories) {
+		for _, n := range models.FlattenCategoryTree(s.Categories) {
+			if !excluded[n.ID] {
+				parents = append(parents, n)
+			}
+		}
+	}
+
+	ren := adminRender(r)
+	_ = ren.HTML(w, http.StatusOK, "admin_category_form", map[string]interface{}{
+		"category":  category,
+		"sections":  sections,
+		"parents":   parents,
+		"user":      admin,
+		"cartCount": server.GetCartCount(w, r),
+		"isAdmin":   IsAdminUser(admin),
+		"isEdit":    isEdit,
+		"error":     GetFlash(w, r, "error"),
+	})
+}
+
+// categoryFromForm: subkategori otomatis ikut section induknya (lihat models.SaveCategory)
+func categoryFromForm(r *http.Request, category *models.Category) {
+	category.Name = strings.TrimSpace(r.FormValue("name"))
+	category.ParentID = r.FormValue("parent_id")
+	category.SectionID = r.FormValue("section_id")
+}
+
+// categoryGroup: pilihan kategori di form produk, dikelompokkan per section
+type categoryGroup struct {
+	Name string
+	Rows []*models.CategoryNode
+}
+
+// productCategoryGroups: semua kategori (section yang kosong tidak ditampilkan)
+func (server *Server) productCategoryGroups() []categoryGroup {
+	var groups []categoryGroup
+	for _, s := range server.catalogMenu() {
+		if len(s.Categories) > 0 {
+			groups = append(groups, categoryGroup{Name: s.Name, Rows: models.FlattenCategoryTree(s.Categories)})
+		}
+	}
+	return groups
+}
+
+// productCategoriesFromForm: kategori yang dicentang di form produk (id yang tidak dikenal diabaikan)
+func (server *Server) productCategoriesFromForm(r *http.Request) ([]models.Category, error) {
+	ids := r.Form["category_ids"]
+	if len(ids) == 0 {
+		return []models.Category{}, nil
+	}
+
+	var categories []models.Category
+	err := server.DB.Where("id IN ?", ids).Find(&categories).Error
+	return categories, err
+}
@@ -2,45 +2,37 @@ package controllers
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/alirogz/goshop/app/consts"
 	"github.com/alirogz/goshop/app/models"
-	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"gorm.io/gorm"
 )
 
+// GET /admin/chats?filter=unassigned|mine|resolved (kosong = semua yang belum selesai)
 func (server *Server) AdminChatsIndex(w http.ResponseWriter, r *http.Request) {
-	ren := userRender()
+	ren := userRender(r)
 	user := server.CurrentUser(w, r)
-	if user == nil || !IsAdminUser(user) {
-		http.Redirect(w, r, "/", http.StatusSeeOther)
-		return
+
+	filter := r.URL.Query().Get("filter")
+	switch filter {
+	case consts.ChatFilterUnassigned, consts.ChatFilterMine, consts.ChatFilterResolved:
+	default:
+		filter = consts.ChatFilterActive
 	}
 
-	var chats []models.Chat
-	// preload user; sort by updated_at desc
-	if err := server.DB.Model(models.Chat{}).Preload("User").Order("updated_at desc").Find(&chats).Error; err != nil {
+	chats, err := models.ListChats(server.DB, filter, user.ID)
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	unread := map[string]int64{}
-
-	for _, c := range chats {
-		var cnt int64
-
-		q := server.DB.Model(models.ChatMessage{}).
-			Where("chat_id = ? AND sender_role = ?", c.ID, "user")
-
-		if c.AdminLastReadAt != nil {
-			q = q.Where("created_at > ?", *c.AdminLastReadAt)
-		}
-
-		q.Count(&cnt)
-		unread[c.ID] = cnt
-	}
+	// jumlah belum dibaca per chat dari cache ChatHub (1 query GROUP BY, bukan COUNT per chat)
+	unread := server.ChatHub.adminUnreadByChat()
 
 	var totalUnread int64
 	for _, v := range unread {
@@ -52,18 +44,17 @@ func (server *Server) AdminChatsIndex(w http.ResponseWriter, r *http.Request) {
 		"isAdmin":     true,
 		"cartCount":   server.GetCartCount(w, r),
 		"chats":       chats,
+		"filter":      filter,
 		"unread":      unread,
 		"totalUnread": totalUnread,
+		"agents":      server.chatAgentNames(),
+		"orderCodes":  server.chatOrderCodes(chats),
 	})
 }
 
 func (server *Server) AdminChatsShow(w http.ResponseWriter, r *http.Request) {
-	ren := userRender()
+	ren := userRender(r)
 	admin := server.CurrentUser(w, r)
-	if admin == nil || !IsAdminUser(admin) {
-		http.Redirect(w, r, "/", http.StatusSeeOther)
-		return
-	}
 	vars := mux.Vars(r)
 	chatID := vars["id"]
 
@@ -86,28 +77,52 @@ func (server *Server) AdminChatsShow(w http.ResponseWriter, r *http.Request) {
 		lastTs = msgs[len(msgs)-1].CreatedAt.UnixMilli()
 	}
 
+	ownLastTs, peerReadTs := chatReceiptTimes(msgs, "admin", chat.UserLastReadAt)
+
 	now := time.Now()
-	server.DB.Model(&models.Chat{}).
-		Where("id = ?", chat.ID).
-		Update("admin_last_read_at", &now)
+	if err := chat.MarkReadBy(server.DB, "admin", now); err == nil {
+		server.ChatHub.ChatRead(*chat, "admin", now)
+	}
+
+	agents, err := models.UsersWithPermission(server.DB, consts.PermChatsManage)
+	if err != nil {
+		log.Println("UsersWithPermission error:", err)
+	}
+
+	// pesanan pembeli yang belum lunas: pilihan "jadikan bukti bayar" untuk lampiran chat
+	var proofOrders []models.Order
+	server.DB.Select("id", "code", "grand_total").
+		Where("user_id = ? AND paid_at IS NULL AND COALESCE(payment_status, '') <> ? AND status NOT IN ?", chat.UserID,
+			consts.OrderPaymentStatusPaid, []int{consts.OrderStatusCancelled, consts.OrderStatusRefunded}).
+		Order("created_at desc").
+		Limit(20).
+		Find(&proofOrders)
+
+	var product models.Product
+	if chat.ProductID != "" {
+		server.DB.Select("id", "name", "slug").Where("id = ?", chat.ProductID).First(&product)
+	}
 
 	_ = ren.HTML(w, http.StatusOK, "admin_chat_show", map[string]interface{}{
-		"user":      admin,
-		"isAdmin":   true,
-		"cartCount": server.GetCartCount(w, r),
-		"chat":      chat,
-		"messages":  msgs,
-		"lastTs":    lastTs,
+		"user":        admin,
+		"isAdmin":     true,
+		"cartCount":   server.GetCartCount(w, r),
+		"chat":        chat,
+		"orderCode":   server.chatOrderCodes([]models.Chat{*chat})[chat.OrderID],
+		"product":     product,
+		"agents":      agents,
+		"proofOrders": proofOrders,
+		"messages":    msgs,
+		"lastTs":      lastTs,
+		"ownLastTs":   ownLastTs,
+		"peerReadTs":  peerReadTs,
+		"success":     GetFlash(w, r, "success"),
+		"error":       GetFlash(w, r, "error"),
 	})
 }
 
 // ADMIN: fetch messages (polling)
 func (server *Server) AdminChatMessages(w http.ResponseWriter, r *http.Request) {
-	admin := server.CurrentUser(w, r)
-	if admin == nil || !IsAdminUser(admin) {
-		w.WriteHeader(http.StatusUnauthorized)
-		return
-	}
 	vars := mux.Vars(r)
 	chatID := vars["id"]
 
@@ -144,35 +159,115 @@ func (server *Server) AdminChatMessages(w http.ResponseWriter, r *http.Request)
 // ADMIN: send message
 func (server *Server) AdminChatSend(w http.ResponseWriter, r *http.Request) {
 	admin := server.CurrentUser(w, r)
-	if admin == nil || !IsAdminUser(admin) {
-		w.WriteHeader(http.StatusUnauthorized)
-		return
-	}
 	vars := mux.Vars(r)
 	chatID := vars["id"]
 
-	if err := r.ParseForm(); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
-	text := r.FormValue("message")
-	if len(text) == 0 {
-		w.WriteHeader(http.StatusBadRequest)
+	var chat models.Chat
+	if err := server.DB.Where("id = ?", chatID).First(&chat).Error; err != nil {
+		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
-	msg := models.ChatMessage{
-		ID:         uuid.NewString(),
-		ChatID:     chatID,
-		SenderID:   admin.ID,
-		SenderRole: "admin",
-		Message:    text,
+	msg, files, err := server.readChatMessage(w, r, admin.ID, "admin")
+	if err != nil {
+		writeChatSendError(w, err)
+		return
 	}
-	if err := server.DB.Create(&msg).Error; err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+	// balasan toko juga dikabari lewat email (1 email untuk beberapa balasan beruntun)
+	err = server.DB.Transaction(func(tx *gorm.DB) error {
+		if err := chat.AddMessage(tx, msg); err != nil {
+			return err
+		}
+		// thread yang belum dipegang siapa pun otomatis dipegang CS yang membalas
+		if chat.AssignedAdminID == "" {
+			if err := chat.Assign(tx, admin.ID); err != nil {
+				return err
+			}
+		}
+		return models.EnqueueChatNotification(tx, chat.UserID, chat.ID, msg.Preview())
+	})
+	if err != nil {
+		server.discardChatFiles(files)
+		writeChatSendError(w, err)
 		return
 	}
+	server.ChatHub.MessageCreated(chat, *msg)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "message": msg})
 }
+
+// POST /admin/chats/{id}/assign: admin_id = CS tujuan ("me" = diri sendiri, kosong = lepas)
+func (server *Server) AdminChatAssign(w http.ResponseWriter, r *http.Request) {
+	admin := server.CurrentUser(w, r)
+	chatID := mux.Vars(r)["id"]
+	back := "/admin/chats/" + chatID
+
+	var chat models.Chat
+	if err := server.DB.Where("id = ?", chatID).First(&chat).Error; err != nil {
+		http.Error(w, "Chat not found", http.StatusNotFound)
+		return
+	}
+
+	assignee := r.FormValue("admin_id")
+	if assignee == "me" {
+		assignee = admin.ID
+	}
+	if assignee != "" {
+		if _, ok := server.chatAgentNames()[assignee]; !ok {
+			SetFlash(w, r, "error", "CS tujuan tidak ditemukan atau tidak punya akses chat.")
+			http.Redirect(w, r, back, http.StatusSeeOther)
+			return
+		}
+	}
+
+	if err := chat.Assign(server.DB, assignee); err != nil {
+		log.Println("chat assign error:", err)
+		SetFlash(w, r, "error", "Gagal menyimpan penanggung jawab chat.")
+		http.Redirect(w, r, back, http.StatusSeeOther)
+		return
+	}
+
+	if assignee == "" {
+		SetFlash(w, r, "success", "Chat dilepas, sekarang masuk daftar belum di-assign.")
+	} else {
+		SetFlash(w, r, "success", "Penanggung jawab chat diperbarui.")
+	}
+	http.Redirect(w, r, back, http.StatusSeeOther)
+}
+
+// POST /admin/chats/{id}/status: open | pending | resolved
+func (server *Server) AdminChatStatus(w http.ResponseWriter, r *http.Request) {
+	chatID := mux.Vars(r)["id"]
+	back := "/admin/chats/" + chatID
+
+	var chat models.Chat
+	if err := server.DB.Where("id = ?", chatID).First(&chat).Error; err != nil {
+		http.Error(w, "Chat not found", http.StatusNotFound)
+		return
+	}
+
+	if err := chat.SetStatus(server.DB, r.FormValue("status")); err != nil {
+		SetFlash(w, r, "error", "Gagal mengubah status chat: "+err.Error())
+		http.Redirect(w, r, back, http.StatusSeeOther)
+		return
+	}
+
+	SetFlash(w, r, "success", "Status chat: "+chat.StatusLabel()+".")
+	http.Redirect(w, r, back, http.StatusSeeOther)
+}
+
+// chatAgentNames: staff yang boleh memegang chat (key: user id)
+func (server *Server) chatAgentNames() map[string]string {
+	names := map[string]string{}
+
+	agents, err := models.UsersWithPermission(server.DB, consts.PermChatsManage)
+	if err != nil {
+		log.Println("UsersWithPermission error:", err)
+		return names
+	}
+	for _, a := range agents {
+		names[a.ID] = a.FirstName + " " + a.LastName
+	}
+	return names
+}
@@ -2,37 +2,28 @@ package controllers
 
 import (
 	"encoding/json"
+	"fmt"
 	"html/template"
-	"io"
 	"log"
 	"net/http"
 	"net/url"
-	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/alirogz/goshop/app/consts"
 	"github.com/alirogz/goshop/app/models"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/gosimple/slug"
 	"github.com/shopspring/decimal"
 	"github.com/unrolled/render"
+	"gorm.io/gorm"
 )
 
 // GET /admin/orders
 func (server *Server) AdminOrdersIndex(w http.ResponseWriter, r *http.Request) {
-	if !IsLoggedIn(r) {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
-		return
-	}
 	admin := server.CurrentUser(w, r)
-	if !IsAdminUser(admin) {
-		SetFlash(w, r, "error", "Unauthorized")
-		http.Redirect(w, r, "/", http.StatusSeeOther)
-		return
-	}
 
 	// Ambil semua order (sederhana, urut terbaru)
 	var orders []models.Order
@@ -42,7 +33,7 @@ func (server *Server) AdminOrdersIndex(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ren := adminRender()
+	ren := adminRender(r)
 	_ = ren.HTML(w, http.StatusOK, "admin_orders", map[string]interface{}{
 		"orders":    orders,
 		"user":      admin,
@@ -55,16 +46,7 @@ func (server *Server) AdminOrdersIndex(w http.ResponseWriter, r *http.Request) {
 
 // GET /admin/orders/{id}
 func (server *Server) AdminOrdersShow(w http.ResponseWriter, r *http.Request) {
-	if !IsLoggedIn(r) {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
-		return
-	}
 	admin := server.CurrentUser(w, r)
-	if !IsAdminUser(admin) {
-		SetFlash(w, r, "error", "Unauthorized")
-		http.Redirect(w, r, "/", http.StatusSeeOther)
-		return
-	}
 
 	vars := mux.Vars(r)
 	id := vars["id"]
@@ -74,7 +56,10 @@ func (server *Server) AdminOrdersShow(w http.ResponseWriter, r *http.Request) {
 		Preload("OrderCustomer").
 		Preload("OrderItems").
 		Preload("OrderItems.Product").
-		Preload("OrderItems.Product.ProductImages").
+		Preload("OrderItems.Product.ProductImages", models.OrderProductImages).
+		Preload("StatusHistories", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at asc")
+		}).
 		Where("id = ?", id).
 		First(&order).Error; err != nil {
 
@@ -104,7 +89,20 @@ func (server *Server) AdminOrdersShow(w http.ResponseWriter, r *http.Request) {
 	// Kg untuk tampilan
 	totalWeightKg := totalWeight / 1000.0
 
-	ren := adminRender()
+	notifications, err := models.OrderNotifications(server.DB, order.ID)
+	if err != nil {
+		log.Println("OrderNotifications error:", err)
+	}
+
+	// bukti bayar hanya untuk staff keuangan
+	var paymentProofs []models.PaymentProof
+	if admin.Can(consts.PermPaymentsManage) {
+		if paymentProofs, err = models.ListPaymentProofs(server.DB, order.ID); err != nil {
+			log.Println("ListPaymentProofs error:", err)
+		}
+	}
+
+	ren := adminRender(r)
 	_ = ren.HTML(w, http.StatusOK, "admin_order_show", map[string]interface{}{
 		"order":         order,
 		"user":          admin,
@@ -113,6 +111,8 @@ func (server *Server) AdminOrdersShow(w http.ResponseWriter, r *http.Request) {
 		"totalItems":    totalItems,
 		"totalWeight":   totalWeight,
 		"totalWeightKg": totalWeightKg,
+		"notifications": notifications,
+		"paymentProofs": paymentProofs,
 		"success":       GetFlash(w, r, "success"),
 		"error":         GetFlash(w, r, "error"),
 	})
@@ -120,16 +120,7 @@ func (server *Server) AdminOrdersShow(w http.ResponseWriter, r *http.Request) {
 
 // POST /admin/orders/{id}/pay-manual
 func (server *Server) AdminPayManual(w http.ResponseWriter, r *http.Request) {
-	if !IsLoggedIn(r) {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
-		return
-	}
 	admin := server.CurrentUser(w, r)
-	if !IsAdminUser(admin) {
-		SetFlash(w, r, "error", "Unauthorized")
-		http.Redirect(w, r, "/", http.StatusSeeOther)
-		return
-	}
 
 	id := mux.Vars(r)["id"]
 
@@ -146,25 +137,17 @@ func (server *Server) AdminPayManual(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// simpan payment manual
-	paymentModel := models.Payment{}
+	// simpan payment manual + tandai lunas
 	raw := json.RawMessage(`{"note":"manual payment by admin"}`)
-	_, err = paymentModel.CreatePayment(server.DB, &models.Payment{
-		OrderID:           order.ID,
+	_, err = order.RecordPayment(server.DB, models.Payment{
 		Amount:            order.GrandTotal,
 		TransactionID:     "ADMIN-MANUAL-" + time.Now().Format("20060102150405"),
-		TransactionStatus: "settlement",
+		TransactionStatus: consts.PaymentStatusSettlement,
 		Payload:           &raw,
 		PaymentType:       "manual",
-	})
+	}, true, admin.ID, "Ditandai lunas manual oleh admin")
 	if err != nil {
-		SetFlash(w, r, "error", "Gagal membuat payment manual")
-		http.Redirect(w, r, "/admin/orders/"+order.ID, http.StatusSeeOther)
-		return
-	}
-
-	if err := order.MarkAsPaid(server.DB); err != nil {
-		SetFlash(w, r, "error", "Gagal menandai lunas")
+		SetFlash(w, r, "error", "Gagal menandai lunas: "+err.Error())
 		http.Redirect(w, r, "/admin/orders/"+order.ID, http.StatusSeeOther)
 		return
 	}
@@ -174,11 +157,7 @@ func (server *Server) AdminPayManual(w http.ResponseWriter, r *http.Request) {
 }
 
 func (server *Server) AdminApprovePayment(w http.ResponseWriter, r *http.Request) {
-	if !IsLoggedIn(r) || !IsAdminUser(server.CurrentUser(w, r)) {
-		SetFlash(w, r, "error", "Unauthorized")
-		http.Redirect(w, r, "/", http.StatusSeeOther)
-		return
-	}
+	admin := server.CurrentUser(w, r)
 
 	vars := mux.Vars(r)
 	id := vars["id"]
@@ -190,9 +169,14 @@ func (server *Server) AdminApprovePayment(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	order.PaymentStatus = "PAID"
-	if err := server.DB.Save(&order).Error; err != nil {
-		SetFlash(w, r, "error", "Gagal mengupdate status pembayaran")
+	// proof_id: setujui 1 bukti bayar tertentu (bukti lain yang masih menunggu tetap tercatat)
+	if proofID := r.FormValue("proof_id"); proofID != "" {
+		server.reviewPaymentProof(w, r, &order, proofID, consts.PaymentProofApproved, admin.ID, "")
+		return
+	}
+
+	if err := order.MarkAsPaid(server.DB, admin.ID, "Bukti pembayaran disetujui"); err != nil {
+		SetFlash(w, r, "error", "Gagal mengupdate status pembayaran: "+err.Error())
 	} else {
 		SetFlash(w, r, "success", "Pembayaran berhasil dikonfirmasi.")
 	}
@@ -201,11 +185,7 @@ func (server *Server) AdminApprovePayment(w http.ResponseWriter, r *http.Request
 }
 
 func (server *Server) AdminRejectPayment(w http.ResponseWriter, r *http.Request) {
-	if !IsLoggedIn(r) || !IsAdminUser(server.CurrentUser(w, r)) {
-		SetFlash(w, r, "error", "Unauthorized")
-		http.Redirect(w, r, "/", http.StatusSeeOther)
-		return
-	}
+	admin := server.CurrentUser(w, r)
 
 	vars := mux.Vars(r)
 	id := vars["id"]
@@ -217,9 +197,13 @@ func (server *Server) AdminRejectPayment(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	order.PaymentStatus = "REJECTED"
-	if err := server.DB.Save(&order).Error; err != nil {
-		SetFlash(w, r, "error", "Gagal mengupdate status pembayaran")
+	if proofID := r.FormValue("proof_id"); proofID != "" {
+		server.reviewPaymentProof(w, r, &order, proofID, consts.PaymentProofRejected, admin.ID, r.FormValue("note"))
+		return
+	}
+
+	if err := order.RejectPayment(server.DB, admin.ID, r.FormValue("note")); err != nil {
+		SetFlash(w, r, "error", "Gagal mengupdate status pembayaran: "+err.Error())
 	} else {
 		SetFlash(w, r, "success", "Pembayaran ditolak.")
 	}
@@ -227,20 +211,21 @@ func (server *Server) AdminRejectPayment(w http.ResponseWriter, r *http.Request)
 	http.Redirect(w, r, "/admin/orders/"+id, http.StatusSeeOther)
 }
 
-// POST /admin/orders/{id}/status  (values: shipped|completed|cancel)
-func (server *Server) AdminUpdateStatus(w http.ResponseWriter, r *http.Request) {
-	if !IsLoggedIn(r) {
-		SetFlash(w, r, "error", "Silakan login terlebih dahulu.")
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
-		return
+func (server *Server) reviewPaymentProof(w http.ResponseWriter, r *http.Request, order *models.Order, proofID, status, adminID, note string) {
+	if err := order.ReviewPaymentProof(server.DB, proofID, status, adminID, note); err != nil {
+		SetFlash(w, r, "error", "Gagal mengupdate bukti pembayaran: "+err.Error())
+	} else if status == consts.PaymentProofApproved {
+		SetFlash(w, r, "success", "Bukti pembayaran disetujui, pesanan lunas.")
+	} else {
+		SetFlash(w, r, "success", "Bukti pembayaran ditolak.")
 	}
 
+	http.Redirect(w, r, "/admin/orders/"+order.ID, http.StatusSeeOther)
+}
+
+// POST /admin/orders/{id}/status  (values: processing|shipped|completed|cancelled|refunded)
+func (server *Server) AdminUpdateStatus(w http.ResponseWriter, r *http.Request) {
 	user := server.CurrentUser(w, r)
-	if !IsAdminUser(user) {
-		SetFlash(w, r, "error", "Anda tidak memiliki akses.")
-		http.Redirect(w, r, "/", http.StatusSeeOther)
-		return
-	}
 
 	vars := mux.Vars(r)
 	id := vars["id"]
@@ -251,23 +236,13 @@ func (server *Server) AdminUpdateStatus(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	statusStr := strings.ToLower(r.FormValue("status"))
-
-	var newStatus int
-	switch statusStr {
-	case "pending":
-		newStatus = 0
-	case "processing":
-		newStatus = 1
-	case "shipped":
-		newStatus = 2
-	case "completed":
-		newStatus = 3
-	default:
+	newStatus, ok := models.OrderStatusFromName(r.FormValue("status"))
+	if !ok {
 		SetFlash(w, r, "error", "Status tidak valid.")
 		http.Redirect(w, r, "/admin/orders/"+id, http.StatusSeeOther)
 		return
 	}
+	note := strings.TrimSpace(r.FormValue("note"))
 
 	// Pastikan order ada
 	var order models.Order
@@ -278,10 +253,10 @@ func (server *Server) AdminUpdateStatus(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Update hanya kolom status (lebih aman daripada Save seluruh struct)
-	if err := server.DB.Model(&order).Update("status", newStatus).Error; err != nil {
+	// Semua perubahan status lewat state machine (cek transisi + simpan riwayat)
+	if err := order.TransitionTo(server.DB, newStatus, user.ID, note); err != nil {
 		log.Println("AdminUpdateStatus: gagal update status:", err)
-		SetFlash(w, r, "error", "Gagal menyimpan status.")
+		SetFlash(w, r, "error", "Gagal menyimpan status: "+err.Error())
 		http.Redirect(w, r, "/admin/orders/"+id, http.StatusSeeOther)
 		return
 	}
@@ -294,72 +269,72 @@ func (server *Server) AdminUpdateStatus(w http.ResponseWriter, r *http.Request)
 
 // GET /admin/products
 func (server *Server) AdminProductsIndex(w http.ResponseWriter, r *http.Request) {
-	if !IsLoggedIn(r) {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
-		return
-	}
 	admin := server.CurrentUser(w, r)
-	if !IsAdminUser(admin) {
-		SetFlash(w, r, "error", "Unauthorized")
-		http.Redirect(w, r, "/", http.StatusSeeOther)
-		return
+
+	// pencarian sama dengan toko (?q=, filter & sort), tanpa pagination
+	query := productQueryFromRequest(r)
+
+	// ?category_id=: hanya produk di kategori itu + subkategorinya
+	var filterCategory *models.Category
+	if categoryID := r.URL.Query().Get("category_id"); categoryID != "" {
+		categories, err := models.ListCategories(server.DB)
+		if err != nil {
+			SetFlash(w, r, "error", "Gagal mengambil kategori: "+err.Error())
+		}
+		for i := range categories {
+			if categories[i].ID == categoryID {
+				filterCategory = &categories[i]
+			}
+		}
+		query.CategoryIDs = models.CategoryDescendantIDs(categories, categoryID)
+		query.OnlyCategories = true
 	}
 
-	var products []models.Product
-	if err := server.DB.Order("created_at desc").Find(&products).Error; err != nil {
+	products := []models.Product{}
+	result, err := server.Search.Search(query)
+	if err != nil {
 		SetFlash(w, r, "error", "Gagal mengambil data produk: "+err.Error())
+	} else {
+		products = result.Products
 	}
 
-	ren := adminRender()
+	ren := adminRender(r)
 	_ = ren.HTML(w, http.StatusOK, "admin_products", map[string]interface{}{
-		"products":  products,
-		"user":      admin,
-		"cartCount": server.GetCartCount(w, r),
-		"isAdmin":   IsAdminUser(admin),
-		"success":   GetFlash(w, r, "success"),
-		"error":     GetFlash(w, r, "error"),
+		"products":       products,
+		"search":         result,
+		"filters":        r.URL.Query(),
+		"sortOptions":    productSortOptions,
+		"filterCategory": filterCategory,
+		"user":           admin,
+		"cartCount":      server.GetCartCount(w, r),
+		"isAdmin":        IsAdminUser(admin),
+		"success":        GetFlash(w, r, "success"),
+		"error":          GetFlash(w, r, "error"),
 	})
 }
 
 // GET /admin/products/new
 // GET /admin/products/new
 func (server *Server) AdminProductsNew(w http.ResponseWriter, r *http.Request) {
-	if !IsLoggedIn(r) {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
-		return
-	}
-
 	admin := server.CurrentUser(w, r)
-	if !IsAdminUser(admin) {
-		SetFlash(w, r, "error", "Unauthorized")
-		http.Redirect(w, r, "/", http.StatusSeeOther)
-		return
-	}
 
-	ren := adminRender()
+	ren := adminRender(r)
 	_ = ren.HTML(w, http.StatusOK, "admin_product_form", map[string]interface{}{
-		"product":   models.Product{}, // kosong
-		"user":      admin,
-		"cartCount": server.GetCartCount(w, r),
-		"isAdmin":   IsAdminUser(admin),
-		"isEdit":    false, // beda dengan edit
+		"product":            models.Product{}, // kosong
+		"categoryGroups":     server.productCategoryGroups(),
+		"selectedCategories": map[string]bool{},
+		"user":               admin,
+		"cartCount":          server.GetCartCount(w, r),
+		"isAdmin":            IsAdminUser(admin),
+		"isEdit":             false, // beda dengan edit
 	})
 }
 
 // POST /admin/products
 // POST /admin/products
 func (server *Server) AdminProductsCreate(w http.ResponseWriter, r *http.Request) {
-	if !IsLoggedIn(r) {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
-		return
-	}
-
 	admin := server.CurrentUser(w, r)
-	if !IsAdminUser(admin) {
-		SetFlash(w, r, "error", "Unauthorized")
-		http.Redirect(w, r, "/", http.StatusSeeOther)
-		return
-	}
+	limitProductForm(w, r)
 
 	// ambil data form teks
 	name := r.FormValue("name")
@@ -368,8 +343,8 @@ func (server *Server) AdminProductsCreate(w http.ResponseWriter, r *http.Request
 	shortDesc := r.FormValue("short_description")
 	desc := r.FormValue("description")
 
-	if name == "" || priceStr == "" || stockStr == "" {
-		SetFlash(w, r, "error", "Nama, harga, dan stok wajib diisi")
+	if name == "" || priceStr == "" {
+		SetFlash(w, r, "error", "Nama dan harga wajib diisi")
 		http.Redirect(w, r, "/admin/products/new", http.StatusSeeOther)
 		return
 	}
@@ -381,63 +356,70 @@ func (server *Server) AdminProductsCreate(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	stock, err := strconv.Atoi(stockStr)
+	stock, err := parseStockField(stockStr)
 	if err != nil {
 		SetFlash(w, r, "error", "Format stok tidak valid")
 		http.Redirect(w, r, "/admin/products/new", http.StatusSeeOther)
 		return
 	}
 
-	// -------- UPLOAD GAMBAR --------
-	var imageFilename string
-
-	file, header, err := r.FormFile("image")
-	if err == nil {
-		defer file.Close()
-
-		// pastikan folder upload ada
-		if err := os.MkdirAll("public/uploads", 0755); err != nil {
-			log.Println("mkdir uploads error:", err)
-		} else {
-			ext := filepath.Ext(header.Filename)
-			imageFilename = uuid.New().String() + ext
+	variants, err := variantInputsFromForm(r)
+	if err != nil {
+		SetFlash(w, r, "error", err.Error())
+		http.Redirect(w, r, "/admin/products/new", http.StatusSeeOther)
+		return
+	}
 
-			dstPath := filepath.Join("public/uploads", imageFilename)
-			dst, err := os.Create(dstPath)
-			if err != nil {
-				log.Println("create file error:", err)
-			} else {
-				defer dst.Close()
-				if _, err := io.Copy(dst, file); err != nil {
-					log.Println("copy file error:", err)
-				}
-			}
-		}
-	} else if err != http.ErrMissingFile {
-		// kalau error selain "tidak ada file" akan kita log, tapi tidak blokir
-		log.Println("FormFile image error:", err)
+	categories, err := server.productCategoriesFromForm(r)
+	if err != nil {
+		SetFlash(w, r, "error", "Gagal mengambil kategori: "+err.Error())
+		http.Redirect(w, r, "/admin/products/new", http.StatusSeeOther)
+		return
 	}
-	// -------- /UPLOAD GAMBAR --------
 
 	now := time.Now()
+	productID := uuid.New().String()
+
+	// gambar diproses dulu (lama), baris product_images ikut transaksi di bawah
+	images, err := server.saveUploadedProductImages(r, productID, 0)
+	if err != nil {
+		SetFlash(w, r, "error", "Gagal upload gambar: "+err.Error())
+		http.Redirect(w, r, "/admin/products/new", http.StatusSeeOther)
+		return
+	}
 
 	product := models.Product{
-		ID:               uuid.New().String(),
+		ID:               productID,
 		UserID:           admin.ID,
 		Sku:              slug.Make(name),
 		Name:             name,
 		Slug:             slug.Make(name),
 		Price:            price,
-		Stock:            stock,
+		Stock:            0, // diisi lewat ledger di bawah
+		SizeOptions:      r.FormValue("size_options"),
+		ColorOptions:     r.FormValue("color_options"),
+		TaxClass:         taxClassFromForm(r),
 		ShortDescription: shortDesc,
 		Description:      desc,
 		Status:           1,
-		Image:            imageFilename, // ← ini sekarang PASTI terdefinisi
 		CreatedAt:        now,
 		UpdatedAt:        now,
 	}
 
-	if err := server.DB.Create(&product).Error; err != nil {
+	err = server.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&product).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&product).Association("Categories").Replace(categories); err != nil {
+			return err
+		}
+		if err := createProductImages(tx, product.ID, images, ""); err != nil {
+			return err
+		}
+		return saveProductStock(tx, product.ID, stock, variants, consts.InventoryReasonInitial, admin.ID, "")
+	})
+	if err != nil {
+		server.deleteProductImageFiles(images...)
 		SetFlash(w, r, "error", "Gagal menyimpan produk: "+err.Error())
 		http.Redirect(w, r, "/admin/products/new", http.StatusSeeOther)
 		return
@@ -449,16 +431,7 @@ func (server *Server) AdminProductsCreate(w http.ResponseWriter, r *http.Request
 
 // GET /admin/products/{id}/edit
 func (server *Server) AdminProductsEdit(w http.ResponseWriter, r *http.Request) {
-	if !IsLoggedIn(r) {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
-		return
-	}
 	admin := server.CurrentUser(w, r)
-	if !IsAdminUser(admin) {
-		SetFlash(w, r, "error", "Unauthorized")
-		http.Redirect(w, r, "/", http.StatusSeeOther)
-		return
-	}
 
 	id := mux.Vars(r)["id"]
 
@@ -470,28 +443,31 @@ func (server *Server) AdminProductsEdit(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	ren := adminRender()
+	var assigned []models.Category
+	if err := server.DB.Model(product).Association("Categories").Find(&assigned); err != nil {
+		log.Println("product categories error:", err)
+	}
+	selected := map[string]bool{}
+	for _, c := range assigned {
+		selected[c.ID] = true
+	}
+
+	ren := adminRender(r)
 	_ = ren.HTML(w, http.StatusOK, "admin_product_form", map[string]interface{}{
-		"product":   product,
-		"user":      admin,
-		"cartCount": server.GetCartCount(w, r),
-		"isAdmin":   IsAdminUser(admin),
-		"isEdit":    true,
+		"product":            product,
+		"categoryGroups":     server.productCategoryGroups(),
+		"selectedCategories": selected,
+		"user":               admin,
+		"cartCount":          server.GetCartCount(w, r),
+		"isAdmin":            IsAdminUser(admin),
+		"isEdit":             true,
 	})
 }
 
 // POST /admin/products/{id}
 func (server *Server) AdminProductsUpdate(w http.ResponseWriter, r *http.Request) {
-	if !IsLoggedIn(r) {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
-		return
-	}
 	admin := server.CurrentUser(w, r)
-	if !IsAdminUser(admin) {
-		SetFlash(w, r, "error", "Unauthorized")
-		http.Redirect(w, r, "/", http.StatusSeeOther)
-		return
-	}
+	limitProductForm(w, r)
 
 	id := mux.Vars(r)["id"]
 
@@ -516,56 +492,71 @@ func (server *Server) AdminProductsUpdate(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	stock, err := strconv.Atoi(stockStr)
+	stock, err := parseStockField(stockStr)
 	if err != nil {
 		SetFlash(w, r, "error", "Format stok tidak valid")
 		http.Redirect(w, r, "/admin/products/"+id+"/edit", http.StatusSeeOther)
 		return
 	}
 
+	variants, err := variantInputsFromForm(r)
+	if err != nil {
+		SetFlash(w, r, "error", err.Error())
+		http.Redirect(w, r, "/admin/products/"+id+"/edit", http.StatusSeeOther)
+		return
+	}
+
+	categories, err := server.productCategoriesFromForm(r)
+	if err != nil {
+		SetFlash(w, r, "error", "Gagal mengambil kategori: "+err.Error())
+		http.Redirect(w, r, "/admin/products/"+id+"/edit", http.StatusSeeOther)
+		return
+	}
+
+	images, err := server.saveUploadedProductImages(r, product.ID, len(product.ProductImages))
+	if err != nil {
+		SetFlash(w, r, "error", "Gagal upload gambar: "+err.Error())
+		http.Redirect(w, r, "/admin/products/"+id+"/edit", http.StatusSeeOther)
+		return
+	}
+
 	product.Name = name
 	product.Slug = slug.Make(name)
 	product.Sku = slug.Make(name)
 	product.Price = price
-	product.Stock = stock
+	product.SizeOptions = r.FormValue("size_options")
+	product.ColorOptions = r.FormValue("color_options")
+	product.TaxClass = taxClassFromForm(r)
 	product.ShortDescription = shortDesc
 	product.Description = desc
 	product.UpdatedAt = time.Now()
 
-	if err := server.DB.Save(product).Error; err != nil {
+	// stok tidak ikut di-Save: perubahan stok lewat ledger supaya tercatat
+	var removed []models.ProductImage
+	err = server.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("stock", "Variants", "ProductImages").Save(product).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(product).Association("Categories").Replace(categories); err != nil {
+			return err
+		}
+
+		var err error
+		if removed, err = models.DeleteProductImages(tx, product.ID, r.Form["delete_image"]); err != nil {
+			return err
+		}
+		if err := createProductImages(tx, product.ID, images, r.FormValue("primary_image"), r.Form["image_order"]...); err != nil {
+			return err
+		}
+		return saveProductStock(tx, product.ID, stock, variants, consts.InventoryReasonAdjustment, admin.ID, r.FormValue("stock_note"))
+	})
+	if err != nil {
+		server.deleteProductImageFiles(images...)
 		SetFlash(w, r, "error", "Gagal mengubah produk: "+err.Error())
 		http.Redirect(w, r, "/admin/products/"+id+"/edit", http.StatusSeeOther)
 		return
 	}
-
-	// --- UPLOAD GAMBAR (OPSIONAL) ---
-	file, header, err := r.FormFile("image")
-	if err == nil {
-		defer file.Close()
-
-		if err := os.MkdirAll("public/uploads", 0755); err != nil {
-			log.Println("mkdir uploads error:", err)
-		} else {
-			ext := filepath.Ext(header.Filename)
-			newFilename := uuid.New().String() + ext
-
-			dstPath := filepath.Join("public/uploads", newFilename)
-			dst, err := os.Create(dstPath)
-			if err != nil {
-				log.Println("create file error:", err)
-			} else {
-				defer dst.Close()
-				if _, err := io.Copy(dst, file); err != nil {
-					log.Println("copy file error:", err)
-				} else {
-					// kalau berhasil, update kolom image
-					product.Image = newFilename
-				}
-			}
-		}
-	} else if err != http.ErrMissingFile {
-		log.Println("FormFile image error:", err)
-	}
+	server.deleteProductImageFiles(removed...)
 
 	SetFlash(w, r, "success", "Produk berhasil diubah")
 	http.Redirect(w, r, "/admin/products", http.StatusSeeOther)
@@ -573,34 +564,180 @@ func (server *Server) AdminProductsUpdate(w http.ResponseWriter, r *http.Request
 
 // POST /admin/products/{id}/delete
 func (server *Server) AdminProductsDelete(w http.ResponseWriter, r *http.Request) {
-	if !IsLoggedIn(r) {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
-		return
-	}
-	admin := server.CurrentUser(w, r)
-	if !IsAdminUser(admin) {
-		SetFlash(w, r, "error", "Unauthorized")
-		http.Redirect(w, r, "/", http.StatusSeeOther)
+	id := mux.Vars(r)["id"]
+
+	var product models.Product
+	if err := server.DB.Where("id = ?", id).First(&product).Error; err != nil {
+		SetFlash(w, r, "error", "Produk tidak ditemukan")
+		http.Redirect(w, r, "/admin/products", http.StatusSeeOther)
 		return
 	}
 
-	id := mux.Vars(r)["id"]
+	// gambar ikut dihapus (baris & file); produk sendiri soft delete
+	var removed []models.ProductImage
+	err := server.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if removed, err = models.DeleteAllProductImages(tx, id); err != nil {
+			return err
+		}
+		return tx.Delete(&product).Error
+	})
 
-	if err := server.DB.Where("id = ?", id).Delete(&models.Product{}).Error; err != nil {
+	if err != nil {
 		SetFlash(w, r, "error", "Gagal menghapus produk: "+err.Error())
 	} else {
+		server.deleteProductImageFiles(removed...)
+		server.deleteLegacyProductImage(&product)
 		SetFlash(w, r, "success", "Produk berhasil dihapus")
 	}
 
 	http.Redirect(w, r, "/admin/products", http.StatusSeeOther)
 }
 
+// GET /admin/products/{id}/inventory
+func (server *Server) AdminProductInventory(w http.ResponseWriter, r *http.Request) {
+	admin := server.CurrentUser(w, r)
+
+	id := mux.Vars(r)["id"]
+
+	productModel := models.Product{}
+	product, err := productModel.FindByID(server.DB, id)
+	if err != nil {
+		SetFlash(w, r, "error", "Produk tidak ditemukan")
+		http.Redirect(w, r, "/admin/products", http.StatusSeeOther)
+		return
+	}
+
+	movementModel := models.InventoryMovement{}
+	movements, err := movementModel.ListByProductID(server.DB, product.ID, 200)
+	if err != nil {
+		SetFlash(w, r, "error", "Gagal mengambil riwayat stok: "+err.Error())
+	}
+
+	ren := adminRender(r)
+	_ = ren.HTML(w, http.StatusOK, "admin_product_inventory", map[string]interface{}{
+		"product":   product,
+		"movements": movements,
+		"user":      admin,
+		"cartCount": server.GetCartCount(w, r),
+		"isAdmin":   IsAdminUser(admin),
+		"error":     GetFlash(w, r, "error"),
+	})
+}
+
+// saveProductStock: simpan matrix varian, lalu stok level produk kalau produk tidak punya varian.
+// Produk bervarian: Product.Stock otomatis = total stok varian.
+func saveProductStock(tx *gorm.DB, productID string, stock int, variants []models.ProductVariantInput, reason, actorID, note string) error {
+	if err := models.SyncProductVariants(tx, productID, variants, reason, actorID, note); err != nil {
+		return err
+	}
+
+	count, err := models.CountProductVariants(tx, productID)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	return models.SetStock(tx, productID, "", stock, reason, actorID, note)
+}
+
+// createProductImages: simpan baris gambar baru lalu atur urutan & gambar utama galeri
+func createProductImages(tx *gorm.DB, productID string, images []models.ProductImage, primaryID string, order ...string) error {
+	for i := range images {
+		if err := tx.Omit("Product").Create(&images[i]).Error; err != nil {
+			return err
+		}
+	}
+	return models.ArrangeProductImages(tx, productID, order, primaryID)
+}
+
+// kelas pajak produk, selain "exempt" dianggap kena pajak
+func taxClassFromForm(r *http.Request) string {
+	if r.FormValue("tax_class") == consts.TaxClassExempt {
+		return consts.TaxClassExempt
+	}
+	return consts.TaxClassTaxable
+}
+
+// stok boleh kosong (produk bervarian), kosong = 0
+func parseStockField(raw string) (int, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(raw)
+}
+
+// variantInputsFromForm: baca matrix varian (variant_id[], variant_size[], ...) dari form produk
+func variantInputsFromForm(r *http.Request) ([]models.ProductVariantInput, error) {
+	ids := r.Form["variant_id"]
+	sizes := r.Form["variant_size"]
+	colors := r.Form["variant_color"]
+	skus := r.Form["variant_sku"]
+	prices := r.Form["variant_price"]
+	weights := r.Form["variant_weight"]
+	stocks := r.Form["variant_stock"]
+	deletes := r.Form["variant_delete"]
+
+	at := func(values []string, i int) string {
+		if i < len(values) {
+			return strings.TrimSpace(values[i])
+		}
+		return ""
+	}
+
+	var inputs []models.ProductVariantInput
+	for i := range ids {
+		in := models.ProductVariantInput{
+			ID:     at(ids, i),
+			Size:   at(sizes, i),
+			Color:  at(colors, i),
+			Sku:    at(skus, i),
+			Delete: at(deletes, i) == "1",
+		}
+
+		// baris baru yang kosong diabaikan
+		if in.ID == "" && in.Size == "" && in.Color == "" {
+			continue
+		}
+
+		if raw := at(prices, i); raw != "" {
+			price, err := decimal.NewFromString(raw)
+			if err != nil {
+				return nil, fmt.Errorf("format harga varian %s tidak valid", in.Size+" "+in.Color)
+			}
+			in.Price = decimal.NewNullDecimal(price)
+		}
+
+		if raw := at(weights, i); raw != "" {
+			weight, err := decimal.NewFromString(raw)
+			if err != nil {
+				return nil, fmt.Errorf("format berat varian %s tidak valid", in.Size+" "+in.Color)
+			}
+			in.Weight = decimal.NewNullDecimal(weight)
+		}
+
+		stock, err := parseStockField(at(stocks, i))
+		if err != nil || stock < 0 {
+			return nil, fmt.Errorf("format stok varian %s tidak valid", in.Size+" "+in.Color)
+		}
+		in.Stock = stock
+
+		inputs = append(inputs, in)
+	}
+
+	return inputs, nil
+}
+
 // Helper kecil kalau kamu ingin menghitung ulang grand total di admin, contoh bila mau koreksi ongkir
 func calcGrand(base decimal.Decimal, shipping decimal.Decimal) decimal.Decimal {
 	return base.Add(shipping)
 }
 
-func adminRender() *render.Render {
+// adminRender: r dipakai untuk helper {{ csrfField }} di form
+func adminRender(r *http.Request) *render.Render {
 	funcMap := template.FuncMap{
 		"formatRupiah": formatRupiah,
 
@@ -639,6 +776,6 @@ func adminRender() *render.Render {
 		Directory:  "templates",
 		Layout:     "layout", // pakai layout utama yang sudah ada
 		Extensions: []string{".html", ".tmpl"},
-		Funcs:      []template.FuncMap{funcMap},
+		Funcs:      []template.FuncMap{funcMap, csrfTemplateFuncs(r)},
 	})
 }
@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/alirogz/goshop/app/consts"
+	"github.com/alirogz/goshop/app/models"
+	"github.com/gorilla/mux"
+)
+
+// jumlah notifikasi terbaru yang ditampilkan di halaman admin
+const adminNotificationsLimit = 100
+
+// GET /admin/notifications?status=pending|sent|failed
+func (server *Server) AdminNotificationsIndex(w http.ResponseWriter, r *http.Request) {
+	admin := server.CurrentUser(w, r)
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case consts.NotificationStatusPending, consts.NotificationStatusSent, consts.NotificationStatusFailed:
+	default:
+		status = ""
+	}
+
+	notifications, total, err := models.ListNotifications(server.DB, status, adminNotificationsLimit, 0)
+	if err != nil {
+		log.Println("ListNotifications error:", err)
+		SetFlash(w, r, "error", "Gagal mengambil data notifikasi.")
+	}
+
+	ren := adminRender(r)
+	_ = ren.HTML(w, http.StatusOK, "admin_notifications", map[string]interface{}{
+		"notifications": notifications,
+		"total":         total,
+		"status":        status,
+		"user":          admin,
+		"isAdmin":       IsAdminUser(admin),
+		"cartCount":     server.GetCartCount(w, r),
+		"success":       GetFlash(w, r, "success"),
+		"error":         GetFlash(w, r, "error"),
+	})
+}
+
+// POST /admin/notifications/{id}/resend
+func (server *Server) AdminNotificationResend(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	back := r.FormValue("redirect")
+	if back == "" || back[0] != '/' || (len(back) > 1 && back[1] == '/') {
+		back = "/admin/notifications"
+	}
+
+	if err := models.ResendNotification(server.DB, id); err != nil {
+		if !errors.Is(err, models.ErrNotificationNotFound) {
+			log.Println("ResendNotification error:", err)
+		}
+		SetFlash(w, r, "error", "Gagal mengantrekan ulang email: "+err.Error())
+		http.Redirect(w, r, back, http.StatusSeeOther)
+		return
+	}
+
+	SetFlash(w, r, "success", "Email dimasukkan lagi ke antrean dan akan dikirim dalam beberapa saat.")
+	http.Redirect(w, r, back, http.StatusSeeOther)
+}
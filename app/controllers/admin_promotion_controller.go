@@ -0,0 +1,305 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alirogz/goshop/app/consts"
+	"github.com/alirogz/goshop/app/models"
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+)
+
+// GET /admin/promotions
+func (server *Server) AdminPromotionsIndex(w http.ResponseWriter, r *http.Request) {
+	admin := server.CurrentUser(w, r)
+
+	var promotions []models.Promotion
+	if err := server.DB.Preload("Category").Order("created_at desc").Find(&promotions).Error; err != nil {
+		SetFlash(w, r, "error", "Gagal mengambil data promo: "+err.Error())
+	}
+
+	ren := adminRender(r)
+	_ = ren.HTML(w, http.StatusOK, "admin_promotions", map[string]interface{}{
+		"promotions": promotions,
+		"user":       admin,
+		"cartCount":  server.GetCartCount(w, r),
+		"isAdmin":    IsAdminUser(admin),
+		"success":    GetFlash(w, r, "success"),
+		"error":      GetFlash(w, r, "error"),
+	})
+}
+
+// GET /admin/promotions/new
+func (server *Server) AdminPromotionsNew(w http.ResponseWriter, r *http.Request) {
+	admin := server.CurrentUser(w, r)
+
+	server.renderPromotionForm(w, r, admin, models.Promotion{IsActive: true, Type: consts.PromotionTypePercent}, false)
+}
+
+// POST /admin/promotions
+func (server *Server) AdminPromotionsCreate(w http.ResponseWriter, r *http.Request) {
+	promo := models.Promotion{}
+	if err := server.promotionFromForm(r, &promo); err != nil {
+		SetFlash(w, r, "error", err.Error())
+		http.Redirect(w, r, "/admin/promotions/new", http.StatusSeeOther)
+		return
+	}
+
+	if err := server.DB.Create(&promo).Error; err != nil {
+		SetFlash(w, r, "error", "Gagal menyimpan promo: "+err.Error())
+		http.Redirect(w, r, "/admin/promotions/new", http.StatusSeeOther)
+		return
+	}
+
+	SetFlash(w, r, "success", "Promo berhasil dibuat")
+	http.Redirect(w, r, "/admin/promotions", http.StatusSeeOther)
+}
+
+// GET /admin/promotions/{id}/edit
+func (server *Server) AdminPromotionsEdit(w http.ResponseWriter, r *http.Request) {
+	admin := server.CurrentUser(w, r)
+
+	var promo models.Promotion
+	if err := server.DB.Where("id = ?", mux.Vars(r)["id"]).First(&promo).Error; err != nil {
+		SetFlash(w, r, "error", "Promo tidak ditemukan")
+		http.Redirect(w, r, "/admin/promotions", http.StatusSeeOther)
+		return
+	}
+
+	server.renderPromotionForm(w, r, admin, promo, true)
+}
+
+// POST /admin/promotions/{id}
+func (server *Server) AdminPromotionsUpdate(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var promo models.Promotion
+	if err := server.DB.Where("id = ?", id).First(&promo).Error; err != nil {
+		SetFlash(w, r, "error", "Promo tidak ditemukan")
+		http.Redirect(w, r, "/admin/promotions", http.StatusSeeOther)
+		return
+	}
+
+	if err := server.promotionFromForm(r, &promo); err != nil {
+		SetFlash(w, r, "error", err.Error())
+		http.Redirect(w, r, "/admin/promotions/"+id+"/edit", http.StatusSeeOther)
+		return
+	}
+
+	// used_count hanya diubah oleh checkout / pembatalan order
+	if err := server.DB.Omit("used_count", "Category").Save(&promo).Error; err != nil {
+		SetFlash(w, r, "error", "Gagal mengubah promo: "+err.Error())
+		http.Redirect(w, r, "/admin/promotions/"+id+"/edit", http.StatusSeeOther)
+		return
+	}
+
+	SetFlash(w, r, "success", "Promo berhasil diubah")
+	http.Redirect(w, r, "/admin/promotions", http.StatusSeeOther)
+}
+
+// POST /admin/promotions/{id}/delete
+func (server *Server) AdminPromotionsDelete(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	// soft delete: riwayat pemakaian di laporan tetap ada
+	if err := server.DB.Where("id = ?", id).Delete(&models.Promotion{}).Error; err != nil {
+		SetFlash(w, r, "error", "Gagal menghapus promo: "+err.Error())
+	} else {
+		SetFlash(w, r, "success", "Promo berhasil dihapus")
+	}
+
+	http.Redirect(w, r, "/admin/promotions", http.StatusSeeOther)
+}
+
+// GET /admin/promotions/report?from=2025-01-01&to=2025-01-31&promotion_id=...
+func (server *Server) AdminPromotionsReport(w http.ResponseWriter, r *http.Request) {
+	admin := server.CurrentUser(w, r)
+
+	q := r.URL.Query()
+	dateFrom := q.Get("from")
+	dateTo := q.Get("to")
+	promotionID := q.Get("promotion_id")
+
+	var from, to time.Time
+	if t, err := time.Parse("2006-01-02", dateFrom); err == nil {
+		from = t
+	}
+	if t, err := time.Parse("2006-01-02", dateTo); err == nil {
+		// tambah 1 hari biar inclusive
+		to = t.Add(24 * time.Hour)
+	}
+
+	rows, err := models.PromotionReport(server.DB, from, to)
+	if err != nil {
+		SetFlash(w, r, "error", "Gagal membuat laporan: "+err.Error())
+	}
+
+	redemptionModel := models.PromotionRedemption{}
+	redemptions, err := redemptionModel.ListRecent(server.DB, promotionID, 100)
+	if err != nil {
+		SetFlash(w, r, "error", "Gagal mengambil riwayat pemakaian: "+err.Error())
+	}
+
+	totalDiscount := decimal.Zero
+	totalShipping := decimal.Zero
+	for _, row := range rows {
+		totalDiscount = totalDiscount.Add(row.DiscountTotal)
+		totalShipping = totalShipping.Add(row.ShippingTotal)
+	}
+
+	ren := adminRender(r)
+	_ = ren.HTML(w, http.StatusOK, "admin_promotion_report", map[string]interface{}{
+		"rows":          rows,
+		"redemptions":   redemptions,
+		"totalDiscount": totalDiscount,
+		"totalShipping": totalShipping,
+		"dateFrom":      dateFrom,
+		"dateTo":        dateTo,
+		"promotionID":   promotionID,
+		"user":          admin,
+		"cartCount":     server.GetCartCount(w, r),
+		"isAdmin":       IsAdminUser(admin),
+		"error":         GetFlash(w, r, "error"),
+	})
+}
+
+func (server *Server) renderPromotionForm(w http.ResponseWriter, r *http.Request, admin *models.User, promo models.Promotion, isEdit bool) {
+	var categories []models.Category
+	server.DB.Order("name asc").Find(&categories)
+
+	ren := adminRender(r)
+	_ = ren.HTML(w, http.StatusOK, "admin_promotion_form", map[string]interface{}{
+		"promotion":  promo,
+		"categories": categories,
+		"types": []map[string]string{
+			{"Value": consts.PromotionTypePercent, "Label": "Diskon persen (%)"},
+			{"Value": consts.PromotionTypeFixed, "Label": "Potongan nominal (Rp)"},
+			{"Value": consts.PromotionTypeFreeShipping, "Label": "Gratis ongkir"},
+		},
+		"user":      admin,
+		"cartCount": server.GetCartCount(w, r),
+		"isAdmin":   IsAdminUser(admin),
+		"isEdit":    isEdit,
+		"error":     GetFlash(w, r, "error"),
+	})
+}
+
+// promotionFromForm: isi & validasi field promo dari form admin
+func (server *Server) promotionFromForm(r *http.Request, promo *models.Promotion) error {
+	promo.Name = strings.TrimSpace(r.FormValue("name"))
+	promo.Code = models.NormalizeCouponCode(r.FormValue("code"))
+	promo.Description = strings.TrimSpace(r.FormValue("description"))
+	promo.Type = r.FormValue("type")
+	promo.CategoryID = r.FormValue("category_id")
+	promo.IsActive = r.FormValue("is_active") == "on"
+
+	if promo.Name == "" {
+		return errors.New("nama promo wajib diisi")
+	}
+
+	switch promo.Type {
+	case consts.PromotionTypePercent, consts.PromotionTypeFixed, consts.PromotionTypeFreeShipping:
+	default:
+		return errors.New("jenis promo tidak valid")
+	}
+
+	var err error
+	if promo.Value, err = parseDecimalField(r.FormValue("value")); err != nil {
+		return errors.New("format nilai promo tidak valid")
+	}
+	if promo.MaxDiscount, err = parseDecimalField(r.FormValue("max_discount")); err != nil {
+		return errors.New("format maksimal diskon tidak valid")
+	}
+	if promo.MinSpend, err = parseDecimalField(r.FormValue("min_spend")); err != nil {
+		return errors.New("format minimal belanja tidak valid")
+	}
+
+	if promo.Type == consts.PromotionTypePercent && (!promo.Value.IsPositive() || promo.Value.GreaterThan(decimal.NewFromInt(100))) {
+		return errors.New("diskon persen harus di antara 0 dan 100")
+	}
+	if promo.Type == consts.PromotionTypeFixed && !promo.Value.IsPositive() {
+		return errors.New("potongan nominal harus lebih dari 0")
+	}
+
+	if promo.MinQty, err = parseIntField(r.FormValue("min_qty")); err != nil {
+		return errors.New("format minimal qty tidak valid")
+	}
+	if promo.UsageLimit, err = parseIntField(r.FormValue("usage_limit")); err != nil {
+		return errors.New("format kuota total tidak valid")
+	}
+	if promo.UsagePerCustomer, err = parseIntField(r.FormValue("usage_per_customer")); err != nil {
+		return errors.New("format kuota per customer tidak valid")
+	}
+
+	if promo.StartsAt, err = parseDateField(r.FormValue("starts_at"), false); err != nil {
+		return errors.New("format tanggal mulai tidak valid")
+	}
+	if promo.EndsAt, err = parseDateField(r.FormValue("ends_at"), true); err != nil {
+		return errors.New("format tanggal selesai tidak valid")
+	}
+	if promo.StartsAt.Valid && promo.EndsAt.Valid && promo.EndsAt.Time.Before(promo.StartsAt.Time) {
+		return errors.New("tanggal selesai tidak boleh sebelum tanggal mulai")
+	}
+
+	// kode kupon harus unik di antara promo yang masih ada
+	if promo.Code != "" {
+		var count int64
+		q := server.DB.Model(&models.Promotion{}).Where("code = ?", promo.Code)
+		if promo.ID != "" {
+			q = q.Where("id <> ?", promo.ID)
+		}
+		q.Count(&count)
+		if count > 0 {
+			return errors.New("kode kupon " + promo.Code + " sudah dipakai promo lain")
+		}
+	}
+
+	return nil
+}
+
+// kosong = 0
+func parseDecimalField(raw string) (decimal.Decimal, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return decimal.Zero, nil
+	}
+	d, err := decimal.NewFromString(raw)
+	if err != nil || d.IsNegative() {
+		return decimal.Zero, errors.New("invalid")
+	}
+	return d, nil
+}
+
+// kosong = 0
+func parseIntField(raw string) (int, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		return 0, errors.New("invalid")
+	}
+	return n, nil
+}
+
+// parseDateField: "2006-01-02" → awal hari, atau akhir hari kalau endOfDay
+func parseDateField(raw string, endOfDay bool) (sql.NullTime, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return sql.NullTime{}, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", raw, time.Local)
+	if err != nil {
+		return sql.NullTime{}, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Second)
+	}
+	return sql.NullTime{Time: t, Valid: true}, nil
+}
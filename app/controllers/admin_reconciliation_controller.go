@@ -0,0 +1,209 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/alirogz/goshop/app/consts"
+	"github.com/alirogz/goshop/app/models"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// reconcileRow: 1 mutasi belum dipasangkan + kandidat order-nya
+type reconcileRow struct {
+	Tx         models.BankTransaction
+	Candidates []models.MatchCandidate
+}
+
+// GET /admin/payments/reconcile
+func (server *Server) AdminReconcileIndex(w http.ResponseWriter, r *http.Request) {
+	admin := server.CurrentUser(w, r)
+
+	txs, err := models.UnmatchedBankTransactions(server.DB, 50)
+	if err != nil {
+		log.Println("UnmatchedBankTransactions error:", err)
+	}
+	orders, err := models.UnpaidTransferOrders(server.DB)
+	if err != nil {
+		log.Println("UnpaidTransferOrders error:", err)
+	}
+	suggestions, err := models.SuggestMatches(server.DB, txs, orders, 3)
+	if err != nil {
+		log.Println("SuggestMatches error:", err)
+	}
+
+	rows := make([]reconcileRow, 0, len(txs))
+	for _, tx := range txs {
+		rows = append(rows, reconcileRow{Tx: tx, Candidates: suggestions[tx.ID]})
+	}
+
+	// pasangan terbaru (untuk dilepas kalau salah)
+	var matched []models.BankTransaction
+	server.DB.Where("matched = ?", true).Order("matched_at DESC").Limit(20).Find(&matched)
+	orderCodes := map[string]string{}
+	if len(matched) > 0 {
+		ids := make([]string, 0, len(matched))
+		for _, tx := range matched {
+			ids = append(ids, tx.MatchedOrder)
+		}
+		var matchedOrders []models.Order
+		server.DB.Select("id", "code").Where("id IN ?", ids).Find(&matchedOrders)
+		for _, o := range matchedOrders {
+			orderCodes[o.ID] = o.Code
+		}
+	}
+
+	logs, err := models.RecentReconciliationLogs(server.DB, 30)
+	if err != nil {
+		log.Println("RecentReconciliationLogs error:", err)
+	}
+
+	ren := adminRender(r)
+	_ = ren.HTML(w, http.StatusOK, "admin_reconcile", map[string]interface{}{
+		"rows":       rows,
+		"matched":    matched,
+		"orderCodes": orderCodes,
+		"logs":       logs,
+		"minScore":   models.AutoMatchMinScore,
+		"user":       admin,
+		"cartCount":  server.GetCartCount(w, r),
+		"isAdmin":    IsAdminUser(admin),
+		"success":    GetFlash(w, r, "success"),
+		"error":      GetFlash(w, r, "error"),
+	})
+}
+
+// POST /admin/payments/reconcile/{id}/confirm
+// konfirmasi saran pasangan (order_id dari daftar kandidat)
+func (server *Server) AdminReconcileConfirm(w http.ResponseWriter, r *http.Request) {
+	server.reconcilePair(w, r, consts.ReconcileConfirm)
+}
+
+// POST /admin/payments/reconcile/{id}/pair
+// pasangkan manual dengan kode / ID order yang diketik admin
+func (server *Server) AdminReconcilePair(w http.ResponseWriter, r *http.Request) {
+	server.reconcilePair(w, r, consts.ReconcileManual)
+}
+
+func (server *Server) reconcilePair(w http.ResponseWriter, r *http.Request, action string) {
+	admin := server.CurrentUser(w, r)
+
+	txID, ok := reconcileTxID(r)
+	if !ok {
+		SetFlash(w, r, "error", "Mutasi tidak valid")
+		http.Redirect(w, r, "/admin/payments/reconcile", http.StatusSeeOther)
+		return
+	}
+
+	orderID := strings.TrimSpace(r.FormValue("order_id"))
+	if action == consts.ReconcileManual {
+		order, err := findOrderByCodeOrID(server.DB, strings.TrimSpace(r.FormValue("order")))
+		if err != nil {
+			SetFlash(w, r, "error", "Order dengan kode / ID tersebut tidak ditemukan")
+			http.Redirect(w, r, "/admin/payments/reconcile", http.StatusSeeOther)
+			return
+		}
+		orderID = order.ID
+	}
+	if orderID == "" {
+		SetFlash(w, r, "error", "Order wajib dipilih")
+		http.Redirect(w, r, "/admin/payments/reconcile", http.StatusSeeOther)
+		return
+	}
+
+	// kurang bayar hanya bisa dipasangkan manual, dengan catatan (tercatat di log rekonsiliasi)
+	note := strings.TrimSpace(r.FormValue("note"))
+	acceptShort := action == consts.ReconcileManual && r.FormValue("accept_short") == "1"
+	settled, err := models.PairBankTransaction(server.DB, txID, orderID, action, admin.ID, note, acceptShort)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = errors.New("mutasi / order tidak ditemukan")
+		}
+		SetFlash(w, r, "error", "Gagal memasangkan mutasi: "+err.Error())
+		http.Redirect(w, r, "/admin/payments/reconcile", http.StatusSeeOther)
+		return
+	}
+
+	message := "Mutasi #" + strconv.FormatUint(uint64(txID), 10) + " dipasangkan, order ditandai lunas"
+	if !settled {
+		message = "Mutasi #" + strconv.FormatUint(uint64(txID), 10) + " dicatat sebagai pembayaran sebagian, order belum lunas"
+	}
+	SetFlash(w, r, "success", message)
+	http.Redirect(w, r, "/admin/payments/reconcile", http.StatusSeeOther)
+}
+
+// POST /admin/payments/reconcile/{id}/reject
+// tolak saran pasangan supaya tidak muncul lagi
+func (server *Server) AdminReconcileReject(w http.ResponseWriter, r *http.Request) {
+	admin := server.CurrentUser(w, r)
+
+	txID, ok := reconcileTxID(r)
+	orderID := strings.TrimSpace(r.FormValue("order_id"))
+	if !ok || orderID == "" {
+		SetFlash(w, r, "error", "Saran pasangan tidak valid")
+		http.Redirect(w, r, "/admin/payments/reconcile", http.StatusSeeOther)
+		return
+	}
+
+	if err := models.RejectMatch(server.DB, txID, orderID, admin.ID, strings.TrimSpace(r.FormValue("note"))); err != nil {
+		log.Println("RejectMatch error:", err)
+		SetFlash(w, r, "error", "Gagal menolak saran")
+		http.Redirect(w, r, "/admin/payments/reconcile", http.StatusSeeOther)
+		return
+	}
+
+	SetFlash(w, r, "success", "Saran pasangan ditolak")
+	http.Redirect(w, r, "/admin/payments/reconcile", http.StatusSeeOther)
+}
+
+// POST /admin/payments/reconcile/{id}/unmatch
+// lepas pasangan yang salah; pembayaran order ikut dibatalkan
+func (server *Server) AdminReconcileUnmatch(w http.ResponseWriter, r *http.Request) {
+	admin := server.CurrentUser(w, r)
+
+	txID, ok := reconcileTxID(r)
+	if !ok {
+		SetFlash(w, r, "error", "Mutasi tidak valid")
+		http.Redirect(w, r, "/admin/payments/reconcile", http.StatusSeeOther)
+		return
+	}
+
+	note := strings.TrimSpace(r.FormValue("note"))
+	if note == "" {
+		note = "Pasangan salah"
+	}
+	if err := models.UnmatchBankTransaction(server.DB, txID, admin.ID, note); err != nil {
+		SetFlash(w, r, "error", "Gagal melepas pasangan: "+err.Error())
+		http.Redirect(w, r, "/admin/payments/reconcile", http.StatusSeeOther)
+		return
+	}
+
+	SetFlash(w, r, "success", "Pasangan dilepas, order kembali menunggu pembayaran")
+	http.Redirect(w, r, "/admin/payments/reconcile", http.StatusSeeOther)
+}
+
+func reconcileTxID(r *http.Request) (uint, bool) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id == 0 {
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// findOrderByCodeOrID: admin biasanya mengetik kode order (contoh 12/ORDER/X/2026)
+func findOrderByCodeOrID(db *gorm.DB, value string) (*models.Order, error) {
+	if value == "" {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	var order models.Order
+	err := db.Where("code = ? OR id = ?", value, value).First(&order).Error
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
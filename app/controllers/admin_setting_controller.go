@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/alirogz/goshop/app/consts"
+	"github.com/alirogz/goshop/app/models"
+	"github.com/shopspring/decimal"
+)
+
+// GET /admin/settings
+func (server *Server) AdminSettings(w http.ResponseWriter, r *http.Request) {
+	admin := server.CurrentUser(w, r)
+
+	type sequenceRow struct {
+		Name    string
+		Label   string
+		Format  models.SequenceFormat
+		Example string
+	}
+	labels := map[string]string{
+		consts.SequenceOrder:    "Order",
+		consts.SequencePayment:  "Payment",
+		consts.SequenceInvoice:  "Invoice",
+		consts.SequenceShipment: "Pengiriman",
+	}
+
+	tax, err := models.LoadTaxSettings(server.DB)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	var sequences []sequenceRow
+	for _, name := range models.SequenceNames() {
+		format, err := models.LoadSequenceFormat(server.DB, name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		sequences = append(sequences, sequenceRow{
+			Name:    name,
+			Label:   labels[name],
+			Format:  format,
+			Example: format.Render(1, now),
+		})
+	}
+
+	ren := adminRender(r)
+	_ = ren.HTML(w, http.StatusOK, "admin_settings", map[string]interface{}{
+		"tax":       tax,
+		"sequences": sequences,
+		"user":      admin,
+		"cartCount": server.GetCartCount(w, r),
+		"isAdmin":   IsAdminUser(admin),
+		"success":   GetFlash(w, r, "success"),
+		"error":     GetFlash(w, r, "error"),
+	})
+}
+
+// POST /admin/settings
+// perubahan tarif hanya berlaku untuk cart & order baru, order lama tetap pakai tarif yang tersimpan
+func (server *Server) AdminSettingsUpdate(w http.ResponseWriter, r *http.Request) {
+	admin := server.CurrentUser(w, r)
+
+	rate, err := decimal.NewFromString(strings.TrimSpace(r.FormValue("tax_rate")))
+	if err != nil {
+		SetFlash(w, r, "error", "Format tarif pajak tidak valid")
+		http.Redirect(w, r, "/admin/settings", http.StatusSeeOther)
+		return
+	}
+
+	settings := models.TaxSettings{
+		Rate:             rate,
+		PricesIncludeTax: r.FormValue("prices_include_tax") == "on",
+	}
+	if err := models.SaveTaxSettings(server.DB, settings, admin.ID); err != nil {
+		SetFlash(w, r, "error", "Gagal menyimpan pengaturan: "+err.Error())
+		http.Redirect(w, r, "/admin/settings", http.StatusSeeOther)
+		return
+	}
+
+	// format nomor dokumen (hanya yang dikirim form)
+	for _, name := range models.SequenceNames() {
+		pattern := strings.TrimSpace(r.FormValue("sequence_" + name + "_pattern"))
+		if pattern == "" {
+			continue
+		}
+		format := models.SequenceFormat{Pattern: pattern, Reset: r.FormValue("sequence_" + name + "_reset")}
+		if err := models.SaveSequenceFormat(server.DB, name, format, admin.ID); err != nil {
+			SetFlash(w, r, "error", "Gagal menyimpan format nomor: "+err.Error())
+			http.Redirect(w, r, "/admin/settings", http.StatusSeeOther)
+			return
+		}
+	}
+
+	SetFlash(w, r, "success", "Pengaturan berhasil disimpan")
+	http.Redirect(w, r, "/admin/settings", http.StatusSeeOther)
+}
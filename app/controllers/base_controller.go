@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -13,8 +14,15 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/alirogz/goshop/app/attachments"
+	"github.com/alirogz/goshop/app/consts"
+	"github.com/alirogz/goshop/app/mailer"
 	"github.com/alirogz/goshop/app/models"
+	"github.com/alirogz/goshop/app/payment"
+	"github.com/alirogz/goshop/app/search"
+	"github.com/alirogz/goshop/app/storage"
 	"github.com/alirogz/goshop/database/seeders"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/sessions"
@@ -27,16 +35,63 @@ import (
 )
 
 type Server struct {
-	DB        *gorm.DB
-	Router    *mux.Router
-	AppConfig *AppConfig
+	DB             *gorm.DB
+	Router         *mux.Router
+	AppConfig      *AppConfig
+	PaymentGateway payment.PaymentGateway
+	FakePayment    *payment.FakeServer // hanya terisi kalau PAYMENT_GATEWAY=fake
+	Mailer         mailer.Mailer
+	FakeMail       *mailer.FakeSMTPServer // hanya terisi kalau MAIL_DRIVER=fake
+	ChatHub        *ChatHub               // push realtime chat (WebSocket / SSE) + cache badge unread
+	Uploads        storage.Blob           // file publik: gambar produk (disajikan di /uploads/)
+	Files          storage.Blob           // file privat: hanya dibuka lewat handler yang cek hak akses
+	FakeStorage    *storage.FakeS3        // hanya terisi kalau STORAGE_DRIVER=fake
+	Attachments    *attachments.Store     // lampiran chat (privat, di dalam Files)
+	PaymentProofs  *attachments.Store     // bukti bayar (privat, di dalam Files)
+	Search         *search.Engine         // pencarian produk (FULLTEXT MySQL / tsvector Postgres)
 }
 
 type AppConfig struct {
 	AppName string
-	AppEnv  string
+	AppEnv  string // "development" / "local" = mode dev (kunci session & mailer log boleh dipakai), selain itu production
 	AppPort string
 	AppURL  string
+
+	// payment gateway: "midtrans", "fake" (lokal) atau kosong (hanya transfer manual)
+	PaymentGateway    string
+	MidtransServerKey string
+	MidtransBaseURL   string
+
+	// batas bayar: worker jalan tiap PaymentExpiryInterval (0 = mati),
+	// pengingat dikirim PaymentReminderBefore sebelum PaymentDue (0 = tanpa pengingat)
+	PaymentExpiryInterval time.Duration
+	PaymentReminderBefore time.Duration
+
+	// outbox email dikirim worker tiap NotificationInterval (0 = mati, pakai cron "notifications:send")
+	NotificationInterval time.Duration
+
+	// email: "smtp", "fake" (server SMTP palsu lokal) atau kosong (email hanya dicetak ke log)
+	MailDriver   string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	MailFrom     string
+
+	// penyimpanan file upload: "local" (folder di disk, default), "s3" (S3-compatible: AWS, MinIO, R2, ...)
+	// atau "fake" (tiruan S3 di memori, dipasang di /storage/fake, untuk development)
+	StorageDriver     string
+	StorageUploadDir  string // local: file publik (gambar produk), disajikan di /uploads/
+	StoragePrivateDir string // local: file privat (lampiran chat, bukti bayar), di luar /public
+	S3Endpoint        string
+	S3Region          string
+	S3Bucket          string
+	S3AccessKey       string
+	S3SecretKey       string
+	S3PublicURL       string // opsional: URL publik / CDN bucket untuk file di /uploads/
+
+	// folder lampiran chat sebelum ada STORAGE_*, hanya dibaca storage:migrate
+	ChatAttachmentDir string
 }
 
 type DBConfig struct {
@@ -68,6 +123,7 @@ type PaginationParams struct {
 	TotalRows   int32
 	PerPage     int32
 	CurrentPage int32
+	Query       url.Values // parameter lain (pencarian/filter) yang ikut di setiap link, opsional
 }
 
 type Result struct {
@@ -82,13 +138,25 @@ var sessionShoppingCart = "shopping-cart-session"
 var sessionFlash = "flash-session"
 var sessionUser = "user-session"
 
-func initSessionStore() {
+// devSessionKey: kunci pengganti SESSION_KEY, hanya boleh di mode dev (dicek initializeAppConfig)
+const devSessionKey = "dev-secret-change-me"
+
+// sessionKey: kunci cookie session, juga dipakai untuk tanda tangan token email
+func sessionKey() []byte {
 	key := os.Getenv("SESSION_KEY")
 	if key == "" {
-		// fallback dev; untuk production WAJIB isi SESSION_KEY di .env
-		key = "dev-secret-change-me"
+		key = devSessionKey
 	}
-	store = sessions.NewCookieStore([]byte(key))
+	return []byte(key)
+}
+
+// IsDevelopment: APP_ENV diset eksplisit ke mode dev
+func (config *AppConfig) IsDevelopment() bool {
+	return config.AppEnv == "development" || config.AppEnv == "local"
+}
+
+func initSessionStore() {
+	store = sessions.NewCookieStore(sessionKey())
 	store.Options = &sessions.Options{
 		Path:     "/",
 		MaxAge:   86400 * 7, // 7 hari
@@ -103,8 +171,15 @@ func (server *Server) Initialize(appConfig AppConfig, dbConfig DBConfig) {
 
 	server.initializeDB(dbConfig)
 	server.initializeAppConfig(appConfig)
+	server.initializePaymentGateway()
+	server.initializeMailer()
+	server.ChatHub = newChatHub(server.DB)
+	server.initializeStorage()
+	server.Search = search.New(server.DB)
 	initSessionStore()
 	server.initializeRoutes()
+	server.startPaymentExpiryWorker()
+	server.startNotificationWorker()
 }
 
 func (server *Server) Run(addr string) {
@@ -128,9 +203,111 @@ func (server *Server) initializeDB(dbConfig DBConfig) {
 }
 
 func (server *Server) initializeAppConfig(appConfig AppConfig) {
+	// kunci dev bersifat publik: token reset password / verifikasi email bisa dipalsukan
+	if os.Getenv("SESSION_KEY") == "" && !appConfig.IsDevelopment() {
+		log.Fatal("SESSION_KEY wajib diisi (atau APP_ENV=development untuk memakai kunci dev)")
+	}
 	server.AppConfig = &appConfig
 }
 
+// initializePaymentGateway: pilih payment gateway sesuai konfigurasi.
+// Mode "fake" memasang tiruan Snap API di /payments/fake untuk development.
+func (server *Server) initializePaymentGateway() {
+	config := server.AppConfig
+	appURL := strings.TrimRight(config.AppURL, "/")
+
+	switch config.PaymentGateway {
+	case "midtrans":
+		server.PaymentGateway = payment.NewMidtransGateway(config.MidtransServerKey, config.MidtransBaseURL)
+	case "fake":
+		serverKey := config.MidtransServerKey
+		if serverKey == "" {
+			serverKey = "fake-server-key"
+		}
+		server.FakePayment = payment.NewFakeServer(serverKey, appURL+"/payments/notification", "/payments/fake")
+		server.PaymentGateway = payment.NewMidtransGateway(serverKey, appURL+"/payments/fake")
+	}
+}
+
+// initializeStorage: pilih penyimpanan file upload sesuai konfigurasi.
+// Di S3 file publik & privat ada di bucket yang sama dengan prefix "uploads/" dan "private/".
+// Mode "fake" memasang tiruan S3 di /storage/fake (isi hilang saat aplikasi restart).
+func (server *Server) initializeStorage() {
+	config := server.AppConfig
+
+	switch config.StorageDriver {
+	case "s3", "fake":
+		s3 := storage.S3{
+			Endpoint:   config.S3Endpoint,
+			Region:     config.S3Region,
+			Bucket:     config.S3Bucket,
+			AccessKey:  config.S3AccessKey,
+			SecretKey:  config.S3SecretKey,
+			HTTPClient: &http.Client{Timeout: 30 * time.Second},
+		}
+		if config.StorageDriver == "fake" {
+			if s3.AccessKey == "" {
+				s3.AccessKey, s3.SecretKey = "fake-access-key", "fake-secret-key"
+			}
+			if s3.Bucket == "" {
+				s3.Bucket = "goshop"
+			}
+			server.FakeStorage = storage.NewFakeS3(s3.AccessKey, s3.SecretKey, s3.Region, "/storage/fake")
+			s3.Endpoint = strings.TrimRight(config.AppURL, "/") + "/storage/fake"
+		}
+
+		uploads, files := s3, s3
+		uploads.Prefix, uploads.PublicURL = "uploads", config.S3PublicURL
+		files.Prefix = "private"
+		server.Uploads, server.Files = &uploads, &files
+	default:
+		uploadDir, privateDir := config.StorageUploadDir, config.StoragePrivateDir
+		if uploadDir == "" {
+			uploadDir = "public/uploads"
+		}
+		if privateDir == "" {
+			privateDir = "storage"
+		}
+		server.Uploads = storage.NewLocal(uploadDir, "/uploads", nil)
+		server.Files = storage.NewLocal(privateDir, "/files", sessionKey())
+	}
+
+	server.Attachments = &attachments.Store{Blob: storage.Prefix(server.Files, "chat_attachments"), MaxSize: chatAttachmentMaxSize}
+	server.PaymentProofs = &attachments.Store{Blob: storage.Prefix(server.Files, "payment_proofs"), MaxSize: paymentProofMaxSize}
+}
+
+// initializeMailer: pilih pengirim email sesuai konfigurasi.
+// Mode "fake" menjalankan server SMTP palsu di 127.0.0.1 dan mencetak setiap email ke log.
+func (server *Server) initializeMailer() {
+	config := server.AppConfig
+	from := config.MailFrom
+	if from == "" {
+		from = config.AppName + " <no-reply@localhost>"
+	}
+
+	switch config.MailDriver {
+	case "smtp":
+		server.Mailer = mailer.NewSMTPMailer(config.SMTPHost, config.SMTPPort, config.SMTPUsername, config.SMTPPassword, from)
+	case "fake":
+		fake, err := mailer.StartFakeSMTPServer("127.0.0.1:0")
+		if err != nil {
+			log.Fatal("fake SMTP gagal jalan:", err)
+		}
+		fake.OnMessage = func(msg mailer.ReceivedMessage) {
+			log.Printf("fake SMTP: email ke %s\n%s", strings.Join(msg.To, ", "), msg.Text())
+		}
+		host, port := fake.HostPort()
+		server.FakeMail = fake
+		server.Mailer = mailer.NewSMTPMailer(host, port, "", "", from)
+	default:
+		// LogMailer mencetak isi email (termasuk link reset password) ke log, jadi hanya untuk mode dev
+		if !config.IsDevelopment() {
+			log.Fatal("MAIL_DRIVER wajib diisi (smtp), atau APP_ENV=development untuk mencetak email ke log")
+		}
+		server.Mailer = mailer.LogMailer{}
+	}
+}
+
 func (server *Server) dbMigrate() {
 	for _, model := range models.RegisterModels() {
 		err := server.DB.Debug().AutoMigrate(model.Model)
@@ -140,11 +317,28 @@ func (server *Server) dbMigrate() {
 		}
 	}
 
+	if err := search.Migrate(server.DB); err != nil {
+		log.Fatal(err)
+	}
+
+	if n, err := models.BackfillEmailVerified(server.DB); err != nil {
+		log.Fatal(err)
+	} else if n > 0 {
+		fmt.Printf("%d akun lama ditandai email terverifikasi.\n", n)
+	}
+
 	fmt.Println("Database migrated successfully.")
+
+	if err := server.syncRoles(); err != nil {
+		log.Fatal(err)
+	}
 }
 
 func (server *Server) InitCommands(config AppConfig, dbConfig DBConfig) {
 	server.initializeDB(dbConfig)
+	server.initializeAppConfig(config)
+	server.initializeMailer()
+	server.initializeStorage()
 	initSessionStore()
 
 	cmdApp := cli.NewApp()
@@ -156,6 +350,51 @@ func (server *Server) InitCommands(config AppConfig, dbConfig DBConfig) {
 				return nil
 			},
 		},
+		{
+			Name:  "orders:expire",
+			Usage: "batalkan order yang lewat batas bayar & kirim pengingat (untuk cron)",
+			Action: func(c *cli.Context) error {
+				reminded, expired := server.runPaymentExpiry(time.Now())
+				fmt.Printf("%d pengingat dikirim, %d order dibatalkan\n", reminded, expired)
+				return nil
+			},
+		},
+		{
+			Name:  "notifications:send",
+			Usage: "kirim email notifikasi yang antre / jadwal retry-nya sudah lewat (untuk cron)",
+			Action: func(c *cli.Context) error {
+				sent, failed := server.runNotifications(time.Now())
+				fmt.Printf("%d email terkirim, %d gagal\n", sent, failed)
+				return nil
+			},
+		},
+		{
+			Name:  "storage:migrate",
+			Usage: "salin file upload lama (public/uploads, lampiran chat) ke STORAGE_DRIVER & pindahkan bukti bayar lama ke storage privat",
+			Flags: []cli.Flag{
+				cli.BoolFlag{Name: "dry-run", Usage: "hanya tampilkan file yang akan disalin"},
+				cli.BoolFlag{Name: "delete", Usage: "hapus file lama setelah berhasil disalin"},
+			},
+			Action: server.migrateStorageCommand,
+		},
+		{
+			Name:      "roles:grant",
+			Usage:     "beri role staff ke user (owner, order-staff, catalog-staff, cs-agent, finance)",
+			ArgsUsage: "<email> <role>",
+			Action:    server.grantRoleCommand,
+		},
+		{
+			Name:      "roles:revoke",
+			Usage:     "cabut role staff dari user",
+			ArgsUsage: "<email> <role>",
+			Action:    server.revokeRoleCommand,
+		},
+		{
+			Name:      "roles:list",
+			Usage:     "daftar role & hak aksesnya, atau role milik 1 user",
+			ArgsUsage: "[email]",
+			Action:    server.listRolesCommand,
+		},
 		{
 			Name: "db:seed",
 			Action: func(c *cli.Context) error {
@@ -180,10 +419,21 @@ func GetPaginationLinks(config *AppConfig, params PaginationParams) (PaginationL
 
 	totalPages := int32(math.Ceil(float64(params.TotalRows) / float64(params.PerPage)))
 
+	pageURL := func(page int32) string {
+		query := url.Values{}
+		for key, values := range params.Query {
+			if key != "page" {
+				query[key] = values
+			}
+		}
+		query.Set("page", fmt.Sprint(page))
+		return fmt.Sprintf("%s/%s?%s", config.AppURL, params.Path, query.Encode())
+	}
+
 	for i := 1; int32(i) <= totalPages; i++ {
 		links = append(links, PageLink{
 			Page:          int32(i),
-			Url:           fmt.Sprintf("%s/%s?page=%s", config.AppURL, params.Path, fmt.Sprint(i)),
+			Url:           pageURL(int32(i)),
 			IsCurrentPage: int32(i) == params.CurrentPage,
 		})
 	}
@@ -203,9 +453,9 @@ func GetPaginationLinks(config *AppConfig, params PaginationParams) (PaginationL
 	}
 
 	return PaginationLinks{
-		CurrentPage: fmt.Sprintf("%s/%s?page=%s", config.AppURL, params.Path, fmt.Sprint(params.CurrentPage)),
-		NextPage:    fmt.Sprintf("%s/%s?page=%s", config.AppURL, params.Path, fmt.Sprint(nextPage)),
-		PrevPage:    fmt.Sprintf("%s/%s?page=%s", config.AppURL, params.Path, fmt.Sprint(prevPage)),
+		CurrentPage: pageURL(params.CurrentPage),
+		NextPage:    pageURL(nextPage),
+		PrevPage:    pageURL(prevPage),
 		TotalRows:   params.TotalRows,
 		TotalPages:  totalPages,
 		Links:       links,
@@ -386,7 +636,13 @@ func MakePassword(password string) (string, error) {
 	return string(hashedPassword), err
 }
 
+// currentUserKey: user yang sudah dimuat middleware RequireAdmin/RequirePermission
+type currentUserKey struct{}
+
 func (server *Server) CurrentUser(w http.ResponseWriter, r *http.Request) *models.User {
+	if user, ok := r.Context().Value(currentUserKey{}).(*models.User); ok {
+		return user
+	}
 	if !IsLoggedIn(r) {
 		return nil
 	}
@@ -401,6 +657,11 @@ func (server *Server) CurrentUser(w http.ResponseWriter, r *http.Request) *model
 		return nil
 	}
 
+	// role & hak akses staff; user biasa tidak punya baris di user_roles
+	if err := user.LoadAccess(server.DB); err != nil {
+		log.Println("LoadAccess error:", err)
+	}
+
 	return user
 }
 
@@ -423,12 +684,9 @@ func (server *Server) GetCartCount(w http.ResponseWriter, r *http.Request) int {
 }
 
 // ===== Admin helper =====
+// IsAdminUser: user staff (punya minimal 1 role admin). Hak akses per halaman dicek RequirePermission.
 func IsAdminUser(u *models.User) bool {
-	if u == nil {
-		return false
-	}
-	adminEmail := os.Getenv("ADMIN_EMAIL") // contoh: admin@example.com
-	return strings.EqualFold(strings.TrimSpace(u.Email), strings.TrimSpace(adminEmail))
+	return u.IsStaff()
 }
 
 var templateFuncs = []template.FuncMap{
@@ -453,30 +711,10 @@ func (s *Server) InjectNavbarBadges(data map[string]interface{}, user *models.Us
 		return
 	}
 
-	// ===== user unread (admin -> user) =====
-	// hitung semua pesan dari ADMIN yang belum dibaca user pada chat milik user tsb
-	var userUnread int64
-	_ = s.DB.Raw(`
-		SELECT COUNT(*)
-		FROM chat_messages m
-		JOIN chats c ON c.id = m.chat_id
-		WHERE c.user_id = ?
-		  AND m.sender_role = 'admin'
-		  AND (c.user_last_read_at IS NULL OR m.created_at > c.user_last_read_at)
-	`, user.ID).Scan(&userUnread).Error
-	data["userUnread"] = userUnread
-
-	// ===== admin unread (user -> admin) =====
-	if IsAdminUser(user) {
-		var totalUnread int64
-		_ = s.DB.Raw(`
-			SELECT COUNT(*)
-			FROM chat_messages m
-			JOIN chats c ON c.id = m.chat_id
-			WHERE m.sender_role = 'user'
-			  AND (c.admin_last_read_at IS NULL OR m.created_at > c.admin_last_read_at)
-		`).Scan(&totalUnread).Error
-		data["totalUnread"] = totalUnread
+	// diambil dari cache ChatHub (diperbarui saat ada pesan / chat dibaca), bukan COUNT tiap render
+	data["userUnread"] = s.ChatHub.UserUnread(user.ID)
+	if user.Can(consts.PermChatsManage) {
+		data["totalUnread"] = s.ChatHub.AdminUnread()
 	}
 }
 
@@ -495,7 +733,8 @@ func formatRupiah(price interface{}) string {
 	}
 }
 
-func userRender() *render.Render {
+// userRender: r dipakai untuk helper {{ csrfField }} di form
+func userRender(r *http.Request) *render.Render {
 	funcMap := template.FuncMap{
 		"formatRupiah": formatRupiah,
 
@@ -536,7 +775,7 @@ func userRender() *render.Render {
 		Directory:  "templates", // <- penting: pakai folder templates
 		Layout:     "layout",
 		Extensions: []string{".html", ".tmpl"},
-		Funcs:      []template.FuncMap{funcMap},
+		Funcs:      []template.FuncMap{funcMap, csrfTemplateFuncs(r)},
 	})
 }
 
@@ -562,3 +801,37 @@ func (server *Server) RequireLogin(next http.HandlerFunc) http.HandlerFunc {
 		next(w, r)
 	}
 }
+
+// RequireAdmin: hanya staff (role apa saja) yang boleh lanjut
+func (server *Server) RequireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return server.requireStaff(func(u *models.User) bool { return u.IsStaff() }, next)
+}
+
+// RequirePermission: hanya staff dengan hak akses perm (consts.Perm*) yang boleh lanjut
+func (server *Server) RequirePermission(perm string, next http.HandlerFunc) http.HandlerFunc {
+	return server.requireStaff(func(u *models.User) bool { return u.Can(perm) }, next)
+}
+
+func (server *Server) requireStaff(allowed func(u *models.User) bool, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := server.CurrentUser(w, r)
+		if user == nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+
+		if !allowed(user) {
+			SetFlash(w, r, "error", "Anda tidak memiliki akses ke halaman tersebut")
+			if user.IsStaff() {
+				http.Redirect(w, r, "/admin/dashboard", http.StatusSeeOther)
+			} else {
+				http.Redirect(w, r, "/", http.StatusSeeOther)
+			}
+			return
+		}
+
+		// simpan user di context supaya handler tidak query ulang
+		ctx := context.WithValue(r.Context(), currentUserKey{}, user)
+		next(w, r.WithContext(ctx))
+	}
+}
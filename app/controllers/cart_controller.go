@@ -60,18 +60,20 @@ func GetShoppingCart(db *gorm.DB, cartID string) (*models.Cart, error) {
 	totalWeight := 0
 	for _, item := range existingCart.CartItems {
 		// asumsi Weight disimpan per 1 gram (atau nilai numerik yang kamu pakai)
-		w, _ := item.Product.Weight.Float64()
+		w, _ := item.UnitWeight().Float64()
 		totalWeight += int(w) * item.Qty
 	}
 	existingCart.TotalWeight = totalWeight
 
-	// hitung ulang total cart
-	_, err = existingCart.CalculateCart(db, cartID)
+	// hitung ulang total cart (termasuk promo)
+	calculated, err := existingCart.CalculateCart(db, cartID)
 	if err != nil {
 		log.Println("CalculateCart error:", err)
+		return existingCart, nil
 	}
+	calculated.TotalWeight = totalWeight
 
-	return existingCart, nil
+	return calculated, nil
 }
 
 // =========================
@@ -79,7 +81,7 @@ func GetShoppingCart(db *gorm.DB, cartID string) (*models.Cart, error) {
 // =========================
 
 func (server *Server) GetCart(w http.ResponseWriter, r *http.Request) {
-	ren := userRender()
+	ren := userRender(r)
 	user := server.CurrentUser(w, r)
 
 	cartID := GetShoppingCartID(w, r)
@@ -142,6 +144,8 @@ func (server *Server) GetCart(w http.ResponseWriter, r *http.Request) {
 		"totalPrice":     totalPrice,
 		"addresses":      addresses,
 		"defaultAddress": defaultAddress,
+		"flashes":        GetFlash(w, r, "success"),
+		"errors":         GetFlash(w, r, "error"),
 	})
 }
 
@@ -180,8 +184,10 @@ func (server *Server) AddItemToCart(w http.ResponseWriter, r *http.Request) {
 		qty = q
 	}
 
-	// ambil ukuran dari form (boleh kosong)
+	// varian yang dipilih; ukuran/warna hanya dipakai untuk produk tanpa varian
+	variantID := r.FormValue("variant_id")
 	size := r.FormValue("size")
+	color := r.FormValue("color")
 
 	// ambil / buat cart berdasarkan cookie cart_id
 	cartID := GetShoppingCartID(w, r)
@@ -195,13 +201,16 @@ func (server *Server) AddItemToCart(w http.ResponseWriter, r *http.Request) {
 	// buat item cart
 	item := models.CartItem{
 		ProductID: productID,
+		VariantID: variantID,
 		Qty:       qty,
 		Size:      size,
+		Color:     color,
 	}
 
-	// simpan ke database
+	// simpan ke database (stok divalidasi di AddItem)
 	if _, err := cart.AddItem(server.DB, item); err != nil {
 		log.Println("AddItem error:", err)
+		SetFlash(w, r, "error", "Gagal menambahkan ke keranjang: "+err.Error())
 	}
 
 	http.Redirect(w, r, "/carts", http.StatusSeeOther)
@@ -258,6 +267,7 @@ func (server *Server) UpdateCartItemQty(w http.ResponseWriter, r *http.Request)
 
 	if err := item.UpdateQty(server.DB, itemID, qty); err != nil {
 		log.Println("UpdateQty error:", err)
+		SetFlash(w, r, "error", "Gagal mengubah jumlah: "+err.Error())
 	}
 
 	// setelah update qty, hitung ulang cart
@@ -305,3 +315,59 @@ func (server *Server) RemoveCartItem(w http.ResponseWriter, r *http.Request) {
 	// 5. Redirect ke halaman cart (GetCartDetail nanti yang menghitung ulang total)
 	http.Redirect(w, r, "/carts", http.StatusSeeOther)
 }
+
+// =========================
+// Handler: kupon
+// =========================
+
+// POST /carts/coupon
+func (server *Server) ApplyCartCoupon(w http.ResponseWriter, r *http.Request) {
+	user := server.CurrentUser(w, r)
+	if user == nil {
+		SetFlash(w, r, "error", models.ErrCouponLoginRequired.Error())
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	code := strings.TrimSpace(r.FormValue("coupon_code"))
+	if code == "" {
+		SetFlash(w, r, "error", "Kode kupon wajib diisi")
+		http.Redirect(w, r, "/carts", http.StatusSeeOther)
+		return
+	}
+
+	cartID := GetShoppingCartID(w, r)
+	cart, err := GetShoppingCart(server.DB, cartID)
+	if err != nil {
+		log.Println("GetShoppingCart error:", err)
+		http.Redirect(w, r, "/carts", http.StatusSeeOther)
+		return
+	}
+
+	promo, err := cart.ApplyCoupon(server.DB, code, user.ID)
+	if err != nil {
+		SetFlash(w, r, "error", "Kupon tidak bisa dipakai: "+err.Error())
+		http.Redirect(w, r, "/carts", http.StatusSeeOther)
+		return
+	}
+
+	SetFlash(w, r, "success", "Kupon "+promo.Code+" berhasil dipakai")
+	http.Redirect(w, r, "/carts", http.StatusSeeOther)
+}
+
+// POST /carts/coupon/remove
+func (server *Server) RemoveCartCoupon(w http.ResponseWriter, r *http.Request) {
+	cartID := GetShoppingCartID(w, r)
+	cart, err := GetShoppingCart(server.DB, cartID)
+	if err != nil {
+		log.Println("GetShoppingCart error:", err)
+		http.Redirect(w, r, "/carts", http.StatusSeeOther)
+		return
+	}
+
+	if err := cart.RemoveCoupon(server.DB); err != nil {
+		log.Println("RemoveCoupon error:", err)
+	}
+
+	http.Redirect(w, r, "/carts", http.StatusSeeOther)
+}
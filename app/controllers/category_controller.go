@@ -0,0 +1,288 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/alirogz/goshop/app/consts"
+	"github.com/alirogz/goshop/app/models"
+	"github.com/alirogz/goshop/app/search"
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+)
+
+// Breadcrumb: 1 langkah navigasi di atas halaman (URL kosong = halaman saat ini)
+type Breadcrumb struct {
+	Name string
+	URL  string
+}
+
+// productListing: isi halaman daftar produk (/products, /categories/{slug}, /sections/{slug})
+type productListing struct {
+	Path        string // untuk link pagination, tanpa "/" di depan
+	Title       string
+	Subtitle    string
+	Breadcrumbs []Breadcrumb
+	Sections    []*models.SectionNode
+	Children    []*models.CategoryNode // subkategori dari kategori yang dibuka
+
+	// kategori yang ditampilkan (lihat search.Query.OnlyCategories); false = semua produk
+	CategoryIDs    []string
+	OnlyCategories bool
+}
+
+// productSortOptions: pilihan urutan di halaman daftar produk
+var productSortOptions = []struct {
+	Value string
+	Label string
+}{
+	{consts.ProductSortRelevance, "Paling relevan"},
+	{consts.ProductSortNewest, "Terbaru"},
+	{consts.ProductSortBestSelling, "Terlaris"},
+	{consts.ProductSortPriceAsc, "Harga terendah"},
+	{consts.ProductSortPriceDesc, "Harga tertinggi"},
+}
+
+// GET /categories/{slug}: produk di kategori ini + semua subkategorinya
+func (server *Server) CategoryProducts(w http.ResponseWriter, r *http.Request) {
+	category, err := models.FindCategoryBySlug(server.DB, mux.Vars(r)["slug"])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	categories, sections, err := server.catalogTree()
+	if err != nil {
+		http.Error(w, "Gagal mengambil kategori", http.StatusInternalServerError)
+		return
+	}
+
+	path := models.CategoryPath(categories, category.ID)
+	breadcrumbs := []Breadcrumb{{Name: "Home", URL: "/"}, {Name: category.Section.Name, URL: "/sections/" + category.Section.Slug}}
+	for _, c := range path {
+		breadcrumbs = append(breadcrumbs, Breadcrumb{Name: c.Name, URL: "/categories/" + c.Slug})
+	}
+	breadcrumbs[len(breadcrumbs)-1].URL = ""
+
+	menu := models.BuildSectionTree(sections, categories)
+	var children []*models.CategoryNode
+	for _, s := range menu {
+		s.Active = s.ID == category.SectionID
+		models.MarkActiveCategories(s.Categories, path)
+		for _, n := range models.FlattenCategoryTree(s.Categories) {
+			if n.ID == category.ID {
+				children = n.Children
+			}
+		}
+	}
+
+	server.renderProductListing(w, r, productListing{
+		Path:        "categories/" + category.Slug,
+		Title:       category.Name,
+		Subtitle:    "Produk di kategori " + category.Name + " dan subkategorinya.",
+		Breadcrumbs: breadcrumbs,
+		Sections:    menu,
+		Children:    children,
+
+		CategoryIDs:    models.CategoryDescendantIDs(categories, category.ID),
+		OnlyCategories: true,
+	})
+}
+
+// GET /sections/{slug}: produk di semua kategori section ini
+func (server *Server) SectionProducts(w http.ResponseWriter, r *http.Request) {
+	section, err := models.FindSectionBySlug(server.DB, mux.Vars(r)["slug"])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	categories, sections, err := server.catalogTree()
+	if err != nil {
+		http.Error(w, "Gagal mengambil kategori", http.StatusInternalServerError)
+		return
+	}
+
+	var ids []string
+	for _, c := range categories {
+		if c.SectionID == section.ID {
+			ids = append(ids, c.ID)
+		}
+	}
+
+	menu := models.BuildSectionTree(sections, categories)
+	var children []*models.CategoryNode
+	for _, s := range menu {
+		if s.ID == section.ID {
+			s.Active = true
+			children = s.Categories
+		}
+	}
+
+	server.renderProductListing(w, r, productListing{
+		Path:        "sections/" + section.Slug,
+		Title:       section.Name,
+		Subtitle:    "Semua produk di " + section.Name + ".",
+		Breadcrumbs: []Breadcrumb{{Name: "Home", URL: "/"}, {Name: section.Name}},
+		Sections:    menu,
+		Children:    children,
+
+		CategoryIDs:    ids,
+		OnlyCategories: true,
+	})
+}
+
+// renderProductListing: halaman "products" (pencarian ?q=, filter facet, urutan & pagination)
+// dengan menu kategori & breadcrumb
+func (server *Server) renderProductListing(w http.ResponseWriter, r *http.Request, listing productListing) {
+	// Pakai renderer untuk user yang sudah punya FuncMap (formatRupiah, dll)
+	ren := userRender(r)
+
+	perPage := 9
+	query := productQueryFromRequest(r)
+	query.PerPage = perPage
+	query.CategoryIDs = listing.CategoryIDs
+	query.OnlyCategories = listing.OnlyCategories
+
+	result, err := server.Search.Search(query)
+	if err != nil {
+		log.Println("search error:", err)
+		http.Error(w, "Gagal mengambil data produk", http.StatusInternalServerError)
+		return
+	}
+
+	pagination, _ := GetPaginationLinks(server.AppConfig, PaginationParams{
+		Path:        listing.Path,
+		TotalRows:   int32(result.Total),
+		PerPage:     int32(perPage),
+		CurrentPage: int32(query.Page),
+		Query:       r.URL.Query(),
+	})
+
+	user := server.CurrentUser(w, r)
+
+	data := map[string]interface{}{
+		"products":    result.Products,
+		"search":      result,
+		"filters":     r.URL.Query(),
+		"sortOptions": productSortOptions,
+		"pagination":  pagination,
+		"listing":     listing,
+		"user":        user,
+		"isAdmin":     IsAdminUser(user),
+		"cartCount":   server.GetCartCount(w, r),
+	}
+	server.InjectNavbarBadges(data, user)
+	_ = ren.HTML(w, http.StatusOK, "products", data)
+}
+
+// productQueryFromRequest: ?q=&min_price=&max_price=&size=&color=&in_stock=1&sort=&page=
+// (size & color boleh lebih dari 1). Harga yang tidak valid diabaikan.
+func productQueryFromRequest(r *http.Request) search.Query {
+	values := r.URL.Query()
+
+	page, err := strconv.Atoi(values.Get("page"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+
+	return search.Query{
+		Text:     strings.TrimSpace(values.Get("q")),
+		MinPrice: parsePriceFilter(values.Get("min_price")),
+		MaxPrice: parsePriceFilter(values.Get("max_price")),
+		Sizes:    values["size"],
+		Colors:   values["color"],
+		InStock:  values.Get("in_stock") == "1",
+		Sort:     values.Get("sort"),
+		Page:     page,
+	}
+}
+
+func parsePriceFilter(raw string) decimal.NullDecimal {
+	price, err := decimal.NewFromString(strings.TrimSpace(raw))
+	if err != nil || price.IsNegative() {
+		return decimal.NullDecimal{}
+	}
+	return decimal.NewNullDecimal(price)
+}
+
+// GET /products/suggest?q=: autocomplete kotak pencarian (JSON)
+func (server *Server) ProductSuggest(w http.ResponseWriter, r *http.Request) {
+	suggestions, err := server.Search.Suggest(r.URL.Query().Get("q"), 5)
+	if err != nil {
+		log.Println("search suggest error:", err)
+		http.Error(w, "Gagal mengambil saran", http.StatusInternalServerError)
+		return
+	}
+
+	type productHit struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	}
+	products := []productHit{}
+	for _, p := range suggestions.Products {
+		products = append(products, productHit{Name: p.Name, URL: "/products/" + p.Slug})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"correction":  suggestions.Correction,
+		"completions": suggestions.Completions,
+		"products":    products,
+	})
+}
+
+// catalogTree: semua kategori & section (sedikit, cukup dimuat utuh tiap request)
+func (server *Server) catalogTree() ([]models.Category, []models.Section, error) {
+	categories, err := models.ListCategories(server.DB)
+	if err != nil {
+		return nil, nil, err
+	}
+	sections, err := models.ListSections(server.DB)
+	if err != nil {
+		return nil, nil, err
+	}
+	return categories, sections, nil
+}
+
+// catalogMenu: menu section + kategori tanpa yang aktif (halaman /products)
+func (server *Server) catalogMenu() []*models.SectionNode {
+	categories, sections, err := server.catalogTree()
+	if err != nil {
+		log.Println("catalogTree error:", err)
+		return nil
+	}
+	return models.BuildSectionTree(sections, categories)
+}
+
+// productBreadcrumbs: Home → section → kategori (kategori pertama produk) → produk
+func (server *Server) productBreadcrumbs(product *models.Product) []Breadcrumb {
+	breadcrumbs := []Breadcrumb{{Name: "Home", URL: "/"}, {Name: "Produk", URL: "/products"}}
+
+	var assigned []models.Category
+	if err := server.DB.Model(product).Association("Categories").Find(&assigned); err != nil {
+		log.Println("product categories error:", err)
+	}
+
+	if len(assigned) > 0 {
+		categories, sections, err := server.catalogTree()
+		if err == nil {
+			path := models.CategoryPath(categories, assigned[0].ID)
+			if len(path) > 0 {
+				for _, s := range sections {
+					if s.ID == path[0].SectionID {
+						breadcrumbs = []Breadcrumb{{Name: "Home", URL: "/"}, {Name: s.Name, URL: "/sections/" + s.Slug}}
+					}
+				}
+			}
+			for _, c := range path {
+				breadcrumbs = append(breadcrumbs, Breadcrumb{Name: c.Name, URL: "/categories/" + c.Slug})
+			}
+		}
+	}
+
+	return append(breadcrumbs, Breadcrumb{Name: product.Name})
+}
@@ -0,0 +1,234 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alirogz/goshop/app/attachments"
+	"github.com/alirogz/goshop/app/consts"
+	"github.com/alirogz/goshop/app/models"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+const (
+	chatAttachmentMaxSize  = 5 << 20 // per file
+	chatAttachmentMaxFiles = 3       // per pesan
+)
+
+var (
+	errChatMessageEmpty  = errors.New("pesan atau lampiran tidak boleh kosong")
+	errChatTooManyFiles  = fmt.Errorf("maksimal %d lampiran per pesan", chatAttachmentMaxFiles)
+	errChatUploadInvalid = errors.New("upload lampiran gagal, coba lagi")
+)
+
+// readChatMessage: isi pesan + lampiran (field "attachment", boleh lebih dari 1) dari form biasa / multipart.
+// File lampiran langsung disimpan; kalau pesan gagal masuk DB, hapus lagi dengan discardChatFiles.
+func (server *Server) readChatMessage(w http.ResponseWriter, r *http.Request, senderID, senderRole string) (*models.ChatMessage, []*attachments.File, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, chatAttachmentMaxFiles*chatAttachmentMaxSize+1<<20)
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return nil, nil, attachments.ErrTooLarge
+			}
+			return nil, nil, errChatUploadInvalid
+		}
+		defer r.MultipartForm.RemoveAll()
+	} else if err := r.ParseForm(); err != nil {
+		return nil, nil, errChatUploadInvalid
+	}
+
+	msg := &models.ChatMessage{
+		ID:         uuid.NewString(),
+		SenderID:   senderID,
+		SenderRole: senderRole,
+		Message:    r.FormValue("message"),
+	}
+
+	var headers []*multipart.FileHeader
+	if r.MultipartForm != nil {
+		headers = r.MultipartForm.File["attachment"]
+	}
+	if len(headers) > chatAttachmentMaxFiles {
+		return nil, nil, errChatTooManyFiles
+	}
+
+	var files []*attachments.File
+	for _, header := range headers {
+		file, err := server.saveChatAttachment(r.Context(), header)
+		if err != nil {
+			server.discardChatFiles(files)
+			return nil, nil, fmt.Errorf("%s: %w", chatAttachmentName(header.Filename), err)
+		}
+		files = append(files, file)
+
+		msg.Attachments = append(msg.Attachments, models.ChatAttachment{
+			MessageID:   msg.ID,
+			FileName:    chatAttachmentName(header.Filename),
+			ContentType: file.ContentType,
+			Size:        file.Size,
+			Width:       file.Width,
+			Height:      file.Height,
+			StoragePath: file.Path,
+			ThumbPath:   file.ThumbPath,
+		})
+	}
+
+	if strings.TrimSpace(msg.Message) == "" && len(files) == 0 {
+		return nil, nil, errChatMessageEmpty
+	}
+	return msg, files, nil
+}
+
+func (server *Server) saveChatAttachment(ctx context.Context, header *multipart.FileHeader) (*attachments.File, error) {
+	src, err := header.Open()
+	if err != nil {
+		return nil, errChatUploadInvalid
+	}
+	defer src.Close()
+
+	return server.Attachments.Save(ctx, src)
+}
+
+// discardChatFiles: hapus file lampiran yang sudah tersimpan tapi pesannya gagal disimpan
+func (server *Server) discardChatFiles(files []*attachments.File) {
+	for _, f := range files {
+		server.Attachments.Delete(context.Background(), f)
+	}
+}
+
+// writeChatSendError: kesalahan input (400, pesannya ditampilkan ke user) atau kesalahan server (500)
+func writeChatSendError(w http.ResponseWriter, err error) {
+	status, text := http.StatusInternalServerError, "Gagal mengirim pesan, coba lagi."
+	for _, inputErr := range []error{
+		errChatMessageEmpty, errChatTooManyFiles, errChatUploadInvalid,
+		attachments.ErrEmpty, attachments.ErrTooLarge, attachments.ErrType,
+	} {
+		if errors.Is(err, inputErr) {
+			status, text = http.StatusBadRequest, err.Error()
+			break
+		}
+	}
+	if status == http.StatusInternalServerError {
+		log.Println("chat send error:", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{"ok": false, "error": text})
+}
+
+// chatAttachmentName: nama file dari browser, hanya untuk tampilan / nama unduhan (tidak pernah dipakai sebagai path)
+func chatAttachmentName(name string) string {
+	name = strings.TrimSpace(filepath.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "" || name == "." || name == "/" {
+		return "lampiran"
+	}
+	for utf8.RuneCountInString(name) > 255 {
+		runes := []rune(name)
+		name = string(runes[len(runes)-255:])
+	}
+	return name
+}
+
+// GET /chat/attachments/{id} (?thumb=1 untuk thumbnail): hanya pemilik chat & staff yang pegang chat.
+// Setelah hak akses dicek, browser diarahkan ke URL file bertanda tangan yang berlaku sebentar.
+func (server *Server) ChatAttachmentDownload(w http.ResponseWriter, r *http.Request) {
+	user := server.CurrentUser(w, r)
+	if user == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	attachment, chat, err := models.FindChatAttachment(server.DB, mux.Vars(r)["id"])
+	if err != nil {
+		writeChatLookupError(w, err)
+		return
+	}
+	// lampiran chat orang lain dianggap tidak ada
+	if chat.UserID != user.ID && !user.Can(consts.PermChatsManage) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	rel, contentType := attachment.StoragePath, attachment.ContentType
+	if r.URL.Query().Get("thumb") == "1" && attachment.ThumbPath != "" {
+		rel, contentType = attachment.ThumbPath, "image/jpeg"
+	}
+
+	server.redirectPrivateFile(w, r, server.Attachments, rel, contentType, attachment.FileName)
+}
+
+// POST /admin/chats/{id}/attachments/{attachmentID}/payment-proof: lampiran chat (mis. foto slip transfer)
+// dijadikan bukti bayar order milik pembeli yang sama, lalu menunggu dicek seperti upload biasa.
+func (server *Server) AdminChatAttachmentPaymentProof(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	back := "/admin/chats/" + vars["id"]
+
+	attachment, chat, err := models.FindChatAttachment(server.DB, vars["attachmentID"])
+	if err != nil || chat.ID != vars["id"] {
+		http.Error(w, "Attachment not found", http.StatusNotFound)
+		return
+	}
+	if !attachment.IsImage && attachment.ContentType != "application/pdf" {
+		SetFlash(w, r, "error", "Hanya gambar atau PDF yang bisa dijadikan bukti bayar.")
+		http.Redirect(w, r, back, http.StatusSeeOther)
+		return
+	}
+
+	orderID := r.FormValue("order_id")
+	if orderID == "" {
+		orderID = chat.OrderID
+	}
+	if orderID == "" {
+		SetFlash(w, r, "error", "Pilih pesanan untuk bukti bayar ini.")
+		http.Redirect(w, r, back, http.StatusSeeOther)
+		return
+	}
+
+	var order models.Order
+	if err := server.DB.Where("id = ? AND user_id = ?", orderID, chat.UserID).First(&order).Error; err != nil {
+		SetFlash(w, r, "error", "Pesanan tidak ditemukan untuk pembeli ini.")
+		http.Redirect(w, r, back, http.StatusSeeOther)
+		return
+	}
+	if order.IsPaid() {
+		SetFlash(w, r, "error", "Pesanan "+order.Code+" sudah lunas.")
+		http.Redirect(w, r, back, http.StatusSeeOther)
+		return
+	}
+	if order.Status == consts.OrderStatusCancelled || order.Status == consts.OrderStatusRefunded {
+		SetFlash(w, r, "error", "Pesanan "+order.Code+" sudah dibatalkan.")
+		http.Redirect(w, r, back, http.StatusSeeOther)
+		return
+	}
+
+	src, err := server.Attachments.Open(r.Context(), attachment.StoragePath)
+	if err != nil {
+		log.Println("chat attachment open error:", err)
+		SetFlash(w, r, "error", "File lampiran tidak ditemukan.")
+		http.Redirect(w, r, back, http.StatusSeeOther)
+		return
+	}
+	defer src.Close()
+
+	admin := server.CurrentUser(w, r)
+	if _, err := server.storePaymentProof(r.Context(), &order, src, attachment.FileName, admin.ID, consts.PaymentProofSourceChat); err != nil {
+		SetFlash(w, r, "error", "Pesanan "+order.Code+": "+paymentProofErrorText(err))
+		http.Redirect(w, r, back, http.StatusSeeOther)
+		return
+	}
+
+	SetFlash(w, r, "success", "Lampiran dijadikan bukti bayar pesanan "+order.Code+". Cek & konfirmasi di halaman pesanan.")
+	http.Redirect(w, r, back, http.StatusSeeOther)
+}
@@ -2,31 +2,177 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/alirogz/goshop/app/models"
 	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
 )
 
-// USER CHAT PAGE
-func (server *Server) ChatPage(w http.ResponseWriter, r *http.Request) {
-	ren := userRender()
+// USER: daftar percakapan + form percakapan baru
+func (server *Server) ChatIndex(w http.ResponseWriter, r *http.Request) {
+	ren := userRender(r)
 	user := server.CurrentUser(w, r)
 	if user == nil {
 		http.Redirect(w, r, "/login", http.StatusSeeOther)
 		return
 	}
 
-	// 1 user => 1 chat (create if not exists)
-	chatModel := models.Chat{}
-	chat, err := chatModel.FindOrCreateByUserID(server.DB, uuid.NewString(), user.ID)
+	chats, err := models.UserChats(server.DB, user.ID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	unread, err := models.UserUnreadByChat(server.DB, user.ID)
+	if err != nil {
+		log.Println("UserUnreadByChat error:", err)
+		unread = map[string]int64{}
+	}
+
+	// order terbaru untuk pilihan "soal pesanan" di form percakapan baru
+	var orders []models.Order
+	server.DB.Select("id", "code", "created_at").
+		Where("user_id = ?", user.ID).
+		Order("created_at desc").
+		Limit(20).
+		Find(&orders)
+
+	// dari tombol "Tanya CS" di halaman produk
+	var product models.Product
+	if productID := r.URL.Query().Get("product_id"); productID != "" {
+		server.DB.Select("id", "name").Where("id = ?", productID).First(&product)
+	}
+
+	_ = ren.HTML(w, http.StatusOK, "chats", map[string]interface{}{
+		"user":       user,
+		"isAdmin":    IsAdminUser(user),
+		"cartCount":  server.GetCartCount(w, r),
+		"chats":      chats,
+		"unread":     unread,
+		"orderCodes": server.chatOrderCodes(chats),
+		"orders":     orders,
+		"product":    product,
+		"userUnread": server.ChatHub.UserUnread(user.ID),
+		"errors":     GetFlash(w, r, "error"),
+	})
+}
+
+// USER: mulai percakapan baru (opsional soal 1 order / produk)
+func (server *Server) ChatStart(w http.ResponseWriter, r *http.Request) {
+	user := server.CurrentUser(w, r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	subject := strings.TrimSpace(r.FormValue("subject"))
+	text := strings.TrimSpace(r.FormValue("message"))
+	orderID := r.FormValue("order_id")
+	productID := r.FormValue("product_id")
+
+	if text == "" {
+		SetFlash(w, r, "error", "Pesan tidak boleh kosong.")
+		http.Redirect(w, r, "/chat", http.StatusSeeOther)
+		return
+	}
+
+	if orderID != "" {
+		var order models.Order
+		if err := server.DB.Select("id", "code").Where("id = ? AND user_id = ?", orderID, user.ID).First(&order).Error; err != nil {
+			SetFlash(w, r, "error", "Pesanan tidak ditemukan.")
+			http.Redirect(w, r, "/chat", http.StatusSeeOther)
+			return
+		}
+		if subject == "" {
+			subject = "Pesanan " + order.Code
+		}
+	}
+	if productID != "" {
+		var product models.Product
+		if err := server.DB.Select("id", "name").Where("id = ?", productID).First(&product).Error; err != nil {
+			SetFlash(w, r, "error", "Produk tidak ditemukan.")
+			http.Redirect(w, r, "/chat", http.StatusSeeOther)
+			return
+		}
+		if subject == "" {
+			subject = product.Name
+		}
+	}
+
+	var chat *models.Chat
+	msg := models.ChatMessage{
+		ID:         uuid.NewString(),
+		SenderID:   user.ID,
+		SenderRole: "user",
+		Message:    text,
+	}
+	err := server.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		chat, err = models.StartChat(tx, uuid.NewString(), user.ID, subject, orderID, productID)
+		if err != nil {
+			return err
+		}
+		return chat.AddMessage(tx, &msg)
+	})
+	if err != nil {
+		log.Println("ChatStart error:", err)
+		SetFlash(w, r, "error", "Gagal memulai percakapan, coba lagi.")
+		http.Redirect(w, r, "/chat", http.StatusSeeOther)
+		return
+	}
+	server.ChatHub.MessageCreated(*chat, msg)
+
+	http.Redirect(w, r, "/chat/"+chat.ID, http.StatusSeeOther)
+}
+
+// USER: buka percakapan soal 1 order dari halaman detail pesanan (thread yang belum selesai dipakai lagi)
+func (server *Server) OrderChat(w http.ResponseWriter, r *http.Request) {
+	user := server.CurrentUser(w, r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	var order models.Order
+	if err := server.DB.Where("id = ? AND user_id = ?", mux.Vars(r)["id"], user.ID).First(&order).Error; err != nil {
+		SetFlash(w, r, "error", "Pesanan tidak ditemukan.")
+		http.Redirect(w, r, "/orders", http.StatusSeeOther)
+		return
+	}
+
+	chat, err := models.FindOrCreateOrderChat(server.DB, order)
+	if err != nil {
+		log.Println("FindOrCreateOrderChat error:", err)
+		SetFlash(w, r, "error", "Gagal membuka chat, coba lagi.")
+		http.Redirect(w, r, "/orders/"+order.ID, http.StatusSeeOther)
+		return
+	}
+
+	http.Redirect(w, r, "/chat/"+chat.ID, http.StatusSeeOther)
+}
+
+// USER CHAT PAGE: 1 thread
+func (server *Server) ChatPage(w http.ResponseWriter, r *http.Request) {
+	ren := userRender(r)
+	user := server.CurrentUser(w, r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	chat, err := server.userChat(r, user)
+	if err != nil {
+		http.Redirect(w, r, "/chat", http.StatusSeeOther)
+		return
+	}
+
 	// load last 50 messages
 	msgModel := models.ChatMessage{}
 	msgs, err := msgModel.ListMessagesAfter(server.DB, chat.ID, 0, 50)
@@ -40,29 +186,24 @@ func (server *Server) ChatPage(w http.ResponseWriter, r *http.Request) {
 		lastTs = msgs[len(msgs)-1].CreatedAt.UnixMilli()
 	}
 
-	now := time.Now()
-	server.DB.Model(&models.Chat{}).
-		Where("id = ?", chat.ID).
-		Update("user_last_read_at", &now)
-
-	var userUnread int64
+	ownLastTs, peerReadTs := chatReceiptTimes(msgs, "user", chat.AdminLastReadAt)
 
-	q := server.DB.Model(models.ChatMessage{}).
-		Where("chat_id = ? AND sender_role = ?", chat.ID, "admin")
-
-	if chat.UserLastReadAt != nil {
-		q = q.Where("created_at > ?", *chat.UserLastReadAt)
+	now := time.Now()
+	if err := chat.MarkReadBy(server.DB, "user", now); err == nil {
+		server.ChatHub.ChatRead(*chat, "user", now)
 	}
-	q.Count(&userUnread)
 
 	_ = ren.HTML(w, http.StatusOK, "chat", map[string]interface{}{
 		"user":       user,
 		"isAdmin":    IsAdminUser(user),
 		"cartCount":  server.GetCartCount(w, r),
 		"chat":       chat,
+		"orderCode":  server.chatOrderCodes([]models.Chat{*chat})[chat.OrderID],
 		"messages":   msgs,
 		"lastTs":     lastTs,
-		"userUnread": userUnread,
+		"ownLastTs":  ownLastTs,
+		"peerReadTs": peerReadTs,
+		"userUnread": server.ChatHub.UserUnread(user.ID),
 	})
 }
 
@@ -73,10 +214,9 @@ func (server *Server) ChatMessages(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
-	chatModel := models.Chat{}
-	chat, err := chatModel.FindOrCreateByUserID(server.DB, uuid.NewString(), user.ID)
+	chat, err := server.userChat(r, user)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		writeChatLookupError(w, err)
 		return
 	}
 
@@ -117,35 +257,89 @@ func (server *Server) ChatSend(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
-	if err := r.ParseForm(); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+
+	chat, err := server.userChat(r, user)
+	if err != nil {
+		writeChatLookupError(w, err)
 		return
 	}
-	text := r.FormValue("message")
-	if len(text) == 0 {
-		w.WriteHeader(http.StatusBadRequest)
+
+	msg, files, err := server.readChatMessage(w, r, user.ID, "user")
+	if err != nil {
+		writeChatSendError(w, err)
 		return
 	}
-
-	chatModel := models.Chat{}
-	chat, err := chatModel.FindOrCreateByUserID(server.DB, uuid.NewString(), user.ID)
+	err = server.DB.Transaction(func(tx *gorm.DB) error {
+		return chat.AddMessage(tx, msg)
+	})
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		server.discardChatFiles(files)
+		writeChatSendError(w, err)
 		return
 	}
+	server.ChatHub.MessageCreated(*chat, *msg)
 
-	msg := models.ChatMessage{
-		ID:         uuid.NewString(),
-		ChatID:     chat.ID,
-		SenderID:   user.ID,
-		SenderRole: "user",
-		Message:    text,
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "message": msg})
+}
+
+// userChat: thread milik user dari {id} di URL. Endpoint lama tanpa {id} memakai thread umum user.
+func (server *Server) userChat(r *http.Request, user *models.User) (*models.Chat, error) {
+	chatID := mux.Vars(r)["id"]
+	if chatID == "" {
+		chatModel := models.Chat{}
+		return chatModel.FindOrCreateByUserID(server.DB, uuid.NewString(), user.ID)
 	}
-	if err := server.DB.Create(&msg).Error; err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+
+	var chat models.Chat
+	if err := server.DB.Where("id = ? AND user_id = ?", chatID, user.ID).First(&chat).Error; err != nil {
+		return nil, err
+	}
+	return &chat, nil
+}
+
+func writeChatLookupError(w http.ResponseWriter, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		w.WriteHeader(http.StatusNotFound)
 		return
 	}
+	w.WriteHeader(http.StatusInternalServerError)
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "message": msg})
+// chatOrderCodes: kode order untuk thread yang terkait order (key: order id)
+func (server *Server) chatOrderCodes(chats []models.Chat) map[string]string {
+	ids := []string{}
+	for _, c := range chats {
+		if c.OrderID != "" {
+			ids = append(ids, c.OrderID)
+		}
+	}
+
+	codes := map[string]string{}
+	if len(ids) == 0 {
+		return codes
+	}
+
+	var orders []models.Order
+	if err := server.DB.Select("id", "code").Where("id IN ?", ids).Find(&orders).Error; err != nil {
+		log.Println("chatOrderCodes error:", err)
+		return codes
+	}
+	for _, o := range orders {
+		codes[o.ID] = o.Code
+	}
+	return codes
+}
+
+// chatReceiptTimes: waktu pesan terakhir milik ownRole & waktu baca lawan bicara (unix milli) untuk read receipt
+func chatReceiptTimes(msgs []models.ChatMessage, ownRole string, peerReadAt *time.Time) (ownLastTs, peerReadTs int64) {
+	for _, m := range msgs {
+		if m.SenderRole == ownRole {
+			ownLastTs = m.CreatedAt.UnixMilli()
+		}
+	}
+	if peerReadAt != nil {
+		peerReadTs = peerReadAt.UnixMilli()
+	}
+	return ownLastTs, peerReadTs
 }
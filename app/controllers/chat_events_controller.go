@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/alirogz/goshop/app/consts"
+	"github.com/alirogz/goshop/app/models"
+	"github.com/alirogz/goshop/app/websocket"
+	"github.com/gorilla/mux"
+)
+
+// interval ping WebSocket / heartbeat SSE supaya proxy tidak menutup koneksi idle
+const chatStreamHeartbeat = 25 * time.Second
+
+// ChatEvents: stream event chat realtime. WebSocket kalau client meminta upgrade, selain itu SSE.
+// ?chat_id= opsional: chat yang sedang dibuka (pemilik chat atau staff chats.manage).
+// Tanpa chat_id hanya menerima perubahan badge unread.
+func (server *Server) ChatEvents(w http.ResponseWriter, r *http.Request) {
+	user := server.CurrentUser(w, r)
+	if user == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	admin := user.Can(consts.PermChatsManage)
+
+	chatID := r.URL.Query().Get("chat_id")
+	if chatID != "" {
+		var chat models.Chat
+		if err := server.DB.Select("id", "user_id").Where("id = ?", chatID).First(&chat).Error; err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if chat.UserID != user.ID && !admin {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+	}
+
+	sub := server.ChatHub.subscribe(user.ID, chatID, admin)
+	defer server.ChatHub.unsubscribe(sub)
+
+	// kondisi badge saat tersambung (bisa berubah selama client reconnect)
+	sub.events <- ChatEvent{Type: chatEventUnread, Scope: "user", Count: server.ChatHub.UserUnread(user.ID)}
+	if admin {
+		sub.events <- ChatEvent{Type: chatEventUnread, Scope: "admin", Count: server.ChatHub.AdminUnread()}
+	}
+
+	if websocket.IsUpgrade(r) {
+		server.streamChatWebSocket(w, r, sub)
+		return
+	}
+	server.streamChatSSE(w, r, sub)
+}
+
+func (server *Server) streamChatWebSocket(w http.ResponseWriter, r *http.Request, sub *chatSubscriber) {
+	conn, err := websocket.Upgrade(w, r)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// client tidak mengirim apa-apa; loop baca hanya untuk ping/pong & mendeteksi koneksi ditutup
+	go func() {
+		defer sub.drop()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(chatStreamHeartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event := <-sub.events:
+			raw, err := json.Marshal(event)
+			if err != nil {
+				log.Println("chat event marshal error:", err)
+				continue
+			}
+			if err := conn.WriteText(raw); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-sub.done:
+			return
+		}
+	}
+}
+
+func (server *Server) streamChatSSE(w http.ResponseWriter, r *http.Request, sub *chatSubscriber) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // nginx: jangan di-buffer
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 3000\n\n")
+	flusher.Flush()
+
+	ticker := time.NewTicker(chatStreamHeartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event := <-sub.events:
+			raw, err := json.Marshal(event)
+			if err != nil {
+				log.Println("chat event marshal error:", err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", raw); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		case <-sub.done:
+			return
+		}
+	}
+}
+
+// ChatRead: user menandai chat-nya sudah dibaca (dipanggil halaman chat saat ada pesan masuk realtime)
+func (server *Server) ChatRead(w http.ResponseWriter, r *http.Request) {
+	user := server.CurrentUser(w, r)
+	if user == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	chat, err := server.userChat(r, user)
+	if err != nil {
+		writeChatLookupError(w, err)
+		return
+	}
+
+	server.markChatRead(w, chat, "user")
+}
+
+// AdminChatRead: admin menandai chat sudah dibaca
+func (server *Server) AdminChatRead(w http.ResponseWriter, r *http.Request) {
+	var chat models.Chat
+	if err := server.DB.Where("id = ?", mux.Vars(r)["id"]).First(&chat).Error; err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	server.markChatRead(w, &chat, "admin")
+}
+
+func (server *Server) markChatRead(w http.ResponseWriter, chat *models.Chat, reader string) {
+	now := time.Now()
+	if err := chat.MarkReadBy(server.DB, reader, now); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	server.ChatHub.ChatRead(*chat, reader, now)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "read_at": now})
+}
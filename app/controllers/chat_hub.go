@@ -0,0 +1,254 @@
+package controllers
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/alirogz/goshop/app/models"
+	"gorm.io/gorm"
+)
+
+// cache jumlah unread dianggap basi setelah ini (pesan dari instance lain / worker ikut terhitung lagi)
+const chatUnreadCacheTTL = time.Minute
+
+// buffer event per koneksi; subscriber yang tertinggal sejauh ini diputus (client akan reconnect)
+const chatSubscriberBuffer = 32
+
+// Jenis event realtime chat
+const (
+	chatEventMessage = "message" // pesan baru di chat yang sedang dibuka
+	chatEventRead    = "read"    // lawan bicara sudah membaca (read receipt)
+	chatEventUnread  = "unread"  // jumlah belum dibaca berubah (badge navbar / daftar chat admin)
+)
+
+// ChatEvent: payload JSON yang dikirim lewat WebSocket / SSE
+type ChatEvent struct {
+	Type    string              `json:"type"`
+	ChatID  string              `json:"chat_id,omitempty"`
+	Message *models.ChatMessage `json:"message,omitempty"`
+
+	// read: "user" / "admin" & waktunya
+	Reader string     `json:"reader,omitempty"`
+	ReadAt *time.Time `json:"read_at,omitempty"`
+
+	// unread: scope "user" (badge Chat) atau "admin" (badge Admin Chats, plus jumlah di chat_id)
+	Scope     string `json:"scope,omitempty"`
+	Count     int64  `json:"count"`
+	ChatCount int64  `json:"chat_count,omitempty"`
+}
+
+// chatSubscriber: 1 koneksi browser.
+// Customer & admin sama-sama menerima event chat yang sedang dibuka (chatID);
+// admin juga menerima perubahan unread semua chat.
+type chatSubscriber struct {
+	userID string
+	chatID string
+	admin  bool
+
+	events chan ChatEvent
+	done   chan struct{}
+	once   sync.Once
+}
+
+func (s *chatSubscriber) drop() {
+	s.once.Do(func() { close(s.done) })
+}
+
+type cachedUnread struct {
+	count    int64
+	loadedAt time.Time
+}
+
+// ChatHub: pub/sub realtime chat di dalam 1 proses + cache jumlah unread untuk badge navbar.
+type ChatHub struct {
+	db *gorm.DB
+
+	mu   sync.RWMutex
+	subs map[*chatSubscriber]struct{}
+
+	countMu       sync.Mutex
+	userUnread    map[string]cachedUnread // key: user id (total semua thread milik user)
+	adminUnread   map[string]int64        // key: chat id
+	adminLoadedAt time.Time
+}
+
+func newChatHub(db *gorm.DB) *ChatHub {
+	return &ChatHub{
+		db:         db,
+		subs:       map[*chatSubscriber]struct{}{},
+		userUnread: map[string]cachedUnread{},
+	}
+}
+
+func (h *ChatHub) subscribe(userID, chatID string, admin bool) *chatSubscriber {
+	sub := &chatSubscriber{
+		userID: userID,
+		chatID: chatID,
+		admin:  admin,
+		events: make(chan ChatEvent, chatSubscriberBuffer),
+		done:   make(chan struct{}),
+	}
+
+	h.mu.Lock()
+	h.subs[sub] = struct{}{}
+	h.mu.Unlock()
+	return sub
+}
+
+func (h *ChatHub) unsubscribe(sub *chatSubscriber) {
+	h.mu.Lock()
+	delete(h.subs, sub)
+	h.mu.Unlock()
+	sub.drop()
+}
+
+// publish: kirim tanpa menunggu; subscriber yang buffer-nya penuh diputus
+func (h *ChatHub) publish(match func(*chatSubscriber) bool, event func(*chatSubscriber) ChatEvent) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for sub := range h.subs {
+		if !match(sub) {
+			continue
+		}
+		select {
+		case sub.events <- event(sub):
+		default:
+			sub.drop()
+		}
+	}
+}
+
+// MessageCreated: panggil setelah pesan tersimpan
+func (h *ChatHub) MessageCreated(chat models.Chat, msg models.ChatMessage) {
+	h.publish(func(s *chatSubscriber) bool {
+		return s.chatID == chat.ID
+	}, func(*chatSubscriber) ChatEvent {
+		return ChatEvent{Type: chatEventMessage, ChatID: chat.ID, Message: &msg}
+	})
+
+	if msg.SenderRole == "admin" {
+		h.countMu.Lock()
+		if cached, ok := h.userUnread[chat.UserID]; ok {
+			cached.count++
+			h.userUnread[chat.UserID] = cached
+		}
+		h.countMu.Unlock()
+		h.publishUserUnread(chat.UserID)
+		return
+	}
+
+	h.countMu.Lock()
+	if h.adminUnread != nil {
+		h.adminUnread[chat.ID]++
+	}
+	h.countMu.Unlock()
+	h.publishAdminUnread(chat.ID)
+}
+
+// ChatRead: reader ("user" / "admin") sudah membaca chat sampai `at`
+func (h *ChatHub) ChatRead(chat models.Chat, reader string, at time.Time) {
+	h.publish(func(s *chatSubscriber) bool {
+		return s.chatID == chat.ID
+	}, func(*chatSubscriber) ChatEvent {
+		return ChatEvent{Type: chatEventRead, ChatID: chat.ID, Reader: reader, ReadAt: &at}
+	})
+
+	if reader == "admin" {
+		h.countMu.Lock()
+		if h.adminUnread != nil {
+			delete(h.adminUnread, chat.ID)
+		}
+		h.countMu.Unlock()
+		h.publishAdminUnread(chat.ID)
+		return
+	}
+
+	// user bisa punya beberapa thread: hitung ulang totalnya
+	h.countMu.Lock()
+	delete(h.userUnread, chat.UserID)
+	h.countMu.Unlock()
+	h.publishUserUnread(chat.UserID)
+}
+
+func (h *ChatHub) publishUserUnread(userID string) {
+	count := h.UserUnread(userID)
+	h.publish(func(s *chatSubscriber) bool {
+		return s.userID == userID
+	}, func(*chatSubscriber) ChatEvent {
+		return ChatEvent{Type: chatEventUnread, Scope: "user", Count: count}
+	})
+}
+
+func (h *ChatHub) publishAdminUnread(chatID string) {
+	total := h.AdminUnread()
+	chatCount := h.AdminUnreadFor(chatID)
+	h.publish(func(s *chatSubscriber) bool {
+		return s.admin
+	}, func(*chatSubscriber) ChatEvent {
+		return ChatEvent{Type: chatEventUnread, Scope: "admin", ChatID: chatID, Count: total, ChatCount: chatCount}
+	})
+}
+
+// UserUnread: badge "Chat" untuk customer (cache, query ulang kalau basi)
+func (h *ChatHub) UserUnread(userID string) int64 {
+	h.countMu.Lock()
+	cached, ok := h.userUnread[userID]
+	h.countMu.Unlock()
+	if ok && time.Since(cached.loadedAt) < chatUnreadCacheTTL {
+		return cached.count
+	}
+
+	count, err := models.CountUserUnread(h.db, userID)
+	if err != nil {
+		log.Println("CountUserUnread error:", err)
+		return cached.count
+	}
+
+	h.countMu.Lock()
+	h.userUnread[userID] = cachedUnread{count: count, loadedAt: time.Now()}
+	h.countMu.Unlock()
+	return count
+}
+
+// AdminUnread: badge "Admin Chats" (total semua chat)
+func (h *ChatHub) AdminUnread() int64 {
+	var total int64
+	for _, n := range h.adminUnreadByChat() {
+		total += n
+	}
+	return total
+}
+
+// AdminUnreadFor: jumlah belum dibaca admin di 1 chat
+func (h *ChatHub) AdminUnreadFor(chatID string) int64 {
+	return h.adminUnreadByChat()[chatID]
+}
+
+// adminUnreadByChat: salinan cache per chat, dimuat ulang dengan 1 query GROUP BY kalau basi
+func (h *ChatHub) adminUnreadByChat() map[string]int64 {
+	h.countMu.Lock()
+	fresh := h.adminUnread != nil && time.Since(h.adminLoadedAt) < chatUnreadCacheTTL
+	h.countMu.Unlock()
+
+	if !fresh {
+		counts, err := models.AdminUnreadByChat(h.db)
+		if err != nil {
+			log.Println("AdminUnreadByChat error:", err)
+		} else {
+			h.countMu.Lock()
+			h.adminUnread = counts
+			h.adminLoadedAt = time.Now()
+			h.countMu.Unlock()
+		}
+	}
+
+	h.countMu.Lock()
+	defer h.countMu.Unlock()
+	out := make(map[string]int64, len(h.adminUnread))
+	for id, n := range h.adminUnread {
+		out[id] = n
+	}
+	return out
+}
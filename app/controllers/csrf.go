@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+)
+
+const (
+	csrfFieldName  = "csrf_token"   // hidden input di form
+	csrfHeaderName = "X-CSRF-Token" // untuk fetch / ajax
+	csrfSessionKey = "token"
+)
+
+var sessionCSRF = "csrf-session"
+
+// rute yang dipanggil server lain (bukan form di browser), jadi tidak membawa token
+var csrfExemptPrefixes = []string{
+	"/payments/notification",
+	"/payments/fake/",
+	"/storage/fake/",
+}
+
+type csrfTokenKey struct{}
+
+// CSRFProtect: middleware router. Setiap sesi browser punya 1 token acak;
+// request POST/PUT/PATCH/DELETE wajib mengirim token itu lewat field csrf_token atau header X-CSRF-Token
+// (form multipart: hanya header, lihat submittedCSRFToken).
+func (server *Server) CSRFProtect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if csrfExempt(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, err := sessionCSRFToken(w, r)
+		if err != nil {
+			log.Println("csrf: gagal membuat token:", err)
+			http.Error(w, "Gagal menyiapkan sesi, coba muat ulang halaman", http.StatusInternalServerError)
+			return
+		}
+
+		if !csrfSafeMethod(r.Method) && !validCSRFToken(token, submittedCSRFToken(r)) {
+			http.Error(w, "Sesi formulir tidak valid atau kedaluwarsa. Muat ulang halaman lalu coba lagi.", http.StatusForbidden)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), csrfTokenKey{}, token)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RotateCSRFToken: ganti token setelah login supaya token sebelum login tidak bisa dipakai lagi
+func RotateCSRFToken(w http.ResponseWriter, r *http.Request) {
+	session, _ := store.Get(r, sessionCSRF)
+	token, err := newCSRFToken()
+	if err != nil {
+		log.Println("csrf: gagal membuat token:", err)
+		return
+	}
+	session.Values[csrfSessionKey] = token
+	session.Save(r, w)
+}
+
+// CSRFToken: token sesi untuk request ini (kosong kalau rute tidak lewat CSRFProtect)
+func CSRFToken(r *http.Request) string {
+	if r == nil {
+		return ""
+	}
+	token, _ := r.Context().Value(csrfTokenKey{}).(string)
+	return token
+}
+
+// csrfTemplateFuncs: helper template {{ csrfField }} (hidden input) & {{ csrfToken }} (untuk meta / JS)
+func csrfTemplateFuncs(r *http.Request) template.FuncMap {
+	token := CSRFToken(r)
+	return template.FuncMap{
+		"csrfToken": func() string {
+			return token
+		},
+		"csrfField": func() template.HTML {
+			return template.HTML(`<input type="hidden" name="` + csrfFieldName + `" value="` + template.HTMLEscapeString(token) + `">`)
+		},
+	}
+}
+
+func sessionCSRFToken(w http.ResponseWriter, r *http.Request) (string, error) {
+	session, _ := store.Get(r, sessionCSRF)
+	if token, ok := session.Values[csrfSessionKey].(string); ok && token != "" {
+		return token, nil
+	}
+
+	token, err := newCSRFToken()
+	if err != nil {
+		return "", err
+	}
+	session.Values[csrfSessionKey] = token
+	if err := session.Save(r, w); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// submittedCSRFToken: header X-CSRF-Token, lalu field csrf_token.
+// Body multipart (upload) tidak dibaca di sini supaya batas ukuran di handler (MaxBytesReader) tetap berlaku;
+// form upload dikirim lewat fetch dengan header (lihat layout.html). Token tidak diterima dari query
+// karena URL ikut tercatat di log server & header Referer.
+func submittedCSRFToken(r *http.Request) string {
+	if token := r.Header.Get(csrfHeaderName); token != "" {
+		return token
+	}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/") {
+		return ""
+	}
+	return r.PostFormValue(csrfFieldName)
+}
+
+func validCSRFToken(expected, got string) bool {
+	if expected == "" || got == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(got)) == 1
+}
+
+func csrfSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+func csrfExempt(path string) bool {
+	for _, prefix := range csrfExemptPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func newCSRFToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
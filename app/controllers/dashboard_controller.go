@@ -2,25 +2,34 @@ package controllers
 
 import (
 	"net/http"
+
+	"github.com/alirogz/goshop/app/consts"
 )
 
+// halaman admin sesuai urutan prioritas; dashboard membuka halaman pertama yang boleh diakses staff
+var adminHomePages = []struct {
+	Permission string
+	Path       string
+}{
+	{consts.PermOrdersView, "/admin/orders"},
+	{consts.PermPaymentsManage, "/admin/payments/reconcile"},
+	{consts.PermCatalogManage, "/admin/products"},
+	{consts.PermPromotionsManage, "/admin/promotions"},
+	{consts.PermChatsManage, "/admin/chats"},
+	{consts.PermSettingsManage, "/admin/settings"},
+}
+
 // AdminDashboard: untuk sementara tidak dipakai,
-// jadi langsung redirect ke halaman orders admin.
+// jadi langsung redirect ke halaman admin pertama yang boleh diakses user.
 func (s *Server) AdminDashboard(w http.ResponseWriter, r *http.Request) {
-	// Pastikan user sudah login
-	if !IsLoggedIn(r) {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
-		return
-	}
-
-	// Pastikan user adalah admin
 	user := s.CurrentUser(w, r)
-	if !IsAdminUser(user) {
-		SetFlash(w, r, "error", "Unauthorized")
-		http.Redirect(w, r, "/", http.StatusSeeOther)
-		return
+
+	for _, page := range adminHomePages {
+		if user.Can(page.Permission) {
+			http.Redirect(w, r, page.Path, http.StatusSeeOther)
+			return
+		}
 	}
 
-	// Fitur dashboard dimatikan → langsung alihkan ke daftar order
-	http.Redirect(w, r, "/admin/orders", http.StatusSeeOther)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
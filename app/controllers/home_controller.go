@@ -7,16 +7,16 @@ import (
 )
 
 func (server *Server) Home(w http.ResponseWriter, r *http.Request) {
-	ren := userRender()
+	ren := userRender(r)
 
 	user := server.CurrentUser(w, r)
 
 	// Ambil produk + preload gambar untuk home (trending items)
 	var products []models.Product
 	if err := server.DB.
-		Preload("ProductImages"). // <-- ini yang bikin gambar dari CRUD ikut ke-load
-		Order("created_at desc"). // urutkan dari yang terbaru
-		Limit(8).                 // tampilkan 8 produk
+		Preload("ProductImages", models.OrderProductImages). // <-- ini yang bikin gambar dari CRUD ikut ke-load
+		Order("created_at desc").                            // urutkan dari yang terbaru
+		Limit(8).                                            // tampilkan 8 produk
 		Find(&products).Error; err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -27,6 +27,8 @@ func (server *Server) Home(w http.ResponseWriter, r *http.Request) {
 		"isAdmin":   IsAdminUser(user),
 		"products":  products,
 		"cartCount": server.GetCartCount(w, r),
+		"flashes":   GetFlash(w, r, "success"),
+		"errors":    GetFlash(w, r, "error"),
 	}
 
 	server.InjectNavbarBadges(data, user)
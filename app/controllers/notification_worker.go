@@ -0,0 +1,53 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/alirogz/goshop/app/notifications"
+)
+
+// batas email yang dikirim per putaran worker
+const notificationBatch = 50
+
+// startNotificationWorker: kirim isi outbox email secara berkala.
+// Interval 0 = worker mati (misal kalau sudah dijalankan lewat cron "notifications:send").
+func (server *Server) startNotificationWorker() {
+	interval := server.AppConfig.NotificationInterval
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			server.runNotifications(time.Now())
+			<-ticker.C
+		}
+	}()
+}
+
+// runNotifications: 1 putaran kirim email yang sudah jatuh tempo (baru / jadwal retry)
+func (server *Server) runNotifications(now time.Time) (sent int, failed int) {
+	dispatcher := notifications.Dispatcher{
+		DB:      server.DB,
+		Mailer:  server.Mailer,
+		AppName: server.AppConfig.AppName,
+		BaseURL: server.AppConfig.AppURL,
+		Batch:   notificationBatch,
+	}
+
+	sent, failed, err := dispatcher.RunOnce(context.Background(), now)
+	if err != nil {
+		log.Println("notifications RunOnce error:", err)
+	}
+
+	if sent > 0 || failed > 0 {
+		log.Printf("notifications: %d email terkirim, %d gagal (dijadwal ulang)", sent, failed)
+	}
+
+	return sent, failed
+}
@@ -2,19 +2,17 @@ package controllers
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
-	"io"
 	"math"
-	"math/rand"
 	"net/http"
-	"os"
-	"path/filepath"
+	"sort"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/alirogz/goshop/app/consts"
 	"github.com/alirogz/goshop/app/models"
+	"github.com/alirogz/goshop/app/money"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/shopspring/decimal"
@@ -30,7 +28,7 @@ type CheckoutRequest struct {
 type ShippingFee struct {
 	Courier     string
 	PackageName string
-	Fee         float64
+	Fee         decimal.Decimal
 }
 
 type ShippingAddress struct {
@@ -53,6 +51,16 @@ func (server *Server) Checkout(w http.ResponseWriter, r *http.Request) {
 	}
 
 	user := server.CurrentUser(w, r)
+	if user == nil {
+		SetFlash(w, r, "error", "Anda perlu login!")
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	if !user.IsEmailVerified() {
+		SetFlash(w, r, "error", "Verifikasi email kamu dulu sebelum checkout.")
+		http.Redirect(w, r, "/carts", http.StatusSeeOther)
+		return
+	}
 
 	shippingCost, err := server.getSelectedShippingCost(w, r)
 	if err != nil {
@@ -165,7 +173,7 @@ func (server *Server) Checkout(w http.ResponseWriter, r *http.Request) {
 // app/controllers/order_controller.go
 
 func (server *Server) ShowOrder(w http.ResponseWriter, r *http.Request) {
-	ren := userRender()
+	ren := userRender(r)
 	vars := mux.Vars(r)
 	id := vars["id"]
 
@@ -182,7 +190,8 @@ func (server *Server) ShowOrder(w http.ResponseWriter, r *http.Request) {
 		Preload("OrderCustomer").
 		Preload("OrderItems").
 		Preload("OrderItems.Product").
-		Preload("OrderItems.Product.ProductImages").
+		Preload("OrderItems.Product.ProductImages", models.OrderProductImages).
+		Preload("PaymentProofs", models.OrderPaymentProofs).
 		Where("id = ? AND user_id = ?", id, user.ID).
 		First(&order).Error
 	if err != nil {
@@ -195,12 +204,13 @@ func (server *Server) ShowOrder(w http.ResponseWriter, r *http.Request) {
 	server.hydrateOrderDetail(&order)
 
 	data := map[string]interface{}{
-		"user":      user,
-		"isAdmin":   IsAdminUser(user),
-		"order":     order,
-		"cartCount": server.GetCartCount(w, r),
-		"success":   GetFlash(w, r, "success"),
-		"error":     GetFlash(w, r, "error"),
+		"user":         user,
+		"isAdmin":      IsAdminUser(user),
+		"order":        order,
+		"canPayOnline": server.PaymentGateway != nil && !order.IsPaid() && !order.IsClosed(),
+		"cartCount":    server.GetCartCount(w, r),
+		"success":      GetFlash(w, r, "success"),
+		"error":        GetFlash(w, r, "error"),
 	}
 	server.InjectNavbarBadges(data, user)
 	_ = ren.HTML(w, http.StatusOK, "order_detail", data)
@@ -220,37 +230,22 @@ func (server *Server) hydrateOrderDetail(order *models.Order) {
 			item.Name = item.Product.Name
 		}
 
-		// Gambar produk:
-		// 1) kalau kamu pakai tabel product_images → pakai itu
-		if len(item.Product.ProductImages) > 0 {
-			first := item.Product.ProductImages[0]
-			item.ProductImageURL = "/uploads/products/" + first.Path
-
-			// 2) kalau tidak ada, pakai kolom tunggal Product.Image (hasil upload ke public/uploads)
-		} else if item.Product.Image != "" {
-			item.ProductImageURL = "/public/uploads/" + item.Product.Image
-
-			// 3) fallback terakhir → no-image
+		// Gambar produk: gambar utama galeri, lalu kolom lama Product.Image, terakhir no-image
+		if url := item.Product.ImageURL(consts.ImageSizeSmall); url != "" {
+			item.ProductImageURL = url
 		} else {
 			item.ProductImageURL = "/assets/img/no-image.png"
 		}
 	}
 }
 
-func (server *Server) getSelectedShippingCost(w http.ResponseWriter, r *http.Request) (float64, error) {
+func (server *Server) getSelectedShippingCost(w http.ResponseWriter, r *http.Request) (decimal.Decimal, error) {
 	_ = r.ParseForm()
-	feeStr := r.FormValue("shipping_fee") // harus numeric (contoh: 14000)
-	if feeStr == "" {
-		return 0, nil
-	}
-	f, err := strconv.ParseFloat(feeStr, 64)
-	if err != nil {
-		return 0, nil
-	}
-	if f < 0 {
-		f = 0
+	fee, err := money.Parse(r.FormValue("shipping_fee")) // harus numeric (contoh: 14000)
+	if err != nil || fee.IsNegative() {
+		return decimal.Zero, nil
 	}
-	return f, nil
+	return fee, nil
 }
 
 func (server *Server) SaveOrder(user *models.User, r *CheckoutRequest) (*models.Order, error) {
@@ -260,11 +255,21 @@ func (server *Server) SaveOrder(user *models.User, r *CheckoutRequest) (*models.
 	// Pembayaran manual → tidak pakai payment URL
 	paymentURL := ""
 
+	if r.Cart == nil || len(r.Cart.CartItems) == 0 {
+		return nil, errors.New("keranjang masih kosong")
+	}
+
 	// isi orderItems dari isi cart
 	if len(r.Cart.CartItems) > 0 {
 		for _, cartItem := range r.Cart.CartItems {
+			sku := cartItem.Product.Sku
+			if cartItem.Variant.ID != "" {
+				sku = cartItem.Variant.SkuFor(cartItem.Product)
+			}
+
 			orderItems = append(orderItems, models.OrderItem{
 				ProductID:       cartItem.ProductID,
+				VariantID:       cartItem.VariantID,
 				Qty:             cartItem.Qty,
 				BasePrice:       cartItem.BasePrice,
 				BaseTotal:       cartItem.BaseTotal,
@@ -273,10 +278,11 @@ func (server *Server) SaveOrder(user *models.User, r *CheckoutRequest) (*models.
 				DiscountAmount:  cartItem.DiscountAmount,
 				DiscountPercent: cartItem.DiscountPercent,
 				SubTotal:        cartItem.SubTotal,
-				Sku:             cartItem.Product.Sku,
+				Sku:             sku,
 				Name:            cartItem.Product.Name,
-				Weight:          cartItem.Product.Weight,
+				Weight:          cartItem.UnitWeight(),
 				Size:            cartItem.Size,
+				Color:           cartItem.Color,
 				OrderID:         orderID,
 			})
 		}
@@ -298,12 +304,7 @@ func (server *Server) SaveOrder(user *models.User, r *CheckoutRequest) (*models.
 	}
 
 	// hitung ongkir & grand total
-	shipDec := decimal.NewFromFloat(r.ShippingFee.Fee)
-	grandTotal := r.Cart.GrandTotal.Add(shipDec)
-
-	// generate kode unik 3 digit
-	rand.Seed(time.Now().UnixNano())
-	uniqueCode := rand.Intn(900) + 100 // 100–999
+	shipDec := money.Round(r.ShippingFee.Fee)
 
 	// siapkan data order
 	orderData := &models.Order{
@@ -311,28 +312,66 @@ func (server *Server) SaveOrder(user *models.User, r *CheckoutRequest) (*models.
 		UserID:              user.ID,
 		OrderItems:          orderItems,
 		OrderCustomer:       orderCustomer,
-		Status:              0,
+		Status:              consts.OrderStatusPending,
 		OrderDate:           time.Now(),
 		PaymentDue:          time.Now().AddDate(0, 0, 7),
 		PaymentStatus:       consts.OrderPaymentStatusUnpaid,
-		PaymentMethod:       "Transfer Bank",
+		PaymentMethod:       consts.OrderPaymentMethodBankTransfer,
 		BaseTotalPrice:      r.Cart.BaseTotalPrice,
 		TaxAmount:           r.Cart.TaxAmount,
 		TaxPercent:          r.Cart.TaxPercent,
-		DiscountAmount:      r.Cart.DiscountAmount,
-		DiscountPercent:     r.Cart.DiscountPercent,
 		ShippingCost:        shipDec,
-		GrandTotal:          grandTotal, // subtotal + tax + ongkir
 		ShippingCourier:     r.ShippingFee.Courier,
 		ShippingServiceName: r.ShippingFee.PackageName,
 		PaymentToken:        sql.NullString{String: paymentURL, Valid: paymentURL != ""},
-		// fitur 1: kode unik & total transfer
-		PaymentUniqueCode: uniqueCode,
-		PaymentTotal:      grandTotal.Add(decimal.NewFromInt(int64(uniqueCode))),
 	}
 
-	orderModel := models.Order{}
-	order, err := orderModel.CreateOrder(server.DB, orderData)
+	// potong stok + pakai kuota promo + simpan order dalam 1 transaksi.
+	// AdjustStock & RedeemPromotions memakai row lock, jadi 2 pembeli tidak bisa
+	// mengambil unit terakhir / kuota kupon terakhir bersamaan.
+	var order *models.Order
+	err := server.DB.Transaction(func(tx *gorm.DB) error {
+		// promo dihitung ulang dengan user yang checkout (batas per customer)
+		promotions, couponErr := models.EvaluateCartPromotions(tx, models.PromotionLinesFromCartItems(r.Cart.CartItems), r.Cart.CouponCode, user.ID)
+		if couponErr != nil {
+			return fmt.Errorf("kupon %s tidak bisa dipakai: %w", r.Cart.CouponCode, couponErr)
+		}
+		tax, err := models.LoadTaxSettings(tx)
+		if err != nil {
+			return err
+		}
+		applyOrderTotals(orderData, r.Cart, tax, promotions)
+		if r.Cart.CouponCode != "" {
+			orderData.CouponCode = models.NormalizeCouponCode(r.Cart.CouponCode)
+		}
+
+		// kode unik dipilih setelah grand total final, supaya total transfer tidak bentrok dengan order lain
+		uniqueCode, err := models.AllocatePaymentUniqueCode(tx, orderID, orderData.GrandTotal)
+		if err != nil {
+			return err
+		}
+		orderData.PaymentUniqueCode = uniqueCode
+		orderData.PaymentTotal = orderData.GrandTotal.Add(money.FromInt(int64(uniqueCode)))
+
+		if err := models.RedeemPromotions(tx, orderID, user.ID, promotions, orderData.ShippingDiscount); err != nil {
+			return err
+		}
+
+		for _, line := range stockLinesFromOrderItems(orderItems) {
+			if err := models.AdjustStock(tx, line.ProductID, line.VariantID, -line.Qty, consts.InventoryReasonOrderPlaced, orderID, user.ID, ""); err != nil {
+				return err
+			}
+		}
+
+		orderModel := models.Order{}
+		created, err := orderModel.CreateOrder(tx, orderData)
+		if err != nil {
+			return err
+		}
+
+		order = created
+		return models.EnqueueOrderNotification(tx, consts.NotificationOrderPlaced, created, nil)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -340,6 +379,72 @@ func (server *Server) SaveOrder(user *models.User, r *CheckoutRequest) (*models.
 	return order, nil
 }
 
+// applyOrderTotals: isi total baris item, diskon, potongan ongkir & grand total order.
+// Potongan promo dibagi ke baris item dulu baru pajak dihitung (sama dengan cara CalculateCart),
+// jadi pajak, baris item & grand total memakai dasar yang sama.
+// grand total = (subtotal - diskon + pajak) + (ongkir - potongan ongkir)
+func applyOrderTotals(order *models.Order, cart *models.Cart, tax models.TaxSettings, promotions models.CartPromotionResult) {
+	totals := money.Totals{}
+	for i, cartItem := range cart.CartItems {
+		line := tax.Line(cartItem.Product, cartItem.BasePrice, cartItem.Qty)
+		line.Discount = promotions.LineDiscount(i)
+		lineTotals := line.Calculate()
+		totals = totals.Add(lineTotals)
+
+		if i < len(order.OrderItems) {
+			item := &order.OrderItems[i]
+			item.BaseTotal = lineTotals.Base
+			item.TaxPercent = line.TaxPercent
+			item.TaxAmount = lineTotals.Tax
+			item.DiscountAmount = lineTotals.Discount
+			item.DiscountPercent = money.Ratio(lineTotals.Discount, lineTotals.Base)
+			item.SubTotal = lineTotals.Total
+		}
+	}
+
+	order.BaseTotalPrice = totals.Base
+	order.TaxAmount = totals.Tax
+	order.TaxPercent = tax.Rate
+	order.PricesIncludeTax = tax.PricesIncludeTax
+	order.DiscountAmount = totals.Discount
+	order.DiscountPercent = money.Ratio(totals.Discount, totals.Base)
+
+	order.ShippingDiscount = promotions.ShippingDiscount(order.ShippingCost)
+	order.GrandTotal = totals.Total.
+		Add(order.ShippingCost).
+		Sub(order.ShippingDiscount)
+}
+
+type stockLine struct {
+	ProductID string
+	VariantID string
+	Qty       int
+}
+
+// stockLinesFromOrderItems: gabungkan qty per produk/varian & urutkan berdasarkan ID
+// supaya urutan lock selalu sama (menghindari deadlock antar checkout)
+func stockLinesFromOrderItems(items []models.OrderItem) []stockLine {
+	type stockKey struct{ productID, variantID string }
+
+	qtyByKey := map[stockKey]int{}
+	for _, item := range items {
+		qtyByKey[stockKey{item.ProductID, item.VariantID}] += item.Qty
+	}
+
+	lines := make([]stockLine, 0, len(qtyByKey))
+	for key, qty := range qtyByKey {
+		lines = append(lines, stockLine{ProductID: key.productID, VariantID: key.variantID, Qty: qty})
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		if lines[i].ProductID != lines[j].ProductID {
+			return lines[i].ProductID < lines[j].ProductID
+		}
+		return lines[i].VariantID < lines[j].VariantID
+	})
+
+	return lines
+}
+
 // helper: isi ProductImageURL utk setiap item
 func attachProductImagesToOrder(db *gorm.DB, order *models.Order) {
 	if order == nil {
@@ -350,16 +455,13 @@ func attachProductImagesToOrder(db *gorm.DB, order *models.Order) {
 		item := &order.OrderItems[i]
 
 		// preload ProductImages untuk product ini
-		var images []models.ProductImage
-		if err := db.
-			Where("product_id = ?", item.ProductID).
-			Order("created_at ASC").
-			Find(&images).Error; err != nil {
+		images, err := models.ListProductImages(db, item.ProductID)
+		if err != nil {
 			continue
 		}
 
-		if len(images) > 0 {
-			item.ProductImageURL = "/uploads/products/" + images[0].Path
+		if url := (models.Product{ProductImages: images}).ImageURL(consts.ImageSizeSmall); url != "" {
+			item.ProductImageURL = url
 		}
 	}
 }
@@ -367,7 +469,7 @@ func attachProductImagesToOrder(db *gorm.DB, order *models.Order) {
 // PayManual: simulasi pembayaran manual (tanpa Midtrans)
 // Hanya boleh dilakukan oleh user pemilik order (atau kamu bisa perluas nanti untuk admin).
 func (server *Server) PayManual(w http.ResponseWriter, r *http.Request) {
-	ren := userRender()
+	ren := userRender(r)
 	vars := mux.Vars(r)
 	id := vars["id"]
 
@@ -387,49 +489,8 @@ func (server *Server) PayManual(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// handle upload
-	err := r.ParseMultipartForm(10 << 20) // 10MB
-	if err != nil {
-		SetFlash(w, r, "error", "Gagal membaca form upload.")
-		http.Redirect(w, r, "/orders/"+id+"/pay-manual", http.StatusSeeOther)
-		return
-	}
-
-	file, handler, err := r.FormFile("payment_proof")
-	if err != nil {
-		SetFlash(w, r, "error", "Silakan pilih file bukti transfer.")
-		http.Redirect(w, r, "/orders/"+id+"/pay-manual", http.StatusSeeOther)
-		return
-	}
-	defer file.Close()
-
-	// buat folder kalau belum ada
-	uploadDir := "./uploads/payments"
-	_ = os.MkdirAll(uploadDir, os.ModePerm)
-
-	ext := filepath.Ext(handler.Filename)
-	filename := fmt.Sprintf("order-%s-%d%s", order.ID, time.Now().Unix(), ext)
-	filepathFull := filepath.Join(uploadDir, filename)
-
-	dst, err := os.Create(filepathFull)
-	if err != nil {
-		SetFlash(w, r, "error", "Gagal menyimpan file di server.")
-		http.Redirect(w, r, "/orders/"+id+"/pay-manual", http.StatusSeeOther)
-		return
-	}
-	defer dst.Close()
-
-	if _, err := io.Copy(dst, file); err != nil {
-		SetFlash(w, r, "error", "Gagal menyalin file.")
-		http.Redirect(w, r, "/orders/"+id+"/pay-manual", http.StatusSeeOther)
-		return
-	}
-
-	// update order
-	order.PaymentProof = filename
-	order.PaymentStatus = "waiting_review"
-	if err := server.DB.Save(&order).Error; err != nil {
-		SetFlash(w, r, "error", "Gagal menyimpan data pembayaran.")
+	if err := server.receivePaymentProof(w, r, &order, user); err != nil {
+		SetFlash(w, r, "error", paymentProofErrorText(err))
 		http.Redirect(w, r, "/orders/"+id+"/pay-manual", http.StatusSeeOther)
 		return
 	}
@@ -439,36 +500,7 @@ func (server *Server) PayManual(w http.ResponseWriter, r *http.Request) {
 	_ = ren
 }
 
-func savePaymentProofFile(r *http.Request, fieldName string) (string, error) {
-	file, header, err := r.FormFile(fieldName)
-	if err != nil {
-		return "", err
-	}
-	defer file.Close()
-
-	// buat folder kalau belum ada
-	uploadDir := "public/payment_proofs"
-	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		return "", err
-	}
-
-	// nama file simpel: timestamp + original name
-	filename := fmt.Sprintf("%d_%s", time.Now().UnixNano(), header.Filename)
-	filepath := filepath.Join(uploadDir, filename)
-
-	out, err := os.Create(filepath)
-	if err != nil {
-		return "", err
-	}
-	defer out.Close()
-
-	if _, err := io.Copy(out, file); err != nil {
-		return "", err
-	}
-
-	return filename, nil
-}
-
+// POST /orders/{id}/payment-proof: unggah bukti bayar tambahan dari halaman detail pesanan
 func (server *Server) UploadPaymentProof(w http.ResponseWriter, r *http.Request) {
 	if !IsLoggedIn(r) {
 		SetFlash(w, r, "error", "Silakan login terlebih dahulu.")
@@ -491,19 +523,8 @@ func (server *Server) UploadPaymentProof(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// ambil file dari form dengan name="payment_proof"
-	filename, err := savePaymentProofFile(r, "payment_proof")
-	if err != nil {
-		SetFlash(w, r, "error", "Gagal mengunggah bukti pembayaran.")
-		http.Redirect(w, r, "/orders/"+id, http.StatusSeeOther)
-		return
-	}
-
-	// update order
-	order.PaymentProof = filename
-	order.PaymentStatus = "WAITING_REVIEW" // sesuaikan enum kamu
-	if err := server.DB.Save(&order).Error; err != nil {
-		SetFlash(w, r, "error", "Gagal menyimpan bukti pembayaran.")
+	if err := server.receivePaymentProof(w, r, &order, user); err != nil {
+		SetFlash(w, r, "error", paymentProofErrorText(err))
 		http.Redirect(w, r, "/orders/"+id, http.StatusSeeOther)
 		return
 	}
@@ -517,7 +538,7 @@ func (server *Server) UploadPaymentProof(w http.ResponseWriter, r *http.Request)
 // =========================
 
 func (server *Server) OrdersIndex(w http.ResponseWriter, r *http.Request) {
-	ren := userRender()
+	ren := userRender(r)
 
 	if !IsLoggedIn(r) {
 		SetFlash(w, r, "error", "Anda perlu login dulu untuk melihat pesanan.")
@@ -536,15 +557,8 @@ func (server *Server) OrdersIndex(w http.ResponseWriter, r *http.Request) {
 
 	statusFilter := r.URL.Query().Get("status")
 	if statusFilter != "" && statusFilter != "all" {
-		switch strings.ToLower(statusFilter) {
-		case "pending":
-			q = q.Where("status = ?", 0)
-		case "processing":
-			q = q.Where("status = ?", 1)
-		case "shipped":
-			q = q.Where("status = ?", 2)
-		case "completed":
-			q = q.Where("status = ?", 3)
+		if status, ok := models.OrderStatusFromName(statusFilter); ok {
+			q = q.Where("status = ?", status)
 		}
 	}
 
@@ -617,6 +631,7 @@ func (server *Server) OrdersIndex(w http.ResponseWriter, r *http.Request) {
 		"currentPage":   page,
 		"totalPages":    totalPages,
 		"statusFilter":  statusFilter,
+		"statusOptions": models.OrderStatusOptions(),
 		"paymentFilter": paymentFilter,
 		"dateFrom":      dateFrom,
 		"dateTo":        dateTo,
@@ -627,7 +642,7 @@ func (server *Server) OrdersIndex(w http.ResponseWriter, r *http.Request) {
 }
 
 func (server *Server) PayManualForm(w http.ResponseWriter, r *http.Request) {
-	ren := userRender()
+	ren := userRender(r)
 	vars := mux.Vars(r)
 	id := vars["id"]
 
@@ -640,7 +655,8 @@ func (server *Server) PayManualForm(w http.ResponseWriter, r *http.Request) {
 	user := server.CurrentUser(w, r)
 
 	var order models.Order
-	if err := server.DB.Where("id = ? AND user_id = ?", id, user.ID).
+	if err := server.DB.Preload("PaymentProofs", models.OrderPaymentProofs).
+		Where("id = ? AND user_id = ?", id, user.ID).
 		First(&order).Error; err != nil {
 		SetFlash(w, r, "error", "Pesanan tidak ditemukan.")
 		http.Redirect(w, r, "/orders", http.StatusSeeOther)
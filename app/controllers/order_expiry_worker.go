@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"log"
+	"time"
+
+	"github.com/alirogz/goshop/app/models"
+)
+
+// batas order yang diproses per putaran
+const paymentExpiryBatch = 200
+
+// startPaymentExpiryWorker: jalankan pembatalan otomatis & pengingat pembayaran berkala.
+// Interval 0 = worker mati (misal kalau sudah dijalankan lewat cron "orders:expire").
+func (server *Server) startPaymentExpiryWorker() {
+	interval := server.AppConfig.PaymentExpiryInterval
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			server.runPaymentExpiry(time.Now())
+			<-ticker.C
+		}
+	}()
+}
+
+// runPaymentExpiry: 1 putaran pengingat + pembatalan order yang lewat batas bayar
+func (server *Server) runPaymentExpiry(now time.Time) (reminded int, expired int) {
+	reminded, err := models.SendPaymentReminders(server.DB, now, server.AppConfig.PaymentReminderBefore, paymentExpiryBatch)
+	if err != nil {
+		log.Println("SendPaymentReminders error:", err)
+	}
+
+	expired, err = models.ExpireUnpaidOrders(server.DB, now, paymentExpiryBatch)
+	if err != nil {
+		log.Println("ExpireUnpaidOrders error:", err)
+	}
+
+	if reminded > 0 || expired > 0 {
+		log.Printf("payment expiry: %d pengingat dikirim, %d order dibatalkan", reminded, expired)
+	}
+
+	return reminded, expired
+}
@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/alirogz/goshop/app/consts"
+	"github.com/alirogz/goshop/app/models"
+	"github.com/alirogz/goshop/app/money"
+	"github.com/shopspring/decimal"
+)
+
+// potongan promo di order ikut mengurangi dasar pajak & tercatat di baris item
+func TestApplyOrderTotalsDiscountBeforeTax(t *testing.T) {
+	rate := decimal.NewFromInt(11)
+	for _, inclusive := range []bool{false, true} {
+		price := money.FromInt(100_000)
+		if inclusive {
+			price = money.FromInt(111_000)
+		}
+		cart := &models.Cart{CartItems: []models.CartItem{
+			{Product: models.Product{TaxClass: consts.TaxClassTaxable}, BasePrice: price, Qty: 2},
+		}}
+		promotions := models.CartPromotionResult{
+			ItemDiscount:  money.PercentOf(price.Mul(decimal.NewFromInt(2)), decimal.NewFromInt(10)),
+			LineDiscounts: []decimal.Decimal{money.PercentOf(price.Mul(decimal.NewFromInt(2)), decimal.NewFromInt(10))},
+		}
+		order := &models.Order{OrderItems: make([]models.OrderItem, 1), ShippingCost: money.FromInt(15_000)}
+
+		applyOrderTotals(order, cart, models.TaxSettings{Rate: rate, PricesIncludeTax: inclusive}, promotions)
+
+		base := order.BaseTotalPrice.Sub(order.DiscountAmount)
+		want := money.PercentOf(base, rate)
+		if inclusive {
+			want = money.Round(base.Mul(rate).Div(rate.Add(decimal.NewFromInt(100))))
+		}
+		if !order.TaxAmount.Equal(want) || !order.TaxAmount.Equal(money.FromInt(19_800)) {
+			t.Fatalf("inclusive=%v: pajak %s, want %s", inclusive, order.TaxAmount, want)
+		}
+
+		item := order.OrderItems[0]
+		if !item.DiscountAmount.Equal(order.DiscountAmount) || !item.TaxAmount.Equal(order.TaxAmount) {
+			t.Fatalf("inclusive=%v: baris item %+v tidak sama dengan order", inclusive, item)
+		}
+		if !order.GrandTotal.Equal(item.SubTotal.Add(order.ShippingCost)) || !order.GrandTotal.Equal(money.FromInt(214_800)) {
+			t.Fatalf("inclusive=%v: grand total %s, subtotal baris %s", inclusive, order.GrandTotal, item.SubTotal)
+		}
+	}
+}
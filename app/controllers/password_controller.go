@@ -0,0 +1,249 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/alirogz/goshop/app/consts"
+	"github.com/alirogz/goshop/app/mailer"
+	"github.com/alirogz/goshop/app/models"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+const minPasswordLength = 8
+
+// pesan sama untuk email terdaftar / tidak, supaya form tidak bisa dipakai mengecek email orang lain
+const forgotPasswordSent = "Kalau email tersebut terdaftar, link reset password sudah kami kirim. Link berlaku 1 jam."
+
+// GET /forgot-password
+func (server *Server) ForgotPasswordForm(w http.ResponseWriter, r *http.Request) {
+	ren := userRender(r)
+
+	_ = ren.HTML(w, http.StatusOK, "forgot_password", map[string]interface{}{
+		"user":      nil,
+		"isAdmin":   false,
+		"cartCount": server.GetCartCount(w, r),
+		"success":   GetFlash(w, r, "success"),
+		"error":     GetFlash(w, r, "error"),
+	})
+}
+
+// POST /forgot-password
+func (server *Server) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	email := strings.TrimSpace(r.FormValue("email"))
+	if email == "" {
+		SetFlash(w, r, "error", "Email wajib diisi.")
+		http.Redirect(w, r, "/forgot-password", http.StatusSeeOther)
+		return
+	}
+
+	userModel := models.User{}
+	user, err := userModel.FindByEmail(server.DB, email)
+	if err == nil {
+		// token & email dibuat di background: lama respon sama untuk email terdaftar / tidak
+		go server.sendPasswordReset(user)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Println("FindByEmail error:", err)
+	}
+
+	SetFlash(w, r, "success", forgotPasswordSent)
+	http.Redirect(w, r, "/forgot-password", http.StatusSeeOther)
+}
+
+// GET /reset-password/{token}
+func (server *Server) ResetPasswordForm(w http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+	if _, err := models.FindUserToken(server.DB, sessionKey(), token, consts.UserTokenPasswordReset); err != nil {
+		SetFlash(w, r, "error", "Link reset password tidak valid atau sudah kedaluwarsa. Silakan minta link baru.")
+		http.Redirect(w, r, "/forgot-password", http.StatusSeeOther)
+		return
+	}
+
+	ren := userRender(r)
+	_ = ren.HTML(w, http.StatusOK, "reset_password", map[string]interface{}{
+		"user":      nil,
+		"isAdmin":   false,
+		"cartCount": server.GetCartCount(w, r),
+		"token":     token,
+		"error":     GetFlash(w, r, "error"),
+	})
+}
+
+// POST /reset-password/{token}
+func (server *Server) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+	password := r.FormValue("password")
+	confirm := r.FormValue("password_confirmation")
+
+	if msg := validateNewPassword(password, confirm); msg != "" {
+		SetFlash(w, r, "error", msg)
+		http.Redirect(w, r, "/reset-password/"+token, http.StatusSeeOther)
+		return
+	}
+
+	hashed, err := MakePassword(password)
+	if err != nil {
+		SetFlash(w, r, "error", "Gagal memproses password baru.")
+		http.Redirect(w, r, "/reset-password/"+token, http.StatusSeeOther)
+		return
+	}
+
+	err = server.DB.Transaction(func(tx *gorm.DB) error {
+		userToken, err := models.ConsumeUserToken(tx, sessionKey(), token, consts.UserTokenPasswordReset)
+		if err != nil {
+			return err
+		}
+
+		// link reset juga membuktikan email milik user
+		return tx.Model(&models.User{}).Where("id = ?", userToken.UserID).Updates(map[string]interface{}{
+			"password":          hashed,
+			"email_verified_at": gorm.Expr("COALESCE(email_verified_at, ?)", time.Now()),
+		}).Error
+	})
+	if errors.Is(err, models.ErrUserTokenInvalid) {
+		SetFlash(w, r, "error", "Link reset password tidak valid atau sudah kedaluwarsa. Silakan minta link baru.")
+		http.Redirect(w, r, "/forgot-password", http.StatusSeeOther)
+		return
+	}
+	if err != nil {
+		log.Println("ResetPassword error:", err)
+		SetFlash(w, r, "error", "Gagal menyimpan password baru.")
+		http.Redirect(w, r, "/reset-password/"+token, http.StatusSeeOther)
+		return
+	}
+
+	SetFlash(w, r, "success", "Password berhasil diganti. Silakan login dengan password baru.")
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// GET /verify-email/{token}
+func (server *Server) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+
+	err := server.DB.Transaction(func(tx *gorm.DB) error {
+		userToken, err := models.ConsumeUserToken(tx, sessionKey(), token, consts.UserTokenEmailVerification)
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).
+			Where("id = ? AND email_verified_at IS NULL", userToken.UserID).
+			Update("email_verified_at", time.Now()).Error
+	})
+	if err != nil {
+		if !errors.Is(err, models.ErrUserTokenInvalid) {
+			log.Println("VerifyEmail error:", err)
+		}
+		SetFlash(w, r, "error", "Link verifikasi tidak valid atau sudah kedaluwarsa. Kirim ulang dari halaman keranjang.")
+		http.Redirect(w, r, "/carts", http.StatusSeeOther)
+		return
+	}
+
+	SetFlash(w, r, "success", "Email berhasil diverifikasi. Sekarang kamu bisa checkout.")
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// POST /verify-email/resend
+func (server *Server) ResendVerificationEmail(w http.ResponseWriter, r *http.Request) {
+	user := server.CurrentUser(w, r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	back := "/carts"
+	if user.IsEmailVerified() {
+		SetFlash(w, r, "success", "Email kamu sudah terverifikasi.")
+		http.Redirect(w, r, back, http.StatusSeeOther)
+		return
+	}
+
+	if err := server.startEmailVerification(r.Context(), user); err != nil {
+		log.Println("startEmailVerification error:", err)
+		SetFlash(w, r, "error", "Gagal mengirim email verifikasi, coba beberapa saat lagi.")
+		http.Redirect(w, r, back, http.StatusSeeOther)
+		return
+	}
+
+	SetFlash(w, r, "success", "Link verifikasi sudah dikirim ke "+user.Email+".")
+	http.Redirect(w, r, back, http.StatusSeeOther)
+}
+
+// startEmailVerification: buat token verifikasi baru lalu kirim ke email user
+func (server *Server) startEmailVerification(ctx context.Context, user *models.User) error {
+	token, err := models.IssueUserToken(server.DB, sessionKey(), user.ID, consts.UserTokenEmailVerification, models.EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	link := server.absoluteURL("/verify-email/" + token)
+	body := fmt.Sprintf("Halo %s,\n\n"+
+		"Terima kasih sudah mendaftar di %s. Buka link berikut untuk memverifikasi email kamu:\n\n%s\n\n"+
+		"Link berlaku %d jam. Abaikan email ini kalau kamu tidak merasa mendaftar.\n",
+		user.FirstName, server.AppConfig.AppName, link, int(models.EmailVerificationTTL.Hours()))
+
+	return server.sendMail(ctx, mailer.Message{
+		To:      []string{user.Email},
+		Subject: "Verifikasi email " + server.AppConfig.AppName,
+		Body:    body,
+	})
+}
+
+// sendPasswordReset: buat token reset lalu kirim linknya (dijalankan di goroutine oleh ForgotPassword)
+func (server *Server) sendPasswordReset(user *models.User) {
+	token, err := models.IssueUserToken(server.DB, sessionKey(), user.ID, consts.UserTokenPasswordReset, models.PasswordResetTTL)
+	if err != nil {
+		log.Println("IssueUserToken error:", err)
+		return
+	}
+	if err := server.sendPasswordResetEmail(context.Background(), user, token); err != nil {
+		log.Println("send password reset email error:", err)
+	}
+}
+
+func (server *Server) sendPasswordResetEmail(ctx context.Context, user *models.User, token string) error {
+	link := server.absoluteURL("/reset-password/" + token)
+	body := fmt.Sprintf("Halo %s,\n\n"+
+		"Kami menerima permintaan reset password untuk akun %s kamu. Buka link berikut untuk membuat password baru:\n\n%s\n\n"+
+		"Link berlaku %d menit dan hanya bisa dipakai sekali. Abaikan email ini kalau kamu tidak meminta reset password.\n",
+		user.FirstName, server.AppConfig.AppName, link, int(models.PasswordResetTTL.Minutes()))
+
+	return server.sendMail(ctx, mailer.Message{
+		To:      []string{user.Email},
+		Subject: "Reset password " + server.AppConfig.AppName,
+		Body:    body,
+	})
+}
+
+// sendMail: kirim email dengan batas waktu supaya request tidak menggantung kalau SMTP lambat
+func (server *Server) sendMail(ctx context.Context, msg mailer.Message) error {
+	if server.Mailer == nil {
+		return errors.New("mailer belum dikonfigurasi")
+	}
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	return server.Mailer.Send(ctx, msg)
+}
+
+func (server *Server) absoluteURL(path string) string {
+	return strings.TrimRight(server.AppConfig.AppURL, "/") + path
+}
+
+// validateNewPassword: pesan error untuk form password baru, kosong kalau valid
+func validateNewPassword(password, confirm string) string {
+	if password == "" || confirm == "" {
+		return "Password baru & konfirmasinya wajib diisi."
+	}
+	if len(password) < minPasswordLength {
+		return fmt.Sprintf("Password minimal %d karakter.", minPasswordLength)
+	}
+	if password != confirm {
+		return "Konfirmasi password tidak sama."
+	}
+	return ""
+}
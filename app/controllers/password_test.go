@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alirogz/goshop/app/mailer"
+	"github.com/alirogz/goshop/app/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	resetLinkPattern  = regexp.MustCompile(`http://shop\.test(/reset-password/\S+)`)
+	verifyLinkPattern = regexp.MustCompile(`http://shop\.test(/verify-email/\S+)`)
+)
+
+// startTestMailer: kirim email server lewat SMTP ke FakeSMTPServer
+func startTestMailer(t *testing.T, server *Server) *mailer.FakeSMTPServer {
+	t.Helper()
+	fake, err := mailer.StartFakeSMTPServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { fake.Close() })
+
+	host, port := fake.HostPort()
+	server.Mailer = mailer.NewSMTPMailer(host, port, "", "", "Goshop <no-reply@shop.test>")
+	return fake
+}
+
+// waitForMail: tunggu sampai ada n email (pengiriman reset password jalan di goroutine)
+func waitForMail(t *testing.T, fake *mailer.FakeSMTPServer, n int) []mailer.ReceivedMessage {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if msgs := fake.Messages(); len(msgs) >= n {
+			return msgs
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("email tidak diterima, ada %d dari %d", len(fake.Messages()), n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// mailLink: path link dari body email
+func mailLink(t *testing.T, msg mailer.ReceivedMessage, pattern *regexp.Regexp) string {
+	t.Helper()
+	m := pattern.FindStringSubmatch(msg.Text())
+	if m == nil {
+		t.Fatalf("link tidak ada di email:\n%s", msg.Text())
+	}
+	return m[1]
+}
+
+func createTestUser(t *testing.T, db *gorm.DB, password string) *models.User {
+	t.Helper()
+	hashed, err := MakePassword(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &models.User{
+		ID:        uuid.NewString(),
+		FirstName: "Budi",
+		LastName:  "Santoso",
+		Email:     uuid.NewString() + "@example.test",
+		Password:  hashed,
+	}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func getPath(server *Server, target string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	server.Router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	return rec
+}
+
+func assertRedirect(t *testing.T, rec *httptest.ResponseRecorder, location string) {
+	t.Helper()
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != location {
+		t.Fatalf("got %d → %q, want 303 → %q", rec.Code, rec.Header().Get("Location"), location)
+	}
+}
+
+func TestPasswordResetEmailSMTP(t *testing.T) {
+	server := newTestServer(t, nil)
+	fake := startTestMailer(t, server)
+
+	user := &models.User{FirstName: "Budi", Email: "budi@example.test"}
+	if err := server.sendPasswordResetEmail(context.Background(), user, "random.signature"); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := waitForMail(t, fake, 1)[0]
+	if len(msg.To) != 1 || msg.To[0] != "budi@example.test" {
+		t.Fatalf("To = %v", msg.To)
+	}
+	if got := mailLink(t, msg, resetLinkPattern); got != "/reset-password/random.signature" {
+		t.Fatalf("link = %q", got)
+	}
+	if !strings.Contains(msg.Data, "Subject: Reset password Goshop") {
+		t.Fatalf("subjek salah:\n%s", msg.Data)
+	}
+}
+
+func TestPasswordResetFlow(t *testing.T) {
+	db := testDB(t)
+	server := newTestServer(t, db)
+	fake := startTestMailer(t, server)
+	user := createTestUser(t, db, "password-lama")
+
+	rec := postForm(t, server, "/forgot-password", url.Values{"email": {strings.ToUpper(user.Email)}})
+	assertRedirect(t, rec, "/forgot-password")
+
+	link := mailLink(t, waitForMail(t, fake, 1)[0], resetLinkPattern)
+
+	// token yang diubah ditolak
+	rec = postForm(t, server, link+"x", url.Values{"password": {"password-baru"}, "password_confirmation": {"password-baru"}})
+	assertRedirect(t, rec, "/forgot-password")
+
+	rec = postForm(t, server, link, url.Values{"password": {"password-baru"}, "password_confirmation": {"password-baru"}})
+	assertRedirect(t, rec, "/login")
+
+	var saved models.User
+	if err := db.Where("id = ?", user.ID).First(&saved).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !ComparePassword("password-baru", saved.Password) {
+		t.Fatal("password tidak berubah")
+	}
+	if !saved.IsEmailVerified() {
+		t.Fatal("reset password harus sekaligus memverifikasi email")
+	}
+
+	// token hanya bisa dipakai sekali
+	rec = postForm(t, server, link, url.Values{"password": {"password-lain"}, "password_confirmation": {"password-lain"}})
+	assertRedirect(t, rec, "/forgot-password")
+}
+
+func TestForgotPasswordUnknownEmail(t *testing.T) {
+	db := testDB(t)
+	server := newTestServer(t, db)
+	fake := startTestMailer(t, server)
+
+	rec := postForm(t, server, "/forgot-password", url.Values{"email": {uuid.NewString() + "@example.test"}})
+	assertRedirect(t, rec, "/forgot-password")
+
+	time.Sleep(200 * time.Millisecond)
+	if n := len(fake.Messages()); n != 0 {
+		t.Fatalf("%d email terkirim untuk email yang tidak terdaftar", n)
+	}
+}
+
+func TestEmailVerificationFlow(t *testing.T) {
+	db := testDB(t)
+	server := newTestServer(t, db)
+	fake := startTestMailer(t, server)
+	user := createTestUser(t, db, "password-lama")
+
+	if err := server.startEmailVerification(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	link := mailLink(t, waitForMail(t, fake, 1)[0], verifyLinkPattern)
+
+	assertRedirect(t, getPath(server, link+"x"), "/carts")
+	assertRedirect(t, getPath(server, link), "/")
+
+	var saved models.User
+	if err := db.Where("id = ?", user.ID).First(&saved).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !saved.IsEmailVerified() {
+		t.Fatal("email belum terverifikasi")
+	}
+
+	// link lama tidak bisa dipakai lagi, dan link yang sudah diganti (kirim ulang) ikut hangus
+	assertRedirect(t, getPath(server, link), "/carts")
+	if err := server.startEmailVerification(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	first := mailLink(t, waitForMail(t, fake, 2)[1], verifyLinkPattern)
+	if err := server.startEmailVerification(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	waitForMail(t, fake, 3)
+	assertRedirect(t, getPath(server, first), "/carts")
+}
@@ -1,11 +1,11 @@
 package controllers
 
 import (
-	"encoding/csv"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"io"
 	"log"
-	"math"
 	"net/http"
 	"strconv"
 	"strings"
@@ -13,7 +13,12 @@ import (
 
 	"github.com/alirogz/goshop/app/consts"
 	"github.com/alirogz/goshop/app/models"
+	"github.com/alirogz/goshop/app/money"
+	"github.com/alirogz/goshop/app/payment"
+	"github.com/alirogz/goshop/app/statement"
+	"github.com/gorilla/mux"
 	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
 )
 
 /*
@@ -58,8 +63,9 @@ func (server *Server) ShippingOptions(w http.ResponseWriter, r *http.Request) {
    ==========================
 */
 
-// POST /payments/mock
+// POST /payments/mock (hanya terdaftar kalau PAYMENT_GATEWAY=fake)
 // Body JSON: { "order_id": "xxxx", "amount": 125000, "provider": "manual" }
+// Hanya pemilik order atau staff keuangan.
 type mockPayReq struct {
 	OrderID  string `json:"order_id"`
 	Amount   int64  `json:"amount"`
@@ -79,10 +85,10 @@ func (server *Server) MockPay(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Pastikan order ada dan milik user (opsional: validasi owner)
+	// order orang lain dianggap tidak ada
 	orderModel := models.Order{}
 	order, err := orderModel.FindByID(server.DB, req.OrderID)
-	if err != nil {
+	if err != nil || (order.UserID != user.ID && !user.Can(consts.PermPaymentsManage)) {
 		http.Error(w, "order not found", http.StatusNotFound)
 		return
 	}
@@ -90,30 +96,32 @@ func (server *Server) MockPay(w http.ResponseWriter, r *http.Request) {
 		_ = json.NewEncoder(w).Encode(Result{Code: 200, Data: nil, Message: "Already paid"})
 		return
 	}
+	// nominal harus sama dengan tagihan, seperti notifikasi gateway
+	amount := decimal.NewFromInt(req.Amount)
+	if !money.Equal(amount, order.GrandTotal) && !money.Equal(amount, order.PaymentTotal) {
+		http.Error(w, "amount does not match order total", http.StatusBadRequest)
+		return
+	}
 
-	// Simpan payment record
-	paymentModel := models.Payment{}
-	amt := decimal.NewFromInt(req.Amount)
-	payload := json.RawMessage([]byte(`{"provider":"` + req.Provider + `","at":"` + time.Now().Format(time.RFC3339) + `"}`))
-
-	if _, err := paymentModel.CreatePayment(server.DB, &models.Payment{
-		OrderID:           order.ID,
-		Amount:            amt,
+	// simpan payment record + tandai order lunas
+	raw, err := json.Marshal(map[string]string{"provider": req.Provider, "at": time.Now().Format(time.RFC3339)})
+	if err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	payload := json.RawMessage(raw)
+	_, err = order.RecordPayment(server.DB, models.Payment{
+		Amount:            amount,
 		TransactionID:     "MOCK-" + time.Now().Format("20060102150405"),
 		TransactionStatus: consts.PaymentStatusSettlement,
 		Payload:           &payload,
 		PaymentType:       "manual",
-	}); err != nil {
+	}, true, user.ID, "Mock payment ("+req.Provider+")")
+	if err != nil {
 		http.Error(w, "failed to save payment", http.StatusBadRequest)
 		return
 	}
 
-	// Tandai order sebagai paid
-	if err := order.MarkAsPaid(server.DB); err != nil {
-		http.Error(w, "failed to mark order paid", http.StatusBadRequest)
-		return
-	}
-
 	_ = json.NewEncoder(w).Encode(Result{
 		Code: 200,
 		Data: map[string]string{
@@ -127,198 +135,252 @@ func (server *Server) MockPay(w http.ResponseWriter, r *http.Request) {
 
 /*
    ==========================
-   Auto-match pembayaran
+   Payment gateway (Midtrans / fake)
    ==========================
 */
 
-// POST /admin/payments/auto-match
-// POST /admin/payments/auto-match
-func (s *Server) AutoMatchPayments(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	db := s.DB
+// POST /orders/{id}/pay-online
+// buat tagihan di payment gateway lalu arahkan pembeli ke halaman pembayaran
+func (server *Server) PayOnline(w http.ResponseWriter, r *http.Request) {
+	if !IsLoggedIn(r) {
+		SetFlash(w, r, "error", "Silakan login terlebih dahulu.")
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+	user := server.CurrentUser(w, r)
+	id := mux.Vars(r)["id"]
 
-	// 1. Ambil semua order UNPAID dengan payment_total > 0 dan metode Transfer Bank
-	var orders []models.Order
-	if err := db.
-		Where("payment_status = ? AND payment_method = ? AND payment_total > 0",
-			consts.OrderPaymentStatusUnpaid, "Transfer Bank").
-		Find(&orders).Error; err != nil {
+	if server.PaymentGateway == nil {
+		SetFlash(w, r, "error", payment.ErrGatewayDisabled.Error())
+		http.Redirect(w, r, "/orders/"+id, http.StatusSeeOther)
+		return
+	}
 
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(map[string]interface{}{
-			"status":  "error",
-			"message": "Gagal mengambil data order",
-		})
+	var order models.Order
+	if err := server.DB.Preload("OrderCustomer").Where("id = ? AND user_id = ?", id, user.ID).First(&order).Error; err != nil {
+		SetFlash(w, r, "error", "Pesanan tidak ditemukan.")
+		http.Redirect(w, r, "/orders", http.StatusSeeOther)
+		return
+	}
+	if order.IsPaid() || order.IsClosed() {
+		SetFlash(w, r, "error", "Pesanan ini tidak bisa dibayar lagi.")
+		http.Redirect(w, r, "/orders/"+order.ID, http.StatusSeeOther)
 		return
 	}
 
-	if len(orders) == 0 {
-		_ = json.NewEncoder(w).Encode(map[string]interface{}{
-			"status":  "ok",
-			"message": "Tidak ada order UNPAID dengan payment_total > 0",
-			"matched": 0,
-		})
+	// order_id di gateway dibuat unik per percobaan: tagihan yang kedaluwarsa / gagal bisa dibuat ulang
+	attempt, err := order.NextPaymentAttempt(server.DB)
+	if err != nil {
+		log.Println("NextPaymentAttempt error:", err)
+		SetFlash(w, r, "error", "Gagal membuat tagihan pembayaran.")
+		http.Redirect(w, r, "/orders/"+order.ID, http.StatusSeeOther)
 		return
 	}
 
-	// 2. Ambil semua mutasi bank yang belum dipasangkan
-	var bankTxs []models.BankTransaction
-	if err := db.
-		Where("matched = ?", false).
-		Find(&bankTxs).Error; err != nil {
+	req := payment.ChargeRequest{
+		OrderID:       payment.AttemptOrderID(order.ID, attempt),
+		GrossAmount:   order.GrandTotal,
+		CustomerEmail: user.Email,
+		FinishURL:     strings.TrimRight(server.AppConfig.AppURL, "/") + "/orders/" + order.ID,
+	}
+	if order.OrderCustomer != nil {
+		req.CustomerName = strings.TrimSpace(order.OrderCustomer.FirstName + " " + order.OrderCustomer.LastName)
+		req.CustomerPhone = order.OrderCustomer.Phone
+	}
 
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(map[string]interface{}{
-			"status":  "error",
-			"message": "Gagal mengambil data mutasi bank",
-		})
+	charge, err := server.PaymentGateway.CreateCharge(r.Context(), req)
+	if err != nil {
+		log.Println("CreateCharge error:", err)
+		SetFlash(w, r, "error", "Gagal membuat tagihan pembayaran: "+err.Error())
+		http.Redirect(w, r, "/orders/"+order.ID, http.StatusSeeOther)
 		return
 	}
 
-	if len(bankTxs) == 0 {
-		_ = json.NewEncoder(w).Encode(map[string]interface{}{
-			"status":  "ok",
-			"message": "Tidak ada mutasi bank yang belum dipasangkan",
-			"matched": 0,
-		})
-		return
+	err = server.DB.Model(&models.Order{}).Where("id = ?", order.ID).
+		Update("payment_token", sql.NullString{String: charge.Token, Valid: true}).Error
+	if err != nil {
+		log.Println("save payment token error:", err)
 	}
 
-	// 3. Buat index: amount (dibulatkan ke rupiah) -> list index transaksi
-	amountToIdx := make(map[int][]int)
-	for i, tx := range bankTxs {
-		amtFloat := tx.Amount.InexactFloat64() // contoh 344389.00
-		key := int(math.Round(amtFloat))       // jadi 344389
-		amountToIdx[key] = append(amountToIdx[key], i)
+	http.Redirect(w, r, charge.RedirectURL, http.StatusSeeOther)
+}
+
+// POST /payments/notification
+// webhook payment gateway. Idempotent: notifikasi yang sama boleh datang berkali-kali.
+func (server *Server) PaymentNotification(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	gateway := server.PaymentGateway
+	if gateway == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_ = json.NewEncoder(w).Encode(Result{Code: http.StatusServiceUnavailable, Message: payment.ErrGatewayDisabled.Error()})
+		return
 	}
 
-	var matchedCount int
+	raw, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	var notification models.MidtransNotification
+	if err == nil {
+		err = json.Unmarshal(raw, &notification)
+	}
+	if err != nil || notification.OrderID == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(Result{Code: http.StatusBadRequest, Message: "invalid payload"})
+		return
+	}
 
-	// 4. Untuk setiap order, cari mutasi dengan nominal yang sama
-	//    lalu pilih kandidat terbaik berdasarkan:
-	//    - tanggal transaksi paling dekat dengan tanggal order
-	//    - note/berita mengandung hint ID order (jika ada)
-	for i := range orders {
-		order := &orders[i]
+	if err := gateway.VerifyNotification(notification); err != nil {
+		log.Println("PaymentNotification signature error:", notification.OrderID, err)
+		w.WriteHeader(http.StatusForbidden)
+		_ = json.NewEncoder(w).Encode(Result{Code: http.StatusForbidden, Message: err.Error()})
+		return
+	}
 
-		// konversi PaymentTotal (decimal) ke rupiah int
-		orderAmt := int(math.Round(order.PaymentTotalFloat())) // 344389.00 -> 344389
+	order, err := server.findNotificationOrder(notification.OrderID)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(Result{Code: http.StatusNotFound, Message: "order not found"})
+		return
+	}
 
-		idxList, ok := amountToIdx[orderAmt]
-		if !ok || len(idxList) == 0 {
-			continue // tidak ada mutasi dengan nominal ini
-		}
+	amount, err := decimal.NewFromString(notification.GrossAmount)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(Result{Code: http.StatusBadRequest, Message: "invalid gross_amount"})
+		return
+	}
 
-		// pilih kandidat terbaik di antara transaksi dengan nominal sama
-		bestIdx := -1
-		bestScore := -1
-		var bestDiff time.Duration
+	status := gateway.MapStatus(notification)
+	settle := status == payment.StatusPaid
 
-		for _, idx := range idxList {
-			tx := &bankTxs[idx]
+	// nominal harus sama dengan tagihan; kalau beda dicatat saja tanpa menandai lunas
+	if settle && !money.Equal(amount, order.GrandTotal) && !money.Equal(amount, order.PaymentTotal) {
+		log.Printf("PaymentNotification amount mismatch order=%s amount=%s total=%s", order.ID, amount, order.GrandTotal)
+		settle = false
+	}
 
-			score, diff := calculateMatchScore(order, tx)
+	paymentType := notification.PaymentType
+	if paymentType == "" {
+		paymentType = gateway.Name()
+	}
+	payload := json.RawMessage(raw)
 
-			if bestIdx == -1 || score > bestScore || (score == bestScore && diff < bestDiff) {
-				bestIdx = idx
-				bestScore = score
-				bestDiff = diff
-			}
-		}
+	changed, err := order.RecordPayment(server.DB, models.Payment{
+		Amount:            amount,
+		TransactionID:     notification.TransactionID,
+		TransactionStatus: notification.TransactionStatus,
+		Payload:           &payload,
+		PaymentType:       paymentType,
+	}, settle, consts.OrderActorSystem, "Pembayaran "+gateway.Name()+" ("+notification.TransactionStatus+")")
+	if err != nil && !errors.Is(err, models.ErrOrderClosed) {
+		log.Println("PaymentNotification error:", order.ID, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(Result{Code: http.StatusInternalServerError, Message: "failed to record payment"})
+		return
+	}
+	if err != nil {
+		// order sudah dibatalkan/selesai: pembayaran tetap tercatat untuk ditindaklanjuti admin
+		log.Println("PaymentNotification on closed order:", order.ID, notification.TransactionStatus)
+	}
 
-		if bestIdx == -1 {
-			continue
-		}
+	_ = json.NewEncoder(w).Encode(Result{
+		Code: http.StatusOK,
+		Data: map[string]interface{}{
+			"order_id": order.ID,
+			"status":   status,
+			"changed":  changed,
+		},
+		Message: "ok",
+	})
+}
 
-		// Jika tidak ada petunjuk kuat dan selisih waktu terlalu jauh (> 24 jam),
-		// kita skip supaya tidak salah pasang.
-		if bestDiff > 24*time.Hour && bestScore < 2 {
-			continue
+// findNotificationOrder: order dari order_id gateway ("<id order>-<percobaan>", lihat payment.AttemptOrderID).
+// Tagihan lama yang dibuat sebelum ada nomor percobaan masih memakai ID order apa adanya.
+func (server *Server) findNotificationOrder(gatewayOrderID string) (models.Order, error) {
+	var order models.Order
+	if orderID, _, ok := payment.SplitAttemptOrderID(gatewayOrderID); ok {
+		err := server.DB.Where("id = ?", orderID).First(&order).Error
+		if err == nil || !errors.Is(err, gorm.ErrRecordNotFound) {
+			return order, err
 		}
+	}
+	err := server.DB.Where("id = ?", gatewayOrderID).First(&order).Error
+	return order, err
+}
 
-		// keluarkan bestIdx dari antrian amountToIdx
-		txIdx := bestIdx
-		newList := make([]int, 0, len(idxList)-1)
-		for _, idx := range idxList {
-			if idx != txIdx {
-				newList = append(newList, idx)
-			}
-		}
-		if len(newList) == 0 {
-			delete(amountToIdx, orderAmt)
-		} else {
-			amountToIdx[orderAmt] = newList
-		}
+/*
+   ==========================
+   Auto-match pembayaran
+   ==========================
+*/
 
-		bankTx := bankTxs[txIdx]
+// POST /admin/payments/auto-match
+// Hanya pasangan yang yakin (lihat models.ProposeAutoMatches) yang dipasangkan; sisanya diputuskan
+// admin di /admin/payments/reconcile. dry_run=1 hanya mengembalikan usulan pasangan tanpa menyimpan.
+func (s *Server) AutoMatchPayments(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
 
-		// 5. Tandai order sebagai PAID (pakai helper yang sudah ada)
-		if err := order.MarkAsPaid(db); err != nil {
-			continue
-		}
+	admin := s.CurrentUser(w, r)
 
-		// 6. Tandai mutasi sebagai sudah dipasangkan
-		now := time.Now()
-		bankTx.Matched = true
-		bankTx.MatchedOrder = order.ID
-		bankTx.MatchedAt = now
-		if err := db.Save(&bankTx).Error; err != nil {
-			continue
-		}
+	dryRun := r.FormValue("dry_run") == "1" || r.FormValue("dry_run") == "true"
 
-		matchedCount++
+	proposals, err := models.ProposeAutoMatches(s.DB)
+	if err != nil {
+		log.Println("ProposeAutoMatches error:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":  "error",
+			"message": "Gagal mengambil data order / mutasi bank",
+		})
+		return
 	}
 
-	_ = json.NewEncoder(w).Encode(map[string]interface{}{
-		"status":  "ok",
-		"message": "Proses auto-match selesai",
-		"matched": matchedCount,
-	})
-}
-
-// calculateMatchScore memberikan skor kecocokan antara satu order dan satu mutasi bank.
-// Semakin tinggi skor, semakin yakin bahwa keduanya adalah pasangan yang benar.
-// Selain skor, fungsi ini juga mengembalikan selisih waktu absolut antara
-// waktu order dan waktu transaksi di bank.
-func calculateMatchScore(order *models.Order, tx *models.BankTransaction) (int, time.Duration) {
-	score := 0
-	// default selisih waktu sangat besar
-	diff := time.Duration(1<<63 - 1)
-
-	// 1. Nilai tambah dari kedekatan tanggal/waktu
-	//    Kalau struct Order kamu tidak punya CreatedAt, bisa diganti ke field lain.
-	if !tx.TrxTime.IsZero() && !order.CreatedAt.IsZero() {
-		d := tx.TrxTime.Sub(order.CreatedAt)
-		if d < 0 {
-			d = -d
-		}
-		diff = d
+	type proposalResult struct {
+		BankTransactionID uint     `json:"bank_transaction_id"`
+		OrderID           string   `json:"order_id"`
+		OrderCode         string   `json:"order_code"`
+		Amount            string   `json:"amount"`
+		Score             int      `json:"score"`
+		Reasons           []string `json:"reasons"`
+		Applied           bool     `json:"applied"`
+		Error             string   `json:"error,omitempty"`
+	}
 
-		// <= 24 jam dianggap sangat dekat
-		if d <= 24*time.Hour {
-			score += 1
-		}
-		// <= 6 jam boleh dapat bonus point lagi
-		if d <= 6*time.Hour {
-			score += 1
+	results := make([]proposalResult, 0, len(proposals))
+	matchedCount := 0
+	for _, p := range proposals {
+		res := proposalResult{
+			BankTransactionID: p.BankTransaction.ID,
+			OrderID:           p.Candidate.Order.ID,
+			OrderCode:         p.Candidate.Order.Code,
+			Amount:            p.BankTransaction.Amount.StringFixed(0),
+			Score:             p.Candidate.Score,
+			Reasons:           p.Candidate.Reasons,
 		}
-	}
 
-	// 2. Nilai tambah dari adanya hint order di Note
-	//    Sekarang kita pakai ID order; kalau kamu punya kode lain (misalnya order.Code),
-	//    bisa ditambahkan di sini.
-	note := strings.ToLower(tx.Note)
-	if note != "" {
-		if strings.Contains(note, strings.ToLower(order.ID)) {
-			score += 2
+		if !dryRun {
+			_, err := models.PairBankTransaction(s.DB, p.BankTransaction.ID, p.Candidate.Order.ID, consts.ReconcileAuto, admin.ID, "Auto-match", false)
+			if err != nil {
+				res.Error = err.Error()
+			} else {
+				res.Applied = true
+				matchedCount++
+			}
 		}
 
-		// CONTOH (opsional): kalau di Note ada "order #33" dan kamu punya field
-		// order.Number (int) atau order.Code, kamu bisa tambah logika di sini.
-		// Silakan sesuaikan dengan struktur modelmu sendiri.
+		results = append(results, res)
 	}
 
-	return score, diff
+	message := "Proses auto-match selesai"
+	if dryRun {
+		message = "Dry-run: belum ada yang disimpan"
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":    "ok",
+		"message":   message,
+		"dry_run":   dryRun,
+		"matched":   matchedCount,
+		"proposals": results,
+	})
 }
 
 /*
@@ -327,21 +389,9 @@ func calculateMatchScore(order *models.Order, tx *models.BankTransaction) (int,
    ==========================
 */
 
-// GET /admin/payments/import
-// GET /admin/payments/import
 // GET /admin/payments/import
 func (s *Server) ShowImportBankPage(w http.ResponseWriter, r *http.Request) {
-	if !IsLoggedIn(r) {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
-		return
-	}
-
 	admin := s.CurrentUser(w, r)
-	if !IsAdminUser(admin) {
-		SetFlash(w, r, "error", "Unauthorized")
-		http.Redirect(w, r, "/", http.StatusSeeOther)
-		return
-	}
 
 	// Ambil 20 mutasi bank terbaru
 	var bankTxs []models.BankTransaction
@@ -350,7 +400,12 @@ func (s *Server) ShowImportBankPage(w http.ResponseWriter, r *http.Request) {
 		SetFlash(w, r, "error", "Gagal mengambil data mutasi bank")
 	}
 
-	ren := adminRender()
+	batches, err := models.RecentBankImportBatches(s.DB, 10)
+	if err != nil {
+		log.Println("RecentBankImportBatches error:", err)
+	}
+
+	ren := adminRender(r)
 	_ = ren.HTML(w, http.StatusOK, "admin_payments_import", map[string]interface{}{
 		"user":      admin,
 		"isAdmin":   IsAdminUser(admin),
@@ -358,85 +413,79 @@ func (s *Server) ShowImportBankPage(w http.ResponseWriter, r *http.Request) {
 		"success":   r.URL.Query().Get("success"),
 		"error":     GetFlash(w, r, "error"),
 		"bankTxs":   bankTxs, // <— ini yang dipakai di tabel
+		"formats":   statement.Parsers(),
+		"batches":   batches,
 	})
-
 }
 
 // POST /admin/payments/import
+// File dibaca (format dideteksi otomatis atau dipilih admin) lalu disimpan sebagai batch preview.
+// Mutasi baru masuk ke bank_transactions setelah admin menyimpan batch tersebut.
 func (s *Server) HandleImportBankCSV(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	admin := s.CurrentUser(w, r)
 
-	err := r.ParseMultipartForm(10 << 20) // max 10MB
+	r.Body = http.MaxBytesReader(w, r.Body, 10<<20) // max 10MB
+	err := r.ParseMultipartForm(1 << 20)
 	if err != nil {
-		http.Error(w, "Gagal parsing form", http.StatusBadRequest)
+		SetFlash(w, r, "error", "Gagal membaca form upload")
+		http.Redirect(w, r, "/admin/payments/import", http.StatusSeeOther)
 		return
 	}
 
-	file, _, err := r.FormFile("file")
+	file, header, err := r.FormFile("file")
 	if err != nil {
-		http.Error(w, "File tidak ditemukan", http.StatusBadRequest)
+		SetFlash(w, r, "error", "File mutasi tidak ditemukan")
+		http.Redirect(w, r, "/admin/payments/import", http.StatusSeeOther)
 		return
 	}
 	defer file.Close()
 
-	reader := csv.NewReader(file)
-	reader.Comma = ';'
-
-	// Skip header: Tanggal;Deskripsi;Debit;Kredit;Saldo
-	_, _ = reader.Read()
-
-	for {
-		rec, err := reader.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			continue
-		}
-
-		if len(rec) < 4 {
-			continue
-		}
-
-		tanggalStr := strings.TrimSpace(rec[0])
-		desc := strings.TrimSpace(rec[1])
-		amountStr := strings.TrimSpace(rec[3])
-
-		// buang titik/koma
-		amountStr = strings.ReplaceAll(amountStr, ".", "")
-		amountStr = strings.ReplaceAll(amountStr, ",", "")
+	data, err := io.ReadAll(io.LimitReader(file, 10<<20))
+	if err != nil {
+		SetFlash(w, r, "error", "Gagal membaca file mutasi")
+		http.Redirect(w, r, "/admin/payments/import", http.StatusSeeOther)
+		return
+	}
 
-		amountDec, err := decimal.NewFromString(amountStr)
-		if err != nil {
-			continue
-		}
+	st, err := statement.Parse(data, r.FormValue("format"))
+	if err != nil {
+		SetFlash(w, r, "error", "Import gagal: "+err.Error())
+		http.Redirect(w, r, "/admin/payments/import", http.StatusSeeOther)
+		return
+	}
 
-		trxTime, err := time.Parse("02/01/2006", tanggalStr)
-		if err != nil {
-			trxTime = time.Now()
+	// bank & rekening dari form dipakai kalau file tidak membawanya
+	bank := strings.TrimSpace(r.FormValue("bank"))
+	account := strings.TrimSpace(r.FormValue("account"))
+	if st.Bank == "" && bank == "" {
+		SetFlash(w, r, "error", "Format ini tidak menyertakan nama bank, isi kolom Bank terlebih dahulu")
+		http.Redirect(w, r, "/admin/payments/import", http.StatusSeeOther)
+		return
+	}
+	if st.Bank == "" {
+		st.Bank = bank
+	}
+	if st.Account == "" {
+		st.Account = account
+	}
+	for i := range st.Transactions {
+		if st.Transactions[i].Bank == "" {
+			st.Transactions[i].Bank = st.Bank
 		}
-
-		now := time.Now()
-
-		tx := models.BankTransaction{
-			Bank:         "BANK",
-			Account:      "",
-			Amount:       amountDec,
-			Note:         desc,
-			RefCode:      "",
-			TrxTime:      trxTime,
-			Matched:      false,
-			MatchedOrder: "",
-			MatchedAt:    now,
+		if st.Transactions[i].Account == "" {
+			st.Transactions[i].Account = st.Account
 		}
+	}
 
-		if err := s.DB.Create(&tx).Error; err != nil {
-			log.Println("Insert error:", err)
-			continue
-		}
+	batch, err := models.PreviewBankImport(s.DB, header.Filename, data, st, admin.ID)
+	if err != nil {
+		log.Println("PreviewBankImport error:", err)
+		SetFlash(w, r, "error", "Gagal menyimpan preview import")
+		http.Redirect(w, r, "/admin/payments/import", http.StatusSeeOther)
+		return
 	}
 
-	http.Redirect(w, r, "/admin/payments/import?success=Berhasil+import", http.StatusFound)
+	http.Redirect(w, r, "/admin/payments/imports/"+batch.ID, http.StatusSeeOther)
 }
 
 // DEBUG: cek isi tabel bank_transactions
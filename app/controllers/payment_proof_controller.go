@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/alirogz/goshop/app/attachments"
+	"github.com/alirogz/goshop/app/consts"
+	"github.com/alirogz/goshop/app/models"
+	"github.com/gorilla/mux"
+)
+
+// paymentProofMaxSize: ukuran maksimal 1 file bukti bayar
+const paymentProofMaxSize = 5 << 20
+
+var (
+	errPaymentProofMissing = errors.New("silakan pilih file bukti transfer")
+	errPaymentProofInvalid = errors.New("upload bukti transfer gagal, coba lagi")
+	errPaymentProofTooBig  = fmt.Errorf("bukti transfer maksimal %d MB", paymentProofMaxSize>>20)
+)
+
+// receivePaymentProof: file "payment_proof" dari form pembeli dijadikan bukti bayar baru order
+func (server *Server) receivePaymentProof(w http.ResponseWriter, r *http.Request, order *models.Order, user *models.User) error {
+	r.Body = http.MaxBytesReader(w, r.Body, paymentProofMaxSize+1<<20)
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return errPaymentProofTooBig
+		}
+		return errPaymentProofInvalid
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("payment_proof")
+	if err != nil {
+		return errPaymentProofMissing
+	}
+	defer file.Close()
+
+	_, err = server.storePaymentProof(r.Context(), order, file, header.Filename, user.ID, consts.PaymentProofSourceUpload)
+	return err
+}
+
+// storePaymentProof: cek jenis & ukuran file, simpan dengan nama acak di storage privat lalu catat sebagai bukti bayar order.
+// fileName hanya untuk tampilan / nama unduhan.
+func (server *Server) storePaymentProof(ctx context.Context, order *models.Order, src io.Reader, fileName, uploadedBy, source string) (*models.PaymentProof, error) {
+	file, err := server.PaymentProofs.Save(ctx, src)
+	if err != nil {
+		if errors.Is(err, attachments.ErrTooLarge) {
+			return nil, errPaymentProofTooBig
+		}
+		return nil, err
+	}
+
+	proof := &models.PaymentProof{
+		UploadedBy:  uploadedBy,
+		Source:      source,
+		FileName:    chatAttachmentName(fileName),
+		ContentType: file.ContentType,
+		Size:        file.Size,
+		StoragePath: file.Path,
+		ThumbPath:   file.ThumbPath,
+	}
+	if err := order.AddPaymentProof(server.DB, proof); err != nil {
+		server.PaymentProofs.Delete(context.Background(), file)
+		return nil, err
+	}
+	return proof, nil
+}
+
+// paymentProofErrorText: pesan untuk flash; kesalahan server dicatat di log dan diganti pesan umum
+func paymentProofErrorText(err error) string {
+	for _, inputErr := range []error{
+		errPaymentProofMissing, errPaymentProofInvalid, errPaymentProofTooBig,
+		attachments.ErrEmpty, attachments.ErrType,
+		models.ErrOrderAlreadyPaid, models.ErrOrderClosed,
+	} {
+		if errors.Is(err, inputErr) {
+			return err.Error()
+		}
+	}
+	log.Println("payment proof error:", err)
+	return "Gagal menyimpan bukti pembayaran, coba lagi."
+}
+
+// GET /orders/{id}/payment-proof[/{proofID}] (?thumb=1 untuk thumbnail, tanpa proofID = bukti terbaru):
+// hanya pemilik order & staff keuangan. Setelah hak akses dicek, browser diarahkan ke URL file bertanda tangan.
+func (server *Server) PaymentProofDownload(w http.ResponseWriter, r *http.Request) {
+	user := server.CurrentUser(w, r)
+	if user == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	var order models.Order
+	if err := server.DB.Where("id = ?", vars["id"]).First(&order).Error; err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	// bukti bayar order orang lain dianggap tidak ada
+	if order.UserID != user.ID && !user.Can(consts.PermPaymentsManage) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	proof, err := models.FindPaymentProof(server.DB, order.ID, vars["proofID"])
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	rel, contentType := proof.StoragePath, proof.ContentType
+	if r.URL.Query().Get("thumb") == "1" && proof.ThumbPath != "" {
+		rel, contentType = proof.ThumbPath, "image/jpeg"
+	}
+	server.redirectPrivateFile(w, r, server.PaymentProofs, rel, contentType, proof.FileName)
+}
@@ -2,55 +2,29 @@ package controllers
 
 import (
 	"net/http"
-	"strconv"
+	"strings"
 
 	"github.com/alirogz/goshop/app/models"
 	"github.com/gorilla/mux"
 )
 
 func (server *Server) Products(w http.ResponseWriter, r *http.Request) {
-	// Pakai renderer untuk user yang sudah punya FuncMap (formatRupiah, dll)
-	ren := userRender()
-
-	// --- PAGINATION ---
-	q := r.URL.Query()
-	page, err := strconv.Atoi(q.Get("page"))
-	if err != nil || page <= 0 {
-		page = 1
-	}
-
-	perPage := 9
-
-	productModel := models.Product{}
-	products, totalRows, err := productModel.GetProducts(server.DB, perPage, page)
-	if err != nil {
-		http.Error(w, "Gagal mengambil data produk", http.StatusInternalServerError)
-		return
+	listing := productListing{
+		Path:     "products",
+		Title:    "Katalog Produk",
+		Subtitle: "Jelajahi koleksi produk kami yang beragam dan temukan apa yang Anda cari.",
+		Sections: server.catalogMenu(),
 	}
-
-	pagination, _ := GetPaginationLinks(server.AppConfig, PaginationParams{
-		Path:        "products",
-		TotalRows:   int32(totalRows),
-		PerPage:     int32(perPage),
-		CurrentPage: int32(page),
-	})
-
-	user := server.CurrentUser(w, r)
-
-	data := map[string]interface{}{
-		"products":   products,
-		"pagination": pagination,
-		"user":       user,
-		"isAdmin":    IsAdminUser(user),
-		"cartCount":  server.GetCartCount(w, r),
+	if q := strings.TrimSpace(r.URL.Query().Get("q")); q != "" {
+		listing.Title = "Hasil pencarian \"" + q + "\""
+		listing.Subtitle = "Produk yang cocok dengan nama, deskripsi atau SKU."
+		listing.Breadcrumbs = []Breadcrumb{{Name: "Home", URL: "/"}, {Name: "Produk", URL: "/products"}, {Name: "Pencarian"}}
 	}
-	server.InjectNavbarBadges(data, user)
-	_ = ren.HTML(w, http.StatusOK, "products", data)
-
+	server.renderProductListing(w, r, listing)
 }
 
 func (server *Server) GetProductBySlug(w http.ResponseWriter, r *http.Request) {
-	ren := userRender() // ← PAKAI INI
+	ren := userRender(r) // ← PAKAI INI
 
 	vars := mux.Vars(r)
 	slugStr := vars["slug"]
@@ -65,10 +39,11 @@ func (server *Server) GetProductBySlug(w http.ResponseWriter, r *http.Request) {
 	user := server.CurrentUser(w, r)
 
 	data := map[string]interface{}{
-		"product":   product,
-		"user":      user,
-		"isAdmin":   IsAdminUser(user),
-		"cartCount": server.GetCartCount(w, r),
+		"product":     product,
+		"breadcrumbs": server.productBreadcrumbs(product),
+		"user":        user,
+		"isAdmin":     IsAdminUser(user),
+		"cartCount":   server.GetCartCount(w, r),
 	}
 	server.InjectNavbarBadges(data, user)
 	_ = ren.HTML(w, http.StatusOK, "product", data)
@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path"
+
+	"github.com/alirogz/goshop/app/consts"
+	"github.com/alirogz/goshop/app/imaging"
+	"github.com/alirogz/goshop/app/models"
+	"github.com/alirogz/goshop/app/storage"
+	"github.com/google/uuid"
+)
+
+const (
+	productImageMaxSize  = 8 << 20 // 8 MB per file
+	productImageMaxFiles = 10      // per sekali simpan form produk
+)
+
+var errProductImageTooMany = fmt.Errorf("maksimal %d gambar sekali upload", productImageMaxFiles)
+
+// format asli yang diterima → ekstensi file asli
+var productImageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// limitProductForm: batasi ukuran body form produk (semua gambar + field teks)
+func limitProductForm(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, productImageMaxFiles*productImageMaxSize+(1<<20))
+}
+
+// saveUploadedProductImages: proses semua file "images" di form jadi ProductImage (belum disimpan ke DB).
+// Position mulai dari startPosition. Kalau salah satu gagal, file yang sudah ditulis dihapus lagi.
+func (server *Server) saveUploadedProductImages(r *http.Request, productID string, startPosition int) ([]models.ProductImage, error) {
+	if r.MultipartForm == nil {
+		return nil, nil
+	}
+	headers := r.MultipartForm.File["images"]
+	if len(headers) > productImageMaxFiles {
+		return nil, errProductImageTooMany
+	}
+
+	var images []models.ProductImage
+	for i, fh := range headers {
+		img, err := server.processProductImage(r.Context(), fh, productID)
+		if err != nil {
+			server.deleteProductImageFiles(images...)
+			return nil, fmt.Errorf("%s: %w", fh.Filename, err)
+		}
+		img.Position = startPosition + i
+		images = append(images, *img)
+	}
+	return images, nil
+}
+
+// processProductImage: simpan file asli + 4 ukuran turunan (consts.ProductImage*) di server.Uploads, key products/{productID}/
+func (server *Server) processProductImage(ctx context.Context, fh *multipart.FileHeader, productID string) (*models.ProductImage, error) {
+	if fh.Size > productImageMaxSize {
+		return nil, errors.New("gambar maksimal 8 MB")
+	}
+
+	file, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, productImageMaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > productImageMaxSize {
+		return nil, errors.New("gambar maksimal 8 MB")
+	}
+
+	contentType := http.DetectContentType(data)
+	ext, ok := productImageTypes[contentType]
+	if !ok {
+		return nil, imaging.ErrUnsupported
+	}
+	src, err := imaging.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	id := uuid.NewString()
+	dir := path.Join("products", productID)
+	img := &models.ProductImage{ID: id, ProductID: productID, Path: path.Join(dir, id+ext)}
+	if err := storage.PutBytes(ctx, server.Uploads, img.Path, data, contentType); err != nil {
+		return nil, err
+	}
+
+	// dari besar ke kecil, tiap ukuran diperkecil dari ukuran sebelumnya (lebih cepat, hasil sama)
+	sizes := []struct {
+		suffix string
+		max    int
+		field  *string
+	}{
+		{"xl", consts.ProductImageExtraLarge, &img.ExtraLarge},
+		{"lg", consts.ProductImageLarge, &img.Large},
+		{"md", consts.ProductImageMedium, &img.Medium},
+		{"sm", consts.ProductImageSmall, &img.Small},
+	}
+	current := imaging.Fit(src, consts.ProductImageExtraLarge)
+	for _, size := range sizes {
+		current = imaging.Fit(current, size.max)
+		out, outExt, err := imaging.Encode(current, 85)
+		if err != nil {
+			server.deleteProductImageFiles(*img)
+			return nil, err
+		}
+		rel := path.Join(dir, id+"_"+size.suffix+outExt)
+		if err := storage.PutBytes(ctx, server.Uploads, rel, out, mime.TypeByExtension(outExt)); err != nil {
+			server.deleteProductImageFiles(*img)
+			return nil, err
+		}
+		*size.field = rel
+	}
+
+	return img, nil
+}
+
+// deleteProductImageFiles: hapus semua file gambar (dipanggil setelah / tanpa request, jadi pakai context sendiri)
+func (server *Server) deleteProductImageFiles(images ...models.ProductImage) {
+	ctx := context.Background()
+	for _, img := range images {
+		for _, rel := range img.Files() {
+			if err := server.Uploads.Delete(ctx, rel); err != nil {
+				log.Println("hapus gambar produk gagal:", err)
+			}
+		}
+	}
+}
+
+// deleteLegacyProductImage: file Product.Image dari upload versi lama (langsung di root upload)
+func (server *Server) deleteLegacyProductImage(product *models.Product) {
+	if product.Image == "" {
+		return
+	}
+	if err := server.Uploads.Delete(context.Background(), product.Image); err != nil {
+		log.Println("hapus gambar lama gagal:", err)
+	}
+}
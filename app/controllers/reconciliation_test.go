@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alirogz/goshop/app/consts"
+	"github.com/alirogz/goshop/app/models"
+	"github.com/alirogz/goshop/app/money"
+	"gorm.io/gorm"
+)
+
+func createTestBankTransaction(t *testing.T, db *gorm.DB, amount int64) *models.BankTransaction {
+	t.Helper()
+	bankTx := &models.BankTransaction{Bank: "BCA", Amount: money.FromInt(amount), Note: "TRANSFER", TrxTime: time.Now()}
+	if err := db.Create(bankTx).Error; err != nil {
+		t.Fatal(err)
+	}
+	return bankTx
+}
+
+// mutasi yang kurang dari grand total tidak boleh melunasi order
+func TestPairBankTransactionShortAmount(t *testing.T) {
+	db := testDB(t)
+	user := createTestUser(t, db, "password")
+	order := &models.Order{
+		UserID:        user.ID,
+		PaymentStatus: consts.OrderPaymentStatusUnpaid,
+		PaymentMethod: consts.OrderPaymentMethodBankTransfer,
+		GrandTotal:    money.FromInt(2_000_000),
+		PaymentTotal:  money.FromInt(2_000_123),
+	}
+	if err := db.Create(order).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	short := createTestBankTransaction(t, db, 10_000)
+	if _, err := models.PairBankTransaction(db, short.ID, order.ID, consts.ReconcileManual, user.ID, "", false); !errors.Is(err, models.ErrBankTxAmountShort) {
+		t.Fatalf("err = %v, want ErrBankTxAmountShort", err)
+	}
+	if _, err := models.PairBankTransaction(db, short.ID, order.ID, consts.ReconcileManual, user.ID, "", true); !errors.Is(err, models.ErrShortPaymentNote) {
+		t.Fatalf("tanpa catatan: err = %v, want ErrShortPaymentNote", err)
+	}
+
+	settled, err := models.PairBankTransaction(db, short.ID, order.ID, consts.ReconcileManual, user.ID, "cicilan pertama", true)
+	if err != nil || settled {
+		t.Fatalf("kurang bayar: settled=%v err=%v", settled, err)
+	}
+	var saved models.Order
+	db.Where("id = ?", order.ID).First(&saved)
+	if saved.IsPaid() || saved.InvoiceNumber != "" {
+		t.Fatalf("order kurang bayar ditandai lunas: %s %q", saved.PaymentStatus, saved.InvoiceNumber)
+	}
+	var log models.ReconciliationLog
+	if err := db.Where("bank_transaction_id = ?", short.ID).Order("created_at DESC").First(&log).Error; err != nil || log.Action != consts.ReconcileShort {
+		t.Fatalf("log = %+v, err = %v", log, err)
+	}
+
+	// sisa pembayaran melunasi order
+	rest := createTestBankTransaction(t, db, 1_990_000)
+	settled, err = models.PairBankTransaction(db, rest.ID, order.ID, consts.ReconcileManual, user.ID, "", false)
+	if err != nil || !settled {
+		t.Fatalf("pelunasan: settled=%v err=%v", settled, err)
+	}
+	db.Where("id = ?", order.ID).First(&saved)
+	if !saved.IsPaid() {
+		t.Fatalf("order belum lunas: %s", saved.PaymentStatus)
+	}
+}
@@ -0,0 +1,141 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/alirogz/goshop/app/consts"
+	"github.com/alirogz/goshop/app/models"
+	"github.com/urfave/cli"
+	"gorm.io/gorm"
+)
+
+// syncRoles: role bawaan + owner awal dari ADMIN_EMAIL (pengganti cek email lama).
+// ADMIN_EMAIL hanya dipakai kalau belum ada owner sama sekali.
+func (server *Server) syncRoles() error {
+	if err := models.SyncDefaultRoles(server.DB); err != nil {
+		return err
+	}
+
+	adminEmail := strings.TrimSpace(os.Getenv("ADMIN_EMAIL"))
+	if adminEmail == "" {
+		return nil
+	}
+	owners, err := models.CountUsersWithRole(server.DB, consts.RoleOwner)
+	if err != nil || owners > 0 {
+		return err
+	}
+
+	user, err := (&models.User{}).FindByEmail(server.DB, adminEmail)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		fmt.Printf("ADMIN_EMAIL %s belum terdaftar, owner belum dibuat\n", adminEmail)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := models.GrantRole(server.DB, user.ID, consts.RoleOwner, "ADMIN_EMAIL"); err != nil {
+		return err
+	}
+	fmt.Printf("%s dijadikan owner (dari ADMIN_EMAIL)\n", user.Email)
+	return nil
+}
+
+// roles:grant <email> <role>
+func (server *Server) grantRoleCommand(c *cli.Context) error {
+	user, roleName, err := server.roleCommandArgs(c)
+	if err != nil {
+		return err
+	}
+
+	if err := models.GrantRole(server.DB, user.ID, roleName, "cli"); err != nil {
+		return err
+	}
+
+	fmt.Printf("role %s diberikan ke %s\n", roleName, user.Email)
+	return nil
+}
+
+// roles:revoke <email> <role>
+func (server *Server) revokeRoleCommand(c *cli.Context) error {
+	user, roleName, err := server.roleCommandArgs(c)
+	if err != nil {
+		return err
+	}
+
+	// jangan sampai toko tanpa owner
+	if roleName == consts.RoleOwner {
+		owners, err := models.CountUsersWithRole(server.DB, consts.RoleOwner)
+		if err != nil {
+			return err
+		}
+		if owners <= 1 {
+			return errors.New("owner terakhir tidak bisa dicabut, beri role owner ke user lain dulu")
+		}
+	}
+
+	if err := models.RevokeRole(server.DB, user.ID, roleName); err != nil {
+		return err
+	}
+
+	fmt.Printf("role %s dicabut dari %s\n", roleName, user.Email)
+	return nil
+}
+
+// roles:list [email]: daftar role & hak aksesnya, atau role milik 1 user
+func (server *Server) listRolesCommand(c *cli.Context) error {
+	if email := strings.TrimSpace(c.Args().First()); email != "" {
+		user, err := (&models.User{}).FindByEmail(server.DB, email)
+		if err != nil {
+			return fmt.Errorf("user %s tidak ditemukan", email)
+		}
+		if err := user.LoadAccess(server.DB); err != nil {
+			return err
+		}
+		if len(user.Roles) == 0 {
+			fmt.Printf("%s tidak punya role staff\n", user.Email)
+			return nil
+		}
+		fmt.Printf("%s: %s\n", user.Email, strings.Join(user.Roles, ", "))
+		return nil
+	}
+
+	roles, err := models.AllRoles(server.DB)
+	if err != nil {
+		return err
+	}
+	for _, role := range roles {
+		perms := make([]string, 0, len(role.Permissions))
+		for _, p := range role.Permissions {
+			perms = append(perms, p.Permission)
+		}
+		fmt.Printf("%-15s %-18s %s\n", role.Name, role.Label, strings.Join(perms, ", "))
+	}
+	return nil
+}
+
+func (server *Server) roleCommandArgs(c *cli.Context) (*models.User, string, error) {
+	email := strings.TrimSpace(c.Args().Get(0))
+	roleName := strings.ToLower(strings.TrimSpace(c.Args().Get(1)))
+	if email == "" || roleName == "" {
+		return nil, "", errors.New("pemakaian: <email> <role>")
+	}
+
+	// role bawaan dibuat dulu, supaya grant bisa langsung dipakai setelah deploy
+	if err := models.SyncDefaultRoles(server.DB); err != nil {
+		return nil, "", err
+	}
+
+	user, err := (&models.User{}).FindByEmail(server.DB, email)
+	if err != nil {
+		return nil, "", fmt.Errorf("user %s tidak ditemukan", email)
+	}
+	if _, err := models.FindRoleByName(server.DB, roleName); err != nil {
+		return nil, "", fmt.Errorf("%w: %s", err, roleName)
+	}
+
+	return user, roleName, nil
+}
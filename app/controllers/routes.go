@@ -2,22 +2,38 @@ package controllers
 
 import (
 	"net/http"
+	"path"
+	"strings"
 
+	"github.com/alirogz/goshop/app/consts"
+	"github.com/alirogz/goshop/app/storage"
 	"github.com/gorilla/mux"
 )
 
 func (server *Server) initializeRoutes() {
 	server.Router = mux.NewRouter()
+	// semua POST wajib membawa token CSRF (lihat csrf.go)
+	server.Router.Use(server.CSRFProtect)
+
 	server.Router.HandleFunc("/", server.Home).Methods("GET")
 
 	server.Router.HandleFunc("/login", server.Login).Methods("GET")
 	server.Router.HandleFunc("/login", server.DoLogin).Methods("POST")
 	server.Router.HandleFunc("/register", server.Register).Methods("GET")
 	server.Router.HandleFunc("/register", server.DoRegister).Methods("POST")
+	server.Router.HandleFunc("/forgot-password", server.ForgotPasswordForm).Methods("GET")
+	server.Router.HandleFunc("/forgot-password", server.ForgotPassword).Methods("POST")
+	server.Router.HandleFunc("/reset-password/{token}", server.ResetPasswordForm).Methods("GET")
+	server.Router.HandleFunc("/reset-password/{token}", server.ResetPassword).Methods("POST")
+	server.Router.HandleFunc("/verify-email/resend", server.RequireLogin(server.ResendVerificationEmail)).Methods("POST")
+	server.Router.HandleFunc("/verify-email/{token}", server.VerifyEmail).Methods("GET")
 	server.Router.HandleFunc("/logout", server.Logout).Methods("GET")
 
 	server.Router.HandleFunc("/products", server.Products).Methods("GET")
+	server.Router.HandleFunc("/products/suggest", server.ProductSuggest).Methods("GET")
 	server.Router.HandleFunc("/products/{slug}", server.GetProductBySlug).Methods("GET")
+	server.Router.HandleFunc("/categories/{slug}", server.CategoryProducts).Methods("GET")
+	server.Router.HandleFunc("/sections/{slug}", server.SectionProducts).Methods("GET")
 
 	// CART
 	// cart
@@ -25,6 +41,8 @@ func (server *Server) initializeRoutes() {
 	server.Router.HandleFunc("/carts", server.AddItemToCart).Methods("POST")
 	server.Router.HandleFunc("/carts/update", server.UpdateCartItemQty).Methods("POST")
 	server.Router.HandleFunc("/carts/remove", server.RemoveCartItem).Methods("POST")
+	server.Router.HandleFunc("/carts/coupon", server.ApplyCartCoupon).Methods("POST")
+	server.Router.HandleFunc("/carts/coupon/remove", server.RemoveCartCoupon).Methods("POST")
 
 	// ORDERS
 	server.Router.HandleFunc("/orders", server.OrdersIndex).Methods("GET")
@@ -34,52 +52,106 @@ func (server *Server) initializeRoutes() {
 	server.Router.HandleFunc("/orders/{id}/pay-manual", server.PayManualForm).Methods("GET")
 	server.Router.HandleFunc("/orders/{id}/pay-manual", server.PayManual).Methods("POST")
 	server.Router.HandleFunc("/orders/{id}/payment-proof", server.UploadPaymentProof).Methods("POST")
+	server.Router.HandleFunc("/orders/{id}/payment-proof", server.PaymentProofDownload).Methods("GET")
+	server.Router.HandleFunc("/orders/{id}/payment-proof/{proofID}", server.PaymentProofDownload).Methods("GET")
 
 	// SHIPPING (local, tanpa API)
 	server.Router.HandleFunc("/shipping/options", server.ShippingOptions).Methods("GET")
 
-	// MOCK PAYMENT
-	server.Router.HandleFunc("/payments/mock", server.MockPay).Methods("POST")
+	// PAYMENT GATEWAY
+	server.Router.HandleFunc("/orders/{id}/pay-online", server.PayOnline).Methods("POST")
+	server.Router.HandleFunc("/payments/notification", server.PaymentNotification).Methods("POST")
+	if server.FakePayment != nil {
+		server.Router.PathPrefix("/payments/fake/").Handler(server.FakePayment)
+		// MOCK PAYMENT: hanya untuk development (PAYMENT_GATEWAY=fake)
+		server.Router.HandleFunc("/payments/mock", server.MockPay).Methods("POST")
+	}
 
 	// STATIC FILES (CSS, JS, gambar di /public)
 	staticFileDirectory := http.Dir("./public/")
-	staticFileHandler := http.StripPrefix("/public/", http.FileServer(staticFileDirectory))
+	staticFileHandler := http.StripPrefix("/public/", hidePrivateFiles(http.FileServer(staticFileDirectory), "payment_proofs", "uploads/payment_proofs"))
 	server.Router.PathPrefix("/public/").Handler(staticFileHandler).Methods("GET")
 
-	// UPLOADS (gambar produk yang di-upload dari admin)
-	uploadDir := http.Dir("./public/uploads")
-	uploadHandler := http.StripPrefix("/uploads/", http.FileServer(uploadDir))
+	// UPLOADS (gambar produk): dari disk, atau redirect ke S3 (lihat STORAGE_DRIVER)
+	uploadHandler := http.StripPrefix("/uploads/", hidePrivateFiles(storage.Handler(server.Uploads, uploadURLExpiry), "payment_proofs"))
 	server.Router.PathPrefix("/uploads/").Handler(uploadHandler).Methods("GET")
 
+	// FILE PRIVAT di disk: hanya bisa dibuka dengan URL bertanda tangan dari handler yang sudah cek hak akses
+	if files, ok := server.Files.(*storage.Local); ok {
+		server.Router.PathPrefix("/files/").Handler(http.StripPrefix("/files/", files)).Methods("GET")
+	}
+	if server.FakeStorage != nil {
+		server.Router.PathPrefix("/storage/fake/").Handler(server.FakeStorage)
+	}
+
 	// =======================
 	//      ADMIN ORDERS
 	// =======================
-	server.Router.HandleFunc("/admin/orders", server.AdminOrdersIndex).Methods("GET")
-	server.Router.HandleFunc("/admin/orders/{id}", server.AdminOrdersShow).Methods("GET")
-	server.Router.HandleFunc("/admin/orders/{id}/pay-manual", server.AdminPayManual).Methods("POST")
-	server.Router.HandleFunc("/admin/orders/{id}/status", server.AdminUpdateStatus).Methods("POST")
-	server.Router.HandleFunc("/admin/orders/{id}/payment/approve", server.AdminApprovePayment).Methods("POST")
-	server.Router.HandleFunc("/admin/orders/{id}/payment/reject", server.AdminRejectPayment).Methods("POST")
+	server.Router.HandleFunc("/admin/orders", server.RequirePermission(consts.PermOrdersView, server.AdminOrdersIndex)).Methods("GET")
+	server.Router.HandleFunc("/admin/orders/{id}", server.RequirePermission(consts.PermOrdersView, server.AdminOrdersShow)).Methods("GET")
+	server.Router.HandleFunc("/admin/orders/{id}/pay-manual", server.RequirePermission(consts.PermPaymentsManage, server.AdminPayManual)).Methods("POST")
+	server.Router.HandleFunc("/admin/orders/{id}/status", server.RequirePermission(consts.PermOrdersManage, server.AdminUpdateStatus)).Methods("POST")
+	server.Router.HandleFunc("/admin/orders/{id}/payment/approve", server.RequirePermission(consts.PermPaymentsManage, server.AdminApprovePayment)).Methods("POST")
+	server.Router.HandleFunc("/admin/orders/{id}/payment/reject", server.RequirePermission(consts.PermPaymentsManage, server.AdminRejectPayment)).Methods("POST")
+
+	// outbox email notifikasi
+	server.Router.HandleFunc("/admin/notifications", server.RequirePermission(consts.PermOrdersView, server.AdminNotificationsIndex)).Methods("GET")
+	server.Router.HandleFunc("/admin/notifications/{id}/resend", server.RequirePermission(consts.PermOrdersManage, server.AdminNotificationResend)).Methods("POST")
 
 	// =======================
 	//      ADMIN PRODUCTS
 	// =======================
-	server.Router.HandleFunc("/admin/products", server.AdminProductsIndex).Methods("GET")
-	server.Router.HandleFunc("/admin/products/new", server.AdminProductsNew).Methods("GET")
-	server.Router.HandleFunc("/admin/products", server.AdminProductsCreate).Methods("POST")
-	server.Router.HandleFunc("/admin/products/{id}/edit", server.AdminProductsEdit).Methods("GET")
-	server.Router.HandleFunc("/admin/products/{id}", server.AdminProductsUpdate).Methods("POST")
-	server.Router.HandleFunc("/admin/products/{id}/delete", server.AdminProductsDelete).Methods("POST")
+	server.Router.HandleFunc("/admin/products", server.RequirePermission(consts.PermCatalogManage, server.AdminProductsIndex)).Methods("GET")
+	server.Router.HandleFunc("/admin/products/new", server.RequirePermission(consts.PermCatalogManage, server.AdminProductsNew)).Methods("GET")
+	server.Router.HandleFunc("/admin/products", server.RequirePermission(consts.PermCatalogManage, server.AdminProductsCreate)).Methods("POST")
+	server.Router.HandleFunc("/admin/products/{id}/edit", server.RequirePermission(consts.PermCatalogManage, server.AdminProductsEdit)).Methods("GET")
+	server.Router.HandleFunc("/admin/products/{id}", server.RequirePermission(consts.PermCatalogManage, server.AdminProductsUpdate)).Methods("POST")
+	server.Router.HandleFunc("/admin/products/{id}/delete", server.RequirePermission(consts.PermCatalogManage, server.AdminProductsDelete)).Methods("POST")
+	server.Router.HandleFunc("/admin/products/{id}/inventory", server.RequirePermission(consts.PermCatalogManage, server.AdminProductInventory)).Methods("GET")
+	server.Router.HandleFunc("/admin/categories", server.RequirePermission(consts.PermCatalogManage, server.AdminCategoriesIndex)).Methods("GET")
+	server.Router.HandleFunc("/admin/categories/new", server.RequirePermission(consts.PermCatalogManage, server.AdminCategoriesNew)).Methods("GET")
+	server.Router.HandleFunc("/admin/categories", server.RequirePermission(consts.PermCatalogManage, server.AdminCategoriesCreate)).Methods("POST")
+	server.Router.HandleFunc("/admin/categories/{id}/edit", server.RequirePermission(consts.PermCatalogManage, server.AdminCategoriesEdit)).Methods("GET")
+	server.Router.HandleFunc("/admin/categories/{id}", server.RequirePermission(consts.PermCatalogManage, server.AdminCategoriesUpdate)).Methods("POST")
+	server.Router.HandleFunc("/admin/categories/{id}/delete", server.RequirePermission(consts.PermCatalogManage, server.AdminCategoriesDelete)).Methods("POST")
+	server.Router.HandleFunc("/admin/sections", server.RequirePermission(consts.PermCatalogManage, server.AdminSectionsCreate)).Methods("POST")
+	server.Router.HandleFunc("/admin/sections/{id}/edit", server.RequirePermission(consts.PermCatalogManage, server.AdminSectionsEdit)).Methods("GET")
+	server.Router.HandleFunc("/admin/sections/{id}", server.RequirePermission(consts.PermCatalogManage, server.AdminSectionsUpdate)).Methods("POST")
+	server.Router.HandleFunc("/admin/sections/{id}/delete", server.RequirePermission(consts.PermCatalogManage, server.AdminSectionsDelete)).Methods("POST")
+
+	// =======================
+	//     ADMIN PROMOTIONS
+	// =======================
+	server.Router.HandleFunc("/admin/promotions", server.RequirePermission(consts.PermPromotionsManage, server.AdminPromotionsIndex)).Methods("GET")
+	server.Router.HandleFunc("/admin/promotions/new", server.RequirePermission(consts.PermPromotionsManage, server.AdminPromotionsNew)).Methods("GET")
+	server.Router.HandleFunc("/admin/promotions", server.RequirePermission(consts.PermPromotionsManage, server.AdminPromotionsCreate)).Methods("POST")
+	server.Router.HandleFunc("/admin/promotions/report", server.RequirePermission(consts.PermPromotionsManage, server.AdminPromotionsReport)).Methods("GET")
+	server.Router.HandleFunc("/admin/promotions/{id}/edit", server.RequirePermission(consts.PermPromotionsManage, server.AdminPromotionsEdit)).Methods("GET")
+	server.Router.HandleFunc("/admin/promotions/{id}", server.RequirePermission(consts.PermPromotionsManage, server.AdminPromotionsUpdate)).Methods("POST")
+	server.Router.HandleFunc("/admin/promotions/{id}/delete", server.RequirePermission(consts.PermPromotionsManage, server.AdminPromotionsDelete)).Methods("POST")
+
+	// Admin settings (pajak)
+	server.Router.HandleFunc("/admin/settings", server.RequirePermission(consts.PermSettingsManage, server.AdminSettings)).Methods("GET")
+	server.Router.HandleFunc("/admin/settings", server.RequirePermission(consts.PermSettingsManage, server.AdminSettingsUpdate)).Methods("POST")
+
 	// Admin dashboard
-	server.Router.HandleFunc("/admin/dashboard", server.AdminDashboard).Methods("GET")
+	server.Router.HandleFunc("/admin/dashboard", server.RequireAdmin(server.AdminDashboard)).Methods("GET")
 
 	// =======================
 	//      ADMIN PAYMENTS
 	// =======================
-	server.Router.HandleFunc("/admin/payments/auto-match", server.AutoMatchPayments).Methods("POST")
-	server.Router.HandleFunc("/admin/payments/import", server.ShowImportBankPage).Methods("GET")
-	server.Router.HandleFunc("/admin/payments/import", server.HandleImportBankCSV).Methods("POST")
-	server.Router.HandleFunc("/admin/payments/bank-tx/debug", server.DebugListBankTx).Methods("GET")
+	server.Router.HandleFunc("/admin/payments/auto-match", server.RequirePermission(consts.PermPaymentsManage, server.AutoMatchPayments)).Methods("POST")
+	server.Router.HandleFunc("/admin/payments/import", server.RequirePermission(consts.PermPaymentsManage, server.ShowImportBankPage)).Methods("GET")
+	server.Router.HandleFunc("/admin/payments/import", server.RequirePermission(consts.PermPaymentsManage, server.HandleImportBankCSV)).Methods("POST")
+	server.Router.HandleFunc("/admin/payments/imports/{id}", server.RequirePermission(consts.PermPaymentsManage, server.AdminBankImportShow)).Methods("GET")
+	server.Router.HandleFunc("/admin/payments/imports/{id}/commit", server.RequirePermission(consts.PermPaymentsManage, server.AdminBankImportCommit)).Methods("POST")
+	server.Router.HandleFunc("/admin/payments/imports/{id}/rollback", server.RequirePermission(consts.PermPaymentsManage, server.AdminBankImportRollback)).Methods("POST")
+	server.Router.HandleFunc("/admin/payments/bank-tx/debug", server.RequirePermission(consts.PermPaymentsManage, server.DebugListBankTx)).Methods("GET")
+	server.Router.HandleFunc("/admin/payments/reconcile", server.RequirePermission(consts.PermPaymentsManage, server.AdminReconcileIndex)).Methods("GET")
+	server.Router.HandleFunc("/admin/payments/reconcile/{id}/confirm", server.RequirePermission(consts.PermPaymentsManage, server.AdminReconcileConfirm)).Methods("POST")
+	server.Router.HandleFunc("/admin/payments/reconcile/{id}/pair", server.RequirePermission(consts.PermPaymentsManage, server.AdminReconcilePair)).Methods("POST")
+	server.Router.HandleFunc("/admin/payments/reconcile/{id}/reject", server.RequirePermission(consts.PermPaymentsManage, server.AdminReconcileReject)).Methods("POST")
+	server.Router.HandleFunc("/admin/payments/reconcile/{id}/unmatch", server.RequirePermission(consts.PermPaymentsManage, server.AdminReconcileUnmatch)).Methods("POST")
 
 	// PROFILE
 	server.Router.HandleFunc("/profile", server.RequireLogin(server.ProfileIndex)).Methods("GET")
@@ -101,16 +173,49 @@ func (server *Server) initializeRoutes() {
 	// =======================
 	//         LIVE CHAT
 	// =======================
-	server.Router.HandleFunc("/chat", server.RequireLogin(server.ChatPage)).Methods("GET")
+	server.Router.HandleFunc("/chat", server.RequireLogin(server.ChatIndex)).Methods("GET")
+	server.Router.HandleFunc("/chat", server.RequireLogin(server.ChatStart)).Methods("POST")
+	server.Router.HandleFunc("/chat/events", server.RequireLogin(server.ChatEvents)).Methods("GET")
+	// endpoint lama (sebelum ada thread): memakai thread umum user; harus didaftarkan sebelum /chat/{id}
 	server.Router.HandleFunc("/chat/messages", server.RequireLogin(server.ChatMessages)).Methods("GET")
 	server.Router.HandleFunc("/chat/messages", server.RequireLogin(server.ChatSend)).Methods("POST")
+	server.Router.HandleFunc("/chat/read", server.RequireLogin(server.ChatRead)).Methods("POST")
+	server.Router.HandleFunc("/chat/attachments/{id}", server.RequireLogin(server.ChatAttachmentDownload)).Methods("GET")
+	server.Router.HandleFunc("/chat/{id}", server.RequireLogin(server.ChatPage)).Methods("GET")
+	server.Router.HandleFunc("/chat/{id}/messages", server.RequireLogin(server.ChatMessages)).Methods("GET")
+	server.Router.HandleFunc("/chat/{id}/messages", server.RequireLogin(server.ChatSend)).Methods("POST")
+	server.Router.HandleFunc("/chat/{id}/read", server.RequireLogin(server.ChatRead)).Methods("POST")
+	server.Router.HandleFunc("/orders/{id}/chat", server.RequireLogin(server.OrderChat)).Methods("POST")
 
 	// =======================
 	//      ADMIN LIVE CHAT
 	// =======================
-	server.Router.HandleFunc("/admin/chats", server.AdminChatsIndex).Methods("GET")
-	server.Router.HandleFunc("/admin/chats/{id}", server.AdminChatsShow).Methods("GET")
-	server.Router.HandleFunc("/admin/chats/{id}/messages", server.AdminChatMessages).Methods("GET")
-	server.Router.HandleFunc("/admin/chats/{id}/messages", server.AdminChatSend).Methods("POST")
+	server.Router.HandleFunc("/admin/chats", server.RequirePermission(consts.PermChatsManage, server.AdminChatsIndex)).Methods("GET")
+	server.Router.HandleFunc("/admin/chats/{id}", server.RequirePermission(consts.PermChatsManage, server.AdminChatsShow)).Methods("GET")
+	server.Router.HandleFunc("/admin/chats/{id}/messages", server.RequirePermission(consts.PermChatsManage, server.AdminChatMessages)).Methods("GET")
+	server.Router.HandleFunc("/admin/chats/{id}/messages", server.RequirePermission(consts.PermChatsManage, server.AdminChatSend)).Methods("POST")
+	server.Router.HandleFunc("/admin/chats/{id}/read", server.RequirePermission(consts.PermChatsManage, server.AdminChatRead)).Methods("POST")
+	server.Router.HandleFunc("/admin/chats/{id}/assign", server.RequirePermission(consts.PermChatsManage, server.AdminChatAssign)).Methods("POST")
+	server.Router.HandleFunc("/admin/chats/{id}/status", server.RequirePermission(consts.PermChatsManage, server.AdminChatStatus)).Methods("POST")
+	server.Router.HandleFunc("/admin/chats/{id}/attachments/{attachmentID}/payment-proof", server.RequirePermission(consts.PermChatsManage, server.AdminChatAttachmentPaymentProof)).Methods("POST")
+
+}
 
+// hidePrivateFiles: 404 untuk daftar isi folder & folder bukti bayar lama (sebelum storage:migrate).
+// Bukti bayar hanya dibuka lewat /orders/{id}/payment-proof.
+func hidePrivateFiles(h http.Handler, dirs ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+		if name == "" || strings.HasSuffix(r.URL.Path, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		for _, dir := range dirs {
+			if name == dir || strings.HasPrefix(name, dir+"/") {
+				http.NotFound(w, r)
+				return
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
 }
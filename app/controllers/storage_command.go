@@ -0,0 +1,285 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alirogz/goshop/app/attachments"
+	"github.com/alirogz/goshop/app/consts"
+	"github.com/alirogz/goshop/app/models"
+	"github.com/alirogz/goshop/app/storage"
+	"github.com/urfave/cli"
+	"gorm.io/gorm"
+)
+
+const (
+	uploadURLExpiry      = 24 * time.Hour   // redirect /uploads/... ke S3 (kalau S3_PUBLIC_URL kosong)
+	privateFileURLExpiry = 10 * time.Minute // URL file privat yang diberikan setelah hak akses dicek
+)
+
+// redirectPrivateFile: arahkan browser ke URL bertanda tangan untuk file privat (panggil setelah hak akses dicek).
+// Gambar & PDF dibuka di browser, selain itu diunduh dengan nama fileName.
+func (server *Server) redirectPrivateFile(w http.ResponseWriter, r *http.Request, store *attachments.Store, rel, contentType, fileName string) {
+	disposition := "attachment"
+	if attachments.IsImage(contentType) || contentType == "application/pdf" {
+		disposition = "inline"
+	}
+	if v := mime.FormatMediaType(disposition, map[string]string{"filename": fileName}); v != "" {
+		disposition = v
+	}
+
+	url, err := store.Blob.SignedURL(r.Context(), rel, storage.URLOptions{
+		Expires:     privateFileURLExpiry,
+		ContentType: contentType,
+		Disposition: disposition,
+	})
+	if err != nil {
+		log.Println("private file url error:", err)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	// redirect boleh di-cache sebentar (lebih pendek dari masa berlaku URL-nya)
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	http.Redirect(w, r, url, http.StatusFound)
+}
+
+// storageSource: folder lama yang isinya dipindah ke storage oleh storage:migrate
+type storageSource struct {
+	Dir    string
+	Blob   storage.Blob
+	Prefix string // key tujuan = Prefix/path relatif di Dir
+}
+
+func (server *Server) storageSources() []storageSource {
+	return []storageSource{
+		{Dir: "public/uploads", Blob: server.Uploads},
+		{Dir: server.AppConfig.ChatAttachmentDir, Blob: server.Attachments.Blob},
+	}
+}
+
+// legacyPaymentProofDirs: folder bukti bayar versi lama (isi kolom orders.payment_proof = nama file di sini),
+// selain key payment_proofs/{nama file} di server.Uploads
+var legacyPaymentProofDirs = []string{"public/uploads/payment_proofs", "public/payment_proofs", "uploads/payments"}
+
+// storage:migrate [--dry-run] [--delete]: salin file dari folder lama ke STORAGE_DRIVER yang aktif,
+// lalu pindahkan bukti bayar lama ke storage privat.
+// Aman dijalankan ulang: file yang sudah ada ditimpa dengan isi yang sama, file yang sudah di tempatnya dilewati.
+func (server *Server) migrateStorageCommand(c *cli.Context) error {
+	if server.FakeStorage != nil {
+		return errors.New("STORAGE_DRIVER=fake hanya hidup selama aplikasi jalan, storage:migrate tidak bisa dipakai")
+	}
+
+	ctx := context.Background()
+	dryRun, remove := c.Bool("dry-run"), c.Bool("delete")
+
+	copied, skipped, failed, err := server.migrateStorageFiles(ctx, dryRun, remove)
+	if err != nil {
+		return err
+	}
+
+	moved, movedFailed, err := server.migratePaymentProofs(ctx, dryRun, remove)
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		fmt.Printf("%d file akan disalin, %d sudah di tempatnya, %d bukti bayar akan dipindah (dry run)\n", copied, skipped, moved)
+		return nil
+	}
+	fmt.Printf("%d file disalin, %d sudah di tempatnya, %d gagal\n", copied, skipped, failed)
+	fmt.Printf("%d bukti bayar dipindah ke storage privat, %d gagal\n", moved, movedFailed)
+	if failed+movedFailed > 0 {
+		return fmt.Errorf("%d file gagal disalin, jalankan ulang setelah masalahnya diperbaiki", failed+movedFailed)
+	}
+	return nil
+}
+
+// migrateStorageFiles: salin isi folder lama (storageSources) ke Blob tujuannya, tanpa bukti bayar
+func (server *Server) migrateStorageFiles(ctx context.Context, dryRun, remove bool) (copied, skipped, failed int, err error) {
+	for _, src := range server.storageSources() {
+		if src.Dir == "" {
+			continue
+		}
+
+		err = filepath.WalkDir(src.Dir, func(file string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if file == src.Dir && errors.Is(err, fs.ErrNotExist) {
+					return filepath.SkipDir // folder lama memang tidak ada
+				}
+				return err
+			}
+			if d.IsDir() && file == filepath.Join(src.Dir, "payment_proofs") {
+				return filepath.SkipDir // bukti bayar tidak ikut ke storage publik, lihat migratePaymentProofs
+			}
+			if d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+				return nil
+			}
+
+			rel, err := filepath.Rel(src.Dir, file)
+			if err != nil {
+				return err
+			}
+			key := path.Join(src.Prefix, filepath.ToSlash(rel))
+
+			if dst, ok := storage.LocalPath(src.Blob, key); ok && sameFile(dst, file) {
+				skipped++
+				return nil
+			}
+
+			fmt.Printf("%s -> %s\n", file, key)
+			if dryRun {
+				copied++
+				return nil
+			}
+			if err := putLocalFile(ctx, src.Blob, key, file); err != nil {
+				fmt.Printf("  gagal: %v\n", err)
+				failed++
+				return nil
+			}
+			copied++
+
+			if remove {
+				if err := os.Remove(file); err != nil {
+					fmt.Printf("  file lama gagal dihapus: %v\n", err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return copied, skipped, failed, err
+		}
+	}
+	return copied, skipped, failed, nil
+}
+
+// migratePaymentProofs: bukti bayar lama (kolom orders.payment_proof, file publik) dipindah ke server.PaymentProofs
+// dan dicatat sebagai PaymentProof; kolom lamanya dikosongkan supaya tidak diproses ulang.
+func (server *Server) migratePaymentProofs(ctx context.Context, dryRun, remove bool) (moved, failed int, err error) {
+	var orders []models.Order
+	if err := server.DB.Where("payment_proof <> ?", "").Find(&orders).Error; err != nil {
+		return 0, 0, err
+	}
+
+	for i := range orders {
+		order := &orders[i]
+		fmt.Printf("bukti bayar %s (%s) -> storage privat\n", order.Code, order.PaymentProof)
+		if dryRun {
+			moved++
+			continue
+		}
+		if err := server.migratePaymentProof(ctx, order, remove); err != nil {
+			fmt.Printf("  gagal: %v\n", err)
+			failed++
+			continue
+		}
+		moved++
+	}
+	return moved, failed, nil
+}
+
+func (server *Server) migratePaymentProof(ctx context.Context, order *models.Order, remove bool) error {
+	name := path.Base(strings.ReplaceAll(order.PaymentProof, "\\", "/"))
+	src, removeOld, err := server.openLegacyPaymentProof(ctx, name)
+	if err != nil {
+		return err
+	}
+	file, err := server.PaymentProofs.Save(ctx, src)
+	src.Close()
+	if err != nil {
+		return err
+	}
+
+	proof := &models.PaymentProof{
+		OrderID:     order.ID,
+		Source:      consts.PaymentProofSourceLegacy,
+		FileName:    chatAttachmentName(name),
+		ContentType: file.ContentType,
+		Size:        file.Size,
+		StoragePath: file.Path,
+		ThumbPath:   file.ThumbPath,
+		Status:      legacyPaymentProofStatus(order.PaymentStatus),
+		CreatedAt:   order.UpdatedAt,
+	}
+	err = server.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(proof).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Order{}).Where("id = ?", order.ID).Update("payment_proof", "").Error
+	})
+	if err != nil {
+		server.PaymentProofs.Delete(context.Background(), file)
+		return err
+	}
+
+	if remove {
+		if err := removeOld(); err != nil {
+			fmt.Printf("  file lama gagal dihapus: %v\n", err)
+		}
+	}
+	return nil
+}
+
+// openLegacyPaymentProof: cari file bukti bayar lama di server.Uploads lalu di folder lama; removeOld menghapus sumbernya
+func (server *Server) openLegacyPaymentProof(ctx context.Context, name string) (src io.ReadCloser, removeOld func() error, err error) {
+	key := "payment_proofs/" + name
+	src, err = server.Uploads.Get(ctx, key)
+	if err == nil {
+		return src, func() error { return server.Uploads.Delete(ctx, key) }, nil
+	}
+	if !errors.Is(err, storage.ErrNotFound) && !errors.Is(err, storage.ErrInvalidKey) {
+		return nil, nil, err
+	}
+
+	for _, dir := range legacyPaymentProofDirs {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if f, err := os.Open(file); err == nil {
+			return f, func() error { return os.Remove(file) }, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("file %s tidak ditemukan", name)
+}
+
+// legacyPaymentProofStatus: status bukti bayar lama mengikuti status pembayaran order
+func legacyPaymentProofStatus(paymentStatus string) string {
+	switch paymentStatus {
+	case consts.OrderPaymentStatusPaid:
+		return consts.PaymentProofApproved
+	case consts.OrderPaymentStatusRejected:
+		return consts.PaymentProofRejected
+	}
+	return consts.PaymentProofPending
+}
+
+func putLocalFile(ctx context.Context, blob storage.Blob, key, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return blob.Put(ctx, key, f, mime.TypeByExtension(path.Ext(key)))
+}
+
+func sameFile(a, b string) bool {
+	infoA, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	infoB, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(infoA, infoB)
+}
@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"image"
+	"image/png"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alirogz/goshop/app/consts"
+	"github.com/alirogz/goshop/app/models"
+	"github.com/alirogz/goshop/app/storage"
+	"github.com/urfave/cli"
+)
+
+// newS3TestServer: server dengan STORAGE_DRIVER=s3 ke FakeS3, dijalankan di folder sementara
+// (storage:migrate membaca folder lama relatif terhadap folder kerja)
+func newS3TestServer(t *testing.T) (*Server, *storage.FakeS3) {
+	t.Helper()
+	fake := storage.NewFakeS3("test-access-key", "test-secret-key", "", "")
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	server := &Server{AppConfig: &AppConfig{
+		StorageDriver:     "s3",
+		S3Endpoint:        srv.URL,
+		S3Bucket:          "goshop",
+		S3AccessKey:       "test-access-key",
+		S3SecretKey:       "test-secret-key",
+		ChatAttachmentDir: "uploads/chat",
+	}}
+	server.initializeStorage()
+	return server, fake
+}
+
+func writeTestFile(t *testing.T, file string, data []byte) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readTestBlob(t *testing.T, b storage.Blob, key string) []byte {
+	t.Helper()
+	r, err := b.Get(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Get(%q): %v", key, err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestMigrateStorageFiles(t *testing.T) {
+	server, fake := newS3TestServer(t)
+	ctx := context.Background()
+
+	files := map[string][]byte{
+		"public/uploads/products/p1/kaos.jpg": []byte("gambar produk"),
+		"uploads/chat/2024/05/lampiran.pdf":   []byte("%PDF lampiran"),
+	}
+	for file, data := range files {
+		writeTestFile(t, file, data)
+	}
+	writeTestFile(t, "public/uploads/.gitkeep", nil)
+	writeTestFile(t, "public/uploads/payment_proofs/bukti.jpg", []byte("bukti bayar"))
+
+	copied, _, failed, err := server.migrateStorageFiles(ctx, true, false)
+	if err != nil || copied != 2 || failed != 0 || fake.Len() != 0 {
+		t.Fatalf("dry run: copied=%d failed=%d err=%v, %d file tersimpan", copied, failed, err, fake.Len())
+	}
+
+	copied, _, failed, err = server.migrateStorageFiles(ctx, false, false)
+	if err != nil || copied != 2 || failed != 0 {
+		t.Fatalf("copied=%d failed=%d err=%v", copied, failed, err)
+	}
+	if got := readTestBlob(t, server.Uploads, "products/p1/kaos.jpg"); !bytes.Equal(got, files["public/uploads/products/p1/kaos.jpg"]) {
+		t.Fatalf("isi gambar produk = %q", got)
+	}
+	if got := readTestBlob(t, server.Attachments.Blob, "2024/05/lampiran.pdf"); !bytes.Equal(got, files["uploads/chat/2024/05/lampiran.pdf"]) {
+		t.Fatalf("isi lampiran chat = %q", got)
+	}
+	// bukti bayar tidak boleh ikut ke storage publik
+	if _, err := server.Uploads.Get(ctx, "payment_proofs/bukti.jpg"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("bukti bayar tersalin ke storage publik: err = %v", err)
+	}
+	if fake.Len() != 2 {
+		t.Fatalf("%d file tersimpan, want 2", fake.Len())
+	}
+
+	// dijalankan ulang dengan --delete: isi sama ditimpa, file lama dihapus
+	copied, _, failed, err = server.migrateStorageFiles(ctx, false, true)
+	if err != nil || copied != 2 || failed != 0 || fake.Len() != 2 {
+		t.Fatalf("ulang: copied=%d failed=%d err=%v, %d file tersimpan", copied, failed, err, fake.Len())
+	}
+	for file := range files {
+		if _, err := os.Stat(file); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("%s belum dihapus: %v", file, err)
+		}
+	}
+	if _, err := os.Stat("public/uploads/payment_proofs/bukti.jpg"); err != nil {
+		t.Fatalf("bukti bayar lama ikut terhapus: %v", err)
+	}
+}
+
+func TestMigrateStorageCommand(t *testing.T) {
+	db := testDB(t)
+	server, _ := newS3TestServer(t)
+	server.DB = db
+
+	var proof bytes.Buffer
+	if err := png.Encode(&proof, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, "public/payment_proofs/bukti-lama.png", proof.Bytes())
+	writeTestFile(t, "public/uploads/products/p1/kaos.jpg", []byte("gambar produk"))
+
+	user := createTestUser(t, db, "password")
+	order := &models.Order{UserID: user.ID, PaymentProof: "bukti-lama.png", PaymentStatus: consts.OrderPaymentStatusPaid}
+	if err := db.Create(order).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	set := flag.NewFlagSet("storage:migrate", flag.ContinueOnError)
+	set.Bool("dry-run", false, "")
+	set.Bool("delete", false, "")
+	if err := set.Parse([]string{"--delete"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.migrateStorageCommand(cli.NewContext(nil, set, nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	var proofs []models.PaymentProof
+	if err := db.Where("order_id = ?", order.ID).Find(&proofs).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(proofs) != 1 || proofs[0].Source != consts.PaymentProofSourceLegacy || proofs[0].Status != consts.PaymentProofApproved {
+		t.Fatalf("bukti bayar tercatat = %+v", proofs)
+	}
+	if got := readTestBlob(t, server.PaymentProofs.Blob, proofs[0].StoragePath); !bytes.Equal(got, proof.Bytes()) {
+		t.Fatal("isi bukti bayar di storage privat berbeda")
+	}
+
+	var saved models.Order
+	if err := db.Where("id = ?", order.ID).First(&saved).Error; err != nil {
+		t.Fatal(err)
+	}
+	if saved.PaymentProof != "" {
+		t.Fatalf("orders.payment_proof = %q, harus dikosongkan", saved.PaymentProof)
+	}
+	if _, err := os.Stat("public/payment_proofs/bukti-lama.png"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("bukti bayar lama belum dihapus: %v", err)
+	}
+
+	// dijalankan ulang: bukti bayar tidak dicatat dua kali
+	if err := server.migrateStorageCommand(cli.NewContext(nil, set, nil)); err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	db.Model(&models.PaymentProof{}).Where("order_id = ?", order.ID).Count(&count)
+	if count != 1 {
+		t.Fatalf("%d bukti bayar setelah dijalankan ulang, want 1", count)
+	}
+}
@@ -1,19 +1,22 @@
 package controllers
 
 import (
+	"log"
 	"net/http"
 
+	"github.com/alirogz/goshop/app/consts"
 	"github.com/alirogz/goshop/app/models"
 	"github.com/google/uuid"
 )
 
 func (server *Server) Login(w http.ResponseWriter, r *http.Request) {
-	ren := userRender()
+	ren := userRender(r)
 
 	data := map[string]interface{}{
 		"user":      nil,
 		"isAdmin":   false,
 		"cartCount": server.GetCartCount(w, r),
+		"success":   GetFlash(w, r, "success"),
 		"error":     GetFlash(w, r, "error"),
 	}
 
@@ -21,7 +24,7 @@ func (server *Server) Login(w http.ResponseWriter, r *http.Request) {
 }
 
 func (server *Server) Register(w http.ResponseWriter, r *http.Request) {
-	ren := userRender()
+	ren := userRender(r)
 
 	data := map[string]interface{}{
 		"user":      nil,
@@ -54,6 +57,7 @@ func (server *Server) DoLogin(w http.ResponseWriter, r *http.Request) {
 	session, _ := store.Get(r, sessionUser)
 	session.Values["id"] = user.ID
 	session.Save(r, w)
+	RotateCSRFToken(w, r)
 
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
@@ -97,6 +101,14 @@ func (server *Server) DoRegister(w http.ResponseWriter, r *http.Request) {
 	session, _ := store.Get(r, sessionUser)
 	session.Values["id"] = user.ID
 	session.Save(r, w)
+	RotateCSRFToken(w, r)
+
+	// gagal kirim email tidak membatalkan registrasi, user bisa kirim ulang dari halaman keranjang
+	if err := server.startEmailVerification(r.Context(), user); err != nil {
+		log.Println("startEmailVerification error:", err)
+	} else {
+		SetFlash(w, r, "success", "Registrasi berhasil. Cek email "+user.Email+" untuk verifikasi sebelum checkout.")
+	}
 
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
@@ -111,7 +123,7 @@ func (server *Server) Logout(w http.ResponseWriter, r *http.Request) {
 }
 
 func (server *Server) ProfileIndex(w http.ResponseWriter, r *http.Request) {
-	ren := userRender()
+	ren := userRender(r)
 
 	user := server.CurrentUser(w, r)
 	if user == nil {
@@ -165,6 +177,9 @@ func (server *Server) ProfileUpdate(w http.ResponseWriter, r *http.Request) {
 
 	user.FirstName = r.FormValue("first_name")
 	user.LastName = r.FormValue("last_name")
+	if locale := r.FormValue("locale"); locale == consts.LocaleID || locale == consts.LocaleEN {
+		user.Locale = locale
+	}
 
 	if err := server.DB.Save(user).Error; err != nil {
 		http.Error(w, "gagal update profil", http.StatusInternalServerError)
@@ -178,7 +193,7 @@ func (server *Server) ProfileUpdate(w http.ResponseWriter, r *http.Request) {
 }
 
 func (server *Server) ProfilePasswordForm(w http.ResponseWriter, r *http.Request) {
-	ren := userRender()
+	ren := userRender(r)
 
 	user := server.CurrentUser(w, r)
 	if user == nil {
@@ -0,0 +1,146 @@
+// Package imaging: decode, perkecil & encode gambar hanya dengan codec standar Go (JPEG, PNG, GIF).
+// WebP/AVIF tidak didukung library standar, jadi hasil selalu JPEG (atau PNG kalau ada bagian transparan).
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+
+	_ "image/gif" // decoder GIF
+)
+
+// MaxPixels: batas piksel gambar yang mau di-decode (mencegah "decompression bomb")
+const MaxPixels = 40_000_000
+
+var ErrUnsupported = errors.New("format gambar tidak didukung (hanya JPG, PNG atau GIF)")
+
+// Decode: decode gambar JPEG/PNG/GIF setelah cek ukurannya dari header
+func Decode(data []byte) (image.Image, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > MaxPixels {
+		return nil, fmt.Errorf("imaging: ukuran gambar %dx%d tidak didukung", cfg.Width, cfg.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+	return img, nil
+}
+
+// Flatten: taruh gambar di atas latar warna bg (untuk JPEG yang tidak punya alpha)
+func Flatten(img image.Image, bg color.Color) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Over)
+	return dst
+}
+
+// Fit: perkecil (rata-rata per kotak piksel) supaya sisi terpanjang <= max; gambar kecil tidak diperbesar
+func Fit(img image.Image, max int) *image.RGBA {
+	b := img.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+
+	src, ok := img.(*image.RGBA)
+	if !ok || b.Min != (image.Point{}) {
+		src = image.NewRGBA(image.Rect(0, 0, sw, sh))
+		draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+	}
+
+	if sw <= max && sh <= max {
+		return src
+	}
+
+	dw, dh := max, sh*max/sw
+	if sh > sw {
+		dw, dh = sw*max/sh, max
+	}
+	if dw < 1 {
+		dw = 1
+	}
+	if dh < 1 {
+		dh = 1
+	}
+
+	// RGBA sudah premultiplied, jadi rata-rata biasa juga benar untuk piksel transparan
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*sh/dh, (y+1)*sh/dh
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*sw/dw, (x+1)*sw/dw
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, bl, a, n int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += int(p[0])
+					g += int(p[1])
+					bl += int(p[2])
+					a += int(p[3])
+					n++
+				}
+			}
+
+			i := y*dst.Stride + x*4
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(bl / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// Opaque: true kalau tidak ada piksel transparan
+func Opaque(img *image.RGBA) bool {
+	for i := 3; i < len(img.Pix); i += 4 {
+		if img.Pix[i] != 0xff {
+			return false
+		}
+	}
+	return true
+}
+
+// EncodeJPEG: encode JPEG (bagian transparan jadi putih)
+func EncodeJPEG(img *image.RGBA, quality int) ([]byte, error) {
+	if !Opaque(img) {
+		img = Flatten(img, color.White)
+	}
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// Encode: JPEG kalau gambar tidak transparan, PNG kalau ada transparansi. Mengembalikan ekstensi file.
+func Encode(img *image.RGBA, quality int) ([]byte, string, error) {
+	if Opaque(img) {
+		data, err := EncodeJPEG(img, quality)
+		return data, ".jpg", err
+	}
+
+	var out bytes.Buffer
+	enc := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := enc.Encode(&out, img); err != nil {
+		return nil, "", err
+	}
+	return out.Bytes(), ".png", nil
+}
@@ -0,0 +1,228 @@
+package mailer
+
+import (
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+)
+
+// FakeSMTPServer: server SMTP palsu untuk development & test.
+// Semua email diterima tanpa AUTH/TLS dan disimpan di memori; pakai bersama SMTPMailer (Host/Port = Addr).
+type FakeSMTPServer struct {
+	// OnMessage: dipanggil setiap email diterima (opsional, contoh: cetak ke log)
+	OnMessage func(ReceivedMessage)
+
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu       sync.Mutex
+	messages []ReceivedMessage
+}
+
+// ReceivedMessage: email mentah yang diterima FakeSMTPServer
+type ReceivedMessage struct {
+	From string
+	To   []string
+	Data string // header + body persis seperti dikirim
+}
+
+// Text: body teks email yang sudah di-decode (quoted-printable → teks biasa)
+func (m ReceivedMessage) Text() string {
+	text, ok := m.part("text/plain")
+	if !ok {
+		return m.Data
+	}
+	return text
+}
+
+// HTML: versi HTML email, kosong kalau email hanya teks
+func (m ReceivedMessage) HTML() string {
+	html, _ := m.part("text/html")
+	return html
+}
+
+// part: isi bagian dengan content type tertentu (email biasa / multipart/alternative)
+func (m ReceivedMessage) part(contentType string) (string, bool) {
+	parsed, err := mail.ReadMessage(strings.NewReader(m.Data))
+	if err != nil {
+		return "", false
+	}
+
+	mediaType, params, _ := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		if mediaType != "" && mediaType != contentType {
+			return "", false
+		}
+		return decodePart(parsed.Body, parsed.Header.Get("Content-Transfer-Encoding"))
+	}
+
+	mr := multipart.NewReader(parsed.Body, params["boundary"])
+	for {
+		p, err := mr.NextRawPart()
+		if err != nil {
+			return "", false
+		}
+		if partType, _, _ := mime.ParseMediaType(p.Header.Get("Content-Type")); partType == contentType {
+			return decodePart(p, p.Header.Get("Content-Transfer-Encoding"))
+		}
+	}
+}
+
+func decodePart(body io.Reader, encoding string) (string, bool) {
+	if strings.EqualFold(encoding, "quoted-printable") {
+		body = quotedprintable.NewReader(body)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
+// StartFakeSMTPServer: dengarkan di addr (contoh "127.0.0.1:0" untuk port acak)
+func StartFakeSMTPServer(addr string) (*FakeSMTPServer, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &FakeSMTPServer{listener: ln}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Addr: alamat host:port server
+func (s *FakeSMTPServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// HostPort: host & port terpisah, untuk NewSMTPMailer
+func (s *FakeSMTPServer) HostPort() (string, string) {
+	host, port, _ := net.SplitHostPort(s.Addr())
+	return host, port
+}
+
+// Messages: salinan semua email yang sudah diterima
+func (s *FakeSMTPServer) Messages() []ReceivedMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ReceivedMessage(nil), s.messages...)
+}
+
+func (s *FakeSMTPServer) Close() error {
+	err := s.listener.Close()
+	s.wg.Wait()
+	return err
+}
+
+func (s *FakeSMTPServer) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return // listener ditutup
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+func (s *FakeSMTPServer) handle(conn net.Conn) {
+	tp := textproto.NewConn(conn)
+	defer tp.Close()
+
+	reply := func(format string, args ...interface{}) bool {
+		return tp.PrintfLine(format, args...) == nil
+	}
+
+	if !reply("220 localhost fake SMTP siap") {
+		return
+	}
+
+	var current ReceivedMessage
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			reply("250-localhost")
+			reply("250 8BITMIME")
+		case "HELO":
+			reply("250 localhost")
+		case "MAIL":
+			current = ReceivedMessage{From: smtpPath(arg)}
+			reply("250 OK")
+		case "RCPT":
+			current.To = append(current.To, smtpPath(arg))
+			reply("250 OK")
+		case "DATA":
+			if len(current.To) == 0 {
+				reply("503 RCPT dulu")
+				continue
+			}
+			reply("354 Akhiri dengan <CRLF>.<CRLF>")
+			data, err := io.ReadAll(tp.DotReader())
+			if err != nil {
+				return
+			}
+			current.Data = string(data)
+			s.store(current)
+			current = ReceivedMessage{}
+			reply("250 OK")
+		case "RSET":
+			current = ReceivedMessage{}
+			reply("250 OK")
+		case "NOOP":
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 perintah tidak didukung")
+		}
+	}
+}
+
+func (s *FakeSMTPServer) store(msg ReceivedMessage) {
+	s.mu.Lock()
+	s.messages = append(s.messages, msg)
+	onMessage := s.OnMessage
+	s.mu.Unlock()
+
+	if onMessage != nil {
+		onMessage(msg)
+	} else {
+		log.Printf("fake SMTP: email dari %s ke %s diterima", msg.From, strings.Join(msg.To, ", "))
+	}
+}
+
+// smtpPath: "FROM:<a@b.c> SIZE=10" → "a@b.c"
+func smtpPath(arg string) string {
+	_, path, ok := strings.Cut(arg, ":")
+	if !ok {
+		return ""
+	}
+	path = strings.TrimSpace(path)
+	if end := strings.Index(path, ">"); strings.HasPrefix(path, "<") && end > 0 {
+		return path[1:end]
+	}
+	if sp := strings.IndexByte(path, ' '); sp >= 0 {
+		path = path[:sp]
+	}
+	return path
+}
@@ -0,0 +1,35 @@
+// Package mailer: antarmuka pengiriman email + adapter SMTP & server SMTP palsu untuk lokal/test.
+package mailer
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+)
+
+var ErrNoRecipient = errors.New("alamat email tujuan kosong")
+
+// Message: email UTF-8. Body wajib (teks biasa); HTML opsional, kalau diisi dikirim sebagai multipart/alternative.
+type Message struct {
+	To      []string
+	Subject string
+	Body    string
+	HTML    string
+}
+
+// Mailer: kontrak yang harus dipenuhi setiap pengirim email
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogMailer: hanya mencetak email ke log. Dipakai kalau SMTP belum dikonfigurasi (development).
+type LogMailer struct{}
+
+func (LogMailer) Send(ctx context.Context, msg Message) error {
+	if len(msg.To) == 0 {
+		return ErrNoRecipient
+	}
+	log.Printf("mailer (log): ke %s, subjek %q\n%s", strings.Join(msg.To, ", "), msg.Subject, msg.Body)
+	return nil
+}
@@ -0,0 +1,373 @@
+package models
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alirogz/goshop/app/consts"
+	"github.com/alirogz/goshop/app/statement"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrImportBatchNotPreview   = errors.New("batch import sudah diproses")
+	ErrImportBatchNotCommitted = errors.New("batch import belum disimpan atau sudah dibatalkan")
+	ErrImportBatchMatched      = errors.New("sebagian mutasi di batch ini sudah dipasangkan dengan order")
+)
+
+// BankImportBatch: 1 kali upload file mutasi. Isi file disimpan dulu sebagai preview
+// (bank_import_rows), baru masuk ke bank_transactions setelah admin menekan "Simpan".
+type BankImportBatch struct {
+	ID            string `gorm:"size:36;not null;uniqueIndex;primary_key"`
+	FileName      string `gorm:"size:255"`
+	FileHash      string `gorm:"size:64;index"` // sha256 isi file
+	Format        string `gorm:"size:20"`
+	Bank          string `gorm:"size:50"`
+	Account       string `gorm:"size:100"`
+	Status        string `gorm:"size:20;not null;index"` // lihat consts.BankImportStatus*
+	UploadedBy    string `gorm:"size:36"`
+	TotalRows     int
+	NewRows       int
+	DuplicateRows int
+	SkippedRows   int // mutasi debit, tidak diimport
+	ErrorRows     int
+	ImportedRows  int
+	Rows          []BankImportRow `gorm:"foreignKey:BatchID"`
+	CommittedBy   string          `gorm:"size:36"`
+	CommittedAt   sql.NullTime
+	RolledBackBy  string `gorm:"size:36"`
+	RolledBackAt  sql.NullTime
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// BankImportRow: 1 baris file di batch (mutasi masuk atau baris yang gagal dibaca)
+type BankImportRow struct {
+	ID          uint   `gorm:"primaryKey;autoIncrement"`
+	BatchID     string `gorm:"size:36;not null;index"`
+	Line        int
+	Status      string          `gorm:"size:20;not null"` // lihat consts.BankImportRow*
+	Bank        string          `gorm:"size:50"`
+	Account     string          `gorm:"size:100"`
+	Amount      decimal.Decimal `gorm:"type:decimal(20,2)"`
+	Note        string          `gorm:"size:255"`
+	RefCode     string          `gorm:"size:100"`
+	TrxTime     sql.NullTime
+	Fingerprint string `gorm:"size:64;index"`
+	Error       string `gorm:"size:255"`
+	Raw         string `gorm:"size:255"`
+}
+
+func (b *BankImportBatch) BeforeCreate(db *gorm.DB) error {
+	if b.ID == "" {
+		b.ID = uuid.New().String()
+	}
+
+	return nil
+}
+
+// BankTransactionFingerprint: sidik jari mutasi (bank, rekening, waktu, nominal, keterangan, ref).
+// occurrence membedakan mutasi yang benar-benar identik di 1 file (contoh: 2 transfer sama di hari yang sama
+// tanpa nomor ref); upload ulang file yang sama menghasilkan urutan & fingerprint yang sama.
+func BankTransactionFingerprint(bank, account string, trxTime time.Time, amount decimal.Decimal, note, ref string, occurrence int) string {
+	parts := []string{
+		strings.ToUpper(strings.TrimSpace(bank)),
+		strings.TrimSpace(account),
+		trxTime.Format("2006-01-02T15:04:05"),
+		amount.StringFixed(2),
+		strings.ToUpper(strings.Join(strings.Fields(note), " ")),
+		strings.ToUpper(strings.TrimSpace(ref)),
+	}
+	key := strings.Join(parts, "|")
+	if occurrence > 1 {
+		key += "#" + strconv.Itoa(occurrence)
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// FileHash: sha256 isi file upload
+func FileHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// PreviewBankImport: simpan hasil parsing sebagai batch preview, tandai baris yang sudah pernah diimport
+func PreviewBankImport(db *gorm.DB, fileName string, data []byte, st *statement.Statement, uploadedBy string) (*BankImportBatch, error) {
+	batch := &BankImportBatch{
+		FileName:   fileName,
+		FileHash:   FileHash(data),
+		Format:     st.Format,
+		Bank:       st.Bank,
+		Account:    st.Account,
+		Status:     consts.BankImportStatusPreview,
+		UploadedBy: uploadedBy,
+		TotalRows:  len(st.Transactions) + len(st.Errors),
+		ErrorRows:  len(st.Errors),
+	}
+
+	occurrences := map[string]int{}
+	for _, trx := range st.Transactions {
+		// hanya uang masuk yang perlu dicocokkan dengan order
+		if !trx.IsCredit() {
+			batch.SkippedRows++
+			continue
+		}
+
+		base := BankTransactionFingerprint(trx.Bank, trx.Account, trx.TrxTime, trx.Amount, trx.Note, trx.RefCode, 0)
+		occurrences[base]++
+
+		batch.Rows = append(batch.Rows, BankImportRow{
+			Line:        trx.Line,
+			Status:      consts.BankImportRowNew,
+			Bank:        trx.Bank,
+			Account:     trx.Account,
+			Amount:      trx.Amount,
+			Note:        truncateRunes(trx.Note, 255),
+			RefCode:     truncateRunes(trx.RefCode, 100),
+			TrxTime:     sql.NullTime{Time: trx.TrxTime, Valid: true},
+			Fingerprint: BankTransactionFingerprint(trx.Bank, trx.Account, trx.TrxTime, trx.Amount, trx.Note, trx.RefCode, occurrences[base]),
+		})
+	}
+	for _, rowErr := range st.Errors {
+		batch.Rows = append(batch.Rows, BankImportRow{
+			Line:   rowErr.Line,
+			Status: consts.BankImportRowError,
+			Error:  truncateRunes(rowErr.Err, 255),
+			Raw:    truncateRunes(rowErr.Raw, 255),
+		})
+	}
+
+	// tandai baris yang fingerprint-nya sudah ada di bank_transactions
+	existing, err := existingFingerprints(db, batch.Rows)
+	if err != nil {
+		return nil, err
+	}
+	for i := range batch.Rows {
+		row := &batch.Rows[i]
+		if row.Status != consts.BankImportRowNew {
+			continue
+		}
+		if existing[row.Fingerprint] {
+			row.Status = consts.BankImportRowDuplicate
+			batch.DuplicateRows++
+		} else {
+			batch.NewRows++
+		}
+	}
+
+	if err := db.Create(batch).Error; err != nil {
+		return nil, err
+	}
+
+	return batch, nil
+}
+
+// FindBankImportBatch: batch beserta baris-barisnya
+func FindBankImportBatch(db *gorm.DB, id string) (*BankImportBatch, error) {
+	var batch BankImportBatch
+	err := db.Preload("Rows", func(tx *gorm.DB) *gorm.DB { return tx.Order("line ASC") }).
+		Where("id = ?", id).
+		First(&batch).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &batch, nil
+}
+
+// RecentBankImportBatches: riwayat import terbaru
+func RecentBankImportBatches(db *gorm.DB, limit int) ([]BankImportBatch, error) {
+	var batches []BankImportBatch
+	err := db.Order("created_at DESC").Limit(limit).Find(&batches).Error
+	return batches, err
+}
+
+// PreviousImport: batch lain dengan file yang sama persis yang sudah disimpan (untuk peringatan di preview)
+func (b *BankImportBatch) PreviousImport(db *gorm.DB) *BankImportBatch {
+	var previous BankImportBatch
+	err := db.Where("file_hash = ? AND id <> ? AND status = ?", b.FileHash, b.ID, consts.BankImportStatusCommitted).
+		Order("committed_at DESC").
+		First(&previous).Error
+	if err != nil {
+		return nil
+	}
+
+	return &previous
+}
+
+// Commit: simpan baris preview ke bank_transactions. Baris yang fingerprint-nya sudah ada dilewati
+// (unique index), jadi commit ulang / file yang overlap tidak membuat mutasi ganda.
+func (b *BankImportBatch) Commit(db *gorm.DB, actorID string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var locked BankImportBatch
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", b.ID).First(&locked).Error; err != nil {
+			return err
+		}
+		if locked.Status != consts.BankImportStatusPreview {
+			return ErrImportBatchNotPreview
+		}
+
+		var rows []BankImportRow
+		if err := tx.Where("batch_id = ? AND status IN ?", b.ID, []string{consts.BankImportRowNew, consts.BankImportRowDuplicate}).
+			Order("line ASC").Find(&rows).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		imported, duplicates := 0, 0
+		for _, row := range rows {
+			bankTx := BankTransaction{
+				Bank:        row.Bank,
+				Account:     row.Account,
+				Amount:      row.Amount,
+				Note:        row.Note,
+				RefCode:     row.RefCode,
+				TrxTime:     row.TrxTime.Time,
+				BatchID:     b.ID,
+				Fingerprint: sql.NullString{String: row.Fingerprint, Valid: true},
+				// kolom NOT NULL; nilai sebenarnya diisi ulang saat dipasangkan
+				MatchedAt: now,
+			}
+
+			res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&bankTx)
+			if res.Error != nil {
+				return res.Error
+			}
+
+			status := consts.BankImportRowImported
+			if res.RowsAffected == 0 {
+				status = consts.BankImportRowDuplicate
+				duplicates++
+			} else {
+				imported++
+			}
+			if err := tx.Model(&BankImportRow{}).Where("id = ?", row.ID).Update("status", status).Error; err != nil {
+				return err
+			}
+		}
+
+		updates := map[string]interface{}{
+			"status":         consts.BankImportStatusCommitted,
+			"imported_rows":  imported,
+			"duplicate_rows": duplicates,
+			"committed_by":   actorID,
+			"committed_at":   sql.NullTime{Time: now, Valid: true},
+			"updated_at":     now,
+		}
+		if err := tx.Model(&BankImportBatch{}).Where("id = ?", b.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		b.Status = consts.BankImportStatusCommitted
+		b.ImportedRows = imported
+		b.DuplicateRows = duplicates
+		return nil
+	})
+}
+
+// Rollback: hapus semua mutasi dari batch ini. Ditolak kalau ada yang sudah dipasangkan dengan order.
+func (b *BankImportBatch) Rollback(db *gorm.DB, actorID string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var locked BankImportBatch
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", b.ID).First(&locked).Error; err != nil {
+			return err
+		}
+		if locked.Status != consts.BankImportStatusCommitted {
+			return ErrImportBatchNotCommitted
+		}
+
+		// hapus yang belum dipasangkan dulu, lalu pastikan tidak ada sisa
+		// (auto-match yang jalan bersamaan bisa memasangkan mutasi di antara cek & hapus)
+		if err := tx.Where("batch_id = ? AND matched = ?", b.ID, false).Delete(&BankTransaction{}).Error; err != nil {
+			return err
+		}
+		var remaining int64
+		if err := tx.Model(&BankTransaction{}).Where("batch_id = ?", b.ID).Count(&remaining).Error; err != nil {
+			return err
+		}
+		if remaining > 0 {
+			return ErrImportBatchMatched
+		}
+
+		if err := tx.Model(&BankImportRow{}).
+			Where("batch_id = ? AND status = ?", b.ID, consts.BankImportRowImported).
+			Update("status", consts.BankImportRowRolledBack).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		updates := map[string]interface{}{
+			"status":         consts.BankImportStatusRolledBack,
+			"rolled_back_by": actorID,
+			"rolled_back_at": sql.NullTime{Time: now, Valid: true},
+			"updated_at":     now,
+		}
+		if err := tx.Model(&BankImportBatch{}).Where("id = ?", b.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		b.Status = consts.BankImportStatusRolledBack
+		return nil
+	})
+}
+
+// MatchedCount: jumlah mutasi batch yang sudah dipasangkan (rollback hanya boleh kalau 0)
+func (b *BankImportBatch) MatchedCount(db *gorm.DB) int64 {
+	var n int64
+	db.Model(&BankTransaction{}).Where("batch_id = ? AND matched = ?", b.ID, true).Count(&n)
+	return n
+}
+
+// StatusText: label status batch untuk halaman admin
+func (b BankImportBatch) StatusText() string {
+	switch b.Status {
+	case consts.BankImportStatusPreview:
+		return "Preview"
+	case consts.BankImportStatusCommitted:
+		return "Tersimpan"
+	case consts.BankImportStatusRolledBack:
+		return "Dibatalkan"
+	}
+	return b.Status
+}
+
+func existingFingerprints(db *gorm.DB, rows []BankImportRow) (map[string]bool, error) {
+	existing := map[string]bool{}
+
+	var fingerprints []string
+	for _, row := range rows {
+		if row.Fingerprint != "" {
+			fingerprints = append(fingerprints, row.Fingerprint)
+		}
+	}
+
+	// dicek per 500 supaya query IN tidak terlalu panjang
+	for start := 0; start < len(fingerprints); start += 500 {
+		end := start + 500
+		if end > len(fingerprints) {
+			end = len(fingerprints)
+		}
+
+		var found []string
+		if err := db.Model(&BankTransaction{}).
+			Where("fingerprint IN ?", fingerprints[start:end]).
+			Pluck("fingerprint", &found).Error; err != nil {
+			return nil, err
+		}
+		for _, f := range found {
+			existing[f] = true
+		}
+	}
+
+	return existing, nil
+}
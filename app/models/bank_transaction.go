@@ -1,6 +1,7 @@
 package models
 
 import (
+	"database/sql"
 	"time"
 
 	"github.com/shopspring/decimal"
@@ -16,6 +17,11 @@ type BankTransaction struct {
 	RefCode string          `gorm:"size:100"`           // Kode referensi bank (opsional)
 	TrxTime time.Time       // Waktu transaksi di bank
 
+	// Asal import & sidik jari (unik) supaya upload ulang file yang sama tidak membuat mutasi ganda.
+	// NULL untuk mutasi lama sebelum ada batch import.
+	BatchID     string         `gorm:"size:36;index"`
+	Fingerprint sql.NullString `gorm:"size:64;uniqueIndex"`
+
 	// Untuk penandaan sudah dipasangkan dengan order mana
 	Matched      bool   `gorm:"default:false"`
 	MatchedOrder string `gorm:"type:varchar(36);index"` // orders.id
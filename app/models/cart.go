@@ -1,6 +1,7 @@
 package models
 
 import (
+	"github.com/alirogz/goshop/app/money"
 	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
@@ -14,7 +15,15 @@ type Cart struct {
 	DiscountAmount  decimal.Decimal `gorm:"type:decimal(16,2)"`
 	DiscountPercent decimal.Decimal `gorm:"type:decimal(10,2)"`
 	GrandTotal      decimal.Decimal `gorm:"type:decimal(16,2)"`
+	CouponCode      string          `gorm:"size:50"`
 	TotalWeight     int             `gorm:"-"`
+
+	// harga sudah termasuk pajak (ikut pengaturan toko, tidak disimpan)
+	PricesIncludeTax bool `gorm:"-"`
+
+	// hasil perhitungan promo terakhir (tidak disimpan)
+	Promotions  CartPromotionResult `gorm:"-"`
+	CouponError string              `gorm:"-"`
 }
 
 func (c *Cart) GetCart(db *gorm.DB, cartID string) (*Cart, error) {
@@ -23,6 +32,7 @@ func (c *Cart) GetCart(db *gorm.DB, cartID string) (*Cart, error) {
 
 	err = db.Debug().Preload("CartItems").
 		Preload("CartItems.Product").
+		Preload("CartItems.Variant").
 		Model(Cart{}).Where("id = ?", cartID).First(&cart).Error
 	if err != nil {
 		return nil, err
@@ -32,17 +42,22 @@ func (c *Cart) GetCart(db *gorm.DB, cartID string) (*Cart, error) {
 }
 
 func (c *Cart) CreateCart(db *gorm.DB, cartID string) (*Cart, error) {
+	tax, err := LoadTaxSettings(db)
+	if err != nil {
+		return nil, err
+	}
+
 	cart := &Cart{
 		ID:              cartID,
 		BaseTotalPrice:  decimal.NewFromInt(0),
 		TaxAmount:       decimal.NewFromInt(0),
-		TaxPercent:      decimal.NewFromInt(11),
+		TaxPercent:      tax.Rate,
 		DiscountAmount:  decimal.NewFromInt(0),
 		DiscountPercent: decimal.NewFromInt(0),
 		GrandTotal:      decimal.NewFromInt(0),
 	}
 
-	err := db.Debug().Create(&cart).Error
+	err = db.Debug().Create(&cart).Error
 	if err != nil {
 		return nil, err
 	}
@@ -50,41 +65,117 @@ func (c *Cart) CreateCart(db *gorm.DB, cartID string) (*Cart, error) {
 	return cart, nil
 }
 
+// CalculateCart: hitung ulang total cart dari item + promo yang berlaku, lalu simpan.
+// Item dimuat ulang dari DB jadi c tidak harus berisi CartItems.
 func (c *Cart) CalculateCart(db *gorm.DB, cartID string) (*Cart, error) {
-	cartBaseTotalPrice := 0.0
-	cartTaxAmount := 0.0
-	cartDiscountAmount := 0.0
-	cartGrandTotal := 0.0
+	if cartID == "" {
+		cartID = c.ID
+	}
 
-	for _, item := range c.CartItems {
-		itemBaseTotal, _ := item.BaseTotal.Float64()
-		itemTaxAmount, _ := item.TaxAmount.Float64()
-		itemSubTotalTaxAmount := itemTaxAmount * float64(item.Qty)
-		itemDiscountAmount, _ := item.DiscountAmount.Float64()
-		itemSubTotalDiscountAmount := itemDiscountAmount * float64(item.Qty)
-		itemSubTotal, _ := item.SubTotal.Float64()
+	var cart Cart
+	if err := db.Debug().Model(Cart{}).Where("id = ?", cartID).First(&cart).Error; err != nil {
+		return nil, err
+	}
 
-		cartBaseTotalPrice += itemBaseTotal
-		cartTaxAmount += itemSubTotalTaxAmount
-		cartDiscountAmount += itemSubTotalDiscountAmount
-		cartGrandTotal += itemSubTotal
+	items, err := cart.GetItems(db, cartID)
+	if err != nil {
+		return nil, err
 	}
 
-	var updateCart, cart Cart
+	// promo (aturan otomatis + kupon) dihitung di level cart lalu dibagi ke baris item sebelum pajak dihitung,
+	// jadi pajak, baris item & grand total memakai dasar yang sama.
+	// batas pemakaian per customer dicek ulang saat checkout.
+	promotions, couponErr := EvaluateCartPromotions(db, PromotionLinesFromCartItems(items), cart.CouponCode, "")
 
-	updateCart.BaseTotalPrice = decimal.NewFromFloat(cartBaseTotalPrice)
-	updateCart.TaxAmount = decimal.NewFromFloat(cartTaxAmount)
-	updateCart.DiscountAmount = decimal.NewFromFloat(cartDiscountAmount)
-	updateCart.GrandTotal = decimal.NewFromFloat(cartGrandTotal)
+	// total cart = jumlah baris item (tiap baris sudah rupiah penuh)
+	tax, err := LoadTaxSettings(db)
+	if err != nil {
+		return nil, err
+	}
+	totals := money.Totals{}
+	for i := range items {
+		if items[i].refreshTotals(tax, promotions.LineDiscount(i)) {
+			err = db.Debug().Model(&CartItem{}).Where("id = ?", items[i].ID).
+				Select("base_total", "tax_amount", "tax_percent", "discount_amount", "discount_percent", "sub_total").
+				Updates(&items[i]).Error
+			if err != nil {
+				return nil, err
+			}
+		}
+		totals = totals.Add(items[i].LineTotals())
+	}
 
-	err := db.Debug().First(&cart, "id = ?", c.ID).Updates(updateCart).Error
+	cart.BaseTotalPrice = totals.Base
+	cart.TaxAmount = totals.Tax
+	cart.TaxPercent = tax.Rate
+	cart.PricesIncludeTax = tax.PricesIncludeTax
+	cart.DiscountAmount = totals.Discount
+	cart.DiscountPercent = money.Ratio(totals.Discount, totals.Base)
+	cart.GrandTotal = totals.Total
+
+	err = db.Debug().Model(&Cart{}).Where("id = ?", cart.ID).
+		Select("base_total_price", "tax_amount", "tax_percent", "discount_amount", "discount_percent", "grand_total").
+		Updates(&cart).Error
 	if err != nil {
 		return nil, err
 	}
 
+	cart.CartItems = items
+	cart.Promotions = promotions
+	if couponErr != nil {
+		cart.CouponError = couponErr.Error()
+	}
+
 	return &cart, nil
 }
 
+// PromotionLinesFromCartItems: ubah item cart jadi input mesin promo
+// (Product.Categories harus sudah di-preload)
+func PromotionLinesFromCartItems(items []CartItem) []PromotionLine {
+	lines := make([]PromotionLine, 0, len(items))
+	for _, item := range items {
+		var categoryIDs []string
+		for _, category := range item.Product.Categories {
+			categoryIDs = append(categoryIDs, category.ID)
+		}
+
+		lines = append(lines, PromotionLine{
+			ProductID:   item.ProductID,
+			CategoryIDs: categoryIDs,
+			Qty:         item.Qty,
+			Total:       item.BaseTotal,
+		})
+	}
+	return lines
+}
+
+// ApplyCoupon: validasi kupon terhadap isi cart lalu simpan kodenya
+func (c *Cart) ApplyCoupon(db *gorm.DB, code, userID string) (*Promotion, error) {
+	items, err := c.GetItems(db, c.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	promo, err := ValidateCoupon(db, code, userID, PromotionLinesFromCartItems(items))
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Debug().Model(&Cart{}).Where("id = ?", c.ID).Update("coupon_code", promo.Code).Error
+	if err != nil {
+		return nil, err
+	}
+
+	c.CouponCode = promo.Code
+	return promo, nil
+}
+
+// RemoveCoupon: lepas kupon dari cart
+func (c *Cart) RemoveCoupon(db *gorm.DB) error {
+	c.CouponCode = ""
+	return db.Debug().Model(&Cart{}).Where("id = ?", c.ID).Update("coupon_code", "").Error
+}
+
 func (c *Cart) AddItem(db *gorm.DB, item CartItem) (*CartItem, error) {
 	var existItem, updateItem CartItem
 	var product Product
@@ -94,26 +185,40 @@ func (c *Cart) AddItem(db *gorm.DB, item CartItem) (*CartItem, error) {
 		return nil, err
 	}
 
-	basePrice, _ := product.Price.Float64()
-	taxAmount := GetTaxAmount(basePrice)
-	discountAmount := 0.0
+	// produk bervarian: harga, ukuran & warna ikut varian yang dipilih
+	variant, err := ResolveProductVariant(db, product.ID, item.VariantID, item.Size, item.Color)
+	if err != nil {
+		return nil, err
+	}
 
+	price := product.Price
+	item.VariantID = ""
+	if variant != nil {
+		price = variant.PriceFor(product)
+		item.VariantID = variant.ID
+		item.Size = variant.Size
+		item.Color = variant.Color
+	}
+	tax, err := LoadTaxSettings(db)
+	if err != nil {
+		return nil, err
+	}
+
+	// 1 baris cart = 1 varian (atau 1 kombinasi ukuran/warna untuk produk tanpa varian)
 	err = db.Debug().Model(CartItem{}).
 		Where("cart_id = ?", c.ID).
-		Where("product_id = ?", product.ID).
+		Where("product_id = ? AND variant_id = ?", product.ID, item.VariantID).
+		Where("size = ? AND color = ?", item.Size, item.Color).
 		First(&existItem).Error
 
-	if err != nil {
-		subTotal := float64(item.Qty) * (basePrice + taxAmount - discountAmount)
+	// qty total di cart tidak boleh melebihi stok
+	if stockErr := CheckStock(db, product.ID, item.VariantID, existItem.Qty+item.Qty); stockErr != nil {
+		return nil, stockErr
+	}
 
+	if err != nil {
 		item.CartID = c.ID
-		item.BasePrice = product.Price
-		item.BaseTotal = decimal.NewFromFloat(basePrice * float64(item.Qty))
-		item.TaxPercent = decimal.NewFromFloat(GetTaxPercent())
-		item.TaxAmount = decimal.NewFromFloat(taxAmount)
-		item.DiscountPercent = decimal.NewFromFloat(0)
-		item.DiscountAmount = decimal.NewFromFloat(discountAmount)
-		item.SubTotal = decimal.NewFromFloat(subTotal)
+		item.setPrice(tax.Line(product, price, item.Qty))
 
 		err = db.Debug().Create(&item).Error
 		if err != nil {
@@ -123,11 +228,7 @@ func (c *Cart) AddItem(db *gorm.DB, item CartItem) (*CartItem, error) {
 		return &item, nil
 	}
 
-	updateItem.Qty = existItem.Qty + item.Qty
-	updateItem.BaseTotal = decimal.NewFromFloat(basePrice * float64(updateItem.Qty))
-
-	subTotal := float64(updateItem.Qty) * (basePrice + taxAmount - discountAmount)
-	updateItem.SubTotal = decimal.NewFromFloat(subTotal)
+	updateItem.setPrice(tax.Line(product, price, existItem.Qty+item.Qty))
 
 	err = db.Debug().First(&existItem, "id = ?", existItem.ID).Updates(updateItem).Error
 	if err != nil {
@@ -142,7 +243,9 @@ func (c *Cart) GetItems(db *gorm.DB, cartID string) ([]CartItem, error) {
 
 	err := db.Debug().
 		Preload("Product").
-		Preload("Product.ProductImages"). // <--- tambah baris ini
+		Preload("Product.ProductImages", OrderProductImages). // <--- tambah baris ini
+		Preload("Product.Categories").
+		Preload("Variant").
 		Model(&CartItem{}).
 		Where("cart_id = ?", cartID).
 		Order("created_at desc").
@@ -171,15 +274,24 @@ func (c *Cart) UpdateItemQty(db *gorm.DB, itemID string, qty int) (*CartItem, er
 		return nil, err
 	}
 
-	basePrice, _ := product.Price.Float64()
-	taxAmount := GetTaxAmount(basePrice)
-	discountAmount := 0.0
+	if err := CheckStock(db, product.ID, existItem.VariantID, qty); err != nil {
+		return nil, err
+	}
 
-	updateItem.Qty = qty
-	updateItem.BaseTotal = decimal.NewFromFloat(basePrice * float64(updateItem.Qty))
+	price := product.Price
+	if existItem.VariantID != "" {
+		var variant ProductVariant
+		if err := db.Model(&ProductVariant{}).Where("id = ?", existItem.VariantID).First(&variant).Error; err != nil {
+			return nil, ErrVariantNotFound
+		}
+		price = variant.PriceFor(product)
+	}
 
-	subTotal := float64(updateItem.Qty) * (basePrice + taxAmount - discountAmount)
-	updateItem.SubTotal = decimal.NewFromFloat(subTotal)
+	tax, err := LoadTaxSettings(db)
+	if err != nil {
+		return nil, err
+	}
+	updateItem.setPrice(tax.Line(product, price, qty))
 
 	err = db.Debug().First(&existItem, "id = ?", existItem.ID).Updates(updateItem).Error
 	if err != nil {
@@ -3,6 +3,7 @@ package models
 import (
 	"time"
 
+	"github.com/alirogz/goshop/app/money"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 
@@ -15,7 +16,10 @@ type CartItem struct {
 	CartID          string `gorm:"size:36;index"`
 	Product         Product
 	ProductID       string `gorm:"size:36;index"`
+	Variant         ProductVariant
+	VariantID       string `gorm:"size:36;not null;default:'';index"`
 	Size            string `gorm:"size:20"`
+	Color           string `gorm:"size:50"`
 	Qty             int
 	BasePrice       decimal.Decimal `gorm:"type:decimal(16,2)"`
 	BaseTotal       decimal.Decimal `gorm:"type:decimal(16,2)"`
@@ -41,6 +45,7 @@ func (c *CartItem) GetByID(db *gorm.DB, id string) (*CartItem, error) {
 
 	err := db.Debug().
 		Preload("Product").
+		Preload("Variant").
 		Model(&CartItem{}).
 		Where("id = ?", id).
 		First(&item).Error
@@ -67,3 +72,56 @@ func (c *CartItem) RemoveByID(db *gorm.DB, id string) error {
 		Where("id = ?", id).
 		Delete(&CartItem{}).Error
 }
+
+// VariantLabel: "M / Hitam" (varian atau ukuran/warna lama)
+func (c CartItem) VariantLabel() string {
+	return ProductVariant{Size: c.Size, Color: c.Color}.Label()
+}
+
+// UnitWeight: berat 1 pcs, pakai berat varian kalau ada (Product & Variant harus di-preload)
+func (c CartItem) UnitWeight() decimal.Decimal {
+	if c.VariantID != "" && c.Variant.ID != "" {
+		return c.Variant.WeightFor(c.Product)
+	}
+	return c.Product.Weight
+}
+
+// setPrice: isi harga & total baris (pajak/diskon untuk seluruh baris, rupiah penuh)
+func (c *CartItem) setPrice(line money.Line) {
+	totals := line.Calculate()
+
+	c.Qty = line.Qty
+	c.BasePrice = line.UnitPrice
+	c.BaseTotal = totals.Base
+	c.TaxPercent = line.TaxPercent
+	c.TaxAmount = totals.Tax
+	c.DiscountPercent = money.Ratio(totals.Discount, totals.Base)
+	c.DiscountAmount = totals.Discount
+	c.SubTotal = totals.Total
+}
+
+// refreshTotals: hitung ulang total baris dengan aturan pajak yang berlaku sekarang & potongan promo
+// untuk baris ini (Product harus di-preload), true kalau ada yang berubah
+func (c *CartItem) refreshTotals(tax TaxSettings, discount decimal.Decimal) bool {
+	before := *c
+	line := tax.Line(c.Product, c.BasePrice, c.Qty)
+	line.Discount = discount
+	c.setPrice(line)
+
+	return !before.TaxPercent.Equal(c.TaxPercent) ||
+		!before.BaseTotal.Equal(c.BaseTotal) ||
+		!before.TaxAmount.Equal(c.TaxAmount) ||
+		!before.DiscountAmount.Equal(c.DiscountAmount) ||
+		!before.DiscountPercent.Equal(c.DiscountPercent) ||
+		!before.SubTotal.Equal(c.SubTotal)
+}
+
+// LineTotals: total baris yang tersimpan
+func (c CartItem) LineTotals() money.LineTotals {
+	return money.LineTotals{
+		Base:     c.BaseTotal,
+		Tax:      c.TaxAmount,
+		Discount: c.DiscountAmount,
+		Total:    c.SubTotal,
+	}
+}
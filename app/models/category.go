@@ -1,7 +1,23 @@
 package models
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/gosimple/slug"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCategoryParentCycle = errors.New("induk kategori tidak boleh kategori itu sendiri atau turunannya")
+	ErrCategoryHasChildren = errors.New("kategori masih punya subkategori, pindahkan atau hapus dulu")
+	ErrCategoryInPromotion = errors.New("kategori masih dipakai promo")
+)
+
+// Category: kategori produk bertingkat (ParentID kosong = kategori utama).
+// Setiap kategori ada di 1 Section; subkategori selalu ikut section induknya.
 type Category struct {
 	ID        string `gorm:"size:36;not null;uniqueIndex;primary_key"`
 	ParentID  string `gorm:"size:36;"`
@@ -9,7 +25,254 @@ type Category struct {
 	SectionID string    `gorm:"size:36;index"`
 	Products  []Product `gorm:"many2many:product_categories;"`
 	Name      string    `gorm:"size:100;"`
-	Slug      string    `gorm:"size:100;"`
+	Slug      string    `gorm:"size:100;index"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
+
+// CategoryNode: kategori + subkategorinya, untuk menu / pilihan bertingkat
+type CategoryNode struct {
+	Category
+	Depth    int
+	Active   bool // kategori yang sedang dibuka atau induknya
+	Children []*CategoryNode
+}
+
+func (c *Category) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == "" {
+		c.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// ListCategories: semua kategori urut nama (jumlahnya kecil, tree disusun di memori)
+func ListCategories(db *gorm.DB) ([]Category, error) {
+	var categories []Category
+	err := db.Order("name asc").Find(&categories).Error
+	return categories, err
+}
+
+// FindCategoryBySlug: kategori untuk halaman /categories/{slug}
+func FindCategoryBySlug(db *gorm.DB, slug string) (*Category, error) {
+	var category Category
+	if err := db.Preload("Section").Where("slug = ?", slug).First(&category).Error; err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// BuildCategoryTree: susun kategori jadi tree. Kategori dengan induk yang tidak ada dianggap kategori utama.
+func BuildCategoryTree(categories []Category) []*CategoryNode {
+	nodes := make(map[string]*CategoryNode, len(categories))
+	for _, c := range categories {
+		nodes[c.ID] = &CategoryNode{Category: c}
+	}
+
+	var roots []*CategoryNode
+	for _, c := range categories {
+		node := nodes[c.ID]
+		if parent, ok := nodes[c.ParentID]; ok && c.ParentID != c.ID {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+
+	setCategoryDepth(roots, 0, map[string]bool{})
+	return roots
+}
+
+func setCategoryDepth(nodes []*CategoryNode, depth int, seen map[string]bool) {
+	for _, n := range nodes {
+		if seen[n.ID] {
+			continue
+		}
+		seen[n.ID] = true
+		n.Depth = depth
+		setCategoryDepth(n.Children, depth+1, seen)
+	}
+}
+
+// FlattenCategoryTree: tree jadi list urut (induk lalu anak-anaknya), Depth untuk indentasi
+func FlattenCategoryTree(nodes []*CategoryNode) []*CategoryNode {
+	var out []*CategoryNode
+	var walk func([]*CategoryNode)
+	walk = func(list []*CategoryNode) {
+		for _, n := range list {
+			out = append(out, n)
+			walk(n.Children)
+		}
+	}
+	walk(nodes)
+	return out
+}
+
+// MarkActiveCategories: tandai kategori di path (dari breadcrumb) supaya menu terbuka di posisi itu
+func MarkActiveCategories(nodes []*CategoryNode, path []Category) {
+	active := map[string]bool{}
+	for _, c := range path {
+		active[c.ID] = true
+	}
+	for _, n := range FlattenCategoryTree(nodes) {
+		n.Active = active[n.ID]
+	}
+}
+
+// CategoryDescendantIDs: id kategori + semua turunannya (untuk filter produk)
+func CategoryDescendantIDs(categories []Category, id string) []string {
+	children := map[string][]string{}
+	for _, c := range categories {
+		children[c.ParentID] = append(children[c.ParentID], c.ID)
+	}
+
+	ids := []string{id}
+	seen := map[string]bool{id: true}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range children[ids[i]] {
+			if !seen[child] {
+				seen[child] = true
+				ids = append(ids, child)
+			}
+		}
+	}
+	return ids
+}
+
+// CategoryPath: kategori utama → ... → kategori id (untuk breadcrumb)
+func CategoryPath(categories []Category, id string) []Category {
+	byID := make(map[string]Category, len(categories))
+	for _, c := range categories {
+		byID[c.ID] = c
+	}
+
+	var path []Category
+	seen := map[string]bool{}
+	for c, ok := byID[id]; ok && !seen[c.ID]; c, ok = byID[c.ParentID] {
+		seen[c.ID] = true
+		path = append([]Category{c}, path...)
+	}
+	return path
+}
+
+// SaveCategory: validasi induk, samakan section dengan induk, buat slug unik lalu simpan.
+// Kalau section kategori berubah, section seluruh turunannya ikut diperbarui.
+func SaveCategory(db *gorm.DB, category *Category) error {
+	categories, err := ListCategories(db)
+	if err != nil {
+		return err
+	}
+
+	if category.ParentID != "" {
+		if category.ID != "" {
+			for _, id := range CategoryDescendantIDs(categories, category.ID) {
+				if id == category.ParentID {
+					return ErrCategoryParentCycle
+				}
+			}
+		}
+
+		var parent Category
+		if err := db.Where("id = ?", category.ParentID).First(&parent).Error; err != nil {
+			return fmt.Errorf("induk kategori tidak ditemukan: %w", err)
+		}
+		category.SectionID = parent.SectionID
+	}
+
+	if category.SectionID == "" {
+		return errors.New("section wajib dipilih")
+	}
+	if err := db.Where("id = ?", category.SectionID).First(&Section{}).Error; err != nil {
+		return fmt.Errorf("section tidak ditemukan: %w", err)
+	}
+
+	category.Slug, err = uniqueSlug(db, &Category{}, category.Name, category.ID)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if category.ID == "" {
+			if err := tx.Omit("Section", "Products").Create(category).Error; err != nil {
+				return err
+			}
+		} else if err := tx.Omit("Section", "Products", "created_at").Save(category).Error; err != nil {
+			return err
+		}
+
+		descendants := CategoryDescendantIDs(categories, category.ID)[1:]
+		if len(descendants) == 0 {
+			return nil
+		}
+		return tx.Model(&Category{}).Where("id IN ?", descendants).Update("section_id", category.SectionID).Error
+	})
+}
+
+// DeleteCategory: hapus kategori tanpa subkategori; relasi ke produk ikut dilepas
+func DeleteCategory(db *gorm.DB, id string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&Category{}).Where("parent_id = ?", id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrCategoryHasChildren
+		}
+
+		if err := tx.Model(&Promotion{}).Where("category_id = ?", id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrCategoryInPromotion
+		}
+
+		if err := tx.Exec("DELETE FROM product_categories WHERE category_id = ?", id).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(&Category{}).Error
+	})
+}
+
+// uniqueSlug: slug dari name, ditambah -2, -3, ... kalau sudah dipakai baris lain di tabel model
+func uniqueSlug(db *gorm.DB, model interface{}, name, excludeID string) (string, error) {
+	base := slug.Make(name)
+	if base == "" {
+		return "", errors.New("nama wajib diisi")
+	}
+
+	candidate := base
+	for i := 2; ; i++ {
+		var count int64
+		q := db.Model(model).Where("slug = ?", candidate)
+		if excludeID != "" {
+			q = q.Where("id <> ?", excludeID)
+		}
+		if err := q.Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+// CategoryProductCounts: jumlah produk langsung di tiap kategori (key: category id)
+func CategoryProductCounts(db *gorm.DB) (map[string]int64, error) {
+	var rows []struct {
+		CategoryID string
+		Total      int64
+	}
+	err := db.Table("product_categories").
+		Select("category_id, COUNT(*) AS total").
+		Group("category_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.CategoryID] = row.Total
+	}
+	return counts, nil
+}
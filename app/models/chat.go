@@ -1,18 +1,31 @@
 package models
 
 import (
+	"errors"
+	"strings"
 	"time"
 
+	"github.com/alirogz/goshop/app/consts"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
-// Chat merepresentasikan 1 ruang percakapan antara 1 user dan admin.
-// Versi sederhana: 1 user = 1 chat (1 thread).
+var ErrChatStatusInvalid = errors.New("status chat tidak dikenal")
+
+// Chat merepresentasikan 1 percakapan (thread) antara user dan CS.
+// 1 user bisa punya banyak thread: umum, terkait 1 order, atau terkait 1 produk.
 type Chat struct {
 	ID              string        `gorm:"size:36;not null;uniqueIndex;primary_key"`
 	UserID          string        `gorm:"size:36;not null;index"`
 	User            User          `gorm:"foreignKey:UserID"`
 	Messages        []ChatMessage `gorm:"foreignKey:ChatID"`
+	OrderID         string        `gorm:"size:36;not null;default:'';index"` // kosong = bukan soal order tertentu
+	ProductID       string        `gorm:"size:36;not null;default:'';index"`
+	Subject         string        `gorm:"size:150;not null;default:''"`
+	Status          string        `gorm:"size:20;not null;default:open;index"`
+	AssignedAdminID string        `gorm:"size:36;not null;default:'';index"` // CS yang memegang; kosong = belum diambil
+	LastMessageAt   *time.Time    `gorm:"index"`
+	ResolvedAt      *time.Time
 	AdminLastReadAt *time.Time
 	UserLastReadAt  *time.Time
 	CreatedAt       time.Time
@@ -20,6 +33,40 @@ type Chat struct {
 	DeletedAt       gorm.DeletedAt
 }
 
+func (c *Chat) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == "" {
+		c.ID = uuid.New().String()
+	}
+	if c.Status == "" {
+		c.Status = consts.ChatStatusOpen
+	}
+	return nil
+}
+
+// Title: subject, atau judul default untuk thread lama / umum
+func (c Chat) Title() string {
+	if c.Subject != "" {
+		return c.Subject
+	}
+	return "Percakapan umum"
+}
+
+// StatusLabel: label status untuk tampilan
+func (c Chat) StatusLabel() string {
+	switch c.Status {
+	case consts.ChatStatusPending:
+		return "Menunggu pembeli"
+	case consts.ChatStatusResolved:
+		return "Selesai"
+	default:
+		return "Menunggu CS"
+	}
+}
+
+func (c Chat) IsResolved() bool {
+	return c.Status == consts.ChatStatusResolved
+}
+
 func (c *Chat) FindByID(db *gorm.DB, chatID string) (*Chat, error) {
 	var chat Chat
 	err := db.Model(Chat{}).
@@ -32,20 +79,228 @@ func (c *Chat) FindByID(db *gorm.DB, chatID string) (*Chat, error) {
 	return &chat, nil
 }
 
-// FindOrCreateByUserID: 1 user => 1 chat thread.
+// FindOrCreateByUserID: thread umum user (tanpa order/produk) yang belum selesai, dibuat kalau belum ada.
+// Dipakai endpoint lama /chat/messages yang belum mengenal thread.
 func (c *Chat) FindOrCreateByUserID(db *gorm.DB, chatID, userID string) (*Chat, error) {
 	var chat Chat
-	err := db.Model(Chat{}).Where("user_id = ?", userID).First(&chat).Error
+	err := db.Model(Chat{}).
+		Where("user_id = ? AND order_id = '' AND product_id = '' AND status <> ?", userID, consts.ChatStatusResolved).
+		Order("created_at desc").
+		First(&chat).Error
+	if err == nil {
+		return &chat, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	return StartChat(db, chatID, userID, "", "", "")
+}
+
+// FindOrCreateOrderChat: thread untuk 1 order (yang belum selesai dipakai lagi)
+func FindOrCreateOrderChat(db *gorm.DB, order Order) (*Chat, error) {
+	var chat Chat
+	err := db.Model(Chat{}).
+		Where("user_id = ? AND order_id = ? AND status <> ?", order.UserID, order.ID, consts.ChatStatusResolved).
+		Order("created_at desc").
+		First(&chat).Error
 	if err == nil {
 		return &chat, nil
 	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	return StartChat(db, uuid.NewString(), order.UserID, "Pesanan "+order.Code, order.ID, "")
+}
 
-	chat = Chat{
-		ID:     chatID,
-		UserID: userID,
+// StartChat: buat thread baru. orderID / productID boleh kosong.
+func StartChat(db *gorm.DB, chatID, userID, subject, orderID, productID string) (*Chat, error) {
+	chat := Chat{
+		ID:        chatID,
+		UserID:    userID,
+		Subject:   truncateRunes(strings.TrimSpace(subject), 150),
+		OrderID:   orderID,
+		ProductID: productID,
+		Status:    consts.ChatStatusOpen,
 	}
 	if err := db.Create(&chat).Error; err != nil {
 		return nil, err
 	}
 	return &chat, nil
 }
+
+// UserChats: semua thread milik user, yang terakhir aktif dulu
+func UserChats(db *gorm.DB, userID string) ([]Chat, error) {
+	var chats []Chat
+	err := db.Where("user_id = ?", userID).
+		Order("COALESCE(last_message_at, created_at) desc").
+		Find(&chats).Error
+	return chats, err
+}
+
+// ListChats: daftar thread untuk admin sesuai filter (consts.ChatFilter*). adminID dipakai filter "mine".
+func ListChats(db *gorm.DB, filter, adminID string) ([]Chat, error) {
+	query := db.Model(Chat{}).Preload("User")
+
+	switch filter {
+	case consts.ChatFilterResolved:
+		query = query.Where("status = ?", consts.ChatStatusResolved)
+	case consts.ChatFilterUnassigned:
+		query = query.Where("status <> ? AND assigned_admin_id = ''", consts.ChatStatusResolved)
+	case consts.ChatFilterMine:
+		query = query.Where("status <> ? AND assigned_admin_id = ?", consts.ChatStatusResolved, adminID)
+	default:
+		query = query.Where("status <> ?", consts.ChatStatusResolved)
+	}
+
+	var chats []Chat
+	err := query.Order("COALESCE(last_message_at, created_at) desc").Find(&chats).Error
+	return chats, err
+}
+
+// AddMessage: simpan pesan & perbarui thread. Pesan pembeli membuka lagi thread (status open),
+// balasan CS membuat status pending (menunggu pembeli).
+func (c *Chat) AddMessage(tx *gorm.DB, msg *ChatMessage) error {
+	msg.ChatID = c.ID
+	for i := range msg.Attachments {
+		msg.Attachments[i].ChatID = c.ID
+	}
+	if err := tx.Create(msg).Error; err != nil {
+		return err
+	}
+
+	status := consts.ChatStatusOpen
+	if msg.SenderRole == "admin" {
+		status = consts.ChatStatusPending
+	}
+	updates := map[string]interface{}{
+		"status":          status,
+		"last_message_at": msg.CreatedAt,
+		"resolved_at":     nil,
+	}
+	if err := tx.Model(&Chat{}).Where("id = ?", c.ID).Updates(updates).Error; err != nil {
+		return err
+	}
+
+	c.Status = status
+	c.LastMessageAt = &msg.CreatedAt
+	c.ResolvedAt = nil
+	return nil
+}
+
+// Assign: serahkan thread ke CS (adminID kosong = lepas)
+func (c *Chat) Assign(db *gorm.DB, adminID string) error {
+	if err := db.Model(&Chat{}).Where("id = ?", c.ID).Update("assigned_admin_id", adminID).Error; err != nil {
+		return err
+	}
+	c.AssignedAdminID = adminID
+	return nil
+}
+
+// SetStatus: ubah status manual oleh admin (mis. tandai selesai)
+func (c *Chat) SetStatus(db *gorm.DB, status string) error {
+	var resolvedAt *time.Time
+	switch status {
+	case consts.ChatStatusOpen, consts.ChatStatusPending:
+	case consts.ChatStatusResolved:
+		now := time.Now()
+		resolvedAt = &now
+	default:
+		return ErrChatStatusInvalid
+	}
+
+	err := db.Model(&Chat{}).Where("id = ?", c.ID).Updates(map[string]interface{}{
+		"status":      status,
+		"resolved_at": resolvedAt,
+	}).Error
+	if err != nil {
+		return err
+	}
+	c.Status = status
+	c.ResolvedAt = resolvedAt
+	return nil
+}
+
+// CountUserUnread: pesan dari admin yang belum dibaca user (di chat milik user tsb)
+func CountUserUnread(db *gorm.DB, userID string) (int64, error) {
+	var count int64
+	err := db.Raw(`
+		SELECT COUNT(*)
+		FROM chat_messages m
+		JOIN chats c ON c.id = m.chat_id
+		WHERE c.user_id = ?
+		  AND m.sender_role = 'admin'
+		  AND (c.user_last_read_at IS NULL OR m.created_at > c.user_last_read_at)
+	`, userID).Scan(&count).Error
+	return count, err
+}
+
+// UserUnreadByChat: jumlah balasan CS yang belum dibaca user, per thread
+func UserUnreadByChat(db *gorm.DB, userID string) (map[string]int64, error) {
+	var rows []struct {
+		ChatID string
+		Total  int64
+	}
+	err := db.Raw(`
+		SELECT m.chat_id AS chat_id, COUNT(*) AS total
+		FROM chat_messages m
+		JOIN chats c ON c.id = m.chat_id
+		WHERE c.user_id = ?
+		  AND m.sender_role = 'admin'
+		  AND (c.user_last_read_at IS NULL OR m.created_at > c.user_last_read_at)
+		GROUP BY m.chat_id
+	`, userID).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.ChatID] = row.Total
+	}
+	return counts, nil
+}
+
+// AdminUnreadByChat: jumlah pesan user yang belum dibaca admin, per chat (chat tanpa pesan baru tidak ikut)
+func AdminUnreadByChat(db *gorm.DB) (map[string]int64, error) {
+	var rows []struct {
+		ChatID string
+		Total  int64
+	}
+	err := db.Raw(`
+		SELECT m.chat_id AS chat_id, COUNT(*) AS total
+		FROM chat_messages m
+		JOIN chats c ON c.id = m.chat_id
+		WHERE m.sender_role = 'user'
+		  AND (c.admin_last_read_at IS NULL OR m.created_at > c.admin_last_read_at)
+		GROUP BY m.chat_id
+	`).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.ChatID] = row.Total
+	}
+	return counts, nil
+}
+
+// MarkReadBy: catat waktu baca chat oleh "user" / "admin"
+func (c *Chat) MarkReadBy(db *gorm.DB, reader string, at time.Time) error {
+	column := "user_last_read_at"
+	if reader == "admin" {
+		column = "admin_last_read_at"
+	}
+	if err := db.Model(&Chat{}).Where("id = ?", c.ID).Update(column, &at).Error; err != nil {
+		return err
+	}
+
+	if reader == "admin" {
+		c.AdminLastReadAt = &at
+	} else {
+		c.UserLastReadAt = &at
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ChatAttachment: file yang dilampirkan di 1 pesan chat (foto barang, slip transfer, ...).
+// File disimpan privat (lihat package attachments) dan hanya bisa diunduh lewat /chat/attachments/{id}.
+type ChatAttachment struct {
+	ID          string `gorm:"size:36;not null;uniqueIndex;primary_key"`
+	MessageID   string `gorm:"size:36;not null;index"`
+	ChatID      string `gorm:"size:36;not null;index"`
+	FileName    string `gorm:"size:255;not null"` // nama asli dari user, hanya untuk tampilan
+	ContentType string `gorm:"size:100;not null"` // hasil deteksi isi file
+	Size        int64
+	Width       int
+	Height      int
+	StoragePath string `gorm:"size:255;not null" json:"-"`
+	ThumbPath   string `gorm:"size:255" json:"-"`
+	CreatedAt   time.Time
+
+	// untuk JSON polling / realtime
+	URL      string `gorm:"-"`
+	ThumbURL string `gorm:"-"`
+	IsImage  bool   `gorm:"-"`
+}
+
+func (a *ChatAttachment) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == "" {
+		a.ID = uuid.New().String()
+	}
+	return nil
+}
+
+func (a *ChatAttachment) AfterCreate(tx *gorm.DB) error {
+	a.fillURLs()
+	return nil
+}
+
+func (a *ChatAttachment) AfterFind(tx *gorm.DB) error {
+	a.fillURLs()
+	return nil
+}
+
+func (a *ChatAttachment) fillURLs() {
+	a.URL = "/chat/attachments/" + a.ID
+	a.ThumbURL = ""
+	if a.ThumbPath != "" {
+		a.ThumbURL = a.URL + "?thumb=1"
+	}
+	a.IsImage = strings.HasPrefix(a.ContentType, "image/")
+}
+
+// FindChatAttachment: lampiran + chat-nya (untuk cek hak akses)
+func FindChatAttachment(db *gorm.DB, id string) (*ChatAttachment, *Chat, error) {
+	var attachment ChatAttachment
+	if err := db.Where("id = ?", id).First(&attachment).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var chat Chat
+	if err := db.Where("id = ?", attachment.ChatID).First(&chat).Error; err != nil {
+		return nil, nil, err
+	}
+	return &attachment, &chat, nil
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"fmt"
 	"time"
 
 	"gorm.io/gorm"
@@ -11,10 +12,12 @@ type ChatMessage struct {
 	ChatID     string `gorm:"size:36;not null;index"`
 	SenderID   string `gorm:"size:36;not null;index"`
 	SenderRole string `gorm:"size:20;not null;index"` // "user" | "admin"
-	Message    string `gorm:"type:text;not null"`
+	Message    string `gorm:"type:text;not null"`     // boleh kosong kalau ada lampiran
 	CreatedAt  time.Time
 	UpdatedAt  time.Time
 	DeletedAt  gorm.DeletedAt
+
+	Attachments []ChatAttachment `gorm:"foreignKey:MessageID"`
 }
 
 // ListMessagesAfter: ambil pesan setelah waktu tertentu (unix milli), opsional.
@@ -28,6 +31,16 @@ func (m *ChatMessage) ListMessagesAfter(db *gorm.DB, chatID string, afterUnixMil
 	if limit <= 0 {
 		limit = 50
 	}
-	err := q.Order("created_at asc").Limit(limit).Find(&msgs).Error
+	err := q.Preload("Attachments", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at asc")
+	}).Order("created_at asc").Limit(limit).Find(&msgs).Error
 	return msgs, err
 }
+
+// Preview: isi pesan untuk notifikasi; pesan tanpa teks diganti keterangan lampiran
+func (m ChatMessage) Preview() string {
+	if m.Message != "" || len(m.Attachments) == 0 {
+		return m.Message
+	}
+	return fmt.Sprintf("[%d lampiran]", len(m.Attachments))
+}
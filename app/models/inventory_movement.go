@@ -0,0 +1,285 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alirogz/goshop/app/consts"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// InventoryMovement: ledger perubahan stok produk / varian.
+// Qty bertanda: negatif = stok keluar, positif = stok masuk.
+// StockBefore/After mengikuti level yang berubah (varian kalau VariantID terisi).
+type InventoryMovement struct {
+	ID          string `gorm:"size:36;not null;uniqueIndex;primary_key"`
+	ProductID   string `gorm:"size:36;not null;index"`
+	Product     Product
+	VariantID   string `gorm:"size:36;not null;default:'';index"`
+	Variant     ProductVariant
+	OrderID     string `gorm:"size:36;index"`
+	Qty         int
+	StockBefore int
+	StockAfter  int
+	Reason      string `gorm:"size:50;not null;index"` // lihat consts.InventoryReason*
+	ActorID     string `gorm:"size:36;index"`
+	Note        string `gorm:"size:255"`
+	CreatedAt   time.Time
+}
+
+// InsufficientStockError: stok tidak cukup untuk qty yang diminta
+type InsufficientStockError struct {
+	ProductID   string
+	ProductName string
+	Requested   int
+	Available   int
+}
+
+func (e *InsufficientStockError) Error() string {
+	if e.Available <= 0 {
+		return fmt.Sprintf("stok %s habis", e.ProductName)
+	}
+	return fmt.Sprintf("stok %s tidak cukup (tersisa %d, diminta %d)", e.ProductName, e.Available, e.Requested)
+}
+
+func (m *InventoryMovement) BeforeCreate(db *gorm.DB) error {
+	if m.ID == "" {
+		m.ID = uuid.New().String()
+	}
+
+	return nil
+}
+
+// stockHolder: baris yang stoknya berubah (produk, atau varian kalau ada)
+type stockHolder struct {
+	product *Product
+	variant *ProductVariant
+}
+
+func (h stockHolder) stock() int {
+	if h.variant != nil {
+		return h.variant.Stock
+	}
+	return h.product.Stock
+}
+
+func (h stockHolder) name() string {
+	if h.variant != nil {
+		return h.product.Name + " (" + h.variant.Label() + ")"
+	}
+	return h.product.Name
+}
+
+func (h stockHolder) variantID() string {
+	if h.variant != nil {
+		return h.variant.ID
+	}
+	return ""
+}
+
+// CheckStock: validasi qty terhadap stok saat ini (tanpa lock, untuk cart).
+// variantID kosong = stok level produk.
+func CheckStock(db *gorm.DB, productID, variantID string, qty int) error {
+	holder, err := findStockHolder(db, productID, variantID)
+	if err != nil {
+		return err
+	}
+
+	if qty > holder.stock() {
+		return &InsufficientStockError{
+			ProductID:   holder.product.ID,
+			ProductName: holder.name(),
+			Requested:   qty,
+			Available:   holder.stock(),
+		}
+	}
+
+	return nil
+}
+
+// AdjustStock: ubah stok produk / varian sebesar delta dengan row lock (SELECT ... FOR UPDATE)
+// dan catat ke ledger. Wajib dipanggil di dalam transaksi.
+// delta negatif akan ditolak kalau stok tidak cukup.
+func AdjustStock(tx *gorm.DB, productID, variantID string, delta int, reason, orderID, actorID, note string) error {
+	holder, err := lockStockHolder(tx, productID, variantID)
+	if err != nil {
+		return err
+	}
+
+	if holder.stock()+delta < 0 {
+		return &InsufficientStockError{
+			ProductID:   holder.product.ID,
+			ProductName: holder.name(),
+			Requested:   -delta,
+			Available:   holder.stock(),
+		}
+	}
+
+	return moveStock(tx, holder, delta, reason, orderID, actorID, note)
+}
+
+// SetStock: set stok ke angka tertentu (form admin), selisihnya dicatat ke ledger
+func SetStock(tx *gorm.DB, productID, variantID string, target int, reason, actorID, note string) error {
+	if target < 0 {
+		target = 0
+	}
+
+	holder, err := lockStockHolder(tx, productID, variantID)
+	if err != nil {
+		return err
+	}
+
+	delta := target - holder.stock()
+	if delta == 0 {
+		return nil
+	}
+
+	return moveStock(tx, holder, delta, reason, "", actorID, note)
+}
+
+func findStockHolder(db *gorm.DB, productID, variantID string) (stockHolder, error) {
+	var holder stockHolder
+
+	var product Product
+	if err := db.Model(&Product{}).Where("id = ?", productID).First(&product).Error; err != nil {
+		return holder, err
+	}
+	holder.product = &product
+
+	if variantID != "" {
+		var variant ProductVariant
+		err := db.Model(&ProductVariant{}).
+			Where("id = ? AND product_id = ?", variantID, productID).
+			First(&variant).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return holder, ErrVariantNotFound
+			}
+			return holder, err
+		}
+		holder.variant = &variant
+	}
+
+	return holder, nil
+}
+
+// lockStockHolder: lock baris produk dulu baru varian.
+// Urutan lock selalu sama (produk → varian) supaya tidak deadlock antar checkout.
+func lockStockHolder(tx *gorm.DB, productID, variantID string) (stockHolder, error) {
+	locked := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Session(&gorm.Session{})
+	return findStockHolder(locked, productID, variantID)
+}
+
+// moveStock: tulis stok baru + ledger.
+// Stok varian berubah → Product.Stock ikut berubah supaya tetap = total stok varian.
+func moveStock(tx *gorm.DB, holder stockHolder, delta int, reason, orderID, actorID, note string) error {
+	before := holder.stock()
+	after := before + delta
+
+	if holder.variant != nil {
+		if err := tx.Model(&ProductVariant{}).Where("id = ?", holder.variant.ID).Update("stock", after).Error; err != nil {
+			return err
+		}
+		holder.variant.Stock = after
+	}
+
+	productStock := holder.product.Stock + delta
+	if err := tx.Model(&Product{}).Where("id = ?", holder.product.ID).Update("stock", productStock).Error; err != nil {
+		return err
+	}
+	holder.product.Stock = productStock
+
+	movement := InventoryMovement{
+		ProductID:   holder.product.ID,
+		VariantID:   holder.variantID(),
+		OrderID:     orderID,
+		Qty:         delta,
+		StockBefore: before,
+		StockAfter:  after,
+		Reason:      reason,
+		ActorID:     actorID,
+		Note:        note,
+	}
+	return tx.Create(&movement).Error
+}
+
+// ReleaseOrderStock: kembalikan stok yang masih dipegang 1 order.
+// Idempotent: saldo ledger per produk / varian untuk order tsb dipakai sebagai acuan,
+// jadi memanggil 2x tidak akan menambah stok 2x.
+func ReleaseOrderStock(tx *gorm.DB, orderID, actorID, note string) error {
+	type heldStock struct {
+		ProductID string
+		VariantID string
+		Qty       int
+	}
+
+	var held []heldStock
+	err := tx.Model(&InventoryMovement{}).
+		Select("product_id, variant_id, SUM(qty) AS qty").
+		Where("order_id = ?", orderID).
+		Group("product_id, variant_id").
+		Order("product_id, variant_id").
+		Scan(&held).Error
+	if err != nil {
+		return err
+	}
+
+	for _, h := range held {
+		if h.Qty >= 0 {
+			continue
+		}
+		err := AdjustStock(tx, h.ProductID, h.VariantID, -h.Qty, consts.InventoryReasonOrderCancelled, orderID, actorID, note)
+		if errors.Is(err, ErrVariantNotFound) {
+			// varian sudah dihapus admin, tidak ada tempat untuk mengembalikan stok
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ListByProductID: riwayat stok 1 produk, terbaru di atas
+func (m *InventoryMovement) ListByProductID(db *gorm.DB, productID string, limit int) ([]InventoryMovement, error) {
+	var movements []InventoryMovement
+
+	if limit <= 0 {
+		limit = 100
+	}
+
+	err := db.Model(&InventoryMovement{}).
+		Preload("Variant", func(tx *gorm.DB) *gorm.DB { return tx.Unscoped() }).
+		Where("product_id = ?", productID).
+		Order("created_at desc").
+		Limit(limit).
+		Find(&movements).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return movements, nil
+}
+
+func (m InventoryMovement) ReasonText() string {
+	switch m.Reason {
+	case consts.InventoryReasonInitial:
+		return "Stok awal"
+	case consts.InventoryReasonAdjustment:
+		return "Penyesuaian admin"
+	case consts.InventoryReasonOrderPlaced:
+		return "Order dibuat"
+	case consts.InventoryReasonOrderCancelled:
+		return "Order dibatalkan"
+	default:
+		return m.Reason
+	}
+}
+
+func (m InventoryMovement) CreatedAtFormatted() string {
+	return m.CreatedAt.Format("02 Jan 2006 15:04")
+}
@@ -0,0 +1,301 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+	"unicode/utf8"
+
+	"github.com/alirogz/goshop/app/consts"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// jeda sebelum percobaan kirim ulang ke-n; setelah semua habis notifikasi ditandai gagal
+var notificationRetryBackoff = []time.Duration{
+	time.Minute,
+	5 * time.Minute,
+	30 * time.Minute,
+	2 * time.Hour,
+	6 * time.Hour,
+}
+
+// NotificationMaxAttempts: 1 percobaan awal + semua jadwal retry
+var NotificationMaxAttempts = len(notificationRetryBackoff) + 1
+
+var ErrNotificationNotFound = errors.New("notifikasi tidak ditemukan")
+
+// Notification: outbox email. Baris dibuat di transaksi yang sama dengan event-nya
+// (order dibuat, pembayaran disetujui, dst) lalu dikirim worker secara async.
+type Notification struct {
+	ID            string `gorm:"size:36;not null;uniqueIndex;primary_key"`
+	UserID        string `gorm:"size:36;index"`
+	OrderID       string `gorm:"size:36;index"` // kosong untuk notifikasi non-order (chat)
+	Kind          string `gorm:"size:50;not null;index"`
+	Recipient     string `gorm:"size:100;not null"`
+	Locale        string `gorm:"size:5;not null"`
+	Payload       string `gorm:"type:text"` // JSON data untuk template
+	Subject       string `gorm:"size:255"`  // diisi saat dirender
+	Status        string `gorm:"size:20;not null;index"`
+	Attempts      int
+	NextAttemptAt time.Time `gorm:"index"`
+	LastError     string    `gorm:"type:text"`
+	SentAt        sql.NullTime
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+func (n *Notification) BeforeCreate(tx *gorm.DB) error {
+	if n.ID == "" {
+		n.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// Data: payload JSON → map untuk template
+func (n Notification) Data() (map[string]interface{}, error) {
+	data := map[string]interface{}{}
+	if n.Payload == "" {
+		return data, nil
+	}
+	err := json.Unmarshal([]byte(n.Payload), &data)
+	return data, err
+}
+
+// StatusLabel: label status untuk halaman admin
+func (n Notification) StatusLabel() string {
+	switch n.Status {
+	case consts.NotificationStatusSent:
+		return "Terkirim"
+	case consts.NotificationStatusFailed:
+		return "Gagal"
+	default:
+		if n.Attempts > 0 {
+			return "Menunggu retry"
+		}
+		return "Antre"
+	}
+}
+
+// KindLabel: nama jenis notifikasi untuk halaman admin
+func (n Notification) KindLabel() string {
+	switch n.Kind {
+	case consts.NotificationOrderPlaced:
+		return "Pesanan dibuat"
+	case consts.NotificationPaymentApproved:
+		return "Pembayaran diterima"
+	case consts.NotificationPaymentRejected:
+		return "Pembayaran ditolak"
+	case consts.NotificationOrderStatus:
+		return "Status pesanan"
+	case consts.NotificationChatMessage:
+		return "Pesan chat"
+	}
+	return n.Kind
+}
+
+// EnqueueNotification: simpan email ke outbox untuk user. Panggil dengan tx milik event-nya,
+// supaya email hanya terkirim kalau perubahan datanya benar-benar tersimpan.
+func EnqueueNotification(tx *gorm.DB, kind, userID, orderID string, payload map[string]interface{}) error {
+	var user User
+	err := tx.Select("id", "first_name", "email", "locale").Where("id = ?", userID).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil // user sudah dihapus: event tetap tersimpan, hanya tanpa email
+	}
+	if err != nil {
+		return err
+	}
+	if user.Email == "" {
+		return nil
+	}
+
+	if payload == nil {
+		payload = map[string]interface{}{}
+	}
+	payload["name"] = user.FirstName
+
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	notification := Notification{
+		UserID:        user.ID,
+		OrderID:       orderID,
+		Kind:          kind,
+		Recipient:     user.Email,
+		Locale:        user.EmailLocale(),
+		Payload:       string(raw),
+		Status:        consts.NotificationStatusPending,
+		NextAttemptAt: time.Now(),
+	}
+	return tx.Create(&notification).Error
+}
+
+// EnqueueOrderNotification: notifikasi ke pemilik order, payload berisi ringkasan order
+func EnqueueOrderNotification(tx *gorm.DB, kind string, order *Order, extra map[string]interface{}) error {
+	payload := map[string]interface{}{
+		"order_id":      order.ID,
+		"code":          order.Code,
+		"invoice":       order.InvoiceNumber,
+		"grand_total":   order.GrandTotal.String(),
+		"payment_total": order.PaymentTotal.String(),
+		"payment_due":   order.PaymentDue.Format("02 Jan 2006 15:04"),
+		"status":        order.StatusName(),
+		"courier":       order.ShippingCourier,
+		"service":       order.ShippingServiceName,
+		"path":          "/orders/" + order.ID,
+	}
+	for k, v := range extra {
+		payload[k] = v
+	}
+	return EnqueueNotification(tx, kind, order.UserID, order.ID, payload)
+}
+
+// EnqueueChatNotification: kabari pembeli ada balasan chat dari toko di thread chatID.
+// Kalau masih ada email chat yang antre untuk user itu, tidak dibuat lagi (1 email untuk beberapa balasan beruntun).
+func EnqueueChatNotification(tx *gorm.DB, userID, chatID, message string) error {
+	var queued int64
+	err := tx.Model(&Notification{}).
+		Where("user_id = ? AND kind = ? AND status = ? AND attempts = 0", userID, consts.NotificationChatMessage, consts.NotificationStatusPending).
+		Count(&queued).Error
+	if err != nil {
+		return err
+	}
+	if queued > 0 {
+		return nil
+	}
+
+	return EnqueueNotification(tx, consts.NotificationChatMessage, userID, "", map[string]interface{}{
+		"message": truncateRunes(message, 300),
+		"path":    "/chat/" + chatID,
+	})
+}
+
+// ClaimDueNotifications: ambil notifikasi yang waktunya dikirim. Tiap baris diklaim dengan
+// UPDATE bersyarat (attempts lama) dan dijadwal ulang sejauh `lease`, jadi instance lain
+// tidak mengirim email yang sama; kalau proses mati di tengah jalan baris akan dicoba lagi.
+func ClaimDueNotifications(db *gorm.DB, now time.Time, lease time.Duration, limit int) ([]Notification, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	var due []Notification
+	err := db.Where("status = ? AND next_attempt_at <= ?", consts.NotificationStatusPending, now).
+		Order("next_attempt_at asc").
+		Limit(limit).
+		Find(&due).Error
+	if err != nil {
+		return nil, err
+	}
+
+	claimed := make([]Notification, 0, len(due))
+	for _, n := range due {
+		res := db.Model(&Notification{}).
+			Where("id = ? AND status = ? AND attempts = ?", n.ID, consts.NotificationStatusPending, n.Attempts).
+			Updates(map[string]interface{}{
+				"attempts":        n.Attempts + 1,
+				"next_attempt_at": now.Add(lease),
+			})
+		if res.Error != nil {
+			return claimed, res.Error
+		}
+		if res.RowsAffected == 0 {
+			continue // sudah diambil instance lain
+		}
+		n.Attempts++
+		n.NextAttemptAt = now.Add(lease)
+		claimed = append(claimed, n)
+	}
+
+	return claimed, nil
+}
+
+// MarkSent: email sudah diterima server SMTP
+func (n *Notification) MarkSent(db *gorm.DB, subject string, now time.Time) error {
+	n.Status = consts.NotificationStatusSent
+	n.Subject = subject
+	n.SentAt = sql.NullTime{Time: now, Valid: true}
+	n.LastError = ""
+
+	return db.Model(&Notification{}).Where("id = ?", n.ID).Updates(map[string]interface{}{
+		"status":     n.Status,
+		"subject":    n.Subject,
+		"sent_at":    n.SentAt,
+		"last_error": "",
+	}).Error
+}
+
+// MarkFailed: jadwalkan retry, atau tandai gagal permanen kalau percobaan sudah habis
+func (n *Notification) MarkFailed(db *gorm.DB, subject string, sendErr error, now time.Time) error {
+	n.Subject = subject
+	n.LastError = sendErr.Error()
+	if n.Attempts >= NotificationMaxAttempts {
+		n.Status = consts.NotificationStatusFailed
+	} else {
+		n.Status = consts.NotificationStatusPending
+		n.NextAttemptAt = now.Add(notificationRetryBackoff[n.Attempts-1])
+	}
+
+	return db.Model(&Notification{}).Where("id = ?", n.ID).Updates(map[string]interface{}{
+		"status":          n.Status,
+		"subject":         n.Subject,
+		"last_error":      n.LastError,
+		"next_attempt_at": n.NextAttemptAt,
+	}).Error
+}
+
+// ResendNotification: masukkan lagi ke antrean (dipakai admin untuk email gagal / minta kirim ulang)
+func ResendNotification(db *gorm.DB, id string) error {
+	res := db.Model(&Notification{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":          consts.NotificationStatusPending,
+		"attempts":        0,
+		"next_attempt_at": time.Now(),
+		"last_error":      "",
+	})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotificationNotFound
+	}
+	return nil
+}
+
+// ListNotifications: untuk halaman admin, terbaru dulu. status kosong = semua.
+func ListNotifications(db *gorm.DB, status string, limit, offset int) ([]Notification, int64, error) {
+	query := db.Model(&Notification{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var notifications []Notification
+	err := query.Order("created_at desc").Limit(limit).Offset(offset).Find(&notifications).Error
+	return notifications, total, err
+}
+
+// OrderNotifications: riwayat email untuk 1 order
+func OrderNotifications(db *gorm.DB, orderID string) ([]Notification, error) {
+	var notifications []Notification
+	err := db.Where("order_id = ?", orderID).Order("created_at asc").Find(&notifications).Error
+	return notifications, err
+}
+
+// truncateRunes: potong per karakter (bukan byte, supaya UTF-8 tidak rusak);
+// hasilnya paling banyak max karakter termasuk "…", jadi aman untuk kolom size:max
+func truncateRunes(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	if max <= 0 {
+		return ""
+	}
+	runes := []rune(s)
+	return string(runes[:max-1]) + "…"
+}
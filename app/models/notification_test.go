@@ -0,0 +1,33 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateRunes(t *testing.T) {
+	tests := []struct {
+		s    string
+		max  int
+		want string
+	}{
+		{"TRF BUDI", 255, "TRF BUDI"},
+		{"TRF BUDI", 8, "TRF BUDI"},
+		{"TRF BUDI", 5, "TRF …"},
+		{"Pembayaran 🙏 lunas", 13, "Pembayaran 🙏…"},
+		{"ÄÖÜ", 0, ""},
+	}
+	for _, tt := range tests {
+		if got := truncateRunes(tt.s, tt.max); got != tt.want {
+			t.Errorf("truncateRunes(%q, %d) = %q, want %q", tt.s, tt.max, got, tt.want)
+		}
+	}
+
+	// keterangan mutasi panjang ber-UTF-8 tetap valid & muat di kolom size:255
+	note := strings.Repeat("é", 300)
+	got := truncateRunes(note, 255)
+	if !utf8.ValidString(got) || utf8.RuneCountInString(got) != 255 {
+		t.Fatalf("truncateRunes: valid=%v, %d karakter", utf8.ValidString(got), utf8.RuneCountInString(got))
+	}
+}
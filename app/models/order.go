@@ -2,7 +2,6 @@ package models
 
 import (
 	"database/sql"
-	"strconv"
 	"strings"
 	"time"
 
@@ -13,29 +12,37 @@ import (
 )
 
 type Order struct {
-	ID            string `gorm:"size:36;not null;uniqueIndex;primary_key"`
-	UserID        string `gorm:"size:36;index"`
-	User          User
-	OrderItems    []OrderItem
-	OrderCustomer *OrderCustomer
-	Code          string `gorm:"size:50;index"`
+	ID              string `gorm:"size:36;not null;uniqueIndex;primary_key"`
+	UserID          string `gorm:"size:36;index"`
+	User            User
+	OrderItems      []OrderItem
+	OrderCustomer   *OrderCustomer
+	StatusHistories []OrderStatusHistory
+	PaymentProofs   []PaymentProof
+	Code            string `gorm:"size:50;index"`
+	InvoiceNumber   string `gorm:"size:50;index"` // diisi saat order lunas
 
 	// STATUS & PAYMENT (pakai struktur asli)
-	Status        int
-	OrderDate     time.Time
-	PaymentDue    time.Time
-	PaymentStatus string `gorm:"size:50;index"`
+	Status            int
+	OrderDate         time.Time
+	PaymentDue        time.Time    `gorm:"index"`
+	PaymentReminderAt sql.NullTime // pengingat pembayaran sudah dikirim
+	PaymentStatus     string       `gorm:"size:50;index"`
 
-	PaidAt       sql.NullTime   `gorm:"type:timestamp"`
-	PaymentToken sql.NullString `gorm:"size:100;index"`
+	PaidAt         sql.NullTime   `gorm:"type:timestamp"`
+	PaymentToken   sql.NullString `gorm:"size:100;index"`
+	PaymentAttempt int            // jumlah tagihan payment gateway yang pernah dibuat (lihat NextPaymentAttempt)
 
 	// TOTAL & BIAYA
 	BaseTotalPrice    decimal.Decimal `gorm:"type:decimal(16,2)"`
 	TaxAmount         decimal.Decimal `gorm:"type:decimal(16,2)"`
 	TaxPercent        decimal.Decimal `gorm:"type:decimal(10,2)"`
+	PricesIncludeTax  bool            // aturan pajak saat order dibuat
 	DiscountAmount    decimal.Decimal `gorm:"type:decimal(16,2)"`
 	DiscountPercent   decimal.Decimal `gorm:"type:decimal(10,2)"`
+	CouponCode        string          `gorm:"size:50;index"`
 	ShippingCost      decimal.Decimal `gorm:"type:decimal(16,2)"`
+	ShippingDiscount  decimal.Decimal `gorm:"type:decimal(16,2)"`
 	GrandTotal        decimal.Decimal `gorm:"type:decimal(16,2)"`
 	PaymentUniqueCode int             `gorm:"column:payment_unique_code"`
 	PaymentTotal      decimal.Decimal `gorm:"column:payment_total"`
@@ -52,28 +59,16 @@ type Order struct {
 
 	// FIELD TAMBAHAN UNTUK PEMBAYARAN MANUAL
 	PaymentMethod string `gorm:"size:50"`  // contoh: "Transfer Bank"
-	PaymentProof  string `gorm:"size:255"` // nama file bukti transfer
+	PaymentProof  string `gorm:"size:255"` // nama file bukti transfer versi lama (publik), dipindah ke PaymentProofs oleh storage:migrate
 
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	DeletedAt gorm.DeletedAt
 }
 
-// Step: untuk kebutuhan tampilan tracking status (1–3)
+// Step: untuk kebutuhan tampilan tracking status (0 = cancelled / refund)
 func (o Order) Step() int {
-	switch o.Status {
-	case consts.OrderStatusPending:
-		return 1
-	case consts.OrderStatusReceived:
-		return 2
-	case consts.OrderStatusDelivered:
-		return 3
-	case consts.OrderStatusCancelled:
-		// Cancelled kita kasih 0 (nanti bisa dikasih style khusus)
-		return 0
-	default:
-		return 1
-	}
+	return o.StatusStep()
 }
 
 func (o *Order) BeforeCreate(db *gorm.DB) error {
@@ -81,7 +76,13 @@ func (o *Order) BeforeCreate(db *gorm.DB) error {
 		o.ID = uuid.New().String()
 	}
 
-	o.Code = generateOrderNumber(db)
+	if o.Code == "" {
+		code, err := NextSequence(db, consts.SequenceOrder, time.Now())
+		if err != nil {
+			return err
+		}
+		o.Code = code
+	}
 
 	return nil
 }
@@ -113,22 +114,7 @@ func (o *Order) FindByID(db *gorm.DB, id string) (*Order, error) {
 }
 
 func (o *Order) GetStatusLabel() string {
-	var statusLabel string
-
-	switch o.Status {
-	case consts.OrderStatusPending:
-		statusLabel = "PENDING"
-	case consts.OrderStatusDelivered:
-		statusLabel = "DELIVERED"
-	case consts.OrderStatusReceived:
-		statusLabel = "RECEIVED"
-	case consts.OrderStatusCancelled:
-		statusLabel = "CANCELLED"
-	default:
-		statusLabel = "UNKNOWN"
-	}
-
-	return statusLabel
+	return strings.ToUpper(o.StatusName())
 }
 
 // IsPaid: true kalau PaidAt valid atau status payment = paid
@@ -136,29 +122,6 @@ func (o *Order) IsPaid() bool {
 	return (o.PaidAt.Valid) || (o.PaymentStatus == consts.OrderPaymentStatusPaid)
 }
 
-func generateOrderNumber(db *gorm.DB) string {
-	now := time.Now()
-	month := now.Month()
-	year := strconv.Itoa(now.Year())
-
-	dateCode := "/ORDER/" + intToRoman(int(month)) + "/" + year
-
-	var latestOrder Order
-
-	err := db.Debug().Order("created_at DESC").Find(&latestOrder).Error
-
-	latestNumber, _ := strconv.Atoi(strings.Split(latestOrder.Code, "/")[0])
-	if err != nil {
-		latestNumber = 1
-	}
-
-	number := latestNumber + 1
-
-	invoiceNumber := strconv.Itoa(number) + dateCode
-
-	return invoiceNumber
-}
-
 func intToRoman(num int) string {
 	values := []int{
 		1000, 900, 500, 400,
@@ -190,19 +153,6 @@ func intToRoman(num int) string {
 	return roman
 }
 
-// MarkAsPaid: menandai order sudah dibayar
-func (o *Order) MarkAsPaid(db *gorm.DB) error {
-	now := time.Now()
-	o.PaidAt = sql.NullTime{Time: now, Valid: true}
-	o.PaymentStatus = consts.OrderPaymentStatusPaid
-
-	return db.Model(o).Updates(map[string]interface{}{
-		"paid_at":        o.PaidAt,
-		"payment_status": consts.OrderPaymentStatusPaid,
-		"updated_at":     time.Now(),
-	}).Error
-}
-
 func (o Order) GrandTotalFloat() float64 {
 	return o.GrandTotal.InexactFloat64()
 }
@@ -222,48 +172,38 @@ func (o Order) ShippingCostFloat() float64 {
 	return o.ShippingCost.InexactFloat64()
 }
 
-// StatusText: teks status untuk ditampilkan ke user
+// DiscountFloat: konversi DiscountAmount (decimal) ke float64
+func (o Order) DiscountFloat() float64 {
+	return o.DiscountAmount.InexactFloat64()
+}
+
+// ShippingDiscountFloat: konversi ShippingDiscount (decimal) ke float64
+func (o Order) ShippingDiscountFloat() float64 {
+	return o.ShippingDiscount.InexactFloat64()
+}
+
 // StatusText: ubah kode angka di DB jadi label yang enak dibaca
 func (o Order) StatusText() string {
-	switch o.Status {
-	case 0:
-		return "Pending"
-	case 1:
-		return "Diproses"
-	case 2:
-		return "Dikirim"
-	case 3:
-		return "Selesai"
-	default:
-		return "Unknown"
-	}
+	return OrderStatusLabel(o.Status)
 }
 
-// StatusStep: dipakai untuk stepper (1–4)
+// StatusStep: dipakai untuk stepper (1–4), 0 untuk cancelled / refund
 func (o Order) StatusStep() int {
-	switch o.Status {
-	case 0:
-		return 1 // Pending
-	case 1:
-		return 2 // Diproses
-	case 2:
-		return 3 // Dikirim
-	case 3:
-		return 4 // Selesai
-	default:
-		return 1
+	if st, ok := orderStates[o.Status]; ok {
+		return st.Step
 	}
+	return 1
 }
 
 func (o Order) PaymentStatusText() string {
-	switch o.PaymentStatus {
-	case consts.OrderPaymentStatusUnpaid:
+	switch strings.ToLower(o.PaymentStatus) {
+	case strings.ToLower(consts.OrderPaymentStatusUnpaid):
 		return "Belum Dibayar"
-	case consts.OrderPaymentStatusPaid:
+	case strings.ToLower(consts.OrderPaymentStatusPaid):
 		return "Lunas"
-	case "waiting_review":
+	case consts.OrderPaymentStatusWaitingReview:
 		return "Menunggu Konfirmasi"
-	case "rejected":
+	case consts.OrderPaymentStatusRejected:
 		return "Ditolak"
 	default:
 		return "Unknown"
@@ -0,0 +1,172 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/alirogz/goshop/app/consts"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// status pembayaran yang dianggap "belum bayar" (bukti yang sedang direview tidak ikut kedaluwarsa)
+var unpaidPaymentStatuses = []string{consts.OrderPaymentStatusUnpaid, consts.OrderPaymentStatusRejected}
+
+// ExpireUnpaidOrders: batalkan order pending yang lewat PaymentDue dan belum dibayar.
+// Stok & kuota promo dikembalikan seperti pembatalan biasa. Aman dijalankan di beberapa instance
+// sekaligus: tiap order dikunci (FOR UPDATE) lalu dicek ulang sebelum dibatalkan, jadi instance
+// yang kalah cepat hanya melihat order yang sudah batal.
+func ExpireUnpaidOrders(db *gorm.DB, now time.Time, limit int) (int, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+
+	var ids []string
+	err := db.Model(&Order{}).
+		Where("status = ? AND payment_status IN ? AND payment_due < ?", consts.OrderStatusPending, unpaidPaymentStatuses, now).
+		Order("payment_due asc").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, id := range ids {
+		ok, err := expireOrder(db, id, now)
+		if err != nil {
+			log.Println("expire order error:", id, err)
+			continue
+		}
+		if ok {
+			expired++
+		}
+	}
+
+	return expired, nil
+}
+
+func expireOrder(db *gorm.DB, orderID string, now time.Time) (bool, error) {
+	expired := false
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var order Order
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", orderID).
+			First(&order).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// cek ulang setelah dikunci: bisa saja baru dibayar / diperpanjang
+		if order.Status != consts.OrderStatusPending || order.IsPaid() ||
+			!containsString(unpaidPaymentStatuses, order.PaymentStatus) || !order.PaymentDue.Before(now) {
+			return nil
+		}
+
+		note := fmt.Sprintf("Otomatis dibatalkan: batas pembayaran %s terlewati", order.PaymentDue.Format("02 Jan 2006 15:04"))
+		if err := order.applyTransition(tx, consts.OrderStatusCancelled, consts.OrderActorSystem, note); err != nil {
+			return err
+		}
+
+		expired = true
+		return nil
+	})
+
+	return expired, err
+}
+
+// SendPaymentReminders: kirim pengingat pembayaran untuk order yang akan kedaluwarsa dalam `before`.
+// Tiap order hanya diingatkan sekali: kolom payment_reminder_at diklaim dengan UPDATE bersyarat (lihat remindOrder),
+// jadi instance lain yang berjalan bersamaan tidak mengirim pengingat dobel.
+func SendPaymentReminders(db *gorm.DB, now time.Time, before time.Duration, limit int) (int, error) {
+	if before <= 0 {
+		return 0, nil
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+
+	var orders []Order
+	err := db.Model(&Order{}).
+		Where("status = ? AND payment_status IN ?", consts.OrderStatusPending, unpaidPaymentStatuses).
+		Where("payment_due > ? AND payment_due <= ?", now, now.Add(before)).
+		Where("payment_reminder_at IS NULL").
+		Order("payment_due asc").
+		Limit(limit).
+		Find(&orders).Error
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, order := range orders {
+		ok, err := remindOrder(db, order, now)
+		if err != nil {
+			log.Println("send payment reminder error:", order.ID, err)
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+
+	return sent, nil
+}
+
+// remindOrder: klaim payment_reminder_at & simpan pengingat dalam 1 transaksi.
+// Kalau pengingat gagal disimpan, klaimnya ikut batal sehingga order dicoba lagi di putaran berikutnya;
+// pengiriman emailnya sendiri lewat outbox notifikasi yang punya retry.
+func remindOrder(db *gorm.DB, order Order, now time.Time) (bool, error) {
+	claimed := false
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&Order{}).
+			Where("id = ? AND payment_reminder_at IS NULL", order.ID).
+			Update("payment_reminder_at", sql.NullTime{Time: now, Valid: true})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return nil // sudah diambil instance lain
+		}
+
+		if err := sendPaymentReminder(tx, order); err != nil {
+			return err
+		}
+		claimed = true
+		return nil
+	})
+
+	return claimed, err
+}
+
+// sendPaymentReminder: pengingat dikirim sebagai pesan chat dari admin ke pembeli, di thread order tsb
+func sendPaymentReminder(db *gorm.DB, order Order) error {
+	chat, err := FindOrCreateOrderChat(db, order)
+	if err != nil {
+		return err
+	}
+
+	message := ChatMessage{
+		ID:         uuid.NewString(),
+		SenderID:   consts.OrderActorSystem,
+		SenderRole: "admin",
+		Message: fmt.Sprintf("Pengingat: pesanan %s belum dibayar. Selesaikan pembayaran sebelum %s atau pesanan akan dibatalkan otomatis.",
+			order.Code, order.PaymentDue.Format("02 Jan 2006 15:04")),
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := chat.AddMessage(tx, &message); err != nil {
+			return err
+		}
+		return EnqueueChatNotification(tx, order.UserID, chat.ID, message.Message)
+	})
+}
@@ -14,7 +14,9 @@ type OrderItem struct {
 	OrderID         string `gorm:"size:36;index"`
 	Product         Product
 	ProductID       string `gorm:"size:36;index"`
+	VariantID       string `gorm:"size:36;not null;default:'';index"`
 	Size            string `gorm:"size:20"`
+	Color           string `gorm:"size:50"`
 	Qty             int
 	BasePrice       decimal.Decimal `gorm:"type:decimal(16,2)"`
 	BaseTotal       decimal.Decimal `gorm:"type:decimal(16,2)"`
@@ -24,7 +26,7 @@ type OrderItem struct {
 	DiscountPercent decimal.Decimal `gorm:"type:decimal(10,2)"`
 	SubTotal        decimal.Decimal `gorm:"type:decimal(16,2)"`
 
-	Sku       string          `gorm:"size:36;index"`
+	Sku       string          `gorm:"size:100;index"`
 	Name      string          `gorm:"size:255"`
 	Weight    decimal.Decimal `gorm:"type:decimal(10,2)"`
 	CreatedAt time.Time
@@ -64,3 +66,8 @@ func (oi OrderItem) SubtotalFloat() float64 {
 	f, _ := oi.SubTotal.Float64()
 	return f
 }
+
+// VariantLabel: "M / Hitam"
+func (oi OrderItem) VariantLabel() string {
+	return ProductVariant{Size: oi.Size, Color: oi.Color}.Label()
+}
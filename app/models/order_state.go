@@ -0,0 +1,414 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alirogz/goshop/app/consts"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrOrderTransitionNotAllowed = errors.New("perubahan status tidak diizinkan")
+	ErrOrderNotPaid              = errors.New("pesanan belum dibayar")
+	ErrOrderAlreadyPaid          = errors.New("pesanan sudah dibayar")
+	ErrOrderClosed               = errors.New("pesanan sudah dibatalkan / direfund")
+	ErrOrderStatusChanged        = errors.New("status pesanan sudah berubah, silakan muat ulang")
+	ErrOrderPaymentLocked        = errors.New("pesanan sudah dikirim, pembayaran tidak bisa dibatalkan")
+)
+
+// orderState: metadata 1 status pesanan
+type orderState struct {
+	Name  string // dipakai di form & query string
+	Label string // teks untuk tampilan
+	Step  int    // posisi di stepper (0 = di luar alur normal)
+}
+
+var orderStates = map[int]orderState{
+	consts.OrderStatusPending:    {Name: "pending", Label: "Pending", Step: 1},
+	consts.OrderStatusProcessing: {Name: "processing", Label: "Diproses", Step: 2},
+	consts.OrderStatusShipped:    {Name: "shipped", Label: "Dikirim", Step: 3},
+	consts.OrderStatusCompleted:  {Name: "completed", Label: "Selesai", Step: 4},
+	consts.OrderStatusCancelled:  {Name: "cancelled", Label: "Dibatalkan", Step: 0},
+	consts.OrderStatusRefunded:   {Name: "refunded", Label: "Refund", Step: 0},
+}
+
+// urutan tampil di dropdown / filter
+var orderStateOrder = []int{
+	consts.OrderStatusPending,
+	consts.OrderStatusProcessing,
+	consts.OrderStatusShipped,
+	consts.OrderStatusCompleted,
+	consts.OrderStatusCancelled,
+	consts.OrderStatusRefunded,
+}
+
+// orderTransitions: dari status X boleh pindah ke status apa saja
+var orderTransitions = map[int][]int{
+	consts.OrderStatusPending:    {consts.OrderStatusProcessing, consts.OrderStatusCancelled},
+	consts.OrderStatusProcessing: {consts.OrderStatusShipped, consts.OrderStatusCancelled, consts.OrderStatusRefunded},
+	consts.OrderStatusShipped:    {consts.OrderStatusCompleted, consts.OrderStatusRefunded},
+	consts.OrderStatusCompleted:  {consts.OrderStatusRefunded},
+}
+
+// orderGuards: syarat tambahan sebelum masuk ke status tujuan
+var orderGuards = map[int]func(o *Order) error{
+	consts.OrderStatusProcessing: requireOrderPaid,
+	consts.OrderStatusShipped:    requireOrderPaid,
+	consts.OrderStatusRefunded:   requireOrderPaid,
+}
+
+func requireOrderPaid(o *Order) error {
+	if !o.IsPaid() {
+		return ErrOrderNotPaid
+	}
+	return nil
+}
+
+// OrderStatusOption: pasangan value/label untuk select di template
+type OrderStatusOption struct {
+	Value int
+	Name  string
+	Label string
+}
+
+// OrderStatusLabel: label tampilan dari kode status
+func OrderStatusLabel(status int) string {
+	if st, ok := orderStates[status]; ok {
+		return st.Label
+	}
+	return "Unknown"
+}
+
+// OrderStatusFromName: "shipped" -> consts.OrderStatusShipped
+func OrderStatusFromName(name string) (int, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for code, st := range orderStates {
+		if st.Name == name {
+			return code, true
+		}
+	}
+	return 0, false
+}
+
+// OrderStatusOptions: semua status (untuk filter)
+func OrderStatusOptions() []OrderStatusOption {
+	opts := make([]OrderStatusOption, 0, len(orderStateOrder))
+	for _, code := range orderStateOrder {
+		st := orderStates[code]
+		opts = append(opts, OrderStatusOption{Value: code, Name: st.Name, Label: st.Label})
+	}
+	return opts
+}
+
+// IsClosed: cancelled / refunded tidak bisa diproses lagi
+func (o Order) IsClosed() bool {
+	return o.Status == consts.OrderStatusCancelled || o.Status == consts.OrderStatusRefunded
+}
+
+// StatusName: nama status (pending, processing, ...)
+func (o Order) StatusName() string {
+	if st, ok := orderStates[o.Status]; ok {
+		return st.Name
+	}
+	return "unknown"
+}
+
+// CanTransitionTo: cek tabel transisi + guard
+func (o *Order) CanTransitionTo(to int) error {
+	allowed := false
+	for _, next := range orderTransitions[o.Status] {
+		if next == to {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("%w: %s → %s", ErrOrderTransitionNotAllowed, OrderStatusLabel(o.Status), OrderStatusLabel(to))
+	}
+
+	if guard, ok := orderGuards[to]; ok {
+		if err := guard(o); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// NextStatusOptions: status tujuan yang saat ini valid (dipakai di form admin)
+func (o Order) NextStatusOptions() []OrderStatusOption {
+	var opts []OrderStatusOption
+	for _, to := range orderTransitions[o.Status] {
+		if o.CanTransitionTo(to) != nil {
+			continue
+		}
+		st := orderStates[to]
+		opts = append(opts, OrderStatusOption{Value: to, Name: st.Name, Label: st.Label})
+	}
+	return opts
+}
+
+// TransitionTo: satu-satunya jalan untuk mengubah orders.status.
+// Perubahan disimpan bersama catatan di order_status_histories dalam 1 transaksi.
+func (o *Order) TransitionTo(db *gorm.DB, to int, actorID, note string) error {
+	if err := o.CanTransitionTo(to); err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		return o.applyTransition(tx, to, actorID, note)
+	})
+}
+
+// Cancel: shortcut TransitionTo(cancelled) yang juga mengisi kolom pembatalan
+func (o *Order) Cancel(db *gorm.DB, actorID, note string) error {
+	return o.TransitionTo(db, consts.OrderStatusCancelled, actorID, note)
+}
+
+func (o *Order) applyTransition(tx *gorm.DB, to int, actorID, note string) error {
+	now := time.Now()
+	from := o.Status
+
+	updates := map[string]interface{}{
+		"status":     to,
+		"updated_at": now,
+	}
+
+	if to == consts.OrderStatusCancelled {
+		updates["cancelled_by"] = sql.NullString{String: actorID, Valid: actorID != ""}
+		updates["cancelled_at"] = sql.NullTime{Time: now, Valid: true}
+		updates["cancellation_note"] = sql.NullString{String: note, Valid: note != ""}
+	}
+
+	// optimistic lock: hanya update kalau status di DB masih sama
+	res := tx.Model(&Order{}).
+		Where("id = ? AND status = ?", o.ID, from).
+		Updates(updates)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrOrderStatusChanged
+	}
+
+	history := OrderStatusHistory{
+		OrderID:    o.ID,
+		Event:      consts.OrderEventStatus,
+		FromStatus: from,
+		ToStatus:   to,
+		ActorID:    actorID,
+		Note:       note,
+	}
+	if err := tx.Create(&history).Error; err != nil {
+		return err
+	}
+
+	// order batal → stok yang sudah dipotong, kuota promo & kode unik transfer dikembalikan
+	if to == consts.OrderStatusCancelled {
+		if err := ReleaseOrderStock(tx, o.ID, actorID, note); err != nil {
+			return err
+		}
+		if err := ReleaseOrderPromotions(tx, o.ID); err != nil {
+			return err
+		}
+		if err := ReleasePaymentUniqueCode(tx, o.ID); err != nil {
+			return err
+		}
+	}
+
+	o.Status = to
+	o.UpdatedAt = now
+	if to == consts.OrderStatusCancelled {
+		o.CancelledBy = updates["cancelled_by"].(sql.NullString)
+		o.CancelledAt = updates["cancelled_at"].(sql.NullTime)
+		o.CancellationNote = updates["cancellation_note"].(sql.NullString)
+	}
+
+	if notifyOnStatus[to] {
+		return EnqueueOrderNotification(tx, consts.NotificationOrderStatus, o, map[string]interface{}{"note": note})
+	}
+
+	return nil
+}
+
+// status yang dikabarkan ke pembeli lewat email (diproses sudah tercakup email pembayaran diterima)
+var notifyOnStatus = map[int]bool{
+	consts.OrderStatusShipped:   true,
+	consts.OrderStatusCompleted: true,
+	consts.OrderStatusCancelled: true,
+	consts.OrderStatusRefunded:  true,
+}
+
+// MarkAsPaid: menandai order sudah dibayar.
+// Kalau order masih pending, otomatis lanjut ke "Diproses".
+func (o *Order) MarkAsPaid(db *gorm.DB, actorID, note string) error {
+	if o.IsPaid() {
+		return ErrOrderAlreadyPaid
+	}
+	if o.IsClosed() {
+		return ErrOrderClosed
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		paidAt := sql.NullTime{Time: now, Valid: true}
+		approvedBy := sql.NullString{String: actorID, Valid: actorID != "" && actorID != consts.OrderActorSystem}
+
+		updates := map[string]interface{}{
+			"paid_at":        paidAt,
+			"payment_status": consts.OrderPaymentStatusPaid,
+			"approved_by":    approvedBy,
+			"approved_at":    paidAt,
+			"updated_at":     now,
+		}
+		if o.InvoiceNumber == "" {
+			invoiceNumber, err := NextSequence(tx, consts.SequenceInvoice, now)
+			if err != nil {
+				return err
+			}
+			updates["invoice_number"] = invoiceNumber
+		}
+
+		res := tx.Model(&Order{}).
+			Where("id = ? AND payment_status <> ?", o.ID, consts.OrderPaymentStatusPaid).
+			Updates(updates)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrOrderAlreadyPaid
+		}
+
+		history := OrderStatusHistory{
+			OrderID:    o.ID,
+			Event:      consts.OrderEventPaid,
+			FromStatus: o.Status,
+			ToStatus:   o.Status,
+			ActorID:    actorID,
+			Note:       note,
+		}
+		if err := tx.Create(&history).Error; err != nil {
+			return err
+		}
+
+		// total transfer order ini boleh dipakai order lain lagi
+		if err := ReleasePaymentUniqueCode(tx, o.ID); err != nil {
+			return err
+		}
+
+		o.PaidAt = paidAt
+		o.PaymentStatus = consts.OrderPaymentStatusPaid
+		if number, ok := updates["invoice_number"].(string); ok {
+			o.InvoiceNumber = number
+		}
+		o.ApprovedBy = approvedBy
+		o.ApprovedAt = paidAt
+
+		if o.Status == consts.OrderStatusPending {
+			if err := o.applyTransition(tx, consts.OrderStatusProcessing, actorID, "Pembayaran diterima"); err != nil {
+				return err
+			}
+		}
+
+		return EnqueueOrderNotification(tx, consts.NotificationPaymentApproved, o, nil)
+	})
+}
+
+// RejectPayment: bukti pembayaran ditolak, order tetap menunggu pembayaran
+func (o *Order) RejectPayment(db *gorm.DB, actorID, note string) error {
+	if o.IsPaid() {
+		return ErrOrderAlreadyPaid
+	}
+	if o.IsClosed() {
+		return ErrOrderClosed
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		// jangan timpa order yang baru saja lunas (webhook / MarkAsPaid bersamaan)
+		res := tx.Model(&Order{}).
+			Where("id = ? AND payment_status <> ?", o.ID, consts.OrderPaymentStatusPaid).
+			Updates(map[string]interface{}{
+				"payment_status": consts.OrderPaymentStatusRejected,
+				"updated_at":     time.Now(),
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrOrderAlreadyPaid
+		}
+
+		history := OrderStatusHistory{
+			OrderID:    o.ID,
+			Event:      consts.OrderEventPaymentRejected,
+			FromStatus: o.Status,
+			ToStatus:   o.Status,
+			ActorID:    actorID,
+			Note:       note,
+		}
+		if err := tx.Create(&history).Error; err != nil {
+			return err
+		}
+
+		o.PaymentStatus = consts.OrderPaymentStatusRejected
+		return EnqueueOrderNotification(tx, consts.NotificationPaymentRejected, o, map[string]interface{}{"note": note})
+	})
+}
+
+// RevertPayment: batalkan tanda lunas yang salah (contoh: mutasi bank salah dipasangkan).
+// Hanya untuk order yang belum dikirim; order yang sudah "Diproses" kembali ke "Pending"
+// dan kode unik transfernya dipesan lagi (diganti baru kalau total transfernya sudah dipakai order lain).
+func (o *Order) RevertPayment(db *gorm.DB, actorID, note string) error {
+	if !o.IsPaid() {
+		return ErrOrderNotPaid
+	}
+	if o.Status != consts.OrderStatusPending && o.Status != consts.OrderStatusProcessing {
+		return ErrOrderPaymentLocked
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		updates := map[string]interface{}{
+			"paid_at":        sql.NullTime{},
+			"payment_status": consts.OrderPaymentStatusUnpaid,
+			"approved_by":    sql.NullString{},
+			"approved_at":    sql.NullTime{},
+			"status":         consts.OrderStatusPending,
+			"updated_at":     now,
+		}
+
+		res := tx.Model(&Order{}).
+			Where("id = ? AND status = ? AND payment_status = ?", o.ID, o.Status, consts.OrderPaymentStatusPaid).
+			Updates(updates)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrOrderStatusChanged
+		}
+
+		history := OrderStatusHistory{
+			OrderID:    o.ID,
+			Event:      consts.OrderEventPaymentReverted,
+			FromStatus: o.Status,
+			ToStatus:   consts.OrderStatusPending,
+			ActorID:    actorID,
+			Note:       note,
+		}
+		if err := tx.Create(&history).Error; err != nil {
+			return err
+		}
+
+		o.Status = consts.OrderStatusPending
+		o.PaymentStatus = consts.OrderPaymentStatusUnpaid
+		o.PaidAt = sql.NullTime{}
+		o.ApprovedBy = sql.NullString{}
+		o.ApprovedAt = sql.NullTime{}
+
+		return reservePaymentUniqueCode(tx, o)
+	})
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// OrderStatusHistory: jejak setiap perubahan status / pembayaran order
+type OrderStatusHistory struct {
+	ID         string `gorm:"size:36;not null;uniqueIndex;primary_key"`
+	OrderID    string `gorm:"size:36;not null;index"`
+	Event      string `gorm:"size:50;not null;index"` // lihat consts.OrderEvent*
+	FromStatus int
+	ToStatus   int
+	ActorID    string `gorm:"size:36;index"` // user / admin id, atau "system"
+	Note       string `gorm:"size:255"`
+	CreatedAt  time.Time
+}
+
+func (h *OrderStatusHistory) BeforeCreate(db *gorm.DB) error {
+	if h.ID == "" {
+		h.ID = uuid.New().String()
+	}
+
+	return nil
+}
+
+// ListByOrderID: riwayat status 1 order, urut dari yang paling lama
+func (h *OrderStatusHistory) ListByOrderID(db *gorm.DB, orderID string) ([]OrderStatusHistory, error) {
+	var histories []OrderStatusHistory
+
+	err := db.Model(&OrderStatusHistory{}).
+		Where("order_id = ?", orderID).
+		Order("created_at asc").
+		Find(&histories).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return histories, nil
+}
+
+func (h OrderStatusHistory) FromStatusText() string {
+	return OrderStatusLabel(h.FromStatus)
+}
+
+func (h OrderStatusHistory) ToStatusText() string {
+	return OrderStatusLabel(h.ToStatus)
+}
+
+func (h OrderStatusHistory) CreatedAtFormatted() string {
+	return h.CreatedAt.Format("02 Jan 2006 15:04")
+}
@@ -2,14 +2,15 @@ package models
 
 import (
 	"encoding/json"
-	"strconv"
-	"strings"
+	"errors"
 	"time"
 
+	"github.com/alirogz/goshop/app/consts"
 	"github.com/google/uuid"
 
 	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Payment struct {
@@ -75,32 +76,15 @@ func (p *Payment) BeforeCreate(db *gorm.DB) error {
 		p.ID = uuid.New().String()
 	}
 
-	p.Number = generatePaymentNumber(db)
-
-	return nil
-}
-
-func generatePaymentNumber(db *gorm.DB) string {
-	now := time.Now()
-	month := now.Month()
-	year := strconv.Itoa(now.Year())
-
-	dateCode := "/PAYMENT/" + intToRoman(int(month)) + "/" + year
-
-	var latestPayment Payment
-
-	err := db.Debug().Order("created_at DESC").Find(&latestPayment).Error
-
-	latestNumber, _ := strconv.Atoi(strings.Split(latestPayment.Number, "/")[0])
-	if err != nil {
-		latestNumber = 1
+	if p.Number == "" {
+		number, err := NextSequence(db, consts.SequencePayment, time.Now())
+		if err != nil {
+			return err
+		}
+		p.Number = number
 	}
 
-	number := latestNumber + 1
-
-	invoiceNumber := strconv.Itoa(number) + dateCode
-
-	return invoiceNumber
+	return nil
 }
 
 func (p *Payment) CreatePayment(db *gorm.DB, payment *Payment) (*Payment, error) {
@@ -111,3 +95,93 @@ func (p *Payment) CreatePayment(db *gorm.DB, payment *Payment) (*Payment, error)
 
 	return payment, nil
 }
+
+// RecordPayment: simpan pembayaran untuk order ini dan (kalau settle) tandai order lunas.
+// Aman dipanggil berulang untuk transaksi yang sama (notifikasi gateway bisa dikirim ulang):
+// payment dicari per transaction_id dan row order dikunci selama proses.
+// changed=false kalau status transaksi tidak berubah dari sebelumnya.
+// Kalau order sudah ditutup, payment tetap disimpan dan ErrOrderClosed dikembalikan.
+func (o *Order) RecordPayment(db *gorm.DB, payment Payment, settle bool, actorID, note string) (changed bool, err error) {
+	closed := false
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var locked Order
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", o.ID).First(&locked).Error
+		if err != nil {
+			return err
+		}
+
+		payment.OrderID = locked.ID
+		if payment.Payload == nil {
+			empty := json.RawMessage(`{}`)
+			payment.Payload = &empty
+		}
+
+		var existing Payment
+		found := false
+		if payment.TransactionID != "" {
+			err := tx.Where("order_id = ? AND transaction_id = ?", locked.ID, payment.TransactionID).First(&existing).Error
+			if err == nil {
+				found = true
+			} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+		}
+
+		switch {
+		case found && existing.TransactionStatus == payment.TransactionStatus:
+			// notifikasi dobel, tidak ada yang berubah
+		case found:
+			err := tx.Model(&existing).Updates(map[string]interface{}{
+				"transaction_status": payment.TransactionStatus,
+				"payment_type":       payment.PaymentType,
+				"amount":             payment.Amount,
+				"payload":            payment.Payload,
+			}).Error
+			if err != nil {
+				return err
+			}
+			changed = true
+		default:
+			if err := tx.Create(&payment).Error; err != nil {
+				return err
+			}
+			changed = true
+		}
+
+		if settle && !locked.IsPaid() && locked.IsClosed() {
+			// order sudah dibatalkan/selesai: payment tetap disimpan untuk ditindaklanjuti admin
+			closed = true
+		} else if settle && !locked.IsPaid() {
+			if err := locked.MarkAsPaid(tx, actorID, note); err != nil {
+				return err
+			}
+			changed = true
+		}
+
+		// relasi yang sudah di-preload di o tetap dipertahankan
+		o.Status = locked.Status
+		o.PaymentStatus = locked.PaymentStatus
+		o.PaidAt = locked.PaidAt
+		o.ApprovedBy = locked.ApprovedBy
+		o.ApprovedAt = locked.ApprovedAt
+		return nil
+	})
+	if err == nil && closed {
+		err = ErrOrderClosed
+	}
+
+	return changed, err
+}
+
+// NextPaymentAttempt: naikkan nomor percobaan bayar online order & kembalikan nomor barunya.
+// Dipakai sebagai akhiran order_id di payment gateway karena Midtrans menolak order_id yang sama dipakai ulang.
+func (o *Order) NextPaymentAttempt(db *gorm.DB) (int, error) {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Order{}).Where("id = ?", o.ID).
+			UpdateColumn("payment_attempt", gorm.Expr("payment_attempt + 1")).Error; err != nil {
+			return err
+		}
+		return tx.Model(&Order{}).Select("payment_attempt").Where("id = ?", o.ID).Row().Scan(&o.PaymentAttempt)
+	})
+	return o.PaymentAttempt, err
+}
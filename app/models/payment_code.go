@@ -0,0 +1,157 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/alirogz/goshop/app/consts"
+	"github.com/alirogz/goshop/app/money"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// rentang kode unik transfer (3 digit)
+	PaymentUniqueCodeMin = 100
+	PaymentUniqueCodeMax = 999
+)
+
+var ErrUniqueCodeExhausted = errors.New("kode unik transfer habis untuk nominal ini")
+
+// PaymentCodeReservation: total transfer (grand total + kode unik) yang sedang dipakai 1 order.
+// Amount adalah primary key, jadi 2 order yang belum dibayar tidak mungkin punya total transfer
+// yang sama walaupun checkout bersamaan. Baris dihapus saat order dibayar / batal / kedaluwarsa.
+type PaymentCodeReservation struct {
+	Amount     int64  `gorm:"primary_key;autoIncrement:false"` // total transfer dalam rupiah penuh
+	OrderID    string `gorm:"size:36;not null;uniqueIndex"`
+	BaseAmount int64  `gorm:"not null;index"` // grand total tanpa kode unik
+	UniqueCode int    `gorm:"not null"`
+	CreatedAt  time.Time
+}
+
+// AllocatePaymentUniqueCode: pilih kode unik 100–999 untuk order supaya total transfernya
+// tidak sama dengan order transfer bank lain yang belum dibayar. Dipanggil di dalam transaksi checkout.
+func AllocatePaymentUniqueCode(tx *gorm.DB, orderID string, grandTotal decimal.Decimal) (int, error) {
+	base := money.Rupiah(grandTotal)
+	low := base + PaymentUniqueCodeMin
+	high := base + PaymentUniqueCodeMax
+
+	used, err := usedPaymentAmounts(tx, low, high)
+	if err != nil {
+		return 0, err
+	}
+
+	free := make([]int, 0, PaymentUniqueCodeMax-PaymentUniqueCodeMin+1)
+	for code := PaymentUniqueCodeMin; code <= PaymentUniqueCodeMax; code++ {
+		if !used[base+int64(code)] {
+			free = append(free, code)
+		}
+	}
+
+	// mulai dari posisi acak supaya kode tidak selalu 100, 101, ...
+	if len(free) > 0 {
+		start := rand.Intn(len(free))
+		for i := range free {
+			code := free[(start+i)%len(free)]
+			reservation := PaymentCodeReservation{
+				Amount:     base + int64(code),
+				OrderID:    orderID,
+				BaseAmount: base,
+				UniqueCode: code,
+			}
+
+			// insert gagal diam-diam kalau nominal baru saja diambil checkout lain → coba kode berikutnya
+			res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&reservation)
+			if res.Error != nil {
+				return 0, res.Error
+			}
+			if res.RowsAffected == 1 {
+				return code, nil
+			}
+		}
+	}
+
+	log.Printf("payment code: semua kode unik %d-%d terpakai untuk nominal %d (order %s)",
+		PaymentUniqueCodeMin, PaymentUniqueCodeMax, base, orderID)
+	return 0, fmt.Errorf("%w (Rp %d)", ErrUniqueCodeExhausted, base)
+}
+
+// ReleasePaymentUniqueCode: bebaskan total transfer order supaya bisa dipakai order lain
+func ReleasePaymentUniqueCode(tx *gorm.DB, orderID string) error {
+	return tx.Where("order_id = ?", orderID).Delete(&PaymentCodeReservation{}).Error
+}
+
+// reservePaymentUniqueCode: pesan ulang total transfer order yang kembali belum dibayar.
+// Kalau nominal itu sudah dipakai order lain, order mendapat kode unik baru (PaymentTotal ikut berubah)
+// supaya tidak ada 2 order terbuka dengan total transfer yang sama. Kode habis → error, transaksi dibatalkan.
+func reservePaymentUniqueCode(tx *gorm.DB, o *Order) error {
+	if o.PaymentUniqueCode == 0 {
+		return nil
+	}
+
+	reservation := PaymentCodeReservation{
+		Amount:     money.Rupiah(o.PaymentTotal),
+		OrderID:    o.ID,
+		BaseAmount: money.Rupiah(o.GrandTotal),
+		UniqueCode: o.PaymentUniqueCode,
+	}
+	res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&reservation)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 1 {
+		return nil
+	}
+
+	code, err := AllocatePaymentUniqueCode(tx, o.ID, o.GrandTotal)
+	if err != nil {
+		return err
+	}
+	total := o.GrandTotal.Add(money.FromInt(int64(code)))
+	err = tx.Model(&Order{}).Where("id = ?", o.ID).Updates(map[string]interface{}{
+		"payment_unique_code": code,
+		"payment_total":       total,
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	log.Printf("payment code: total transfer %d order %s sudah dipakai order lain, diganti %s",
+		reservation.Amount, o.ID, total)
+	o.PaymentUniqueCode = code
+	o.PaymentTotal = total
+	return nil
+}
+
+// usedPaymentAmounts: total transfer di rentang [low, high] yang sedang dipakai.
+// Selain tabel reservasi, order lama (dibuat sebelum tabel ini ada) yang masih menunggu transfer juga ikut dihitung.
+func usedPaymentAmounts(tx *gorm.DB, low, high int64) (map[int64]bool, error) {
+	used := map[int64]bool{}
+
+	var reserved []int64
+	if err := tx.Model(&PaymentCodeReservation{}).
+		Where("amount BETWEEN ? AND ?", low, high).
+		Pluck("amount", &reserved).Error; err != nil {
+		return nil, err
+	}
+	for _, amount := range reserved {
+		used[amount] = true
+	}
+
+	var totals []decimal.Decimal
+	if err := tx.Model(&Order{}).
+		Where("status = ? AND payment_status <> ? AND payment_method = ?", consts.OrderStatusPending, consts.OrderPaymentStatusPaid, consts.OrderPaymentMethodBankTransfer).
+		Where("payment_total BETWEEN ? AND ?", low, high).
+		Pluck("payment_total", &totals).Error; err != nil {
+		return nil, err
+	}
+	for _, total := range totals {
+		used[money.Rupiah(total)] = true
+	}
+
+	return used, nil
+}
@@ -0,0 +1,171 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/alirogz/goshop/app/consts"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrPaymentProofReviewed = errors.New("bukti bayar ini sudah dicek sebelumnya")
+
+// PaymentProof: 1 bukti transfer untuk order (boleh lebih dari 1 per order, masing-masing dicek terpisah).
+// File disimpan privat dan hanya bisa dibuka lewat /orders/{id}/payment-proof/{proofID}
+// oleh pemilik order atau staff keuangan.
+type PaymentProof struct {
+	ID          string `gorm:"size:36;not null;uniqueIndex;primary_key"`
+	OrderID     string `gorm:"size:36;not null;index"`
+	UploadedBy  string `gorm:"size:36"`           // user / staff yang mengunggah
+	Source      string `gorm:"size:20;not null"`  // consts.PaymentProofSource*
+	FileName    string `gorm:"size:255;not null"` // nama asli dari user, hanya untuk tampilan
+	ContentType string `gorm:"size:100;not null"` // hasil deteksi isi file
+	Size        int64
+	StoragePath string         `gorm:"size:255;not null" json:"-"`
+	ThumbPath   string         `gorm:"size:255" json:"-"`
+	Status      string         `gorm:"size:20;not null;index"` // consts.PaymentProof*
+	ReviewedBy  sql.NullString `gorm:"size:36"`
+	ReviewedAt  sql.NullTime
+	ReviewNote  string `gorm:"size:255"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (p *PaymentProof) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == "" {
+		p.ID = uuid.New().String()
+	}
+	if p.Status == "" {
+		p.Status = consts.PaymentProofPending
+	}
+	if p.Source == "" {
+		p.Source = consts.PaymentProofSourceUpload
+	}
+	return nil
+}
+
+// URL: alamat unduhan (cek akses di handler), ThumbURL kosong kalau tidak ada thumbnail
+func (p PaymentProof) URL() string {
+	return "/orders/" + p.OrderID + "/payment-proof/" + p.ID
+}
+
+func (p PaymentProof) ThumbURL() string {
+	if p.ThumbPath == "" {
+		return ""
+	}
+	return p.URL() + "?thumb=1"
+}
+
+func (p PaymentProof) IsImage() bool {
+	return strings.HasPrefix(p.ContentType, "image/")
+}
+
+func (p PaymentProof) IsPending() bool {
+	return p.Status == consts.PaymentProofPending
+}
+
+func (p PaymentProof) StatusText() string {
+	switch p.Status {
+	case consts.PaymentProofApproved:
+		return "Disetujui"
+	case consts.PaymentProofRejected:
+		return "Ditolak"
+	}
+	return "Menunggu dicek"
+}
+
+// OrderPaymentProofs: urutan bukti bayar (terbaru dulu), juga untuk Preload("PaymentProofs", ...)
+func OrderPaymentProofs(db *gorm.DB) *gorm.DB {
+	return db.Order("created_at desc")
+}
+
+// ListPaymentProofs: semua bukti bayar 1 order, terbaru dulu
+func ListPaymentProofs(db *gorm.DB, orderID string) ([]PaymentProof, error) {
+	var proofs []PaymentProof
+	err := OrderPaymentProofs(db).Where("order_id = ?", orderID).Find(&proofs).Error
+	return proofs, err
+}
+
+// FindPaymentProof: bukti bayar milik order; proofID kosong = bukti terbaru
+func FindPaymentProof(db *gorm.DB, orderID, proofID string) (*PaymentProof, error) {
+	query := OrderPaymentProofs(db).Where("order_id = ?", orderID)
+	if proofID != "" {
+		query = query.Where("id = ?", proofID)
+	}
+
+	var proof PaymentProof
+	if err := query.First(&proof).Error; err != nil {
+		return nil, err
+	}
+	return &proof, nil
+}
+
+// AddPaymentProof: simpan bukti bayar baru, order jadi "menunggu dicek"
+func (o *Order) AddPaymentProof(db *gorm.DB, proof *PaymentProof) error {
+	if o.IsPaid() {
+		return ErrOrderAlreadyPaid
+	}
+	if o.IsClosed() {
+		return ErrOrderClosed
+	}
+
+	proof.OrderID = o.ID
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(proof).Error; err != nil {
+			return err
+		}
+		err := tx.Model(&Order{}).Where("id = ?", o.ID).Updates(map[string]interface{}{
+			"payment_status": consts.OrderPaymentStatusWaitingReview,
+			"updated_at":     time.Now(),
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		o.PaymentStatus = consts.OrderPaymentStatusWaitingReview
+		return nil
+	})
+}
+
+// ReviewPaymentProof: setujui / tolak 1 bukti bayar (status consts.PaymentProofApproved / Rejected).
+// Disetujui → order lunas. Ditolak → pembayaran order ikut ditolak kalau tidak ada bukti lain yang masih menunggu.
+// Order yang sudah lunas (mis. lewat rekonsiliasi bank) hanya status buktinya yang berubah.
+func (o *Order) ReviewPaymentProof(db *gorm.DB, proofID, status, actorID, note string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		res := tx.Model(&PaymentProof{}).
+			Where("id = ? AND order_id = ? AND status = ?", proofID, o.ID, consts.PaymentProofPending).
+			Updates(map[string]interface{}{
+				"status":      status,
+				"reviewed_by": sql.NullString{String: actorID, Valid: actorID != ""},
+				"reviewed_at": sql.NullTime{Time: now, Valid: true},
+				"review_note": note,
+				"updated_at":  now,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrPaymentProofReviewed
+		}
+
+		if o.IsPaid() {
+			return nil
+		}
+		if status == consts.PaymentProofApproved {
+			return o.MarkAsPaid(tx, actorID, "Bukti pembayaran disetujui")
+		}
+
+		var pending int64
+		err := tx.Model(&PaymentProof{}).
+			Where("order_id = ? AND status = ?", o.ID, consts.PaymentProofPending).
+			Count(&pending).Error
+		if err != nil || pending > 0 {
+			return err
+		}
+		return o.RejectPayment(tx, actorID, note)
+	})
+}
@@ -14,6 +14,7 @@ type Product struct {
 	User             User
 	UserID           string `gorm:"size:36;index"`
 	ProductImages    []ProductImage
+	Variants         []ProductVariant
 	Categories       []Category      `gorm:"many2many:product_categories;"`
 	Sku              string          `gorm:"size:100;index"`
 	Name             string          `gorm:"size:255"`
@@ -23,6 +24,7 @@ type Product struct {
 	SizeOptions      string          `gorm:"column:size_options"`  // contoh: "S,M,L,XL"
 	ColorOptions     string          `gorm:"column:color_options"` // contoh: "Hitam,Putih"
 	Weight           decimal.Decimal `gorm:"type:decimal(10,2);"`
+	TaxClass         string          `gorm:"size:20;not null;default:'taxable'"` // lihat consts.TaxClass*
 	ShortDescription string          `gorm:"type:text"`
 	Description      string          `gorm:"type:text"`
 	Status           int             `gorm:"default:0"`
@@ -56,7 +58,8 @@ func (p *Product) FindBySlug(db *gorm.DB, slug string) (*Product, error) {
 	var err error
 	var product Product
 
-	err = db.Debug().Preload("ProductImages").Model(&Product{}).Where("slug = ?", slug).First(&product).Error
+	err = db.Debug().Preload("ProductImages", OrderProductImages).Preload("Variants", orderVariants).
+		Model(&Product{}).Where("slug = ?", slug).First(&product).Error
 	if err != nil {
 		return nil, err
 	}
@@ -68,7 +71,8 @@ func (p *Product) FindByID(db *gorm.DB, productID string) (*Product, error) {
 	var err error
 	var product Product
 
-	err = db.Debug().Preload("ProductImages").Model(&Product{}).Where("id = ?", productID).First(&product).Error
+	err = db.Debug().Preload("ProductImages", OrderProductImages).Preload("Variants", orderVariants).
+		Model(&Product{}).Where("id = ?", productID).First(&product).Error
 	if err != nil {
 		return nil, err
 	}
@@ -90,7 +94,7 @@ func (p *Product) GetLatestProductsWithImages(db *gorm.DB, limit int) ([]Product
 	var products []Product
 
 	err := db.
-		Preload("ProductImages"). // PENTING: preload relasi gambar
+		Preload("ProductImages", OrderProductImages). // PENTING: preload relasi gambar
 		Order("created_at desc").
 		Limit(limit).
 		Find(&products).Error
@@ -98,32 +102,79 @@ func (p *Product) GetLatestProductsWithImages(db *gorm.DB, limit int) ([]Product
 	return products, err
 }
 
-func (p *Product) SizeList() []string {
-	if p.SizeOptions == "" {
-		return []string{"S", "M", "L", "XL"} // fallback
+// PrimaryImage: gambar utama dari galeri (ProductImages harus sudah di-preload), nil kalau galeri kosong
+func (p Product) PrimaryImage() *ProductImage {
+	for i := range p.ProductImages {
+		if p.ProductImages[i].IsPrimary {
+			return &p.ProductImages[i]
+		}
 	}
-	parts := strings.Split(p.SizeOptions, ",")
-	var out []string
-	for _, s := range parts {
-		trimmed := strings.TrimSpace(s)
-		if trimmed != "" {
-			out = append(out, trimmed)
+	if len(p.ProductImages) > 0 {
+		return &p.ProductImages[0]
+	}
+	return nil
+}
+
+// ImageURL: gambar utama ukuran size (consts.ImageSize*). Produk lama tanpa galeri memakai kolom Image
+// (file asli hasil upload versi lama); kosong kalau tidak ada gambar sama sekali.
+func (p Product) ImageURL(size string) string {
+	if img := p.PrimaryImage(); img != nil {
+		return img.URL(size)
+	}
+	if p.Image != "" {
+		return "/uploads/" + p.Image
+	}
+	return ""
+}
+
+func orderVariants(db *gorm.DB) *gorm.DB {
+	return db.Order("position asc, created_at asc")
+}
+
+// HasVariants: true kalau produk dijual per varian (Variants harus sudah di-preload)
+func (p Product) HasVariants() bool {
+	return len(p.Variants) > 0
+}
+
+// SizeList: ukuran dari varian; kalau belum pakai varian, dari SizeOptions
+func (p Product) SizeList() []string {
+	if p.HasVariants() {
+		var sizes []string
+		for _, v := range p.Variants {
+			sizes = append(sizes, v.Size)
 		}
+		return uniqueOptions(sizes)
 	}
-	return out
+	return splitOptions(p.SizeOptions)
 }
 
-func (p *Product) ColorList() []string {
-	if p.ColorOptions == "" {
-		return nil
+// ColorList: warna dari varian; kalau belum pakai varian, dari ColorOptions
+func (p Product) ColorList() []string {
+	if p.HasVariants() {
+		var colors []string
+		for _, v := range p.Variants {
+			colors = append(colors, v.Color)
+		}
+		return uniqueOptions(colors)
 	}
-	parts := strings.Split(p.ColorOptions, ",")
+	return splitOptions(p.ColorOptions)
+}
+
+// "S, M,,L" -> [S M L]
+func splitOptions(raw string) []string {
+	return uniqueOptions(strings.Split(raw, ","))
+}
+
+func uniqueOptions(values []string) []string {
 	var out []string
-	for _, s := range parts {
+	seen := map[string]bool{}
+	for _, s := range values {
 		trimmed := strings.TrimSpace(s)
-		if trimmed != "" {
-			out = append(out, trimmed)
+		if trimmed == "" || seen[trimmed] {
+			continue
 		}
+		seen[trimmed] = true
+		out = append(out, trimmed)
 	}
 	return out
 }
@@ -1,9 +1,16 @@
 package models
 
 import (
+	"sort"
 	"time"
+
+	"github.com/alirogz/goshop/app/consts"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
+// ProductImage: 1 foto di galeri produk. Path = file asli, kolom lain = versi yang diperkecil
+// (lihat consts.ProductImage*). Semua path relatif terhadap folder upload (/uploads/...).
 type ProductImage struct {
 	ID         string `gorm:"size:36;not null;uniqueIndex;primary_key"`
 	Product    Product
@@ -13,6 +20,134 @@ type ProductImage struct {
 	Large      string `gorm:"type:text"`
 	Medium     string `gorm:"type:text"`
 	Small      string `gorm:"type:text"`
+	Position   int
+	IsPrimary  bool `gorm:"not null;default:false"`
 	CreatedAt  time.Time
 	UpdatedAt  time.Time
 }
+
+func (i *ProductImage) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == "" {
+		i.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// URL: alamat gambar ukuran size (consts.ImageSize*); ukuran yang kosong turun ke versi lebih besar
+func (i ProductImage) URL(size string) string {
+	candidates := []string{i.Small, i.Medium, i.Large, i.ExtraLarge, i.Path}
+	switch size {
+	case consts.ImageSizeMedium:
+		candidates = candidates[1:]
+	case consts.ImageSizeLarge:
+		candidates = candidates[2:]
+	case consts.ImageSizeExtraLarge:
+		candidates = candidates[3:]
+	case consts.ImageSizeOriginal:
+		candidates = candidates[4:]
+	}
+
+	for _, p := range candidates {
+		if p != "" {
+			return "/uploads/" + p
+		}
+	}
+	return ""
+}
+
+// Files: semua file milik gambar ini (untuk dihapus)
+func (i ProductImage) Files() []string {
+	var files []string
+	for _, p := range []string{i.Path, i.ExtraLarge, i.Large, i.Medium, i.Small} {
+		if p != "" {
+			files = append(files, p)
+		}
+	}
+	return files
+}
+
+// ListProductImages: galeri produk sesuai urutan
+func ListProductImages(db *gorm.DB, productID string) ([]ProductImage, error) {
+	var images []ProductImage
+	err := OrderProductImages(db).Where("product_id = ?", productID).Find(&images).Error
+	return images, err
+}
+
+// OrderProductImages: urutan galeri, juga untuk Preload, mis. db.Preload("ProductImages", models.OrderProductImages)
+func OrderProductImages(db *gorm.DB) *gorm.DB {
+	return db.Order("position asc, created_at asc")
+}
+
+// ArrangeProductImages: simpan urutan galeri sesuai orderedIDs (id yang tidak disebut ditaruh di belakang)
+// dan tandai primaryID sebagai gambar utama. Kalau primaryID tidak valid, gambar pertama jadi utama.
+func ArrangeProductImages(tx *gorm.DB, productID string, orderedIDs []string, primaryID string) error {
+	images, err := ListProductImages(tx, productID)
+	if err != nil {
+		return err
+	}
+
+	rank := map[string]int{}
+	for i, id := range orderedIDs {
+		if _, ok := rank[id]; !ok {
+			rank[id] = i
+		}
+	}
+	sort.SliceStable(images, func(i, j int) bool {
+		ri, okI := rank[images[i].ID]
+		rj, okJ := rank[images[j].ID]
+		if okI && okJ {
+			return ri < rj
+		}
+		return okI && !okJ
+	})
+
+	hasPrimary := false
+	for _, img := range images {
+		if img.ID == primaryID {
+			hasPrimary = true
+		}
+	}
+
+	for i, img := range images {
+		primary := img.ID == primaryID || (!hasPrimary && i == 0)
+		if img.Position == i && img.IsPrimary == primary {
+			continue
+		}
+		err := tx.Model(&ProductImage{}).Where("id = ?", img.ID).
+			Updates(map[string]interface{}{"position": i, "is_primary": primary}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteProductImages: hapus baris gambar ids milik produk (ids kosong = tidak ada yang dihapus).
+// Mengembalikan baris yang dihapus supaya filenya bisa dihapus setelah transaksi berhasil.
+func DeleteProductImages(tx *gorm.DB, productID string, ids []string) ([]ProductImage, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return deleteProductImages(tx.Where("product_id = ? AND id IN ?", productID, ids))
+}
+
+// DeleteAllProductImages: hapus seluruh galeri produk (produk dihapus)
+func DeleteAllProductImages(tx *gorm.DB, productID string) ([]ProductImage, error) {
+	return deleteProductImages(tx.Where("product_id = ?", productID))
+}
+
+func deleteProductImages(q *gorm.DB) ([]ProductImage, error) {
+	var images []ProductImage
+	if err := q.Find(&images).Error; err != nil {
+		return nil, err
+	}
+	if len(images) == 0 {
+		return images, nil
+	}
+
+	ids := make([]string, len(images))
+	for i, img := range images {
+		ids[i] = img.ID
+	}
+	return images, q.Session(&gorm.Session{NewDB: true}).Where("id IN ?", ids).Delete(&ProductImage{}).Error
+}
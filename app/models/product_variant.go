@@ -0,0 +1,242 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/alirogz/goshop/app/consts"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrVariantRequired  = errors.New("silakan pilih varian produk")
+	ErrVariantNotFound  = errors.New("varian produk tidak ditemukan / sudah dihapus")
+	ErrVariantDuplicate = errors.New("kombinasi ukuran & warna varian tidak boleh dobel")
+	ErrVariantEmpty     = errors.New("varian wajib punya ukuran atau warna")
+)
+
+// ProductVariant: 1 kombinasi ukuran/warna dari sebuah produk (menggantikan ide Product.ParentID).
+// Stok, SKU, harga & berat bisa beda per varian; Product.Stock = total stok semua varian.
+type ProductVariant struct {
+	ID        string              `gorm:"size:36;not null;uniqueIndex;primary_key"`
+	ProductID string              `gorm:"size:36;not null;index"`
+	Sku       string              `gorm:"size:100;index"`
+	Size      string              `gorm:"size:20"`
+	Color     string              `gorm:"size:50"`
+	Price     decimal.NullDecimal `gorm:"type:decimal(16,2)"` // kosong = ikut harga produk
+	Stock     int
+	Weight    decimal.NullDecimal `gorm:"type:decimal(10,2)"` // kosong = ikut berat produk
+	Position  int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt
+}
+
+// ProductVariantInput: 1 baris dari matrix varian di form admin
+type ProductVariantInput struct {
+	ID     string
+	Size   string
+	Color  string
+	Sku    string
+	Price  decimal.NullDecimal
+	Weight decimal.NullDecimal
+	Stock  int
+	Delete bool
+}
+
+func (v *ProductVariant) BeforeCreate(db *gorm.DB) error {
+	if v.ID == "" {
+		v.ID = uuid.New().String()
+	}
+
+	return nil
+}
+
+// Label: "M / Hitam"
+func (v ProductVariant) Label() string {
+	var parts []string
+	if v.Size != "" {
+		parts = append(parts, v.Size)
+	}
+	if v.Color != "" {
+		parts = append(parts, v.Color)
+	}
+	return strings.Join(parts, " / ")
+}
+
+// PriceFor: harga varian, atau harga produk kalau varian tidak override
+func (v ProductVariant) PriceFor(product Product) decimal.Decimal {
+	if v.Price.Valid {
+		return v.Price.Decimal
+	}
+	return product.Price
+}
+
+// WeightFor: berat varian, atau berat produk kalau varian tidak override
+func (v ProductVariant) WeightFor(product Product) decimal.Decimal {
+	if v.Weight.Valid {
+		return v.Weight.Decimal
+	}
+	return product.Weight
+}
+
+// SkuFor: SKU varian, atau SKU produk kalau kosong
+func (v ProductVariant) SkuFor(product Product) string {
+	if v.Sku != "" {
+		return v.Sku
+	}
+	return product.Sku
+}
+
+func (v ProductVariant) key() string {
+	return strings.ToLower(v.Size) + "|" + strings.ToLower(v.Color)
+}
+
+// ListByProductID: semua varian aktif 1 produk
+func (v *ProductVariant) ListByProductID(db *gorm.DB, productID string) ([]ProductVariant, error) {
+	var variants []ProductVariant
+
+	err := db.Model(&ProductVariant{}).
+		Where("product_id = ?", productID).
+		Order("position asc, created_at asc").
+		Find(&variants).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return variants, nil
+}
+
+// CountProductVariants: jumlah varian aktif 1 produk
+func CountProductVariants(db *gorm.DB, productID string) (int64, error) {
+	var count int64
+	err := db.Model(&ProductVariant{}).Where("product_id = ?", productID).Count(&count).Error
+	return count, err
+}
+
+// ResolveProductVariant: cari varian yang dipilih pembeli.
+// Produk tanpa varian → (nil, nil). Produk bervarian wajib punya varian yang cocok.
+func ResolveProductVariant(db *gorm.DB, productID, variantID, size, color string) (*ProductVariant, error) {
+	count, err := CountProductVariants(db, productID)
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, nil
+	}
+
+	q := db.Model(&ProductVariant{}).Where("product_id = ?", productID)
+	if variantID != "" {
+		q = q.Where("id = ?", variantID)
+	} else {
+		if size == "" && color == "" {
+			return nil, ErrVariantRequired
+		}
+		q = q.Where("size = ? AND color = ?", size, color)
+	}
+
+	var variant ProductVariant
+	if err := q.First(&variant).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrVariantNotFound
+		}
+		return nil, err
+	}
+
+	return &variant, nil
+}
+
+// SyncProductVariants: simpan matrix varian dari form admin (tambah / ubah / hapus).
+// Perubahan stok lewat SetStock supaya tercatat di ledger. Wajib dipanggil di dalam transaksi.
+func SyncProductVariants(tx *gorm.DB, productID string, inputs []ProductVariantInput, reason, actorID, note string) error {
+	var existing []ProductVariant
+	if err := tx.Where("product_id = ?", productID).Find(&existing).Error; err != nil {
+		return err
+	}
+
+	byID := make(map[string]*ProductVariant, len(existing))
+	for i := range existing {
+		byID[existing[i].ID] = &existing[i]
+	}
+
+	// validasi dulu sebelum ada yang ditulis
+	seen := map[string]bool{}
+	keep := 0
+	for i := range inputs {
+		in := &inputs[i]
+		in.Size = strings.TrimSpace(in.Size)
+		in.Color = strings.TrimSpace(in.Color)
+		in.Sku = strings.TrimSpace(in.Sku)
+
+		if in.ID != "" && byID[in.ID] == nil {
+			return ErrVariantNotFound
+		}
+		if in.Delete {
+			continue
+		}
+		if in.Size == "" && in.Color == "" {
+			return ErrVariantEmpty
+		}
+
+		key := ProductVariant{Size: in.Size, Color: in.Color}.key()
+		if seen[key] {
+			return ErrVariantDuplicate
+		}
+		seen[key] = true
+		keep++
+	}
+
+	// produk yang baru pertama kali punya varian: stok level produk dinolkan dulu,
+	// setelah ini Product.Stock = jumlah stok varian
+	if len(existing) == 0 && keep > 0 {
+		if err := SetStock(tx, productID, "", 0, consts.InventoryReasonAdjustment, actorID, "Stok dipindah ke varian"); err != nil {
+			return err
+		}
+	}
+
+	position := 0
+	for _, in := range inputs {
+		if in.Delete {
+			if in.ID == "" {
+				continue
+			}
+			if err := SetStock(tx, productID, in.ID, 0, consts.InventoryReasonAdjustment, actorID, "Varian dihapus"); err != nil {
+				return err
+			}
+			if err := tx.Delete(byID[in.ID]).Error; err != nil {
+				return err
+			}
+			continue
+		}
+
+		position++
+		variant := byID[in.ID]
+		if variant == nil {
+			variant = &ProductVariant{ProductID: productID}
+		}
+
+		variant.Size = in.Size
+		variant.Color = in.Color
+		variant.Sku = in.Sku
+		variant.Price = in.Price
+		variant.Weight = in.Weight
+		variant.Position = position
+
+		if variant.ID == "" {
+			if err := tx.Create(variant).Error; err != nil {
+				return err
+			}
+		} else if err := tx.Omit("stock").Save(variant).Error; err != nil {
+			return err
+		}
+
+		if err := SetStock(tx, productID, variant.ID, in.Stock, reason, actorID, note); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,487 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alirogz/goshop/app/consts"
+	"github.com/alirogz/goshop/app/money"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrCouponNotFound      = errors.New("kode kupon tidak ditemukan")
+	ErrCouponInactive      = errors.New("kupon sudah tidak aktif")
+	ErrCouponNotStarted    = errors.New("kupon belum bisa dipakai")
+	ErrCouponExpired       = errors.New("kupon sudah kedaluwarsa")
+	ErrCouponUsageLimit    = errors.New("kuota kupon sudah habis")
+	ErrCouponCustomerLimit = errors.New("kupon sudah pernah kamu pakai")
+	ErrCouponNotEligible   = errors.New("tidak ada produk di keranjang yang memenuhi syarat kupon")
+	ErrCouponLoginRequired = errors.New("silakan login untuk memakai kupon")
+)
+
+// Promotion: kupon (Code terisi) atau aturan otomatis keranjang (Code kosong).
+// Contoh aturan otomatis: "beli 2 produk kategori X diskon 10%" → MinQty=2, CategoryID=X, Type=percent, Value=10.
+type Promotion struct {
+	ID               string          `gorm:"size:36;not null;uniqueIndex;primary_key"`
+	Name             string          `gorm:"size:150;not null"`
+	Code             string          `gorm:"size:50;index"` // kosong = aturan otomatis
+	Description      string          `gorm:"type:text"`
+	Type             string          `gorm:"size:20;not null"`   // lihat consts.PromotionType*
+	Value            decimal.Decimal `gorm:"type:decimal(16,2)"` // persen / nominal / batas ongkir (0 = gratis penuh)
+	MaxDiscount      decimal.Decimal `gorm:"type:decimal(16,2)"` // batas potongan tipe persen (0 = tanpa batas)
+	MinSpend         decimal.Decimal `gorm:"type:decimal(16,2)"` // minimal subtotal keranjang
+	MinQty           int             // minimal qty produk yang memenuhi syarat
+	CategoryID       string          `gorm:"size:36;index"` // kosong = semua produk
+	Category         Category
+	UsageLimit       int // 0 = tanpa batas
+	UsagePerCustomer int // 0 = tanpa batas
+	UsedCount        int
+	StartsAt         sql.NullTime
+	EndsAt           sql.NullTime
+	IsActive         bool
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	DeletedAt        gorm.DeletedAt
+}
+
+// PromotionLine: 1 baris keranjang yang dinilai oleh mesin promo
+type PromotionLine struct {
+	ProductID   string
+	CategoryIDs []string
+	Qty         int
+	Total       decimal.Decimal // harga dasar x qty (sebelum pajak)
+}
+
+// AppliedPromotion: promo yang berlaku untuk 1 keranjang
+type AppliedPromotion struct {
+	Promotion    Promotion
+	Amount       decimal.Decimal // potongan harga barang
+	FreeShipping bool
+}
+
+// CartPromotionResult: hasil perhitungan semua promo untuk 1 keranjang
+type CartPromotionResult struct {
+	Applied       []AppliedPromotion
+	ItemDiscount  decimal.Decimal
+	LineDiscounts []decimal.Decimal // ItemDiscount yang dibagi ke tiap baris (urutan sama dengan lines)
+}
+
+func (p *Promotion) BeforeCreate(db *gorm.DB) error {
+	if p.ID == "" {
+		p.ID = uuid.New().String()
+	}
+
+	return nil
+}
+
+func (p *Promotion) BeforeSave(db *gorm.DB) error {
+	p.Code = NormalizeCouponCode(p.Code)
+	return nil
+}
+
+// NormalizeCouponCode: kode kupon tidak case-sensitive
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func (p Promotion) IsCoupon() bool {
+	return p.Code != ""
+}
+
+func (p Promotion) TypeText() string {
+	switch p.Type {
+	case consts.PromotionTypePercent:
+		return "Diskon %"
+	case consts.PromotionTypeFixed:
+		return "Potongan harga"
+	case consts.PromotionTypeFreeShipping:
+		return "Gratis ongkir"
+	default:
+		return p.Type
+	}
+}
+
+// ValueText: "10%", "Rp 25000", "Gratis ongkir"
+func (p Promotion) ValueText() string {
+	switch p.Type {
+	case consts.PromotionTypePercent:
+		return p.Value.String() + "%"
+	case consts.PromotionTypeFreeShipping:
+		if p.Value.IsPositive() {
+			return "Gratis ongkir s.d. Rp " + p.Value.StringFixed(0)
+		}
+		return "Gratis ongkir"
+	default:
+		return "Rp " + p.Value.StringFixed(0)
+	}
+}
+
+func (p Promotion) PeriodText() string {
+	format := func(t sql.NullTime) string {
+		if !t.Valid {
+			return "…"
+		}
+		return t.Time.Format("02 Jan 2006")
+	}
+	if !p.StartsAt.Valid && !p.EndsAt.Valid {
+		return "Tanpa batas waktu"
+	}
+	return format(p.StartsAt) + " – " + format(p.EndsAt)
+}
+
+// checkAvailable: aktif, dalam periode & kuota total masih ada
+func (p Promotion) checkAvailable(now time.Time) error {
+	if !p.IsActive {
+		return ErrCouponInactive
+	}
+	if p.StartsAt.Valid && now.Before(p.StartsAt.Time) {
+		return ErrCouponNotStarted
+	}
+	if p.EndsAt.Valid && now.After(p.EndsAt.Time) {
+		return ErrCouponExpired
+	}
+	if p.UsageLimit > 0 && p.UsedCount >= p.UsageLimit {
+		return ErrCouponUsageLimit
+	}
+	return nil
+}
+
+// eligible: baris masuk kategori promo
+func (p Promotion) eligible(line PromotionLine) bool {
+	return p.CategoryID == "" || containsString(line.CategoryIDs, p.CategoryID)
+}
+
+// eligibleLines: total qty & nominal baris yang masuk kategori promo
+func (p Promotion) eligibleLines(lines []PromotionLine) (int, decimal.Decimal) {
+	qty := 0
+	total := decimal.Zero
+	for _, line := range lines {
+		if !p.eligible(line) {
+			continue
+		}
+		qty += line.Qty
+		total = total.Add(line.Total)
+	}
+	return qty, total
+}
+
+// Evaluate: hitung potongan promo untuk isi keranjang (tanpa cek kuota per customer)
+func (p Promotion) Evaluate(lines []PromotionLine, subtotal decimal.Decimal, now time.Time) (AppliedPromotion, error) {
+	applied := AppliedPromotion{Promotion: p, Amount: decimal.Zero}
+
+	if err := p.checkAvailable(now); err != nil {
+		return applied, err
+	}
+	if p.MinSpend.IsPositive() && subtotal.LessThan(p.MinSpend) {
+		return applied, fmt.Errorf("minimal belanja Rp %s untuk promo %s", p.MinSpend.StringFixed(0), p.Name)
+	}
+
+	qty, total := p.eligibleLines(lines)
+	if qty == 0 || (p.MinQty > 0 && qty < p.MinQty) {
+		return applied, ErrCouponNotEligible
+	}
+
+	switch p.Type {
+	case consts.PromotionTypePercent:
+		applied.Amount = money.PercentOf(total, p.Value)
+		if p.MaxDiscount.IsPositive() && applied.Amount.GreaterThan(p.MaxDiscount) {
+			applied.Amount = p.MaxDiscount
+		}
+	case consts.PromotionTypeFixed:
+		applied.Amount = decimal.Min(money.Round(p.Value), total)
+	case consts.PromotionTypeFreeShipping:
+		applied.FreeShipping = true
+	}
+
+	return applied, nil
+}
+
+// ShippingDiscount: potongan ongkir dari promo gratis ongkir yang berlaku
+func (r CartPromotionResult) ShippingDiscount(fee decimal.Decimal) decimal.Decimal {
+	best := decimal.Zero
+	for _, a := range r.Applied {
+		if !a.FreeShipping {
+			continue
+		}
+		amount := money.Round(fee)
+		if a.Promotion.Value.IsPositive() && a.Promotion.Value.LessThan(amount) {
+			amount = money.Round(a.Promotion.Value)
+		}
+		if amount.GreaterThan(best) {
+			best = amount
+		}
+	}
+	return best
+}
+
+// FreeShippingCap: batas potongan ongkir terbesar (0 = gratis penuh), dipakai untuk hitung di halaman cart
+func (r CartPromotionResult) FreeShippingCap() decimal.Decimal {
+	best := decimal.Zero
+	for _, a := range r.Applied {
+		if !a.FreeShipping {
+			continue
+		}
+		if !a.Promotion.Value.IsPositive() {
+			return decimal.Zero
+		}
+		if a.Promotion.Value.GreaterThan(best) {
+			best = a.Promotion.Value
+		}
+	}
+	return best
+}
+
+func (r CartPromotionResult) HasFreeShipping() bool {
+	for _, a := range r.Applied {
+		if a.FreeShipping {
+			return true
+		}
+	}
+	return false
+}
+
+// EvaluateCartPromotions: aturan otomatis terbaik + aturan gratis ongkir otomatis + 1 kupon.
+// Error kupon dikembalikan terpisah supaya aturan otomatis tetap berlaku.
+// userID kosong = tanpa cek batas pemakaian per customer (misal keranjang tamu).
+func EvaluateCartPromotions(db *gorm.DB, lines []PromotionLine, couponCode, userID string) (CartPromotionResult, error) {
+	result := CartPromotionResult{ItemDiscount: decimal.Zero}
+	now := time.Now()
+
+	subtotal := decimal.Zero
+	for _, line := range lines {
+		subtotal = subtotal.Add(line.Total)
+	}
+
+	var rules []Promotion
+	if err := db.Where("code = ? AND is_active = ?", "", true).Find(&rules).Error; err != nil {
+		return result, err
+	}
+
+	var bestRule *AppliedPromotion
+	for _, rule := range rules {
+		applied, err := rule.Evaluate(lines, subtotal, now)
+		if err != nil {
+			continue
+		}
+		if applied.FreeShipping {
+			result.Applied = append(result.Applied, applied)
+			continue
+		}
+		if bestRule == nil || applied.Amount.GreaterThan(bestRule.Amount) {
+			a := applied
+			bestRule = &a
+		}
+	}
+	if bestRule != nil && bestRule.Amount.IsPositive() {
+		result.Applied = append(result.Applied, *bestRule)
+	}
+
+	var couponErr error
+	if code := NormalizeCouponCode(couponCode); code != "" {
+		var applied AppliedPromotion
+		applied, couponErr = evaluateCoupon(db, code, userID, lines, subtotal, now)
+		if couponErr == nil {
+			result.Applied = append(result.Applied, applied)
+		}
+	}
+
+	result.allocate(lines)
+
+	sort.SliceStable(result.Applied, func(i, j int) bool {
+		return !result.Applied[i].Promotion.IsCoupon() && result.Applied[j].Promotion.IsCoupon()
+	})
+
+	return result, couponErr
+}
+
+// allocate: bagi potongan tiap promo ke baris yang memenuhi syarat (sebanding nominal baris),
+// supaya pajak dihitung dari harga setelah diskon. Potongan 1 baris tidak boleh melebihi nominal barisnya.
+func (r *CartPromotionResult) allocate(lines []PromotionLine) {
+	r.ItemDiscount = decimal.Zero
+	r.LineDiscounts = make([]decimal.Decimal, len(lines))
+	for i := range r.LineDiscounts {
+		r.LineDiscounts[i] = decimal.Zero
+	}
+
+	for _, a := range r.Applied {
+		if !a.Amount.IsPositive() {
+			continue
+		}
+		weights := make([]decimal.Decimal, len(lines))
+		for i, line := range lines {
+			weights[i] = decimal.Zero
+			if a.Promotion.eligible(line) {
+				weights[i] = line.Total
+			}
+		}
+		for i, share := range money.Allocate(a.Amount, weights) {
+			r.LineDiscounts[i] = r.LineDiscounts[i].Add(share)
+		}
+	}
+
+	for i, line := range lines {
+		r.LineDiscounts[i] = money.Clamp(r.LineDiscounts[i], line.Total)
+		r.ItemDiscount = r.ItemDiscount.Add(r.LineDiscounts[i])
+	}
+}
+
+// LineDiscount: potongan promo untuk baris ke-i
+func (r CartPromotionResult) LineDiscount(i int) decimal.Decimal {
+	if i < 0 || i >= len(r.LineDiscounts) {
+		return decimal.Zero
+	}
+	return r.LineDiscounts[i]
+}
+
+// ValidateCoupon: cek kupon untuk keranjang tertentu (dipakai saat user memasukkan kode)
+func ValidateCoupon(db *gorm.DB, code, userID string, lines []PromotionLine) (*Promotion, error) {
+	subtotal := decimal.Zero
+	for _, line := range lines {
+		subtotal = subtotal.Add(line.Total)
+	}
+
+	applied, err := evaluateCoupon(db, NormalizeCouponCode(code), userID, lines, subtotal, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &applied.Promotion, nil
+}
+
+func evaluateCoupon(db *gorm.DB, code, userID string, lines []PromotionLine, subtotal decimal.Decimal, now time.Time) (AppliedPromotion, error) {
+	var promo Promotion
+	if err := db.Where("code = ?", code).First(&promo).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return AppliedPromotion{}, ErrCouponNotFound
+		}
+		return AppliedPromotion{}, err
+	}
+
+	if userID != "" {
+		if err := checkCustomerUsage(db, promo, userID); err != nil {
+			return AppliedPromotion{}, err
+		}
+	}
+
+	return promo.Evaluate(lines, subtotal, now)
+}
+
+func checkCustomerUsage(db *gorm.DB, promo Promotion, userID string) error {
+	if promo.UsagePerCustomer <= 0 {
+		return nil
+	}
+
+	var used int64
+	err := db.Model(&PromotionRedemption{}).
+		Where("promotion_id = ? AND user_id = ? AND status = ?", promo.ID, userID, consts.RedemptionStatusApplied).
+		Count(&used).Error
+	if err != nil {
+		return err
+	}
+	if int(used) >= promo.UsagePerCustomer {
+		return ErrCouponCustomerLimit
+	}
+	return nil
+}
+
+// RedeemPromotions: catat pemakaian promo untuk 1 order & naikkan used_count.
+// Baris promo di-lock supaya kuota tidak terlewati oleh checkout bersamaan. Wajib di dalam transaksi.
+func RedeemPromotions(tx *gorm.DB, orderID, userID string, result CartPromotionResult, shippingDiscount decimal.Decimal) error {
+	shippingLeft := shippingDiscount
+
+	// urutan lock selalu berdasarkan ID promo supaya tidak deadlock
+	applied := append([]AppliedPromotion(nil), result.Applied...)
+	sort.Slice(applied, func(i, j int) bool {
+		return applied[i].Promotion.ID < applied[j].Promotion.ID
+	})
+
+	for _, a := range applied {
+		var promo Promotion
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", a.Promotion.ID).
+			First(&promo).Error
+		if err != nil {
+			return err
+		}
+
+		if err := promo.checkAvailable(time.Now()); err != nil {
+			return fmt.Errorf("promo %s: %w", promo.Name, err)
+		}
+		if promo.IsCoupon() {
+			if err := checkCustomerUsage(tx, promo, userID); err != nil {
+				return fmt.Errorf("promo %s: %w", promo.Name, err)
+			}
+		}
+
+		redemption := PromotionRedemption{
+			PromotionID:    promo.ID,
+			OrderID:        orderID,
+			UserID:         userID,
+			Code:           promo.Code,
+			DiscountAmount: a.Amount,
+			Status:         consts.RedemptionStatusApplied,
+		}
+		if a.FreeShipping {
+			redemption.ShippingDiscount = shippingLeft
+			shippingLeft = decimal.Zero
+		}
+
+		if err := tx.Create(&redemption).Error; err != nil {
+			return err
+		}
+
+		err = tx.Model(&Promotion{}).
+			Where("id = ?", promo.ID).
+			UpdateColumn("used_count", gorm.Expr("used_count + 1")).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReleaseOrderPromotions: order batal → kuota promo dikembalikan (idempotent)
+func ReleaseOrderPromotions(tx *gorm.DB, orderID string) error {
+	var redemptions []PromotionRedemption
+	err := tx.Where("order_id = ? AND status = ?", orderID, consts.RedemptionStatusApplied).
+		Order("promotion_id").
+		Find(&redemptions).Error
+	if err != nil {
+		return err
+	}
+
+	for _, r := range redemptions {
+		err := tx.Model(&PromotionRedemption{}).
+			Where("id = ?", r.ID).
+			Update("status", consts.RedemptionStatusReleased).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&Promotion{}).
+			Where("id = ? AND used_count > 0", r.PromotionID).
+			UpdateColumn("used_count", gorm.Expr("used_count - 1")).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func containsString(values []string, needle string) bool {
+	for _, v := range values {
+		if v == needle {
+			return true
+		}
+	}
+	return false
+}
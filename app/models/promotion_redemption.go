@@ -0,0 +1,112 @@
+package models
+
+import (
+	"time"
+
+	"github.com/alirogz/goshop/app/consts"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// PromotionRedemption: 1 pemakaian promo oleh 1 order (dasar kuota & laporan)
+type PromotionRedemption struct {
+	ID               string `gorm:"size:36;not null;uniqueIndex;primary_key"`
+	PromotionID      string `gorm:"size:36;not null;index"`
+	Promotion        Promotion
+	OrderID          string `gorm:"size:36;not null;index"`
+	Order            Order
+	UserID           string          `gorm:"size:36;index"`
+	Code             string          `gorm:"size:50"`
+	DiscountAmount   decimal.Decimal `gorm:"type:decimal(16,2)"`
+	ShippingDiscount decimal.Decimal `gorm:"type:decimal(16,2)"`
+	Status           string          `gorm:"size:20;not null;index"` // lihat consts.RedemptionStatus*
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+// PromotionReportRow: ringkasan pemakaian 1 promo
+type PromotionReportRow struct {
+	PromotionID   string
+	Name          string
+	Code          string
+	Redemptions   int64
+	Released      int64
+	DiscountTotal decimal.Decimal
+	ShippingTotal decimal.Decimal
+}
+
+func (r *PromotionRedemption) BeforeCreate(db *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = uuid.New().String()
+	}
+
+	return nil
+}
+
+// ListRecent: pemakaian promo terbaru (opsional filter 1 promo)
+func (r *PromotionRedemption) ListRecent(db *gorm.DB, promotionID string, limit int) ([]PromotionRedemption, error) {
+	var redemptions []PromotionRedemption
+
+	if limit <= 0 {
+		limit = 100
+	}
+
+	q := db.Model(&PromotionRedemption{}).
+		Preload("Promotion", func(tx *gorm.DB) *gorm.DB { return tx.Unscoped() }).
+		Preload("Order")
+	if promotionID != "" {
+		q = q.Where("promotion_id = ?", promotionID)
+	}
+
+	err := q.Order("created_at desc").Limit(limit).Find(&redemptions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return redemptions, nil
+}
+
+// PromotionReport: jumlah pemakaian & total potongan per promo (yang dibatalkan tidak dihitung)
+func PromotionReport(db *gorm.DB, from, to time.Time) ([]PromotionReportRow, error) {
+	var rows []PromotionReportRow
+
+	q := db.Model(&PromotionRedemption{}).
+		Select(`promotion_redemptions.promotion_id,
+			promotions.name,
+			promotions.code,
+			SUM(CASE WHEN promotion_redemptions.status = ? THEN 1 ELSE 0 END) AS redemptions,
+			SUM(CASE WHEN promotion_redemptions.status = ? THEN 1 ELSE 0 END) AS released,
+			COALESCE(SUM(CASE WHEN promotion_redemptions.status = ? THEN promotion_redemptions.discount_amount ELSE 0 END), 0) AS discount_total,
+			COALESCE(SUM(CASE WHEN promotion_redemptions.status = ? THEN promotion_redemptions.shipping_discount ELSE 0 END), 0) AS shipping_total`,
+			consts.RedemptionStatusApplied, consts.RedemptionStatusReleased,
+			consts.RedemptionStatusApplied, consts.RedemptionStatusApplied).
+		Joins("JOIN promotions ON promotions.id = promotion_redemptions.promotion_id")
+
+	if !from.IsZero() {
+		q = q.Where("promotion_redemptions.created_at >= ?", from)
+	}
+	if !to.IsZero() {
+		q = q.Where("promotion_redemptions.created_at < ?", to)
+	}
+
+	err := q.Group("promotion_redemptions.promotion_id, promotions.name, promotions.code").
+		Order("discount_total desc").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+func (r PromotionRedemption) StatusText() string {
+	if r.Status == consts.RedemptionStatusReleased {
+		return "Dibatalkan"
+	}
+	return "Terpakai"
+}
+
+func (r PromotionRedemption) CreatedAtFormatted() string {
+	return r.CreatedAt.Format("02 Jan 2006 15:04")
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/alirogz/goshop/app/money"
+	"github.com/shopspring/decimal"
+)
+
+func TestCartPromotionAllocate(t *testing.T) {
+	lines := []PromotionLine{
+		{ProductID: "kaos", CategoryIDs: []string{"pakaian"}, Qty: 2, Total: money.FromInt(100_000)},
+		{ProductID: "topi", CategoryIDs: []string{"aksesoris"}, Qty: 1, Total: money.FromInt(50_000)},
+		{ProductID: "celana", CategoryIDs: []string{"pakaian"}, Qty: 1, Total: money.FromInt(200_000)},
+	}
+	result := CartPromotionResult{Applied: []AppliedPromotion{
+		{Promotion: Promotion{}, Amount: money.FromInt(35_000)},                      // semua produk
+		{Promotion: Promotion{CategoryID: "pakaian"}, Amount: money.FromInt(30_000)}, // hanya pakaian
+		{Promotion: Promotion{}, FreeShipping: true, Amount: decimal.Zero},           // tidak memotong barang
+	}}
+	result.allocate(lines)
+
+	want := []int64{10_000 + 10_000, 5_000, 20_000 + 20_000}
+	for i, w := range want {
+		if !result.LineDiscount(i).Equal(money.FromInt(w)) {
+			t.Fatalf("baris %d: potongan %s, want %d", i, result.LineDiscount(i), w)
+		}
+	}
+	if !result.ItemDiscount.Equal(money.FromInt(65_000)) {
+		t.Fatalf("ItemDiscount = %s", result.ItemDiscount)
+	}
+	if !result.LineDiscount(len(lines)).IsZero() {
+		t.Fatal("baris di luar jangkauan harus 0")
+	}
+
+	// potongan tidak melebihi nominal baris
+	result = CartPromotionResult{Applied: []AppliedPromotion{
+		{Amount: money.FromInt(300_000)},
+		{Amount: money.FromInt(300_000)},
+	}}
+	result.allocate(lines)
+	for i, line := range lines {
+		if !result.LineDiscount(i).Equal(line.Total) {
+			t.Fatalf("baris %d: potongan %s, want %s", i, result.LineDiscount(i), line.Total)
+		}
+	}
+	if !result.ItemDiscount.Equal(money.FromInt(350_000)) {
+		t.Fatalf("ItemDiscount = %s", result.ItemDiscount)
+	}
+}
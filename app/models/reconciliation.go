@@ -0,0 +1,518 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alirogz/goshop/app/consts"
+	"github.com/alirogz/goshop/app/money"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// skor minimal supaya auto-match berani memasangkan tanpa konfirmasi admin
+// (nominal persis + waktu dekat, atau nominal persis + kode order di berita)
+const AutoMatchMinScore = 5
+
+var (
+	ErrBankTxAlreadyMatched = errors.New("mutasi sudah dipasangkan dengan order lain")
+	ErrBankTxNotMatched     = errors.New("mutasi belum dipasangkan")
+	ErrBankTxAmountShort    = errors.New("nominal mutasi kurang dari total order")
+	ErrShortPaymentNote     = errors.New("catatan wajib diisi untuk mutasi yang nominalnya kurang")
+)
+
+var nonAlphanumeric = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// ReconciliationLog: jejak setiap keputusan pemasangan mutasi bank ↔ order
+type ReconciliationLog struct {
+	ID                string `gorm:"size:36;not null;uniqueIndex;primary_key"`
+	BankTransactionID uint   `gorm:"not null;index"`
+	OrderID           string `gorm:"size:36;index"`
+	Action            string `gorm:"size:20;not null;index"` // lihat consts.Reconcile*
+	Score             int
+	ActorID           string `gorm:"size:36"`
+	Note              string `gorm:"size:255"`
+	CreatedAt         time.Time
+}
+
+func (l *ReconciliationLog) BeforeCreate(db *gorm.DB) error {
+	if l.ID == "" {
+		l.ID = uuid.New().String()
+	}
+
+	return nil
+}
+
+// MatchCandidate: 1 order yang mungkin pasangan sebuah mutasi
+type MatchCandidate struct {
+	Order    Order
+	Score    int
+	Exact    bool // nominal mutasi = total transfer order (termasuk kode unik)
+	TimeDiff time.Duration
+	Reasons  []string
+}
+
+// ProposedMatch: pasangan yang akan dibuat auto-match
+type ProposedMatch struct {
+	BankTransaction BankTransaction
+	Candidate       MatchCandidate
+}
+
+// ScoreMatch: seberapa yakin mutasi ini pembayaran order tersebut.
+// Nominal harus masuk rentang grand total s/d grand total + kode unik; di luar itu bukan kandidat.
+func ScoreMatch(order *Order, tx *BankTransaction) (MatchCandidate, bool) {
+	c := MatchCandidate{Order: *order}
+
+	amount := money.Rupiah(tx.Amount)
+	paymentTotal := money.Rupiah(order.PaymentTotal)
+	grandTotal := money.Rupiah(order.GrandTotal)
+	switch {
+	case paymentTotal > 0 && amount == paymentTotal:
+		c.Exact = true
+		c.Score += 4
+		c.Reasons = append(c.Reasons, "nominal + kode unik cocok")
+	case amount == grandTotal:
+		c.Score += 2
+		c.Reasons = append(c.Reasons, "nominal cocok tanpa kode unik")
+	case amount > grandTotal && amount <= grandTotal+PaymentUniqueCodeMax:
+		c.Score += 1
+		c.Reasons = append(c.Reasons, "nominal dalam rentang kode unik")
+	default:
+		return c, false
+	}
+
+	note := normalizeMatchText(tx.Note)
+	if note != "" {
+		if order.Code != "" && codeInNote(tx.Note, order.Code) {
+			c.Score += 3
+			c.Reasons = append(c.Reasons, "kode order di berita transfer")
+		} else if strings.Contains(note, normalizeMatchText(order.ID)) {
+			c.Score += 3
+			c.Reasons = append(c.Reasons, "ID order di berita transfer")
+		}
+
+		if order.OrderCustomer != nil {
+			matched, total := 0, 0
+			for _, part := range strings.Fields(strings.ToUpper(order.OrderCustomer.FirstName + " " + order.OrderCustomer.LastName)) {
+				part = nonAlphanumeric.ReplaceAllString(part, "")
+				if len(part) < 3 {
+					continue
+				}
+				total++
+				if strings.Contains(note, part) {
+					matched++
+				}
+			}
+			if total > 0 && matched == total {
+				c.Score += 2
+				c.Reasons = append(c.Reasons, "nama pemesan cocok")
+			} else if matched > 0 {
+				c.Score += 1
+				c.Reasons = append(c.Reasons, "sebagian nama pemesan cocok")
+			}
+		}
+	}
+
+	c.TimeDiff = time.Duration(1<<63 - 1)
+	if !tx.TrxTime.IsZero() && !order.CreatedAt.IsZero() {
+		d := tx.TrxTime.Sub(order.CreatedAt)
+		// mutasi BCA/Mandiri sering hanya bertanggal (jam 00:00), jadi toleransi 1 hari ke belakang
+		if d < -24*time.Hour {
+			c.Score -= 2
+			c.Reasons = append(c.Reasons, "transfer sebelum order dibuat")
+		}
+		if d < 0 {
+			d = -d
+		}
+		c.TimeDiff = d
+
+		if d <= 24*time.Hour {
+			c.Score += 1
+			c.Reasons = append(c.Reasons, "waktu ≤ 24 jam")
+		}
+		if d <= 6*time.Hour {
+			c.Score += 1
+		}
+	}
+
+	return c, true
+}
+
+// normalizeMatchText: huruf besar & hanya huruf/angka, supaya "12/ORDER/X/2026" cocok dengan "12ORDERX2026"
+func normalizeMatchText(s string) string {
+	return nonAlphanumeric.ReplaceAllString(strings.ToUpper(s), "")
+}
+
+// codeInNote: kode order ada di berita transfer, boleh tanpa / dengan pemisah apa pun
+// ("7/ORDER/X/2026", "7 ORDER X 2026", "7ORDERX2026"), tapi tidak cocok dengan "17/ORDER/X/2026"
+func codeInNote(note, code string) bool {
+	parts := strings.Fields(nonAlphanumeric.ReplaceAllString(strings.ToUpper(code), " "))
+	if len(parts) == 0 {
+		return false
+	}
+	for i := range parts {
+		parts[i] = regexp.QuoteMeta(parts[i])
+	}
+	pattern := `(^|[^A-Z0-9])` + strings.Join(parts, `[^A-Z0-9]*`) + `($|[^A-Z0-9])`
+	matched, _ := regexp.MatchString(pattern, strings.ToUpper(note))
+	return matched
+}
+
+// UnpaidTransferOrders: order transfer bank yang masih menunggu pembayaran (kandidat rekonsiliasi)
+func UnpaidTransferOrders(db *gorm.DB) ([]Order, error) {
+	var orders []Order
+	err := db.Preload("OrderCustomer").
+		Where("status = ? AND payment_status <> ? AND payment_method = ? AND payment_total > 0",
+			consts.OrderStatusPending, consts.OrderPaymentStatusPaid, consts.OrderPaymentMethodBankTransfer).
+		Order("created_at ASC").
+		Find(&orders).Error
+	return orders, err
+}
+
+// UnmatchedBankTransactions: mutasi masuk yang belum dipasangkan
+func UnmatchedBankTransactions(db *gorm.DB, limit int) ([]BankTransaction, error) {
+	var txs []BankTransaction
+	q := db.Where("matched = ?", false).Order("trx_time DESC")
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+	err := q.Find(&txs).Error
+	return txs, err
+}
+
+// SuggestMatches: kandidat order untuk tiap mutasi, skor tertinggi dulu.
+// Pasangan yang pernah ditolak admin tidak disarankan lagi.
+func SuggestMatches(db *gorm.DB, txs []BankTransaction, orders []Order, perTx int) (map[uint][]MatchCandidate, error) {
+	rejected, err := rejectedPairs(db, txs)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := map[uint][]MatchCandidate{}
+	for i := range txs {
+		tx := &txs[i]
+		var candidates []MatchCandidate
+		for j := range orders {
+			if rejected[rejectedKey(tx.ID, orders[j].ID)] {
+				continue
+			}
+			if c, ok := ScoreMatch(&orders[j], tx); ok {
+				candidates = append(candidates, c)
+			}
+		}
+
+		sort.SliceStable(candidates, func(a, b int) bool {
+			if candidates[a].Score != candidates[b].Score {
+				return candidates[a].Score > candidates[b].Score
+			}
+			return candidates[a].TimeDiff < candidates[b].TimeDiff
+		})
+		if perTx > 0 && len(candidates) > perTx {
+			candidates = candidates[:perTx]
+		}
+		if len(candidates) > 0 {
+			suggestions[tx.ID] = candidates
+		}
+	}
+
+	return suggestions, nil
+}
+
+// ProposeAutoMatches: pasangan yang cukup yakin untuk dipasangkan otomatis.
+// Syarat: nominal persis, skor ≥ AutoMatchMinScore, dan kandidat terbaik tidak seri dengan kandidat lain
+// (baik dari sisi mutasi maupun order). Yang ragu-ragu dibiarkan untuk diputuskan admin.
+func ProposeAutoMatches(db *gorm.DB) ([]ProposedMatch, error) {
+	orders, err := UnpaidTransferOrders(db)
+	if err != nil {
+		return nil, err
+	}
+	txs, err := UnmatchedBankTransactions(db, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 || len(txs) == 0 {
+		return nil, nil
+	}
+
+	suggestions, err := SuggestMatches(db, txs, orders, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var proposals []ProposedMatch
+	for _, tx := range txs {
+		candidates := suggestions[tx.ID]
+		if len(candidates) == 0 {
+			continue
+		}
+		best := candidates[0]
+		if !best.Exact || best.Score < AutoMatchMinScore {
+			continue
+		}
+		if len(candidates) > 1 && candidates[1].Score == best.Score {
+			continue
+		}
+		proposals = append(proposals, ProposedMatch{BankTransaction: tx, Candidate: best})
+	}
+
+	// 1 order hanya boleh dapat 1 mutasi; kalau diperebutkan dengan skor sama, jangan pilih
+	sort.SliceStable(proposals, func(a, b int) bool {
+		return proposals[a].Candidate.Score > proposals[b].Candidate.Score
+	})
+	bestForOrder := map[string]int{}
+	tied := map[string]bool{}
+	for _, p := range proposals {
+		id := p.Candidate.Order.ID
+		if score, ok := bestForOrder[id]; ok {
+			if score == p.Candidate.Score {
+				tied[id] = true
+			}
+			continue
+		}
+		bestForOrder[id] = p.Candidate.Score
+	}
+
+	used := map[string]bool{}
+	result := make([]ProposedMatch, 0, len(proposals))
+	for _, p := range proposals {
+		id := p.Candidate.Order.ID
+		if used[id] || tied[id] {
+			continue
+		}
+		used[id] = true
+		result = append(result, p)
+	}
+
+	return result, nil
+}
+
+// PairBankTransaction: pasangkan mutasi dengan order, catat payment & tandai order lunas.
+// action: consts.ReconcileAuto / ReconcileConfirm / ReconcileManual. Skor dihitung ulang untuk log.
+// Mutasi yang (bersama transfer sebelumnya untuk order ini) kurang dari grand total ditolak dengan ErrBankTxAmountShort,
+// kecuali acceptShort: payment dicatat tanpa melunasi order dan keputusannya masuk log sebagai consts.ReconcileShort.
+// settled true kalau order jadi lunas.
+func PairBankTransaction(db *gorm.DB, bankTxID uint, orderID, action, actorID, note string, acceptShort bool) (settled bool, err error) {
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var bankTx BankTransaction
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", bankTxID).First(&bankTx).Error; err != nil {
+			return err
+		}
+		if bankTx.Matched {
+			return ErrBankTxAlreadyMatched
+		}
+
+		orderModel := Order{}
+		order, err := orderModel.FindByID(tx, orderID)
+		if err != nil {
+			return err
+		}
+		if order.IsClosed() {
+			return ErrOrderClosed
+		}
+		if order.IsPaid() {
+			return ErrOrderAlreadyPaid
+		}
+		score := 0
+		if c, ok := ScoreMatch(order, &bankTx); ok {
+			score = c.Score
+		}
+
+		paid, err := bankTransferPaid(tx, order.ID)
+		if err != nil {
+			return err
+		}
+		settled = !money.Round(paid.Add(bankTx.Amount)).LessThan(money.Round(order.GrandTotal))
+		if !settled {
+			if !acceptShort {
+				return ErrBankTxAmountShort
+			}
+			if strings.TrimSpace(note) == "" {
+				return ErrShortPaymentNote
+			}
+			action = consts.ReconcileShort
+		}
+
+		payload, _ := json.Marshal(map[string]interface{}{
+			"bank_transaction_id": bankTx.ID,
+			"bank":                bankTx.Bank,
+			"note":                bankTx.Note,
+			"ref":                 bankTx.RefCode,
+		})
+		raw := json.RawMessage(payload)
+		historyNote := fmt.Sprintf("Mutasi bank #%d (%s)", bankTx.ID, action)
+		if _, err := order.RecordPayment(tx, Payment{
+			Amount:            bankTx.Amount,
+			TransactionID:     bankPaymentTransactionID(bankTx.ID),
+			TransactionStatus: consts.PaymentStatusSettlement,
+			Payload:           &raw,
+			PaymentType:       "bank_transfer",
+		}, settled, actorID, historyNote); err != nil {
+			return err
+		}
+
+		res := tx.Model(&BankTransaction{}).
+			Where("id = ? AND matched = ?", bankTx.ID, false).
+			Updates(map[string]interface{}{
+				"matched":       true,
+				"matched_order": order.ID,
+				"matched_at":    time.Now(),
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrBankTxAlreadyMatched
+		}
+
+		return logReconciliation(tx, bankTx.ID, order.ID, action, score, actorID, note)
+	})
+	return settled && err == nil, err
+}
+
+// bankTransferPaid: jumlah mutasi bank yang sudah dipasangkan ke order (pembayaran sebagian sebelumnya)
+func bankTransferPaid(db *gorm.DB, orderID string) (decimal.Decimal, error) {
+	var total decimal.NullDecimal
+	err := db.Model(&Payment{}).
+		Where("order_id = ? AND transaction_id LIKE ?", orderID, bankPaymentPrefix+"%").
+		Select("SUM(amount)").Row().Scan(&total)
+	if err != nil || !total.Valid {
+		return decimal.Zero, err
+	}
+	return total.Decimal, nil
+}
+
+// UnmatchBankTransaction: lepas pasangan yang salah. Pembayaran order dibatalkan lagi
+// (hanya bisa kalau order belum dikirim) dan mutasi kembali ke daftar belum dipasangkan.
+func UnmatchBankTransaction(db *gorm.DB, bankTxID uint, actorID, note string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var bankTx BankTransaction
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", bankTxID).First(&bankTx).Error; err != nil {
+			return err
+		}
+		if !bankTx.Matched {
+			return ErrBankTxNotMatched
+		}
+
+		if bankTx.MatchedOrder != "" {
+			var order Order
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", bankTx.MatchedOrder).First(&order).Error
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			if err == nil && order.IsPaid() {
+				if err := order.RevertPayment(tx, actorID, fmt.Sprintf("Mutasi bank #%d dilepas: %s", bankTx.ID, note)); err != nil {
+					return err
+				}
+			}
+			if err := tx.Where("order_id = ? AND transaction_id = ?", bankTx.MatchedOrder, bankPaymentTransactionID(bankTx.ID)).
+				Delete(&Payment{}).Error; err != nil {
+				return err
+			}
+		}
+
+		err := tx.Model(&BankTransaction{}).
+			Where("id = ?", bankTx.ID).
+			Updates(map[string]interface{}{
+				"matched":       false,
+				"matched_order": "",
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		return logReconciliation(tx, bankTx.ID, bankTx.MatchedOrder, consts.ReconcileUnmatch, 0, actorID, note)
+	})
+}
+
+// RejectMatch: admin menolak saran pasangan; pasangan ini tidak disarankan lagi
+func RejectMatch(db *gorm.DB, bankTxID uint, orderID, actorID, note string) error {
+	return logReconciliation(db, bankTxID, orderID, consts.ReconcileReject, 0, actorID, note)
+}
+
+// RecentReconciliationLogs: keputusan rekonsiliasi terbaru
+func RecentReconciliationLogs(db *gorm.DB, limit int) ([]ReconciliationLog, error) {
+	var logs []ReconciliationLog
+	err := db.Order("created_at DESC").Limit(limit).Find(&logs).Error
+	return logs, err
+}
+
+// ActionText: label aksi untuk halaman admin
+func (l ReconciliationLog) ActionText() string {
+	switch l.Action {
+	case consts.ReconcileAuto:
+		return "Auto-match"
+	case consts.ReconcileConfirm:
+		return "Dikonfirmasi"
+	case consts.ReconcileManual:
+		return "Dipasangkan manual"
+	case consts.ReconcileReject:
+		return "Saran ditolak"
+	case consts.ReconcileUnmatch:
+		return "Pasangan dilepas"
+	case consts.ReconcileShort:
+		return "Kurang bayar (belum lunas)"
+	}
+	return l.Action
+}
+
+func logReconciliation(db *gorm.DB, bankTxID uint, orderID, action string, score int, actorID, note string) error {
+	entry := ReconciliationLog{
+		BankTransactionID: bankTxID,
+		OrderID:           orderID,
+		Action:            action,
+		Score:             score,
+		ActorID:           actorID,
+		Note:              truncateRunes(note, 255),
+	}
+	return db.Create(&entry).Error
+}
+
+// bankPaymentPrefix: awalan payments.transaction_id untuk pembayaran dari mutasi bank
+const bankPaymentPrefix = "BANK-"
+
+func bankPaymentTransactionID(bankTxID uint) string {
+	return bankPaymentPrefix + strconv.FormatUint(uint64(bankTxID), 10)
+}
+
+func rejectedKey(bankTxID uint, orderID string) string {
+	return strconv.FormatUint(uint64(bankTxID), 10) + "|" + orderID
+}
+
+func rejectedPairs(db *gorm.DB, txs []BankTransaction) (map[string]bool, error) {
+	rejected := map[string]bool{}
+	if len(txs) == 0 {
+		return rejected, nil
+	}
+
+	ids := make([]uint, 0, len(txs))
+	for _, tx := range txs {
+		ids = append(ids, tx.ID)
+	}
+
+	var logs []ReconciliationLog
+	for start := 0; start < len(ids); start += 500 {
+		end := start + 500
+		if end > len(ids) {
+			end = len(ids)
+		}
+		var chunk []ReconciliationLog
+		if err := db.Where("action = ? AND bank_transaction_id IN ?", consts.ReconcileReject, ids[start:end]).
+			Find(&chunk).Error; err != nil {
+			return nil, err
+		}
+		logs = append(logs, chunk...)
+	}
+
+	for _, l := range logs {
+		rejected[rejectedKey(l.BankTransactionID, l.OrderID)] = true
+	}
+	return rejected, nil
+}
@@ -8,18 +8,36 @@ func RegisterModels() []Model {
 	return []Model{
 		{Model: User{}},
 		{Model: Address{}},
+		{Model: Role{}},
+		{Model: RolePermission{}},
+		{Model: UserRole{}},
+		{Model: UserToken{}},
 		{Model: Product{}},
 		{Model: ProductImage{}},
+		{Model: ProductVariant{}},
+		{Model: InventoryMovement{}},
 		{Model: Section{}},
 		{Model: Category{}},
 		{Model: Order{}},
 		{Model: OrderItem{}},
 		{Model: OrderCustomer{}},
+		{Model: OrderStatusHistory{}},
+		{Model: PaymentProof{}},
 		{Model: Shipment{}},
 		{Model: Cart{}},
 		{Model: CartItem{}},
+		{Model: Promotion{}},
+		{Model: PromotionRedemption{}},
+		{Model: Setting{}},
+		{Model: SequenceCounter{}},
+		{Model: PaymentCodeReservation{}},
 		{Model: BankTransaction{}},
+		{Model: BankImportBatch{}},
+		{Model: BankImportRow{}},
+		{Model: ReconciliationLog{}},
 		{Model: Chat{}},
 		{Model: ChatMessage{}},
+		{Model: ChatAttachment{}},
+		{Model: Notification{}},
 	}
 }
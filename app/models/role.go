@@ -0,0 +1,236 @@
+package models
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alirogz/goshop/app/consts"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Role: kelompok hak akses staff (owner, order-staff, ...)
+type Role struct {
+	ID          string `gorm:"size:36;not null;uniqueIndex;primary_key"`
+	Name        string `gorm:"size:50;not null;uniqueIndex"`
+	Label       string `gorm:"size:100"`
+	Permissions []RolePermission
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// RolePermission: 1 hak akses (consts.Perm*) milik 1 role
+type RolePermission struct {
+	RoleID     string `gorm:"size:36;not null;primary_key"`
+	Permission string `gorm:"size:50;not null;primary_key"`
+	CreatedAt  time.Time
+}
+
+// UserRole: role yang diberikan ke user. 1 user boleh punya beberapa role.
+type UserRole struct {
+	UserID    string `gorm:"size:36;not null;primary_key"`
+	RoleID    string `gorm:"size:36;not null;primary_key;index"`
+	GrantedBy string `gorm:"size:100"` // user id atau "cli"
+	CreatedAt time.Time
+}
+
+// PermissionSet: hak akses efektif user (gabungan semua role-nya)
+type PermissionSet map[string]bool
+
+var (
+	ErrRoleUnknown = errors.New("role tidak dikenal")
+	ErrRoleNotHeld = errors.New("user tidak memiliki role tersebut")
+)
+
+// AllPermissions: semua hak akses yang dikenal aplikasi
+var AllPermissions = []string{
+	consts.PermOrdersView,
+	consts.PermOrdersManage,
+	consts.PermPaymentsManage,
+	consts.PermCatalogManage,
+	consts.PermPromotionsManage,
+	consts.PermChatsManage,
+	consts.PermSettingsManage,
+}
+
+// role bawaan; hak aksesnya ditambahkan (tidak pernah dihapus) setiap SyncDefaultRoles
+var defaultRoles = []struct {
+	Name        string
+	Label       string
+	Permissions []string
+}{
+	{consts.RoleOwner, "Owner", AllPermissions},
+	{consts.RoleOrderStaff, "Staff Order", []string{
+		consts.PermOrdersView, consts.PermOrdersManage,
+	}},
+	{consts.RoleCatalogStaff, "Staff Katalog", []string{
+		consts.PermCatalogManage, consts.PermPromotionsManage,
+	}},
+	{consts.RoleCSAgent, "Customer Service", []string{
+		consts.PermOrdersView, consts.PermChatsManage,
+	}},
+	{consts.RoleFinance, "Keuangan", []string{
+		consts.PermOrdersView, consts.PermPaymentsManage,
+	}},
+}
+
+func (r *Role) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// SyncDefaultRoles: buat role bawaan beserta hak aksesnya kalau belum ada.
+// Aman dijalankan berulang (dipanggil setiap db:migrate).
+func SyncDefaultRoles(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, def := range defaultRoles {
+			role := Role{Name: def.Name, Label: def.Label}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&role).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("name = ?", def.Name).First(&role).Error; err != nil {
+				return err
+			}
+
+			for _, perm := range def.Permissions {
+				rp := RolePermission{RoleID: role.ID, Permission: perm}
+				if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rp).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// FindRoleByName: cari role berdasarkan nama (owner, finance, ...)
+func FindRoleByName(db *gorm.DB, name string) (*Role, error) {
+	var role Role
+	err := db.Preload("Permissions").Where("name = ?", strings.ToLower(strings.TrimSpace(name))).First(&role).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRoleUnknown
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// AllRoles: semua role urut nama, dengan hak aksesnya
+func AllRoles(db *gorm.DB) ([]Role, error) {
+	var roles []Role
+	err := db.Preload("Permissions").Order("name ASC").Find(&roles).Error
+	return roles, err
+}
+
+// GrantRole: beri role ke user. Tidak error kalau user sudah punya role itu.
+func GrantRole(db *gorm.DB, userID, roleName, grantedBy string) error {
+	role, err := FindRoleByName(db, roleName)
+	if err != nil {
+		return err
+	}
+
+	userRole := UserRole{UserID: userID, RoleID: role.ID, GrantedBy: grantedBy}
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&userRole).Error
+}
+
+// RevokeRole: cabut role dari user
+func RevokeRole(db *gorm.DB, userID, roleName string) error {
+	role, err := FindRoleByName(db, roleName)
+	if err != nil {
+		return err
+	}
+
+	res := db.Where("user_id = ? AND role_id = ?", userID, role.ID).Delete(&UserRole{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrRoleNotHeld
+	}
+	return nil
+}
+
+// CountUsersWithRole: dipakai untuk mencegah owner terakhir dicabut
+func CountUsersWithRole(db *gorm.DB, roleName string) (int64, error) {
+	var count int64
+	err := db.Model(&UserRole{}).
+		Joins("JOIN roles ON roles.id = user_roles.role_id").
+		Where("roles.name = ?", roleName).
+		Count(&count).Error
+	return count, err
+}
+
+// UsersWithPermission: staff yang punya hak akses perm (mis. daftar CS untuk assign chat)
+func UsersWithPermission(db *gorm.DB, perm string) ([]User, error) {
+	var users []User
+	err := db.Model(&User{}).
+		Distinct("users.id", "users.first_name", "users.last_name", "users.email").
+		Joins("JOIN user_roles ON user_roles.user_id = users.id").
+		Joins("JOIN role_permissions ON role_permissions.role_id = user_roles.role_id").
+		Where("role_permissions.permission = ?", perm).
+		Order("users.first_name ASC").
+		Find(&users).Error
+	return users, err
+}
+
+// LoadAccess: isi Roles & Permissions user dari DB
+func (u *User) LoadAccess(db *gorm.DB) error {
+	var rows []struct {
+		Name       string
+		Permission *string
+	}
+	err := db.Table("user_roles").
+		Select("roles.name AS name, role_permissions.permission AS permission").
+		Joins("JOIN roles ON roles.id = user_roles.role_id").
+		Joins("LEFT JOIN role_permissions ON role_permissions.role_id = roles.id").
+		Where("user_roles.user_id = ?", u.ID).
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	u.Roles = nil
+	u.Permissions = PermissionSet{}
+	seen := map[string]bool{}
+	for _, row := range rows {
+		if !seen[row.Name] {
+			seen[row.Name] = true
+			u.Roles = append(u.Roles, row.Name)
+		}
+		if row.Permission != nil {
+			u.Permissions[*row.Permission] = true
+		}
+	}
+	sort.Strings(u.Roles)
+
+	return nil
+}
+
+// Can: apakah user punya hak akses perm. Dipakai juga di template: {{ if .user.Can "orders.view" }}
+func (u *User) Can(perm string) bool {
+	return u != nil && u.Permissions[perm]
+}
+
+// IsStaff: user punya minimal 1 hak akses admin
+func (u *User) IsStaff() bool {
+	return u != nil && len(u.Permissions) > 0
+}
+
+// HasRole: apakah user memegang role tertentu
+func (u *User) HasRole(name string) bool {
+	if u == nil {
+		return false
+	}
+	for _, role := range u.Roles {
+		if role == name {
+			return true
+		}
+	}
+	return false
+}
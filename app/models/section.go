@@ -1,12 +1,95 @@
 package models
 
-import "time"
+import (
+	"errors"
+	"time"
 
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrSectionHasCategories = errors.New("section masih punya kategori, pindahkan atau hapus dulu")
+
+// Section: kelompok besar di menu toko (mis. Pria, Wanita), berisi tree kategori
 type Section struct {
 	ID         string `gorm:"size:36;not null;uniqueIndex;primary_key"`
 	Name       string `gorm:"size:100;"`
-	Slug       string `gorm:"size:100;"`
+	Slug       string `gorm:"size:100;index"`
 	CreatedAt  time.Time
 	UpdatedAt  time.Time
 	Categories []Category
 }
+
+// SectionNode: section + tree kategorinya (menu storefront & halaman admin)
+type SectionNode struct {
+	Section
+	Active     bool
+	Categories []*CategoryNode
+}
+
+func (s *Section) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == "" {
+		s.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// ListSections: semua section urut nama
+func ListSections(db *gorm.DB) ([]Section, error) {
+	var sections []Section
+	err := db.Order("name asc").Find(&sections).Error
+	return sections, err
+}
+
+// FindSectionBySlug: section untuk halaman /sections/{slug}
+func FindSectionBySlug(db *gorm.DB, slug string) (*Section, error) {
+	var section Section
+	if err := db.Where("slug = ?", slug).First(&section).Error; err != nil {
+		return nil, err
+	}
+	return &section, nil
+}
+
+// BuildSectionTree: kelompokkan tree kategori per section (urutan mengikuti sections)
+func BuildSectionTree(sections []Section, categories []Category) []*SectionNode {
+	out := make([]*SectionNode, 0, len(sections))
+	index := map[string]*SectionNode{}
+	for _, s := range sections {
+		node := &SectionNode{Section: s}
+		index[s.ID] = node
+		out = append(out, node)
+	}
+
+	for _, root := range BuildCategoryTree(categories) {
+		if node, ok := index[root.SectionID]; ok {
+			node.Categories = append(node.Categories, root)
+		}
+	}
+	return out
+}
+
+// SaveSection: buat slug unik dari nama lalu simpan
+func SaveSection(db *gorm.DB, section *Section) error {
+	var err error
+	section.Slug, err = uniqueSlug(db, &Section{}, section.Name, section.ID)
+	if err != nil {
+		return err
+	}
+
+	if section.ID == "" {
+		return db.Omit("Categories").Create(section).Error
+	}
+	return db.Omit("Categories", "created_at").Save(section).Error
+}
+
+// DeleteSection: hanya section yang sudah kosong
+func DeleteSection(db *gorm.DB, id string) error {
+	var count int64
+	if err := db.Model(&Category{}).Where("section_id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrSectionHasCategories
+	}
+	return db.Where("id = ?", id).Delete(&Section{}).Error
+}
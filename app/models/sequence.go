@@ -0,0 +1,220 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alirogz/goshop/app/consts"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SequenceCounter: nomor urut terakhir per jenis dokumen & periode reset
+type SequenceCounter struct {
+	Name      string `gorm:"size:50;not null;primary_key"` // lihat consts.Sequence*
+	Period    string `gorm:"size:10;not null;primary_key"` // "2026-10", "2026" atau "all"
+	Value     int64  `gorm:"not null;default:0"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// SequenceFormat: pola nomor dokumen.
+// Token: {seq} atau {seq:5} (nomor urut, opsional di-pad nol), {year}, {yy}, {month}, {roman_month}.
+type SequenceFormat struct {
+	Pattern string
+	Reset   string // lihat consts.SequenceReset*
+}
+
+var ErrSequenceUnknown = errors.New("jenis nomor dokumen tidak dikenal")
+
+// format bawaan; bisa ditimpa lewat settings "sequence.<jenis>.pattern" & "sequence.<jenis>.reset"
+var defaultSequenceFormats = map[string]SequenceFormat{
+	consts.SequenceOrder:    {Pattern: "{seq}/ORDER/{roman_month}/{year}", Reset: consts.SequenceResetMonthly},
+	consts.SequencePayment:  {Pattern: "{seq}/PAYMENT/{roman_month}/{year}", Reset: consts.SequenceResetMonthly},
+	consts.SequenceInvoice:  {Pattern: "INV/{year}/{roman_month}/{seq:5}", Reset: consts.SequenceResetMonthly},
+	consts.SequenceShipment: {Pattern: "SHP/{yy}{month}/{seq:5}", Reset: consts.SequenceResetMonthly},
+}
+
+// kolom yang menyimpan nomor dokumen, dipakai untuk melanjutkan nomor lama saat counter baru dibuat
+var sequenceColumns = map[string]struct{ table, column string }{
+	consts.SequenceOrder:    {"orders", "code"},
+	consts.SequencePayment:  {"payments", "number"},
+	consts.SequenceInvoice:  {"orders", "invoice_number"},
+	consts.SequenceShipment: {"shipments", "number"},
+}
+
+var sequenceToken = regexp.MustCompile(`\{seq(?::(\d+))?\}`)
+
+// LoadSequenceFormat: format nomor untuk 1 jenis dokumen (settings → default)
+func LoadSequenceFormat(db *gorm.DB, name string) (SequenceFormat, error) {
+	format, ok := defaultSequenceFormats[name]
+	if !ok {
+		return SequenceFormat{}, ErrSequenceUnknown
+	}
+
+	var err error
+	if format.Pattern, err = GetSetting(db, "sequence."+name+".pattern", format.Pattern); err != nil {
+		return SequenceFormat{}, err
+	}
+	if format.Reset, err = GetSetting(db, "sequence."+name+".reset", format.Reset); err != nil {
+		return SequenceFormat{}, err
+	}
+	if !sequenceToken.MatchString(format.Pattern) {
+		return SequenceFormat{}, fmt.Errorf("pola nomor %s harus memuat {seq}", name)
+	}
+
+	return format, nil
+}
+
+// SaveSequenceFormat: simpan pola & reset nomor dokumen dari halaman admin
+func SaveSequenceFormat(db *gorm.DB, name string, format SequenceFormat, actorID string) error {
+	if _, ok := defaultSequenceFormats[name]; !ok {
+		return ErrSequenceUnknown
+	}
+	if !sequenceToken.MatchString(format.Pattern) {
+		return fmt.Errorf("pola nomor %s harus memuat {seq}", name)
+	}
+	switch format.Reset {
+	case consts.SequenceResetMonthly, consts.SequenceResetYearly, consts.SequenceResetNever:
+	default:
+		return fmt.Errorf("reset nomor %s tidak valid", name)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := SetSetting(tx, "sequence."+name+".pattern", format.Pattern, actorID); err != nil {
+			return err
+		}
+		return SetSetting(tx, "sequence."+name+".reset", format.Reset, actorID)
+	})
+}
+
+// SequenceNames: jenis dokumen yang bisa diatur, urutan tetap untuk tampilan admin
+func SequenceNames() []string {
+	return []string{consts.SequenceOrder, consts.SequencePayment, consts.SequenceInvoice, consts.SequenceShipment}
+}
+
+// Period: key periode reset untuk waktu t
+func (f SequenceFormat) Period(t time.Time) string {
+	switch f.Reset {
+	case consts.SequenceResetYearly:
+		return t.Format("2006")
+	case consts.SequenceResetNever:
+		return "all"
+	default:
+		return t.Format("2006-01")
+	}
+}
+
+// Render: nomor dokumen untuk nomor urut seq pada waktu t
+func (f SequenceFormat) Render(seq int64, t time.Time) string {
+	out := sequenceToken.ReplaceAllStringFunc(f.Pattern, func(token string) string {
+		width := sequenceToken.FindStringSubmatch(token)[1]
+		if width == "" {
+			return strconv.FormatInt(seq, 10)
+		}
+		return fmt.Sprintf("%0"+width+"d", seq)
+	})
+
+	return f.renderDate(out, t)
+}
+
+func (f SequenceFormat) renderDate(s string, t time.Time) string {
+	return strings.NewReplacer(
+		"{year}", t.Format("2006"),
+		"{yy}", t.Format("06"),
+		"{month}", t.Format("01"),
+		"{roman_month}", intToRoman(int(t.Month())),
+	).Replace(s)
+}
+
+// NextSequence: ambil nomor dokumen berikutnya.
+// Counter dibuat dengan upsert lalu dinaikkan dengan UPDATE value = value + 1: row counter terkunci
+// sampai transaksi selesai, jadi 2 checkout bersamaan tidak pernah mendapat nomor yang sama
+// (MySQL & Postgres). Panggil di dalam transaksi yang sama dengan penyimpanan dokumennya.
+func NextSequence(db *gorm.DB, name string, now time.Time) (string, error) {
+	format, err := LoadSequenceFormat(db, name)
+	if err != nil {
+		return "", err
+	}
+	period := format.Period(now)
+
+	var counter SequenceCounter
+	err = db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&SequenceCounter{Name: name, Period: period, Value: 0})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected > 0 {
+			// counter baru: lanjutkan dari nomor lama di periode ini (data sebelum ada counter)
+			if last := lastSequenceInUse(tx, name, format, now); last > 0 {
+				err := tx.Model(&SequenceCounter{}).
+					Where("name = ? AND period = ?", name, period).
+					Update("value", last).Error
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		err := tx.Model(&SequenceCounter{}).
+			Where("name = ? AND period = ?", name, period).
+			UpdateColumn("value", gorm.Expr("value + 1")).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Where("name = ? AND period = ?", name, period).First(&counter).Error
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return format.Render(counter.Value, now), nil
+}
+
+// lastSequenceInUse: nomor urut terbesar yang sudah dipakai dokumen lama dengan pola yang sama
+func lastSequenceInUse(db *gorm.DB, name string, format SequenceFormat, now time.Time) int64 {
+	target, ok := sequenceColumns[name]
+	if !ok || format.Reset != consts.SequenceResetMonthly && format.Reset != consts.SequenceResetYearly {
+		return 0
+	}
+
+	// pola LIKE & regex dari format dengan {seq} sebagai wildcard
+	// (reset tahunan: token bulan juga wildcard, supaya semua bulan di tahun itu ikut dihitung)
+	pattern := format.Pattern
+	if format.Reset == consts.SequenceResetYearly {
+		pattern = strings.NewReplacer("{month}", "\x00", "{roman_month}", "\x00").Replace(pattern)
+	}
+	rendered := format.renderDate(pattern, now)
+	like := strings.ReplaceAll(sequenceToken.ReplaceAllString(rendered, "%"), "\x00", "%")
+	parts := sequenceToken.Split(rendered, -1)
+	for i := range parts {
+		parts[i] = strings.ReplaceAll(regexp.QuoteMeta(parts[i]), "\x00", "[^/]*?")
+	}
+	re, err := regexp.Compile("^" + strings.Join(parts, `(\d+)`) + "$")
+	if err != nil {
+		return 0
+	}
+
+	var values []string
+	if err := db.Table(target.table).Where(target.column+" LIKE ?", like).Pluck(target.column, &values).Error; err != nil {
+		return 0
+	}
+
+	var last int64
+	for _, v := range values {
+		m := re.FindStringSubmatch(v)
+		if m == nil {
+			continue
+		}
+		if n, err := strconv.ParseInt(m[1], 10, 64); err == nil && n > last {
+			last = n
+		}
+	}
+	return last
+}
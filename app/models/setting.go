@@ -0,0 +1,46 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Setting: pengaturan toko yang bisa diubah dari admin tanpa deploy
+type Setting struct {
+	Key       string `gorm:"size:100;not null;primary_key"`
+	Value     string `gorm:"type:text"`
+	UpdatedBy string `gorm:"size:36"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// GetSetting: nilai setting, fallback kalau belum pernah disimpan.
+// Error database lain dikembalikan (bersama fallback) supaya pemanggil di dalam transaksi ikut gagal,
+// bukan diam-diam memakai nilai default.
+func GetSetting(db *gorm.DB, key, fallback string) (string, error) {
+	var setting Setting
+
+	// pakai struct condition supaya nama kolom "key" di-quote sesuai driver
+	err := db.Where(&Setting{Key: key}).First(&setting).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fallback, nil
+	}
+	if err != nil {
+		return fallback, err
+	}
+
+	return setting.Value, nil
+}
+
+// SetSetting: simpan / timpa nilai setting
+func SetSetting(db *gorm.DB, key, value, actorID string) error {
+	setting := Setting{Key: key, Value: value, UpdatedBy: actorID}
+
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_by", "updated_at"}),
+	}).Create(&setting).Error
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestGetSettingDBError: database tidak bisa dihubungi = error, bukan diam-diam memakai nilai default
+func TestGetSettingDBError(t *testing.T) {
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		DSN:                       "user:pass@tcp(127.0.0.1:1)/goshop?timeout=1s",
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{DisableAutomaticPing: true, Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := GetSetting(db, "tax.rate", "11")
+	if err == nil {
+		t.Fatal("GetSetting: error database tidak dikembalikan")
+	}
+	if value != "11" {
+		t.Fatalf("GetSetting = %q, want fallback", value)
+	}
+
+	if _, err := LoadTaxSettings(db); err == nil {
+		t.Fatal("LoadTaxSettings: error database tidak dikembalikan")
+	}
+	if _, err := BackfillEmailVerified(db); err == nil {
+		t.Fatal("BackfillEmailVerified jalan walau penanda tidak bisa dibaca")
+	}
+}
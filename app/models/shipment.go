@@ -3,6 +3,8 @@ package models
 import (
 	"time"
 
+	"github.com/alirogz/goshop/app/consts"
+	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
@@ -13,6 +15,7 @@ type Shipment struct {
 	UserID      string `gorm:"size:36;index"`
 	Order       Order
 	OrderID     string `gorm:"size:36;index"`
+	Number      string `gorm:"size:50;index"`
 	TrackNumber string `gorm:"size:255;index"`
 	Status      string `gorm:"size:36;index"`
 	TotalQty    int
@@ -32,3 +35,19 @@ type Shipment struct {
 	UpdatedAt   time.Time
 	DeletedAt   gorm.DeletedAt
 }
+
+func (s *Shipment) BeforeCreate(db *gorm.DB) error {
+	if s.ID == "" {
+		s.ID = uuid.New().String()
+	}
+
+	if s.Number == "" {
+		number, err := NextSequence(db, consts.SequenceShipment, time.Now())
+		if err != nil {
+			return err
+		}
+		s.Number = number
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/alirogz/goshop/app/consts"
+	"github.com/alirogz/goshop/app/money"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// TaxSettings: aturan pajak toko (disimpan di tabel settings).
+// Tarif disalin ke cart item / order item saat dihitung, jadi order lama
+// tetap memakai tarif yang berlaku waktu order dibuat.
+type TaxSettings struct {
+	Rate             decimal.Decimal // persen, contoh 11 = PPN 11%
+	PricesIncludeTax bool            // harga produk sudah termasuk pajak
+}
+
+// LoadTaxSettings: baca pengaturan pajak, pakai default kalau belum diatur / tidak valid.
+// Error kalau tabel settings tidak bisa dibaca (bukan dianggap pajak default).
+func LoadTaxSettings(db *gorm.DB) (TaxSettings, error) {
+	rawRate, err := GetSetting(db, consts.SettingTaxRate, consts.DefaultTaxRate)
+	if err != nil {
+		return TaxSettings{}, err
+	}
+	rawInclusive, err := GetSetting(db, consts.SettingPricesIncludeTax, "false")
+	if err != nil {
+		return TaxSettings{}, err
+	}
+
+	rate, err := decimal.NewFromString(rawRate)
+	if err != nil || rate.IsNegative() {
+		rate = decimal.RequireFromString(consts.DefaultTaxRate)
+	}
+	inclusive, _ := strconv.ParseBool(rawInclusive)
+
+	return TaxSettings{Rate: rate, PricesIncludeTax: inclusive}, nil
+}
+
+// SaveTaxSettings: simpan pengaturan pajak dari halaman admin
+func SaveTaxSettings(db *gorm.DB, settings TaxSettings, actorID string) error {
+	if settings.Rate.IsNegative() || settings.Rate.GreaterThan(decimal.NewFromInt(100)) {
+		return fmt.Errorf("tarif pajak harus di antara 0 dan 100")
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := SetSetting(tx, consts.SettingTaxRate, settings.Rate.String(), actorID); err != nil {
+			return err
+		}
+		return SetSetting(tx, consts.SettingPricesIncludeTax, strconv.FormatBool(settings.PricesIncludeTax), actorID)
+	})
+}
+
+// RateFor: tarif pajak untuk 1 produk (produk bebas pajak = 0)
+func (s TaxSettings) RateFor(product Product) decimal.Decimal {
+	if product.TaxClass == consts.TaxClassExempt {
+		return decimal.Zero
+	}
+	return s.Rate
+}
+
+// Line: baris perhitungan harga untuk produk ini
+func (s TaxSettings) Line(product Product, price decimal.Decimal, qty int) money.Line {
+	return money.Line{
+		UnitPrice:        price,
+		Qty:              qty,
+		TaxPercent:       s.RateFor(product),
+		PricesIncludeTax: s.PricesIncludeTax,
+	}
+}
+
+// TaxClassText: label kelas pajak untuk tampilan admin
+func TaxClassText(class string) string {
+	switch class {
+	case consts.TaxClassExempt:
+		return "Bebas pajak"
+	default:
+		return "Kena pajak"
+	}
+}
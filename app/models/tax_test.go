@@ -0,0 +1,83 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alirogz/goshop/app/consts"
+	"github.com/alirogz/goshop/app/money"
+	"github.com/shopspring/decimal"
+)
+
+// kupon persen mengurangi dasar pajak: pajak = tarif x (harga - diskon), baik harga belum maupun sudah termasuk pajak.
+// Alurnya sama dengan CalculateCart: promo dinilai, dibagi ke baris, lalu tiap baris dihitung ulang.
+func TestPercentCouponTaxBase(t *testing.T) {
+	rate := decimal.NewFromInt(11)
+	coupon := Promotion{Code: "HEMAT10", Type: consts.PromotionTypePercent, Value: decimal.NewFromInt(10), IsActive: true}
+	taxable := Product{ID: "kaos", TaxClass: consts.TaxClassTaxable}
+	exempt := Product{ID: "buku", TaxClass: consts.TaxClassExempt}
+
+	tests := []struct {
+		name      string
+		inclusive bool
+		prices    [2]int64 // kaos, buku
+		discount  int64
+		taxBase   int64 // bagian kena pajak setelah diskon
+		tax       int64
+		total     int64
+	}{
+		// (2 x 100.000 + 50.000) x 10% = 25.000; kaos dapat 20.000 → pajak 11% x 180.000
+		{name: "belum termasuk pajak", prices: [2]int64{100_000, 50_000}, discount: 25_000, taxBase: 180_000, tax: 19_800, total: 244_800},
+		// (2 x 111.000 + 50.000) x 10% = 27.200; kaos dapat 22.200 → 199.800 sudah termasuk pajak 11%
+		{name: "termasuk pajak", inclusive: true, prices: [2]int64{111_000, 50_000}, discount: 27_200, taxBase: 199_800, tax: 19_800, total: 244_800},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tax := TaxSettings{Rate: rate, PricesIncludeTax: tt.inclusive}
+			items := []CartItem{
+				{Product: taxable, ProductID: taxable.ID, BasePrice: money.FromInt(tt.prices[0]), Qty: 2},
+				{Product: exempt, ProductID: exempt.ID, BasePrice: money.FromInt(tt.prices[1]), Qty: 1},
+			}
+			for i := range items {
+				items[i].refreshTotals(tax, decimal.Zero)
+			}
+
+			lines := PromotionLinesFromCartItems(items)
+			subtotal := lines[0].Total.Add(lines[1].Total)
+			applied, err := coupon.Evaluate(lines, subtotal, time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			result := CartPromotionResult{Applied: []AppliedPromotion{applied}}
+			result.allocate(lines)
+
+			totals := money.Totals{}
+			for i := range items {
+				items[i].refreshTotals(tax, result.LineDiscount(i))
+				totals = totals.Add(items[i].LineTotals())
+			}
+
+			if !totals.Discount.Equal(money.FromInt(tt.discount)) {
+				t.Fatalf("diskon = %s, want %d", totals.Discount, tt.discount)
+			}
+			base := totals.Base.Sub(totals.Discount).Sub(items[1].SubTotal) // baris kaos saja, buku bebas pajak
+			if !base.Equal(money.FromInt(tt.taxBase)) {
+				t.Fatalf("dasar pajak = %s, want %d", base, tt.taxBase)
+			}
+
+			want := money.PercentOf(base, rate)
+			if tt.inclusive {
+				want = money.Round(base.Mul(rate).Div(rate.Add(decimal.NewFromInt(100))))
+			}
+			if !totals.Tax.Equal(want) || !totals.Tax.Equal(money.FromInt(tt.tax)) {
+				t.Fatalf("pajak = %s, want %s", totals.Tax, want)
+			}
+			if !items[1].TaxAmount.IsZero() {
+				t.Fatalf("produk bebas pajak kena pajak %s", items[1].TaxAmount)
+			}
+			if !totals.Total.Equal(money.FromInt(tt.total)) {
+				t.Fatalf("total = %s, want %d", totals.Total, tt.total)
+			}
+		})
+	}
+}
@@ -1,9 +1,11 @@
 package models
 
 import (
+	"database/sql"
 	"strings"
 	"time"
 
+	"github.com/alirogz/goshop/app/consts"
 	"gorm.io/gorm"
 )
 
@@ -16,9 +18,17 @@ type User struct {
 	Phone         string
 	Password      string `gorm:"size:255;not null"`
 	RememberToken string `gorm:"size:255;not null"`
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	DeletedAt     gorm.DeletedAt
+	// EmailVerifiedAt: terisi setelah user membuka link verifikasi; akun belum terverifikasi tidak bisa checkout
+	EmailVerifiedAt sql.NullTime
+	// Locale: bahasa email notifikasi (consts.LocaleID / consts.LocaleEN)
+	Locale    string `gorm:"size:5;not null;default:id"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt
+
+	// diisi LoadAccess, tidak disimpan di tabel users
+	Roles       []string      `gorm:"-"`
+	Permissions PermissionSet `gorm:"-"`
 }
 
 func (u *User) FindByEmail(db *gorm.DB, email string) (*User, error) {
@@ -65,3 +75,45 @@ func (u *User) CreateUser(db *gorm.DB, param *User) (*User, error) {
 
 	return user, nil
 }
+
+// IsEmailVerified: email sudah dibuktikan milik user (lewat link verifikasi / reset password)
+func (u *User) IsEmailVerified() bool {
+	return u != nil && u.EmailVerifiedAt.Valid
+}
+
+// EmailLocale: bahasa email untuk user ini, default Bahasa Indonesia
+func (u *User) EmailLocale() string {
+	if u.Locale == consts.LocaleEN {
+		return consts.LocaleEN
+	}
+	return consts.LocaleID
+}
+
+// settingEmailVerifiedBackfill: penanda BackfillEmailVerified sudah pernah jalan
+const settingEmailVerifiedBackfill = "migration.users_email_verified_backfill"
+
+// BackfillEmailVerified: akun lama (dibuat sebelum verifikasi email diwajibkan) dianggap terverifikasi
+// sejak tanggal daftar, supaya tidak terkunci dari checkout. Hanya jalan sekali (dicatat di settings),
+// jadi akun yang mendaftar setelahnya tetap wajib verifikasi.
+func BackfillEmailVerified(db *gorm.DB) (int64, error) {
+	done, err := GetSetting(db, settingEmailVerifiedBackfill, "")
+	if err != nil {
+		return 0, err
+	}
+	if done != "" {
+		return 0, nil
+	}
+
+	var updated int64
+	err = db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Unscoped().Model(&User{}).
+			Where("email_verified_at IS NULL").
+			UpdateColumn("email_verified_at", gorm.Expr("created_at"))
+		if res.Error != nil {
+			return res.Error
+		}
+		updated = res.RowsAffected
+		return SetSetting(tx, settingEmailVerifiedBackfill, time.Now().Format(time.RFC3339), "")
+	})
+	return updated, err
+}
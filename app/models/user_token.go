@@ -0,0 +1,135 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	PasswordResetTTL     = time.Hour
+	EmailVerificationTTL = 48 * time.Hour
+)
+
+var ErrUserTokenInvalid = errors.New("link tidak valid, sudah dipakai, atau sudah kedaluwarsa")
+
+// UserToken: token sekali pakai untuk reset password / verifikasi email.
+// Yang disimpan hanya hash SHA-256; token asli hanya ada di link email.
+type UserToken struct {
+	ID        string `gorm:"size:36;not null;uniqueIndex;primary_key"`
+	UserID    string `gorm:"size:36;not null;index"`
+	User      User
+	Purpose   string `gorm:"size:30;not null;index"` // lihat consts.UserToken*
+	TokenHash string `gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+	CreatedAt time.Time
+}
+
+func (t *UserToken) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == "" {
+		t.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// IssueUserToken: buat token baru (format "<acak>.<tanda tangan HMAC>").
+// Token lama yang belum dipakai untuk keperluan yang sama langsung dibatalkan.
+func IssueUserToken(db *gorm.DB, secret []byte, userID, purpose string, ttl time.Duration) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	random := base64.RawURLEncoding.EncodeToString(b)
+	token := random + "." + signUserToken(secret, purpose, random)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&UserToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+			Update("used_at", sql.NullTime{Time: time.Now(), Valid: true}).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&UserToken{
+			UserID:    userID,
+			Purpose:   purpose,
+			TokenHash: hashUserToken(token),
+			ExpiresAt: time.Now().Add(ttl),
+		}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// FindUserToken: cek token masih berlaku tanpa memakainya (untuk menampilkan form reset)
+func FindUserToken(db *gorm.DB, secret []byte, token, purpose string) (*UserToken, error) {
+	if !validUserTokenSignature(secret, token, purpose) {
+		return nil, ErrUserTokenInvalid
+	}
+
+	var userToken UserToken
+	err := db.Preload("User").
+		Where("token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", hashUserToken(token), purpose, time.Now()).
+		First(&userToken).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserTokenInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &userToken, nil
+}
+
+// ConsumeUserToken: pakai token (sekali saja). Dijalankan di dalam transaksi yang sama
+// dengan perubahan data user supaya token tidak hangus kalau perubahan gagal.
+func ConsumeUserToken(tx *gorm.DB, secret []byte, token, purpose string) (*UserToken, error) {
+	userToken, err := FindUserToken(tx, secret, token, purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	// klaim dengan WHERE used_at IS NULL supaya 2 request bersamaan tidak sama-sama berhasil
+	res := tx.Model(&UserToken{}).
+		Where("id = ? AND used_at IS NULL", userToken.ID).
+		Update("used_at", sql.NullTime{Time: time.Now(), Valid: true})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrUserTokenInvalid
+	}
+
+	return userToken, nil
+}
+
+func signUserToken(secret []byte, purpose, random string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(purpose + "." + random))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func validUserTokenSignature(secret []byte, token, purpose string) bool {
+	random, sig, ok := strings.Cut(token, ".")
+	if !ok || random == "" || sig == "" {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(signUserToken(secret, purpose, random)))
+}
+
+func hashUserToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/alirogz/goshop/app/consts"
+)
+
+func TestUserTokenSignature(t *testing.T) {
+	secret := []byte("test-secret")
+	token := "random." + signUserToken(secret, consts.UserTokenPasswordReset, "random")
+
+	if !validUserTokenSignature(secret, token, consts.UserTokenPasswordReset) {
+		t.Fatal("token asli ditolak")
+	}
+
+	tests := map[string]struct {
+		secret  []byte
+		token   string
+		purpose string
+	}{
+		"keperluan lain":     {secret, token, consts.UserTokenEmailVerification},
+		"kunci lain":         {[]byte("other-secret"), token, consts.UserTokenPasswordReset},
+		"bagian acak diubah": {secret, "randoM." + token[len("random."):], consts.UserTokenPasswordReset},
+		"tanpa tanda tangan": {secret, "random.", consts.UserTokenPasswordReset},
+		"tanpa titik":        {secret, "random", consts.UserTokenPasswordReset},
+	}
+	for name, tt := range tests {
+		if validUserTokenSignature(tt.secret, tt.token, tt.purpose) {
+			t.Errorf("%s: token diterima", name)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+// Package money: semua hitungan harga, pajak & diskon dalam decimal.
+//
+// Aturan pembulatan: nominal uang selalu rupiah penuh, setengah ke atas
+// (0,5 → 1). Setiap baris dibulatkan sendiri lalu total = jumlah baris,
+// jadi total keranjang/order selalu sama persis dengan jumlah barisnya.
+package money
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	hundred = decimal.NewFromInt(100)
+	half    = decimal.New(5, -1)
+)
+
+// Line: 1 baris keranjang / order
+type Line struct {
+	UnitPrice        decimal.Decimal
+	Qty              int
+	TaxPercent       decimal.Decimal // persen, contoh 11 = 11%
+	DiscountPerUnit  decimal.Decimal
+	Discount         decimal.Decimal // potongan untuk seluruh baris (bagian baris ini dari promo keranjang, lihat Allocate)
+	PricesIncludeTax bool            // harga sudah termasuk pajak
+}
+
+// LineTotals: hasil hitung 1 baris (semua sudah rupiah penuh)
+type LineTotals struct {
+	Base     decimal.Decimal // harga x qty
+	Tax      decimal.Decimal // pajak untuk seluruh baris
+	Discount decimal.Decimal // diskon untuk seluruh baris
+	Total    decimal.Decimal // base + pajak - diskon (harga termasuk pajak: base - diskon)
+}
+
+// Totals: jumlah beberapa baris
+type Totals struct {
+	Base     decimal.Decimal
+	Tax      decimal.Decimal
+	Discount decimal.Decimal
+	Total    decimal.Decimal
+}
+
+// Round: bulatkan ke rupiah penuh, setengah ke atas (2,5 → 3; -2,5 → -2)
+func Round(d decimal.Decimal) decimal.Decimal {
+	return d.Add(half).Floor()
+}
+
+// FromInt: nominal rupiah dari angka bulat
+func FromInt(n int64) decimal.Decimal {
+	return decimal.NewFromInt(n)
+}
+
+// Parse: baca nominal dari input form (contoh "14000"), dibulatkan ke rupiah
+func Parse(raw string) (decimal.Decimal, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return decimal.Zero, nil
+	}
+
+	d, err := decimal.NewFromString(raw)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return Round(d), nil
+}
+
+// Rupiah: nominal sebagai angka bulat (dipakai untuk mencocokkan mutasi bank)
+func Rupiah(d decimal.Decimal) int64 {
+	return Round(d).IntPart()
+}
+
+// Equal: sama persis setelah dibulatkan ke rupiah
+func Equal(a, b decimal.Decimal) bool {
+	return Round(a).Equal(Round(b))
+}
+
+// Mul: harga x qty, dibulatkan
+func Mul(price decimal.Decimal, qty int) decimal.Decimal {
+	return Round(price.Mul(decimal.NewFromInt(int64(qty))))
+}
+
+// PercentOf: percent% dari amount, dibulatkan
+func PercentOf(amount, percent decimal.Decimal) decimal.Decimal {
+	return Round(amount.Mul(percent).Div(hundred))
+}
+
+// Ratio: part terhadap whole dalam persen (2 angka di belakang koma), 0 kalau whole <= 0
+func Ratio(part, whole decimal.Decimal) decimal.Decimal {
+	if !whole.IsPositive() {
+		return decimal.Zero
+	}
+	return part.Mul(hundred).Div(whole).Round(2)
+}
+
+// Sum: jumlahkan nominal
+func Sum(values ...decimal.Decimal) decimal.Decimal {
+	total := decimal.Zero
+	for _, v := range values {
+		total = total.Add(v)
+	}
+	return total
+}
+
+// Clamp: batasi nominal ke [0, max]
+func Clamp(d, max decimal.Decimal) decimal.Decimal {
+	if d.IsNegative() {
+		return decimal.Zero
+	}
+	if d.GreaterThan(max) {
+		return max
+	}
+	return d
+}
+
+// Calculate: hitung 1 baris. Pajak dihitung dari total baris setelah diskon (bukan per unit)
+// supaya tidak ada selisih pembulatan per unit.
+// Kalau harga sudah termasuk pajak, Tax = bagian pajak di dalam harga dan tidak ditambahkan lagi.
+func (l Line) Calculate() LineTotals {
+	base := Mul(l.UnitPrice, l.Qty)
+	discount := Clamp(Mul(l.DiscountPerUnit, l.Qty).Add(Round(l.Discount)), base)
+	taxable := base.Sub(discount)
+
+	if l.PricesIncludeTax {
+		tax := Round(taxable.Mul(l.TaxPercent).Div(hundred.Add(l.TaxPercent)))
+		return LineTotals{
+			Base:     base,
+			Tax:      tax,
+			Discount: discount,
+			Total:    taxable,
+		}
+	}
+
+	tax := PercentOf(taxable, l.TaxPercent)
+	return LineTotals{
+		Base:     base,
+		Tax:      tax,
+		Discount: discount,
+		Total:    taxable.Add(tax),
+	}
+}
+
+// Add: tambahkan 1 baris ke total
+func (t Totals) Add(line LineTotals) Totals {
+	return Totals{
+		Base:     t.Base.Add(line.Base),
+		Tax:      t.Tax.Add(line.Tax),
+		Discount: t.Discount.Add(line.Discount),
+		Total:    t.Total.Add(line.Total),
+	}
+}
+
+// SumLines: total dari beberapa baris
+func SumLines(lines []LineTotals) Totals {
+	totals := Totals{Base: decimal.Zero, Tax: decimal.Zero, Discount: decimal.Zero, Total: decimal.Zero}
+	for _, line := range lines {
+		totals = totals.Add(line)
+	}
+	return totals
+}
+
+// Allocate: bagi amount (rupiah penuh) ke beberapa baris sebanding weights.
+// Sisa pembulatan diberikan ke baris dengan pecahan terbesar, jadi jumlah hasilnya selalu sama dengan amount.
+// Semua weight 0 (atau negatif) = tidak ada yang dapat bagian.
+func Allocate(amount decimal.Decimal, weights []decimal.Decimal) []decimal.Decimal {
+	shares := make([]decimal.Decimal, len(weights))
+	total := decimal.Zero
+	for i, w := range weights {
+		shares[i] = decimal.Zero
+		if w.IsPositive() {
+			total = total.Add(w)
+		}
+	}
+	amount = Round(amount)
+	if !total.IsPositive() || !amount.IsPositive() {
+		return shares
+	}
+
+	type remainder struct {
+		index int
+		frac  decimal.Decimal
+	}
+	var remainders []remainder
+	left := amount
+	for i, w := range weights {
+		if !w.IsPositive() {
+			continue
+		}
+		exact := amount.Mul(w).Div(total)
+		shares[i] = exact.Floor()
+		left = left.Sub(shares[i])
+		remainders = append(remainders, remainder{index: i, frac: exact.Sub(shares[i])})
+	}
+
+	sort.SliceStable(remainders, func(a, b int) bool {
+		return remainders[a].frac.GreaterThan(remainders[b].frac)
+	})
+	for i := 0; left.IsPositive(); i++ {
+		r := remainders[i%len(remainders)]
+		shares[r.index] = shares[r.index].Add(decimal.NewFromInt(1))
+		left = left.Sub(decimal.NewFromInt(1))
+	}
+	return shares
+}
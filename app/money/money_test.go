@@ -0,0 +1,221 @@
+package money
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// properti dicek dengan data acak (seed tetap supaya hasil bisa diulang)
+const propertyRuns = 5000
+
+func isWholeRupiah(d decimal.Decimal) bool {
+	return d.Equal(d.Truncate(0))
+}
+
+// randomAmount: nominal sampai 2 angka di belakang koma, kadang tepat ,5
+func randomAmount(rng *rand.Rand, max int64) decimal.Decimal {
+	if rng.Intn(4) == 0 {
+		return decimal.New(rng.Int63n(max)*10+5, -1)
+	}
+	return decimal.New(rng.Int63n(max*100), -2)
+}
+
+func randomLine(rng *rand.Rand) Line {
+	return Line{
+		UnitPrice:        randomAmount(rng, 2_000_000),
+		Qty:              rng.Intn(50) + 1,
+		TaxPercent:       decimal.New(rng.Int63n(2500), -2), // 0 - 24,99%
+		DiscountPerUnit:  randomAmount(rng, 100_000),
+		PricesIncludeTax: rng.Intn(2) == 0,
+	}
+}
+
+func TestRoundHalfUp(t *testing.T) {
+	tests := map[string]string{
+		"0.5":      "1",
+		"1.5":      "2",
+		"2.5":      "3",
+		"2.49":     "2",
+		"2.51":     "3",
+		"-2.5":     "-2",
+		"-2.51":    "-3",
+		"14999.5":  "15000",
+		"10000":    "10000",
+		"0.499999": "0",
+	}
+	for in, want := range tests {
+		if got := Round(decimal.RequireFromString(in)); !got.Equal(decimal.RequireFromString(want)) {
+			t.Errorf("Round(%s) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestRoundProperties(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < propertyRuns; i++ {
+		d := randomAmount(rng, 10_000_000)
+		if rng.Intn(2) == 0 {
+			d = d.Neg()
+		}
+		got := Round(d)
+
+		if !isWholeRupiah(got) {
+			t.Fatalf("Round(%s) = %s bukan rupiah penuh", d, got)
+		}
+		if diff := got.Sub(d); diff.GreaterThan(half) || diff.LessThanOrEqual(half.Neg()) {
+			t.Fatalf("Round(%s) = %s, selisih %s di luar (-0,5, 0,5]", d, got, diff)
+		}
+		if frac := d.Sub(d.Floor()); frac.Equal(half) && !got.Equal(d.Ceil()) {
+			t.Fatalf("Round(%s) = %s, ,5 harus ke atas", d, got)
+		}
+		if !Round(got).Equal(got) {
+			t.Fatalf("Round tidak idempoten untuk %s", d)
+		}
+	}
+}
+
+func TestLineTotalsProperties(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	for i := 0; i < propertyRuns; i++ {
+		line := randomLine(rng)
+		got := line.Calculate()
+
+		for name, v := range map[string]decimal.Decimal{"Base": got.Base, "Tax": got.Tax, "Discount": got.Discount, "Total": got.Total} {
+			if !isWholeRupiah(v) {
+				t.Fatalf("%+v: %s = %s bukan rupiah penuh", line, name, v)
+			}
+		}
+		if got.Discount.IsNegative() || got.Discount.GreaterThan(got.Base) {
+			t.Fatalf("%+v: diskon %s di luar [0, %s]", line, got.Discount, got.Base)
+		}
+		if got.Tax.IsNegative() {
+			t.Fatalf("%+v: pajak negatif %s", line, got.Tax)
+		}
+
+		gross := got.Base.Sub(got.Discount)
+		if line.PricesIncludeTax {
+			if !got.Total.Equal(gross) {
+				t.Fatalf("%+v: harga termasuk pajak, total %s != %s", line, got.Total, gross)
+			}
+		} else if !got.Total.Equal(gross.Add(got.Tax)) {
+			t.Fatalf("%+v: total %s != %s + pajak %s", line, got.Total, gross, got.Tax)
+		}
+	}
+}
+
+// harga termasuk pajak: bruto dipecah jadi neto + pajak tanpa selisih, dan pajaknya sesuai tarif dari neto
+func TestInclusiveTaxSplit(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	for i := 0; i < propertyRuns; i++ {
+		line := randomLine(rng)
+		line.PricesIncludeTax = true
+		got := line.Calculate()
+
+		gross := got.Total
+		net := gross.Sub(got.Tax)
+		if !net.Add(got.Tax).Equal(gross) || !isWholeRupiah(net) {
+			t.Fatalf("%+v: neto %s + pajak %s != bruto %s", line, net, got.Tax, gross)
+		}
+		if net.IsNegative() {
+			t.Fatalf("%+v: neto negatif %s", line, net)
+		}
+
+		// pajak = tarif x neto, paling jauh selisih pembulatan (0,5 dari pajak + 0,5 dari neto)
+		exact := net.Mul(line.TaxPercent).Div(hundred)
+		if diff := got.Tax.Sub(exact).Abs(); diff.GreaterThan(decimal.NewFromInt(1)) {
+			t.Fatalf("%+v: pajak %s, seharusnya ±%s", line, got.Tax, exact)
+		}
+	}
+
+	// contoh tetap: 111.000 termasuk PPN 11% = 100.000 + 11.000
+	got := Line{UnitPrice: FromInt(111_000), Qty: 1, TaxPercent: decimal.NewFromInt(11), PricesIncludeTax: true}.Calculate()
+	if !got.Tax.Equal(FromInt(11_000)) || !got.Total.Equal(FromInt(111_000)) {
+		t.Fatalf("111.000 incl. 11%%: pajak %s, total %s", got.Tax, got.Total)
+	}
+}
+
+// total order = jumlah total baris, tanpa selisih pembulatan
+func TestSumLinesEqualsLineTotals(t *testing.T) {
+	rng := rand.New(rand.NewSource(4))
+	for i := 0; i < propertyRuns/10; i++ {
+		var lines []LineTotals
+		base, tax, discount, total := decimal.Zero, decimal.Zero, decimal.Zero, decimal.Zero
+		for n := rng.Intn(20) + 1; n > 0; n-- {
+			line := randomLine(rng).Calculate()
+			lines = append(lines, line)
+			base, tax = base.Add(line.Base), tax.Add(line.Tax)
+			discount, total = discount.Add(line.Discount), total.Add(line.Total)
+		}
+
+		got := SumLines(lines)
+		if !got.Total.Equal(total) || !got.Base.Equal(base) || !got.Tax.Equal(tax) || !got.Discount.Equal(discount) {
+			t.Fatalf("SumLines = %+v, jumlah baris = %s/%s/%s/%s", got, base, tax, discount, total)
+		}
+		if !isWholeRupiah(got.Total) {
+			t.Fatalf("total %s bukan rupiah penuh", got.Total)
+		}
+	}
+
+	if got := SumLines(nil); !got.Total.IsZero() {
+		t.Fatalf("SumLines(nil).Total = %s", got.Total)
+	}
+}
+
+// potongan keranjang dibagi ke baris: jumlahnya tepat, sebanding bobot, baris berbobot 0 tidak dapat bagian
+func TestAllocateProperties(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	one := decimal.NewFromInt(1)
+	for i := 0; i < propertyRuns; i++ {
+		weights := make([]decimal.Decimal, rng.Intn(10)+1)
+		total := decimal.Zero
+		for j := range weights {
+			if rng.Intn(5) > 0 {
+				weights[j] = Mul(randomAmount(rng, 2_000_000), rng.Intn(5)+1)
+			}
+			total = total.Add(weights[j])
+		}
+		amount := Round(randomAmount(rng, 500_000))
+
+		shares := Allocate(amount, weights)
+		sum := Sum(shares...)
+		if !total.IsPositive() {
+			if !sum.IsZero() {
+				t.Fatalf("bobot 0 semua tapi terbagi %s", sum)
+			}
+			continue
+		}
+		if !sum.Equal(amount) {
+			t.Fatalf("Allocate(%s, %v) = %v, jumlah %s", amount, weights, shares, sum)
+		}
+		for j, share := range shares {
+			if !isWholeRupiah(share) || share.IsNegative() {
+				t.Fatalf("bagian %s bukan rupiah penuh / negatif", share)
+			}
+			exact := amount.Mul(weights[j]).Div(total)
+			if share.Sub(exact).Abs().GreaterThanOrEqual(one) {
+				t.Fatalf("bagian %s, seharusnya ±%s", share, exact)
+			}
+		}
+	}
+
+	got := Allocate(FromInt(100), []decimal.Decimal{FromInt(1), FromInt(1), FromInt(1)})
+	if !Sum(got...).Equal(FromInt(100)) {
+		t.Fatalf("Allocate(100, 1/1/1) = %v", got)
+	}
+}
+
+// diskon seluruh baris mengurangi dasar pajak
+func TestLineDiscountBeforeTax(t *testing.T) {
+	line := Line{UnitPrice: FromInt(50_000), Qty: 2, TaxPercent: decimal.NewFromInt(11), Discount: FromInt(10_000)}
+	got := line.Calculate()
+	if !got.Discount.Equal(FromInt(10_000)) || !got.Tax.Equal(FromInt(9_900)) || !got.Total.Equal(FromInt(99_900)) {
+		t.Fatalf("got %+v", got)
+	}
+
+	line.Discount = FromInt(500_000)
+	if got := line.Calculate(); !got.Discount.Equal(FromInt(100_000)) || !got.Total.IsZero() {
+		t.Fatalf("diskon melebihi baris: %+v", got)
+	}
+}
@@ -0,0 +1,96 @@
+package notifications
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/alirogz/goshop/app/mailer"
+	"github.com/alirogz/goshop/app/models"
+	"gorm.io/gorm"
+)
+
+// Dispatcher: kirim notifikasi yang sudah jatuh tempo di outbox.
+// Aman dijalankan di beberapa instance: tiap baris diklaim dulu sebelum dikirim.
+type Dispatcher struct {
+	DB      *gorm.DB
+	Mailer  mailer.Mailer
+	AppName string
+	BaseURL string // dipakai untuk link absolut di email
+
+	Batch       int           // maksimal notifikasi per putaran (default 50)
+	Lease       time.Duration // lama klaim sebelum baris boleh diambil lagi (default 5 menit)
+	SendTimeout time.Duration // batas waktu kirim 1 email (default 30 detik)
+}
+
+// RunOnce: 1 putaran kirim. Gagal kirim tidak menghentikan putaran; baris dijadwal ulang.
+func (d *Dispatcher) RunOnce(ctx context.Context, now time.Time) (sent, failed int, err error) {
+	lease := d.Lease
+	if lease <= 0 {
+		lease = 5 * time.Minute
+	}
+
+	due, err := models.ClaimDueNotifications(d.DB, now, lease, d.Batch)
+	for i := range due {
+		if d.deliver(ctx, &due[i], now) {
+			sent++
+		} else {
+			failed++
+		}
+	}
+
+	return sent, failed, err
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, n *models.Notification, now time.Time) bool {
+	email, err := d.render(n)
+	if err == nil {
+		err = d.send(ctx, n.Recipient, email)
+	}
+
+	if err != nil {
+		if markErr := n.MarkFailed(d.DB, email.Subject, err, now); markErr != nil {
+			log.Println("notification MarkFailed error:", n.ID, markErr)
+		}
+		log.Printf("notification %s (%s ke %s) gagal, percobaan %d: %v", n.ID, n.Kind, n.Recipient, n.Attempts, err)
+		return false
+	}
+
+	if err := n.MarkSent(d.DB, email.Subject, time.Now()); err != nil {
+		log.Println("notification MarkSent error:", n.ID, err)
+	}
+	return true
+}
+
+func (d *Dispatcher) render(n *models.Notification) (Email, error) {
+	data, err := n.Data()
+	if err != nil {
+		return Email{}, err
+	}
+
+	baseURL := strings.TrimRight(d.BaseURL, "/")
+	data["app_name"] = d.AppName
+	data["base_url"] = baseURL
+	if path, ok := data["path"].(string); ok {
+		data["link"] = baseURL + path
+	}
+
+	return Render(n.Kind, n.Locale, data)
+}
+
+func (d *Dispatcher) send(ctx context.Context, to string, email Email) error {
+	timeout := d.SendTimeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	return d.Mailer.Send(ctx, mailer.Message{
+		To:      []string{to},
+		Subject: email.Subject,
+		Body:    email.Text,
+		HTML:    email.HTML,
+	})
+}
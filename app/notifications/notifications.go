@@ -0,0 +1,151 @@
+// Package notifications: template email transaksional (Bahasa Indonesia & Inggris) dan
+// dispatcher yang mengirim isi outbox (models.Notification) lewat mailer.
+package notifications
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/alirogz/goshop/app/consts"
+	"github.com/shopspring/decimal"
+)
+
+// Struktur folder: templates/<locale>/<kind>.txt berisi define "subject" & "text",
+// templates/<locale>/<kind>.html berisi define "content" yang dibungkus templates/layout.html.
+//
+//go:embed templates
+var templateFS embed.FS
+
+var ErrUnknownKind = errors.New("jenis notifikasi tidak dikenal")
+
+// Kinds: semua jenis notifikasi yang punya template
+var Kinds = []string{
+	consts.NotificationOrderPlaced,
+	consts.NotificationPaymentApproved,
+	consts.NotificationPaymentRejected,
+	consts.NotificationOrderStatus,
+	consts.NotificationChatMessage,
+}
+
+var locales = []string{consts.LocaleID, consts.LocaleEN}
+
+// Email: hasil render satu notifikasi
+type Email struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+type emailTemplate struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// catalog[locale][kind]; diparse sekali saat start supaya template rusak langsung ketahuan
+var catalog = mustLoadTemplates()
+
+func mustLoadTemplates() map[string]map[string]emailTemplate {
+	out := map[string]map[string]emailTemplate{}
+	for _, locale := range locales {
+		funcs := templateFuncs(locale)
+		out[locale] = map[string]emailTemplate{}
+		for _, kind := range Kinds {
+			base := "templates/" + locale + "/" + kind
+			text := texttemplate.Must(texttemplate.New(kind).Funcs(funcs).ParseFS(templateFS, base+".txt"))
+			html := htmltemplate.Must(htmltemplate.New(kind).Funcs(funcs).ParseFS(templateFS, "templates/layout.html", base+".html"))
+			out[locale][kind] = emailTemplate{text: text, html: html}
+		}
+	}
+	return out
+}
+
+// Render: buat subjek, versi teks & HTML. Locale tidak dikenal jatuh ke Bahasa Indonesia.
+func Render(kind, locale string, data map[string]interface{}) (Email, error) {
+	templates, ok := catalog[locale]
+	if !ok {
+		templates = catalog[consts.LocaleID]
+	}
+	tmpl, ok := templates[kind]
+	if !ok {
+		return Email{}, fmt.Errorf("%w: %s", ErrUnknownKind, kind)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := tmpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Email{}, err
+	}
+	if err := tmpl.text.ExecuteTemplate(&text, "text", data); err != nil {
+		return Email{}, err
+	}
+	if err := tmpl.html.ExecuteTemplate(&html, "layout", data); err != nil {
+		return Email{}, err
+	}
+
+	return Email{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
+
+var statusLabels = map[string]map[string]string{
+	consts.LocaleID: {
+		"pending":    "Menunggu pembayaran",
+		"processing": "Diproses",
+		"shipped":    "Dikirim",
+		"completed":  "Selesai",
+		"cancelled":  "Dibatalkan",
+		"refunded":   "Dikembalikan (refund)",
+	},
+	consts.LocaleEN: {
+		"pending":    "Awaiting payment",
+		"processing": "Processing",
+		"shipped":    "Shipped",
+		"completed":  "Completed",
+		"cancelled":  "Cancelled",
+		"refunded":   "Refunded",
+	},
+}
+
+func templateFuncs(locale string) map[string]interface{} {
+	return map[string]interface{}{
+		"rupiah": formatRupiah,
+		"statusLabel": func(name interface{}) string {
+			key := fmt.Sprint(name)
+			if label, ok := statusLabels[locale][key]; ok {
+				return label
+			}
+			return key
+		},
+	}
+}
+
+// formatRupiah: "150000.00" → "Rp 150.000" (payload menyimpan nominal sebagai string desimal)
+func formatRupiah(value interface{}) string {
+	d, err := decimal.NewFromString(fmt.Sprint(value))
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+
+	n := d.Round(0).IntPart()
+	sign := ""
+	if n < 0 {
+		sign = "-"
+		n = -n
+	}
+
+	digits := fmt.Sprint(n)
+	var grouped strings.Builder
+	for i, c := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			grouped.WriteByte('.')
+		}
+		grouped.WriteRune(c)
+	}
+	return sign + "Rp " + grouped.String()
+}
@@ -0,0 +1,205 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alirogz/goshop/app/consts"
+	"github.com/alirogz/goshop/app/models"
+	"github.com/google/uuid"
+)
+
+// FakeServer: tiruan Snap API Midtrans untuk development & test.
+// Pakai bersama MidtransGateway (BaseURL = alamat FakeServer), misalnya lewat httptest.NewServer.
+// Halaman /pay/{token} menyediakan tombol untuk mengirim notifikasi bertanda tangan ke NotifyURL.
+type FakeServer struct {
+	ServerKey  string
+	NotifyURL  string // webhook toko, contoh http://localhost:9000/payments/notification
+	BasePath   string // prefix path kalau di-mount di router lain, contoh "/payments/fake"
+	HTTPClient *http.Client
+
+	mu      sync.Mutex
+	charges map[string]fakeCharge // token → tagihan
+}
+
+type fakeCharge struct {
+	OrderID     string
+	GrossAmount int64
+	FinishURL   string
+}
+
+func NewFakeServer(serverKey, notifyURL, basePath string) *FakeServer {
+	return &FakeServer{
+		ServerKey:  serverKey,
+		NotifyURL:  notifyURL,
+		BasePath:   strings.TrimRight(basePath, "/"),
+		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+		charges:    map[string]fakeCharge{},
+	}
+}
+
+func (f *FakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, f.BasePath)
+
+	switch {
+	case path == "/snap/v1/transactions" && r.Method == http.MethodPost:
+		f.createTransaction(w, r)
+	case strings.HasPrefix(path, "/pay/") && r.Method == http.MethodGet:
+		f.payPage(w, r, strings.TrimPrefix(path, "/pay/"))
+	case strings.HasPrefix(path, "/pay/") && r.Method == http.MethodPost:
+		f.pay(w, r, strings.TrimPrefix(path, "/pay/"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *FakeServer) createTransaction(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if key, _, ok := r.BasicAuth(); !ok || key != f.ServerKey {
+		w.WriteHeader(http.StatusUnauthorized)
+		_ = json.NewEncoder(w).Encode(snapResponse{ErrorMessages: []string{"Access denied due to unauthorized transaction"}})
+		return
+	}
+
+	var req snapRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.TransactionDetails.OrderID == "" || req.TransactionDetails.GrossAmount <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(snapResponse{ErrorMessages: []string{"transaction_details is required"}})
+		return
+	}
+
+	token := uuid.New().String()
+	f.mu.Lock()
+	f.charges[token] = fakeCharge{
+		OrderID:     req.TransactionDetails.OrderID,
+		GrossAmount: req.TransactionDetails.GrossAmount,
+		FinishURL:   req.Callbacks.Finish,
+	}
+	f.mu.Unlock()
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(snapResponse{
+		Token:       token,
+		RedirectURL: scheme + "://" + r.Host + f.BasePath + "/pay/" + token,
+	})
+}
+
+var fakePayTemplate = template.Must(template.New("fake_pay").Parse(`<!doctype html>
+<html><head><meta charset="utf-8"><title>Fake Payment</title></head>
+<body style="font-family:sans-serif;max-width:420px;margin:40px auto;">
+<h3>Fake Payment Gateway</h3>
+<p>Order: <code>{{ .OrderID }}</code><br>Total: Rp {{ .GrossAmount }}</p>
+<form method="POST">
+<button name="status" value="settlement">Bayar (settlement)</button>
+<button name="status" value="pending">Pending</button>
+<button name="status" value="deny">Tolak (deny)</button>
+<button name="status" value="expire">Kedaluwarsa</button>
+</form>
+</body></html>`))
+
+func (f *FakeServer) payPage(w http.ResponseWriter, r *http.Request, token string) {
+	charge, ok := f.charge(token)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	_ = fakePayTemplate.Execute(w, charge)
+}
+
+func (f *FakeServer) pay(w http.ResponseWriter, r *http.Request, token string) {
+	charge, ok := f.charge(token)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	status := r.FormValue("status")
+	if status == "" {
+		status = consts.PaymentStatusSettlement
+	}
+
+	n := f.Notification(charge.OrderID, status, strconv.FormatInt(charge.GrossAmount, 10)+".00")
+	if err := f.Notify(r.Context(), n); err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	if charge.FinishURL != "" {
+		http.Redirect(w, r, charge.FinishURL, http.StatusSeeOther)
+		return
+	}
+	fmt.Fprintf(w, "notifikasi %s terkirim", status)
+}
+
+func (f *FakeServer) charge(token string) (fakeCharge, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	charge, ok := f.charges[token]
+	return charge, ok
+}
+
+// Notification: notifikasi Midtrans yang sudah ditandatangani dengan ServerKey
+func (f *FakeServer) Notification(orderID, transactionStatus, grossAmount string) models.MidtransNotification {
+	statusCode := "200"
+	switch transactionStatus {
+	case consts.PaymentStatusPending:
+		statusCode = "201"
+	case consts.PaymentStatusDeny, consts.PaymentStatusCancel, consts.PaymentStatusExpire, consts.PaymentStatusFailure:
+		statusCode = "202"
+	}
+
+	n := models.MidtransNotification{
+		TransactionTime:   time.Now().Format("2006-01-02 15:04:05"),
+		TransactionStatus: transactionStatus,
+		TransactionID:     "FAKE-" + orderID,
+		StatusMessage:     "fake notification",
+		StatusCode:        statusCode,
+		PaymentType:       "bank_transfer",
+		OrderID:           orderID,
+		GrossAmount:       grossAmount,
+		FraudStatus:       consts.FraudStatusAccept,
+		Currency:          "IDR",
+	}
+	n.SignatureKey = Signature(n.OrderID, n.StatusCode, n.GrossAmount, f.ServerKey)
+
+	return n
+}
+
+// Notify: kirim notifikasi ke webhook toko
+func (f *FakeServer) Notify(ctx context.Context, n models.MidtransNotification) error {
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.NotifyURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := f.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook membalas HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+// Package payment: antarmuka payment gateway + adapter Midtrans & gateway palsu untuk lokal/test.
+package payment
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/alirogz/goshop/app/models"
+	"github.com/shopspring/decimal"
+)
+
+// Status: status pembayaran versi toko (hasil mapping status gateway)
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusPaid      Status = "paid"
+	StatusChallenge Status = "challenge" // perlu review manual di dashboard gateway
+	StatusFailed    Status = "failed"    // deny, cancel, expire, failure
+	StatusRefunded  Status = "refunded"
+)
+
+var (
+	ErrInvalidSignature = errors.New("signature notifikasi tidak valid")
+	ErrGatewayDisabled  = errors.New("payment gateway belum dikonfigurasi")
+)
+
+// ChargeRequest: data untuk membuat tagihan di gateway
+type ChargeRequest struct {
+	OrderID       string          // order_id di gateway, unik per percobaan (lihat AttemptOrderID)
+	GrossAmount   decimal.Decimal // rupiah penuh
+	CustomerName  string
+	CustomerEmail string
+	CustomerPhone string
+	FinishURL     string // halaman yang dibuka setelah pembeli selesai bayar
+}
+
+// Charge: hasil pembuatan tagihan
+type Charge struct {
+	Token       string
+	RedirectURL string
+}
+
+// PaymentGateway: kontrak yang harus dipenuhi setiap payment gateway
+type PaymentGateway interface {
+	// Name: nama gateway (disimpan di payments.payment_type kalau notifikasi tidak membawa jenis)
+	Name() string
+	// CreateCharge: buat tagihan, pembeli diarahkan ke RedirectURL
+	CreateCharge(ctx context.Context, req ChargeRequest) (*Charge, error)
+	// VerifyNotification: cek signature notifikasi webhook
+	VerifyNotification(n models.MidtransNotification) error
+	// MapStatus: ubah status gateway jadi Status toko
+	MapStatus(n models.MidtransNotification) Status
+}
+
+// AttemptOrderID: order_id yang dikirim ke gateway untuk percobaan bayar ke-attempt.
+// Midtrans menolak order_id yang sudah pernah dipakai, jadi tiap tagihan baru memakai akhiran berbeda.
+func AttemptOrderID(orderID string, attempt int) string {
+	return orderID + "-" + strconv.Itoa(attempt)
+}
+
+// SplitAttemptOrderID: kebalikan AttemptOrderID. ok false kalau id tidak berakhiran nomor percobaan
+// (misalnya notifikasi tagihan lama yang masih memakai ID order apa adanya).
+func SplitAttemptOrderID(id string) (orderID string, attempt int, ok bool) {
+	i := strings.LastIndex(id, "-")
+	if i <= 0 {
+		return id, 0, false
+	}
+	attempt, err := strconv.Atoi(id[i+1:])
+	if err != nil || attempt <= 0 || strconv.Itoa(attempt) != id[i+1:] {
+		return id, 0, false
+	}
+	return id[:i], attempt, true
+}
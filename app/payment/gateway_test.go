@@ -0,0 +1,25 @@
+package payment
+
+import "testing"
+
+func TestAttemptOrderID(t *testing.T) {
+	orderID := "3f1c2b9e-6a47-4d0e-9b1a-2c5d7e8f9a01"
+
+	for _, attempt := range []int{1, 2, 15} {
+		id := AttemptOrderID(orderID, attempt)
+		gotID, gotAttempt, ok := SplitAttemptOrderID(id)
+		if !ok || gotID != orderID || gotAttempt != attempt {
+			t.Fatalf("SplitAttemptOrderID(%q) = %q, %d, %v", id, gotID, gotAttempt, ok)
+		}
+	}
+	if AttemptOrderID(orderID, 1) == AttemptOrderID(orderID, 2) {
+		t.Fatal("order_id percobaan berbeda harus berbeda")
+	}
+
+	// order_id tanpa nomor percobaan (tagihan lama)
+	for _, id := range []string{"3f1c2b9e-6a47-4d0e-9b1a-2c5d7e8f9abc", "order-1-x", "order-1-01", "order-1-0", "-1", "order"} {
+		if _, _, ok := SplitAttemptOrderID(id); ok {
+			t.Errorf("SplitAttemptOrderID(%q) dianggap punya nomor percobaan", id)
+		}
+	}
+}
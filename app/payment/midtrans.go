@@ -0,0 +1,160 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/alirogz/goshop/app/consts"
+	"github.com/alirogz/goshop/app/models"
+)
+
+// URL Snap Midtrans
+const (
+	MidtransSandboxURL    = "https://app.sandbox.midtrans.com"
+	MidtransProductionURL = "https://app.midtrans.com"
+)
+
+// MidtransGateway: adapter Snap API Midtrans (juga dipakai untuk FakeServer)
+type MidtransGateway struct {
+	ServerKey  string
+	BaseURL    string // tanpa "/" di akhir, contoh MidtransSandboxURL
+	HTTPClient *http.Client
+}
+
+func NewMidtransGateway(serverKey, baseURL string) *MidtransGateway {
+	if baseURL == "" {
+		baseURL = MidtransSandboxURL
+	}
+
+	return &MidtransGateway{
+		ServerKey:  serverKey,
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		HTTPClient: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (g *MidtransGateway) Name() string {
+	return "midtrans"
+}
+
+type snapRequest struct {
+	TransactionDetails struct {
+		OrderID     string `json:"order_id"`
+		GrossAmount int64  `json:"gross_amount"`
+	} `json:"transaction_details"`
+	CustomerDetails struct {
+		FirstName string `json:"first_name,omitempty"`
+		Email     string `json:"email,omitempty"`
+		Phone     string `json:"phone,omitempty"`
+	} `json:"customer_details"`
+	Callbacks struct {
+		Finish string `json:"finish,omitempty"`
+	} `json:"callbacks"`
+}
+
+type snapResponse struct {
+	Token         string   `json:"token"`
+	RedirectURL   string   `json:"redirect_url"`
+	ErrorMessages []string `json:"error_messages"`
+}
+
+// CreateCharge: POST /snap/v1/transactions (basic auth server key)
+func (g *MidtransGateway) CreateCharge(ctx context.Context, req ChargeRequest) (*Charge, error) {
+	if g.ServerKey == "" {
+		return nil, ErrGatewayDisabled
+	}
+
+	var body snapRequest
+	body.TransactionDetails.OrderID = req.OrderID
+	body.TransactionDetails.GrossAmount = req.GrossAmount.Round(0).IntPart()
+	body.CustomerDetails.FirstName = req.CustomerName
+	body.CustomerDetails.Email = req.CustomerEmail
+	body.CustomerDetails.Phone = req.CustomerPhone
+	body.Callbacks.Finish = req.FinishURL
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, g.BaseURL+"/snap/v1/transactions", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.SetBasicAuth(g.ServerKey, "")
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+
+	resp, err := g.HTTPClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	var snap snapResponse
+	if err := json.Unmarshal(raw, &snap); err != nil {
+		return nil, fmt.Errorf("respon midtrans tidak valid (HTTP %d)", resp.StatusCode)
+	}
+	if resp.StatusCode >= 300 || snap.Token == "" {
+		return nil, fmt.Errorf("midtrans menolak transaksi (HTTP %d): %s", resp.StatusCode, strings.Join(snap.ErrorMessages, "; "))
+	}
+
+	return &Charge{Token: snap.Token, RedirectURL: snap.RedirectURL}, nil
+}
+
+// VerifyNotification: signature_key = SHA512(order_id + status_code + gross_amount + server key)
+func (g *MidtransGateway) VerifyNotification(n models.MidtransNotification) error {
+	if g.ServerKey == "" {
+		return ErrGatewayDisabled
+	}
+
+	expected := Signature(n.OrderID, n.StatusCode, n.GrossAmount, g.ServerKey)
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(strings.ToLower(n.SignatureKey))) != 1 {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// MapStatus: tabel status Midtrans → Status toko
+func (g *MidtransGateway) MapStatus(n models.MidtransNotification) Status {
+	switch n.TransactionStatus {
+	case consts.PaymentStatusCapture:
+		if n.FraudStatus == "" || n.FraudStatus == consts.FraudStatusAccept {
+			return StatusPaid
+		}
+		if n.FraudStatus == consts.FraudStatusChallenge {
+			return StatusChallenge
+		}
+		return StatusFailed
+	case consts.PaymentStatusSettlement:
+		return StatusPaid
+	case consts.PaymentStatusPending:
+		return StatusPending
+	case consts.PaymentStatusDeny, consts.PaymentStatusCancel, consts.PaymentStatusExpire, consts.PaymentStatusFailure:
+		return StatusFailed
+	case consts.PaymentStatusRefund, consts.PaymentStatusPartialRefund:
+		return StatusRefunded
+	default:
+		return StatusPending
+	}
+}
+
+// Signature: hitung signature_key notifikasi Midtrans
+func Signature(orderID, statusCode, grossAmount, serverKey string) string {
+	sum := sha512.Sum512([]byte(orderID + statusCode + grossAmount + serverKey))
+	return hex.EncodeToString(sum[:])
+}
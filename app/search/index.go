@@ -0,0 +1,162 @@
+// Package search: pencarian produk (full-text + filter facet + saran ejaan).
+// Bagian yang beda per database ada di balik interface Index.
+package search
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/alirogz/goshop/app/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// indexName: nama index full-text di tabel products
+const indexName = "idx_products_search"
+
+// Index: kontrak pencarian teks yang harus dipenuhi setiap database.
+// terms sudah bersih (huruf/angka saja, huruf kecil), lihat Terms.
+type Index interface {
+	// Filter: batasi query ke produk yang cocok dengan SEMUA terms (kata terakhir boleh prefix)
+	Filter(tx *gorm.DB, terms []string) *gorm.DB
+	// Score: ekspresi skor relevansi untuk produk yang lolos Filter (makin besar makin relevan)
+	Score(terms []string) clause.Expr
+}
+
+// NewIndex: pilih Index sesuai driver database. Kalau index full-text belum dibuat
+// (db:migrate belum dijalankan) dipakai LikeIndex supaya pencarian tetap jalan.
+func NewIndex(db *gorm.DB) Index {
+	var index Index
+	switch db.Dialector.Name() {
+	case "mysql":
+		index = MySQLIndex{}
+	case "postgres":
+		index = PostgresIndex{}
+	default:
+		return LikeIndex{}
+	}
+
+	if !db.Migrator().HasIndex(&models.Product{}, indexName) {
+		log.Printf("search: index %s belum ada, pakai LIKE (jalankan db:migrate)", indexName)
+		return LikeIndex{}
+	}
+	return index
+}
+
+// Migrate: buat index full-text di tabel products kalau belum ada (dipanggil db:migrate)
+func Migrate(db *gorm.DB) error {
+	if db.Migrator().HasIndex(&models.Product{}, indexName) {
+		return nil
+	}
+
+	switch db.Dialector.Name() {
+	case "mysql":
+		return db.Exec("ALTER TABLE products ADD FULLTEXT INDEX " + indexName + " (name, short_description, description, sku)").Error
+	case "postgres":
+		return db.Exec("CREATE INDEX " + indexName + " ON products USING GIN ((" + postgresVector + "))").Error
+	}
+	return nil
+}
+
+// MySQLIndex: FULLTEXT InnoDB dalam BOOLEAN MODE.
+// Kata yang lebih pendek dari innodb_ft_min_token_size (default 3) diabaikan FULLTEXT, jadi dicari pakai LIKE.
+type MySQLIndex struct{}
+
+const mysqlMatch = "MATCH (name, short_description, description, sku) AGAINST (? IN BOOLEAN MODE)"
+
+func (MySQLIndex) Filter(tx *gorm.DB, terms []string) *gorm.DB {
+	long, short := splitShortTerms(terms, 3)
+	if len(long) > 0 {
+		tx = tx.Where(mysqlMatch, mysqlBoolean(long))
+	}
+	for _, t := range short {
+		tx = tx.Where("(name LIKE ? OR sku LIKE ?)", "%"+t+"%", t+"%")
+	}
+	return tx
+}
+
+func (MySQLIndex) Score(terms []string) clause.Expr {
+	long, _ := splitShortTerms(terms, 3)
+	if len(long) == 0 {
+		return nameScore(terms)
+	}
+	score := nameScore(terms)
+	return clause.Expr{SQL: mysqlMatch + " + ?", Vars: []interface{}{mysqlBoolean(long), score}}
+}
+
+// "kaos hitam" → "+kaos* +hitam*" (semua kata wajib, boleh prefix)
+func mysqlBoolean(terms []string) string {
+	parts := make([]string, len(terms))
+	for i, t := range terms {
+		parts[i] = "+" + t + "*"
+	}
+	return strings.Join(parts, " ")
+}
+
+// PostgresIndex: tsvector (config "simple", tanpa stemming bahasa) dengan bobot nama > SKU > deskripsi
+type PostgresIndex struct{}
+
+const postgresVector = "setweight(to_tsvector('simple', coalesce(name, '')), 'A') || " +
+	"setweight(to_tsvector('simple', coalesce(sku, '')), 'B') || " +
+	"setweight(to_tsvector('simple', coalesce(short_description, '') || ' ' || coalesce(description, '')), 'C')"
+
+func (PostgresIndex) Filter(tx *gorm.DB, terms []string) *gorm.DB {
+	return tx.Where("("+postgresVector+") @@ to_tsquery('simple', ?)", postgresQuery(terms))
+}
+
+func (PostgresIndex) Score(terms []string) clause.Expr {
+	return clause.Expr{
+		SQL:  "ts_rank((" + postgresVector + "), to_tsquery('simple', ?)) + ?",
+		Vars: []interface{}{postgresQuery(terms), nameScore(terms)},
+	}
+}
+
+// "kaos hitam" → "kaos:* & hitam:*"
+func postgresQuery(terms []string) string {
+	parts := make([]string, len(terms))
+	for i, t := range terms {
+		parts[i] = t + ":*"
+	}
+	return strings.Join(parts, " & ")
+}
+
+// LikeIndex: LIKE biasa, untuk database lain atau kalau index full-text belum dibuat
+type LikeIndex struct{}
+
+func (LikeIndex) Filter(tx *gorm.DB, terms []string) *gorm.DB {
+	for _, t := range terms {
+		like := "%" + t + "%"
+		tx = tx.Where("(LOWER(name) LIKE ? OR LOWER(sku) LIKE ? OR LOWER(short_description) LIKE ? OR LOWER(description) LIKE ?)", like, like, like, like)
+	}
+	return tx
+}
+
+func (LikeIndex) Score(terms []string) clause.Expr {
+	return nameScore(terms)
+}
+
+// nameScore: +1 untuk setiap kata yang ada di nama produk, +2 kalau SKU sama persis dengan kata tsb
+func nameScore(terms []string) clause.Expr {
+	var sql []string
+	var vars []interface{}
+	for _, t := range terms {
+		sql = append(sql, "(CASE WHEN LOWER(name) LIKE ? THEN 1 ELSE 0 END) + (CASE WHEN LOWER(sku) = ? THEN 2 ELSE 0 END)")
+		vars = append(vars, "%"+t+"%", t)
+	}
+	if len(sql) == 0 {
+		return clause.Expr{SQL: "0"}
+	}
+	return clause.Expr{SQL: fmt.Sprintf("(%s)", strings.Join(sql, " + ")), Vars: vars}
+}
+
+func splitShortTerms(terms []string, min int) (long, short []string) {
+	for _, t := range terms {
+		if len([]rune(t)) < min {
+			short = append(short, t)
+		} else {
+			long = append(long, t)
+		}
+	}
+	return long, short
+}
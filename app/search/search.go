@@ -0,0 +1,476 @@
+package search
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alirogz/goshop/app/consts"
+	"github.com/alirogz/goshop/app/models"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// vocabularyTTL: kata-kata untuk saran ejaan dimuat ulang dari DB paling cepat tiap 5 menit
+const vocabularyTTL = 5 * time.Minute
+
+// Query: parameter pencarian / daftar produk
+type Query struct {
+	Text string
+
+	// OnlyCategories: hanya produk di CategoryIDs (turunan sudah dimasukkan pemanggil).
+	// CategoryIDs kosong + OnlyCategories = tidak ada hasil (mis. section tanpa kategori).
+	CategoryIDs    []string
+	OnlyCategories bool
+
+	MinPrice decimal.NullDecimal
+	MaxPrice decimal.NullDecimal
+	Sizes    []string
+	Colors   []string
+	InStock  bool
+
+	Sort    string // consts.ProductSort*, kosong = relevansi (ada Text) / terbaru
+	Page    int
+	PerPage int // 0 = semua hasil tanpa pagination (admin)
+}
+
+// FacetValue: 1 pilihan filter + jumlah produk kalau pilihan itu dicentang
+type FacetValue struct {
+	Value    string
+	Count    int
+	Selected bool
+}
+
+// Facets: pilihan filter dari produk yang cocok. Jumlah tiap facet dihitung dengan
+// filter lain tetap aktif, tapi filter facet itu sendiri diabaikan.
+type Facets struct {
+	Sizes    []FacetValue
+	Colors   []FacetValue
+	InStock  int // produk yang stoknya ada
+	PriceMin decimal.Decimal
+	PriceMax decimal.Decimal
+}
+
+// Result: 1 halaman hasil pencarian
+type Result struct {
+	Products   []models.Product
+	Total      int64
+	Facets     Facets
+	Sort       string // urutan yang benar-benar dipakai
+	Suggestion string // "mungkin maksud Anda": diisi kalau hasil kosong & ada kata yang bisa dikoreksi
+}
+
+// Suggestions: isi autocomplete kotak pencarian
+type Suggestions struct {
+	Correction  string // koreksi ejaan, hanya kalau tidak ada produk yang cocok
+	Completions []string
+	Products    []models.Product
+}
+
+// Engine: pencarian produk di atas Index sesuai database
+type Engine struct {
+	DB    *gorm.DB
+	Index Index
+
+	mu         sync.Mutex
+	vocabulary []string
+	loadedAt   time.Time
+}
+
+func New(db *gorm.DB) *Engine {
+	return &Engine{DB: db, Index: NewIndex(db)}
+}
+
+// Search: cari produk, hitung facet lalu ambil 1 halaman.
+// Filter, urutan & pagination dijalankan di database, facet dihitung dengan GROUP BY,
+// jadi yang dimuat ke memori hanya produk di halaman yang diminta.
+func (e *Engine) Search(q Query) (*Result, error) {
+	terms := Terms(q.Text)
+	result := &Result{Sort: resolveSort(q.Sort, terms)}
+
+	if q.OnlyCategories && len(q.CategoryIDs) == 0 {
+		return result, nil
+	}
+
+	if err := e.filtered(q, terms, "").Count(&result.Total).Error; err != nil {
+		return nil, err
+	}
+
+	facets, err := e.facets(q, terms)
+	if err != nil {
+		return nil, err
+	}
+	result.Facets = facets
+
+	result.Products = []models.Product{}
+	if result.Total == 0 {
+		if len(terms) > 0 {
+			if vocabulary, err := e.Vocabulary(); err == nil {
+				result.Suggestion = Correct(terms, vocabulary)
+			}
+		}
+		return result, nil
+	}
+
+	err = e.page(q, terms, result.Sort).
+		Preload("ProductImages", models.OrderProductImages).
+		Find(&result.Products).Error
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Suggest: autocomplete kotak pencarian (kata lanjutan, koreksi ejaan & beberapa produk teratas)
+func (e *Engine) Suggest(text string, limit int) (*Suggestions, error) {
+	terms := Terms(text)
+	out := &Suggestions{Completions: []string{}}
+	if len(terms) == 0 {
+		return out, nil
+	}
+
+	vocabulary, err := e.Vocabulary()
+	if err != nil {
+		return nil, err
+	}
+
+	last := terms[len(terms)-1]
+	head := strings.Join(terms[:len(terms)-1], " ")
+	for _, w := range Complete(last, vocabulary, limit) {
+		out.Completions = append(out.Completions, strings.TrimSpace(head+" "+w))
+	}
+
+	err = e.Index.Filter(e.DB.Model(&models.Product{}), terms).
+		Clauses(clause.OrderBy{Expression: clause.Expr{SQL: "? DESC", Vars: []interface{}{e.Index.Score(terms)}, WithoutParentheses: true}}).
+		Limit(limit).
+		Find(&out.Products).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if len(out.Products) == 0 {
+		out.Correction = Correct(terms, vocabulary)
+	}
+	return out, nil
+}
+
+// Vocabulary: kata dari nama produk & kategori (cache vocabularyTTL), dasar saran ejaan & autocomplete
+func (e *Engine) Vocabulary() ([]string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.vocabulary != nil && time.Since(e.loadedAt) < vocabularyTTL {
+		return e.vocabulary, nil
+	}
+
+	var names, categoryNames []string
+	if err := e.DB.Model(&models.Product{}).Pluck("name", &names).Error; err != nil {
+		return nil, err
+	}
+	if err := e.DB.Model(&models.Category{}).Pluck("name", &categoryNames).Error; err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	vocabulary := []string{}
+	for _, name := range append(names, categoryNames...) {
+		for _, w := range Terms(name) {
+			if !seen[w] && len([]rune(w)) >= 2 {
+				seen[w] = true
+				vocabulary = append(vocabulary, w)
+			}
+		}
+	}
+	sort.Strings(vocabulary)
+
+	e.vocabulary, e.loadedAt = vocabulary, time.Now()
+	return vocabulary, nil
+}
+
+// scope: produk yang cocok dengan kata pencarian & kategori
+func (e *Engine) scope(q Query, terms []string) *gorm.DB {
+	tx := e.DB.Model(&models.Product{})
+	if len(terms) > 0 {
+		tx = e.Index.Filter(tx, terms)
+	}
+	if q.OnlyCategories {
+		tx = tx.Where("id IN (?)", e.DB.Table("product_categories").Select("product_id").Where("category_id IN ?", q.CategoryIDs))
+	}
+	return tx
+}
+
+// hasVariants: produk punya varian aktif; ukuran/warna/stok produk bervarian diambil dari variannya
+const hasVariants = "EXISTS (SELECT 1 FROM product_variants WHERE product_variants.product_id = products.id AND product_variants.deleted_at IS NULL)"
+
+// filtered: scope + semua filter facet, kecuali facet skip ("price", "size", "color", "stock")
+func (e *Engine) filtered(q Query, terms []string, skip string) *gorm.DB {
+	tx := e.scope(q, terms)
+	if skip != "price" {
+		if q.MinPrice.Valid {
+			tx = tx.Where("price >= ?", q.MinPrice.Decimal)
+		}
+		if q.MaxPrice.Valid {
+			tx = tx.Where("price <= ?", q.MaxPrice.Decimal)
+		}
+	}
+	if sizes := normalizeOptions(q.Sizes); skip != "size" && len(sizes) > 0 {
+		tx = tx.Where(optionFilter("size", "size_options", sizes))
+	}
+	if colors := normalizeOptions(q.Colors); skip != "color" && len(colors) > 0 {
+		tx = tx.Where(optionFilter("color", "color_options", colors))
+	}
+	if skip != "stock" && q.InStock {
+		tx = tx.Where(q.inStockFilter())
+	}
+	return tx
+}
+
+// optionFilter: produk bervarian cocok kalau ada varian dengan ukuran/warna terpilih;
+// produk lama tanpa varian dicocokkan ke daftar "S, M, L" di kolom optionsColumn (tanpa beda huruf besar/kecil & spasi)
+func optionFilter(variantColumn, optionsColumn string, selected []string) clause.Expr {
+	var likes []string
+	vars := []interface{}{selected}
+	for _, s := range selected {
+		likes = append(likes, "CONCAT(',', REPLACE(LOWER("+optionsColumn+"), ' ', ''), ',') LIKE ?")
+		vars = append(vars, "%,"+escapeLike(strings.ReplaceAll(s, " ", ""))+",%")
+	}
+
+	return clause.Expr{
+		SQL: "(EXISTS (SELECT 1 FROM product_variants WHERE product_variants.product_id = products.id AND product_variants.deleted_at IS NULL" +
+			" AND LOWER(TRIM(product_variants." + variantColumn + ")) IN ?)" +
+			" OR (NOT " + hasVariants + " AND (" + strings.Join(likes, " OR ") + ")))",
+		Vars: vars,
+	}
+}
+
+// inStockFilter: produk bervarian dianggap ada stok kalau ada varian (sesuai filter ukuran/warna) yang stoknya > 0
+func (q Query) inStockFilter() clause.Expr {
+	sql := "EXISTS (SELECT 1 FROM product_variants WHERE product_variants.product_id = products.id AND product_variants.deleted_at IS NULL" +
+		" AND product_variants.stock > 0"
+	var vars []interface{}
+	if sizes := normalizeOptions(q.Sizes); len(sizes) > 0 {
+		sql += " AND LOWER(TRIM(product_variants.size)) IN ?"
+		vars = append(vars, sizes)
+	}
+	if colors := normalizeOptions(q.Colors); len(colors) > 0 {
+		sql += " AND LOWER(TRIM(product_variants.color)) IN ?"
+		vars = append(vars, colors)
+	}
+	sql += ")"
+
+	return clause.Expr{SQL: "(" + sql + " OR (NOT " + hasVariants + " AND stock > 0))", Vars: vars}
+}
+
+// page: produk yang lolos semua filter, sudah diurutkan & dipotong 1 halaman (PerPage 0 = semua)
+func (e *Engine) page(q Query, terms []string, by string) *gorm.DB {
+	tx := e.filtered(q, terms, "").Clauses(orderBy(by, e.Index.Score(terms)))
+	if q.PerPage > 0 {
+		page := q.Page
+		if page < 1 {
+			page = 1
+		}
+		tx = tx.Limit(q.PerPage).Offset((page - 1) * q.PerPage)
+	}
+	return tx
+}
+
+// orderBy: urutan hasil; produk dengan nilai sama diurutkan terbaru dulu lalu ID supaya pagination stabil
+func orderBy(by string, score clause.Expr) clause.OrderBy {
+	const tieBreak = "created_at DESC, id ASC"
+
+	expr := clause.Expr{SQL: tieBreak}
+	switch by {
+	case consts.ProductSortRelevance:
+		expr = clause.Expr{SQL: "? DESC, " + tieBreak, Vars: []interface{}{score}, WithoutParentheses: true}
+	case consts.ProductSortPriceAsc:
+		expr = clause.Expr{SQL: "price ASC, " + tieBreak}
+	case consts.ProductSortPriceDesc:
+		expr = clause.Expr{SQL: "price DESC, " + tieBreak}
+	case consts.ProductSortBestSelling:
+		expr = clause.Expr{
+			SQL: "(SELECT COALESCE(SUM(order_items.qty), 0) FROM order_items JOIN orders ON orders.id = order_items.order_id" +
+				" WHERE order_items.product_id = products.id AND orders.payment_status = ? AND orders.status NOT IN ?) DESC, " + tieBreak,
+			Vars: []interface{}{consts.OrderPaymentStatusPaid, []int{consts.OrderStatusCancelled, consts.OrderStatusRefunded}},
+		}
+	}
+	return clause.OrderBy{Expression: expr}
+}
+
+// facets: pilihan filter beserta jumlah produknya, tiap facet dengan filter lain tetap aktif
+func (e *Engine) facets(q Query, terms []string) (Facets, error) {
+	var facets Facets
+
+	var price struct {
+		PriceMin decimal.NullDecimal
+		PriceMax decimal.NullDecimal
+	}
+	if err := e.filtered(q, terms, "price").Select("MIN(price) AS price_min, MAX(price) AS price_max").Scan(&price).Error; err != nil {
+		return facets, err
+	}
+	facets.PriceMin, facets.PriceMax = price.PriceMin.Decimal, price.PriceMax.Decimal
+
+	var inStock int64
+	if err := e.filtered(q, terms, "stock").Where(q.inStockFilter()).Count(&inStock).Error; err != nil {
+		return facets, err
+	}
+	facets.InStock = int(inStock)
+
+	sizes, err := e.optionFacet(q, terms, "size", "size_options", q.Sizes)
+	if err != nil {
+		return facets, err
+	}
+	colors, err := e.optionFacet(q, terms, "color", "color_options", q.Colors)
+	if err != nil {
+		return facets, err
+	}
+
+	facets.Sizes = sizes.values(lessSize)
+	facets.Colors = colors.values(func(a, b string) bool { return strings.ToLower(a) < strings.ToLower(b) })
+	return facets, nil
+}
+
+type facetRow struct {
+	Value string
+	Total int
+}
+
+// optionFacet: jumlah produk per ukuran/warna. Produk bervarian dihitung dari varian (GROUP BY nilai),
+// produk tanpa varian dari daftar opsi (GROUP BY isi kolom, dipecah per opsi di sini).
+func (e *Engine) optionFacet(q Query, terms []string, variantColumn, optionsColumn string, selected []string) (*facetCounter, error) {
+	counter := newFacetCounter(selected)
+	products := e.filtered(q, terms, variantColumn)
+
+	var variantRows []facetRow
+	err := e.DB.Model(&models.ProductVariant{}).
+		Select("MIN(TRIM("+variantColumn+")) AS value, COUNT(DISTINCT product_id) AS total").
+		Where("product_id IN (?)", products.Select("id")).
+		Where("TRIM(" + variantColumn + ") <> ''").
+		Group("LOWER(TRIM(" + variantColumn + "))").
+		Scan(&variantRows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range variantRows {
+		counter.add([]string{row.Value}, row.Total)
+	}
+
+	var optionRows []facetRow
+	err = e.filtered(q, terms, variantColumn).
+		Where("NOT " + hasVariants).
+		Where(optionsColumn + " <> ''").
+		Select(optionsColumn + " AS value, COUNT(*) AS total").
+		Group(optionsColumn).
+		Scan(&optionRows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range optionRows {
+		counter.add(strings.Split(row.Value, ","), row.Total)
+	}
+
+	return counter, nil
+}
+
+// normalizeOptions: pilihan filter dari URL jadi huruf kecil tanpa spasi di ujung, tanpa duplikat
+func normalizeOptions(values []string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, v := range values {
+		key := strings.ToLower(strings.TrimSpace(v))
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, key)
+	}
+	return out
+}
+
+// escapeLike: % dan _ dari input dicari apa adanya
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// facetCounter: hitung pilihan facet tanpa beda huruf besar/kecil, tampil dengan ejaan pertama yang ditemukan
+type facetCounter struct {
+	counts   map[string]*FacetValue
+	selected map[string]bool
+}
+
+func newFacetCounter(selected []string) *facetCounter {
+	f := &facetCounter{counts: map[string]*FacetValue{}, selected: map[string]bool{}}
+	for _, s := range selected {
+		key := strings.ToLower(strings.TrimSpace(s))
+		if key == "" {
+			continue
+		}
+		f.selected[key] = true
+		// pilihan yang dicentang tetap tampil walau jumlahnya 0, supaya bisa dilepas
+		f.counts[key] = &FacetValue{Value: strings.TrimSpace(s), Selected: true}
+	}
+	return f
+}
+
+// add: tambahkan n produk untuk setiap nilai di values (nilai yang sama dalam 1 produk dihitung sekali)
+func (f *facetCounter) add(values []string, n int) {
+	seen := map[string]bool{}
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		key := strings.ToLower(v)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		fv, ok := f.counts[key]
+		if !ok {
+			fv = &FacetValue{Value: v, Selected: f.selected[key]}
+			f.counts[key] = fv
+		} else if fv.Count == 0 {
+			fv.Value = v // ejaan dari produk, bukan dari URL
+		}
+		fv.Count += n
+	}
+}
+
+func (f *facetCounter) values(less func(a, b string) bool) []FacetValue {
+	out := make([]FacetValue, 0, len(f.counts))
+	for _, fv := range f.counts {
+		out = append(out, *fv)
+	}
+	sort.Slice(out, func(i, j int) bool { return less(out[i].Value, out[j].Value) })
+	return out
+}
+
+// sizeOrder: urutan ukuran baju; ukuran angka diurutkan sebagai angka, sisanya abjad
+var sizeOrder = map[string]int{"xxs": 1, "xs": 2, "s": 3, "m": 4, "l": 5, "xl": 6, "xxl": 7, "2xl": 7, "xxxl": 8, "3xl": 8, "4xl": 9}
+
+func lessSize(a, b string) bool {
+	ra, rb := sizeOrder[strings.ToLower(a)], sizeOrder[strings.ToLower(b)]
+	if ra > 0 && rb > 0 {
+		return ra < rb
+	}
+	if ra > 0 || rb > 0 {
+		return ra > 0
+	}
+	na, errA := strconv.ParseFloat(a, 64)
+	nb, errB := strconv.ParseFloat(b, 64)
+	if errA == nil && errB == nil {
+		return na < nb
+	}
+	return strings.ToLower(a) < strings.ToLower(b)
+}
+
+func resolveSort(sort string, terms []string) string {
+	switch sort {
+	case consts.ProductSortNewest, consts.ProductSortPriceAsc, consts.ProductSortPriceDesc, consts.ProductSortBestSelling:
+		return sort
+	}
+	if len(terms) > 0 {
+		return consts.ProductSortRelevance
+	}
+	return consts.ProductSortNewest
+}
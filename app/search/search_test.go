@@ -0,0 +1,198 @@
+package search
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/alirogz/goshop/app/consts"
+	"github.com/alirogz/goshop/app/models"
+	"github.com/shopspring/decimal"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func TestTerms(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"", []string{}},
+		{"  Kaos   Polos ", []string{"kaos", "polos"}},
+		{"kaos+polos* \"hitam\" -(xl)", []string{"kaos", "polos", "hitam", "xl"}},
+		{"Sepatu 42 Ü", []string{"sepatu", "42", "ü"}},
+		{"a b c d e f g h i j", []string{"a", "b", "c", "d", "e", "f", "g", "h"}},
+	}
+	for _, tt := range tests {
+		if got := Terms(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Terms(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestCorrect(t *testing.T) {
+	vocabulary := []string{"kaos", "kemeja", "polos", "sepatu", "celana", "hitam"}
+	tests := []struct {
+		terms []string
+		want  string
+	}{
+		{[]string{"kaos", "polos"}, ""}, // semua kata dikenal
+		{[]string{"kaso"}, "kaos"},      // tukar 2 huruf bersebelahan
+		{[]string{"kameja", "htam"}, "kemeja hitam"},
+		{[]string{"sptu"}, ""},                 // kata pendek hanya boleh salah 1 huruf
+		{[]string{"sepatoo"}, "sepatu"},        // kata panjang boleh salah 2 huruf
+		{[]string{"xy", "celan"}, "xy celana"}, // kata < 3 huruf dibiarkan
+		{[]string{"laptop"}, ""},
+	}
+	for _, tt := range tests {
+		if got := Correct(tt.terms, vocabulary); got != tt.want {
+			t.Errorf("Correct(%q) = %q, want %q", tt.terms, got, tt.want)
+		}
+	}
+}
+
+func TestComplete(t *testing.T) {
+	vocabulary := []string{"kemeja", "kaos", "kaoskaki", "kain", "ka", "celana"}
+	if got, want := Complete("ka", vocabulary, 10), []string{"kain", "kaos", "kaoskaki"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Complete = %q, want %q", got, want)
+	}
+	if got, want := Complete("ka", vocabulary, 2), []string{"kain", "kaos"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Complete limit 2 = %q, want %q", got, want)
+	}
+	if got := Complete("sepatu", vocabulary, 10); len(got) != 0 {
+		t.Fatalf("Complete tanpa hasil = %q", got)
+	}
+}
+
+func TestLessSize(t *testing.T) {
+	sizes := []string{"kids", "42", "XL", "m", "3XL", "40", "S", "allsize", "39.5", "XXS"}
+	sort.Slice(sizes, func(i, j int) bool { return lessSize(sizes[i], sizes[j]) })
+
+	want := []string{"XXS", "S", "m", "XL", "3XL", "39.5", "40", "42", "allsize", "kids"}
+	if !reflect.DeepEqual(sizes, want) {
+		t.Fatalf("urutan ukuran = %q, want %q", sizes, want)
+	}
+}
+
+func TestFacetCounter(t *testing.T) {
+	f := newFacetCounter([]string{" xl ", "Hijau", ""})
+	f.add([]string{"M", "XL", "m"}, 3) // "m" dobel di 1 produk dihitung sekali
+	f.add([]string{" xl", "S", ""}, 2)
+	f.add(strings.Split("S, L", ","), 1)
+
+	want := []FacetValue{
+		{Value: "S", Count: 3},
+		{Value: "M", Count: 3},
+		{Value: "L", Count: 1},
+		{Value: "XL", Count: 5, Selected: true},
+		{Value: "Hijau", Count: 0, Selected: true}, // pilihan tercentang tetap tampil
+	}
+	if got := f.values(lessSize); !reflect.DeepEqual(got, want) {
+		t.Fatalf("values = %+v\nwant %+v", got, want)
+	}
+}
+
+// dryRunEngine: Engine tanpa koneksi DB, query hanya dibangun supaya SQL-nya bisa diperiksa
+func dryRunEngine(t *testing.T) *Engine {
+	t.Helper()
+	db, err := gorm.Open(mysql.New(mysql.Config{SkipInitializeWithVersion: true, DSN: "user:pass@tcp(127.0.0.1:3306)/goshop"}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Engine{DB: db, Index: LikeIndex{}}
+}
+
+func dryRunSQL(tx *gorm.DB) (string, []interface{}) {
+	var products []models.Product
+	stmt := tx.Find(&products).Statement
+	return stmt.SQL.String(), stmt.Vars
+}
+
+func TestFilteredSQL(t *testing.T) {
+	e := dryRunEngine(t)
+	q := Query{
+		MinPrice: decimal.NewNullDecimal(decimal.NewFromInt(50_000)),
+		Sizes:    []string{" XL", "xl", "Big_Size"},
+		Colors:   []string{"Hijau Army"},
+		InStock:  true,
+	}
+
+	sql, vars := dryRunSQL(e.filtered(q, nil, ""))
+	for _, part := range []string{
+		"price >= ?",
+		"LOWER(TRIM(product_variants.size)) IN (?,?)",
+		"CONCAT(',', REPLACE(LOWER(size_options), ' ', ''), ',') LIKE ?",
+		"LOWER(TRIM(product_variants.color)) IN (?)",
+		"product_variants.stock > 0",
+		"AND stock > 0",
+		"`products`.`deleted_at` IS NULL",
+	} {
+		if !strings.Contains(sql, part) {
+			t.Errorf("SQL tidak berisi %q:\n%s", part, sql)
+		}
+	}
+	// pilihan ukuran di-lowercase & dedupe, LIKE tanpa spasi dan wildcard di-escape
+	for _, v := range []interface{}{"xl", "big_size", "%,xl,%", `%,big\_size,%`, "hijau army", "%,hijauarmy,%"} {
+		if !containsVar(vars, v) {
+			t.Errorf("vars %v tidak berisi %q", vars, v)
+		}
+	}
+
+	// facet ukuran: filter ukuran sendiri diabaikan, filter lain tetap
+	sql, _ = dryRunSQL(e.filtered(q, nil, "size"))
+	if strings.Contains(sql, "size_options") || !strings.Contains(sql, "color_options") || !strings.Contains(sql, "price >= ?") {
+		t.Errorf("filtered skip size:\n%s", sql)
+	}
+	sql, _ = dryRunSQL(e.filtered(q, nil, "price"))
+	if strings.Contains(sql, "price >= ?") {
+		t.Errorf("filtered skip price:\n%s", sql)
+	}
+}
+
+func containsVar(vars []interface{}, want interface{}) bool {
+	for _, v := range vars {
+		if reflect.DeepEqual(v, want) {
+			return true
+		}
+		if list, ok := v.([]string); ok {
+			for _, s := range list {
+				if s == want {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func TestPageSQL(t *testing.T) {
+	e := dryRunEngine(t)
+	terms := []string{"kaos"}
+	tests := []struct {
+		sort    string
+		page    int
+		perPage int
+		order   string
+		limit   string
+	}{
+		{consts.ProductSortRelevance, 2, 9, "ORDER BY ((CASE WHEN LOWER(name) LIKE ?", "LIMIT 9 OFFSET 9"},
+		{consts.ProductSortPriceAsc, 0, 9, "ORDER BY price ASC, created_at DESC, id ASC", "LIMIT 9"},
+		{consts.ProductSortPriceDesc, 3, 12, "ORDER BY price DESC, created_at DESC, id ASC", "LIMIT 12 OFFSET 24"},
+		{consts.ProductSortBestSelling, 1, 9, "ORDER BY (SELECT COALESCE(SUM(order_items.qty), 0) FROM order_items", "LIMIT 9"},
+		{consts.ProductSortNewest, 1, 0, "ORDER BY created_at DESC, id ASC", ""},
+	}
+	for _, tt := range tests {
+		sql, _ := dryRunSQL(e.page(Query{Page: tt.page, PerPage: tt.perPage}, terms, tt.sort))
+		if !strings.Contains(sql, tt.order) {
+			t.Errorf("%s: SQL tidak berisi %q:\n%s", tt.sort, tt.order, sql)
+		}
+		if tt.limit == "" && strings.Contains(sql, "LIMIT") || !strings.HasSuffix(sql, tt.limit) {
+			t.Errorf("%s: SQL harus diakhiri %q:\n%s", tt.sort, tt.limit, sql)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// maxTerms: kata setelah ini diabaikan (query super panjang tidak ada gunanya)
+const maxTerms = 8
+
+// Terms: pecah teks pencarian jadi kata huruf kecil (huruf/angka saja).
+// Karakter lain dibuang supaya aman dipakai di sintaks FULLTEXT / tsquery.
+func Terms(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) > maxTerms {
+		words = words[:maxTerms]
+	}
+	return words
+}
+
+// Correct: perbaiki kata yang tidak dikenal ke kata terdekat di vocabulary (jarak edit kecil).
+// Hasil kosong kalau tidak ada yang bisa/perlu diperbaiki.
+func Correct(terms []string, vocabulary []string) string {
+	known := make(map[string]bool, len(vocabulary))
+	for _, w := range vocabulary {
+		known[w] = true
+	}
+
+	changed := false
+	out := make([]string, len(terms))
+	for i, t := range terms {
+		out[i] = t
+		if known[t] || len([]rune(t)) < 3 {
+			continue
+		}
+
+		limit := maxEditDistance(t)
+		best, bestDist := "", 0
+		for _, w := range vocabulary {
+			d := editDistance(t, w, limit+1)
+			if d > limit {
+				continue
+			}
+			if best == "" || d < bestDist || (d == bestDist && w < best) {
+				best, bestDist = w, d
+			}
+		}
+		if best != "" {
+			out[i] = best
+			changed = true
+		}
+	}
+
+	if !changed {
+		return ""
+	}
+	return strings.Join(out, " ")
+}
+
+// Complete: kata di vocabulary yang diawali prefix (untuk autocomplete), urut abjad
+func Complete(prefix string, vocabulary []string, limit int) []string {
+	var out []string
+	for _, w := range vocabulary {
+		if w != prefix && strings.HasPrefix(w, prefix) {
+			out = append(out, w)
+		}
+	}
+	sort.Strings(out)
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+// kata pendek hanya boleh salah 1 huruf, kata panjang 2
+func maxEditDistance(term string) int {
+	if len([]rune(term)) <= 4 {
+		return 1
+	}
+	return 2
+}
+
+// editDistance: jarak Damerau-Levenshtein (termasuk tukar 2 huruf bersebelahan).
+// Berhenti lebih awal dan mengembalikan limit kalau jaraknya pasti >= limit.
+func editDistance(a, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	if diff := len(ra) - len(rb); diff >= limit || -diff >= limit {
+		return limit
+	}
+
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] && prev2[j-2]+1 < cur[j] {
+				cur[j] = prev2[j-2] + 1
+			}
+			if cur[j] < rowMin {
+				rowMin = cur[j]
+			}
+		}
+		if rowMin >= limit {
+			return limit
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+
+	if prev[len(rb)] > limit {
+		return limit
+	}
+	return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -4,6 +4,7 @@ import (
 	"flag"
 	"log"
 	"os"
+	"time"
 
 	"github.com/alirogz/goshop/app/controllers"
 	"github.com/joho/godotenv"
@@ -17,6 +18,22 @@ func getEnv(key, fallback string) string {
 	return fallback
 }
 
+// getDurationEnv: durasi dari env (contoh "5m", "24h"), fallback kalau kosong / tidak valid
+func getDurationEnv(key string, fallback time.Duration) time.Duration {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("invalid %s=%q, pakai default %s", key, value, fallback)
+		return fallback
+	}
+
+	return d
+}
+
 func Run() {
 	var server = controllers.Server{}
 	var appConfig = controllers.AppConfig{}
@@ -28,9 +45,31 @@ func Run() {
 	}
 
 	appConfig.AppName = getEnv("APP_NAME", "GoToko")
-	appConfig.AppEnv = getEnv("APP_ENV", "development")
+	appConfig.AppEnv = getEnv("APP_ENV", "production")
 	appConfig.AppPort = getEnv("APP_PORT", "9000")
 	appConfig.AppURL = getEnv("APP_URL", "http://localhost:9000")
+	appConfig.PaymentGateway = getEnv("PAYMENT_GATEWAY", "")
+	appConfig.MidtransServerKey = getEnv("MIDTRANS_SERVER_KEY", "")
+	appConfig.MidtransBaseURL = getEnv("MIDTRANS_BASE_URL", "")
+	appConfig.PaymentExpiryInterval = getDurationEnv("PAYMENT_EXPIRY_INTERVAL", 5*time.Minute)
+	appConfig.PaymentReminderBefore = getDurationEnv("PAYMENT_REMINDER_BEFORE", 24*time.Hour)
+	appConfig.NotificationInterval = getDurationEnv("NOTIFICATION_INTERVAL", 30*time.Second)
+	appConfig.MailDriver = getEnv("MAIL_DRIVER", "")
+	appConfig.SMTPHost = getEnv("SMTP_HOST", "")
+	appConfig.SMTPPort = getEnv("SMTP_PORT", "587")
+	appConfig.SMTPUsername = getEnv("SMTP_USERNAME", "")
+	appConfig.SMTPPassword = getEnv("SMTP_PASSWORD", "")
+	appConfig.MailFrom = getEnv("MAIL_FROM", "")
+	appConfig.StorageDriver = getEnv("STORAGE_DRIVER", "local")
+	appConfig.StorageUploadDir = getEnv("STORAGE_UPLOAD_DIR", "public/uploads")
+	appConfig.StoragePrivateDir = getEnv("STORAGE_PRIVATE_DIR", "storage")
+	appConfig.S3Endpoint = getEnv("S3_ENDPOINT", "")
+	appConfig.S3Region = getEnv("S3_REGION", "us-east-1")
+	appConfig.S3Bucket = getEnv("S3_BUCKET", "")
+	appConfig.S3AccessKey = getEnv("S3_ACCESS_KEY", "")
+	appConfig.S3SecretKey = getEnv("S3_SECRET_KEY", "")
+	appConfig.S3PublicURL = getEnv("S3_PUBLIC_URL", "")
+	appConfig.ChatAttachmentDir = getEnv("CHAT_ATTACHMENT_DIR", "storage/chat_attachments")
 
 	dbConfig.DBHost = getEnv("DB_HOST", "localhost")
 	dbConfig.DBUser = getEnv("DB_USER", "root")
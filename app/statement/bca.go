@@ -0,0 +1,141 @@
+package statement
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// BCAParser: export mutasi KlikBCA (CSV).
+//
+//	No. rekening : ,'0123456789
+//	Periode : ,01/10/2026 - 17/10/2026
+//	Tanggal Transaksi,Keterangan,Cabang,Jumlah,,Saldo
+//	'01/10,TRSF E-BANKING CR 0110/FTSCY/WS95031 150123.00 BUDI,'0000,150123.00,CR,1650123.00
+type BCAParser struct{}
+
+// nomor referensi di keterangan BCA, contoh 0110/FTSCY/WS95031
+var bcaRefPattern = regexp.MustCompile(`\b\d{4}/[A-Z]{5}/[A-Z0-9]+\b`)
+
+// baris ringkasan di bawah tabel mutasi
+var bcaSummaryRows = []string{"saldo awal", "mutasi kredit", "mutasi debet", "saldo akhir"}
+
+func (BCAParser) Name() string  { return "bca" }
+func (BCAParser) Label() string { return "BCA (KlikBCA CSV)" }
+
+func (BCAParser) Detect(data []byte) bool {
+	h := head(data)
+	return strings.Contains(h, "tanggal transaksi") && strings.Contains(h, "keterangan") && strings.Contains(h, "cabang")
+}
+
+func (BCAParser) Parse(data []byte) (*Statement, error) {
+	st := &Statement{Bank: "BCA"}
+
+	rows, errs := readCSV(data, "tanggal transaksi")
+	st.Errors = append(st.Errors, errs...)
+
+	headerAt := findHeader(rows, "tanggal transaksi", "keterangan")
+	if headerAt < 0 {
+		return nil, errors.New("header \"Tanggal Transaksi\" tidak ditemukan")
+	}
+
+	// info rekening & periode di atas header
+	var periodEnd time.Time
+	for _, row := range rows[:headerAt] {
+		label := strings.ToLower(row.Get(0))
+		value := row.Get(1)
+		switch {
+		case strings.HasPrefix(label, "no. rekening"), strings.HasPrefix(label, "no rekening"):
+			st.Account = value
+		case strings.HasPrefix(label, "periode"):
+			if parts := strings.Split(value, "-"); len(parts) == 2 {
+				periodEnd, _ = parseDate(parts[1], "02/01/2006")
+			}
+		}
+	}
+	if periodEnd.IsZero() {
+		periodEnd = time.Now()
+	}
+
+	idx := headerIndex(rows[headerAt].Fields)
+	dateCol := column(idx, "tanggal transaksi")
+	noteCol := column(idx, "keterangan")
+	amountCol := column(idx, "jumlah")
+	if amountCol < 0 {
+		return nil, errors.New("kolom \"Jumlah\" tidak ditemukan")
+	}
+
+	for _, row := range rows[headerAt+1:] {
+		if row.Empty() {
+			continue
+		}
+		first := strings.ToLower(row.Get(0))
+		if isSummaryRow(first, bcaSummaryRows) {
+			continue
+		}
+		if first == "pend" {
+			st.addError(row.Line, row.Raw(), errors.New("transaksi masih pending, import ulang setelah tanggal efektif"))
+			continue
+		}
+
+		trxTime, err := parseBCADate(row.Get(dateCol), periodEnd)
+		if err != nil {
+			st.addError(row.Line, row.Raw(), err)
+			continue
+		}
+
+		amount, err := ParseAmount(row.Get(amountCol))
+		if err != nil {
+			st.addError(row.Line, row.Raw(), err)
+			continue
+		}
+		// kolom setelah Jumlah berisi CR / DB
+		switch strings.ToUpper(row.Get(amountCol + 1)) {
+		case "CR":
+			amount = amount.Abs()
+		case "DB":
+			amount = amount.Abs().Neg()
+		default:
+			st.addError(row.Line, row.Raw(), errors.New("penanda CR/DB tidak ada"))
+			continue
+		}
+
+		note := strings.Join(strings.Fields(row.Get(noteCol)), " ")
+		st.add(Transaction{
+			Line:    row.Line,
+			Amount:  amount,
+			Note:    note,
+			RefCode: bcaRefPattern.FindString(note),
+			TrxTime: trxTime,
+		})
+	}
+
+	return st, nil
+}
+
+// parseBCADate: "01/10/2026" atau "01/10" (tahun diambil dari periode; Desember di periode Januari = tahun lalu)
+func parseBCADate(raw string, periodEnd time.Time) (time.Time, error) {
+	if t, err := parseDate(raw, "02/01/2006", "02/01/06"); err == nil {
+		return t, nil
+	}
+
+	t, err := parseDate(raw, "02/01")
+	if err != nil {
+		return time.Time{}, err
+	}
+	year := periodEnd.Year()
+	if t.Month() > periodEnd.Month() {
+		year--
+	}
+	return time.Date(year, t.Month(), t.Day(), 0, 0, 0, 0, time.Local), nil
+}
+
+func isSummaryRow(first string, labels []string) bool {
+	for _, l := range labels {
+		if strings.HasPrefix(first, l) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package statement
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// BNIParser: export mutasi BNI (BNIDirect / Internet Banking CSV).
+// Nominal bisa berupa kolom Debit & Credit, atau Amount + Db/Cr.
+//
+//	Account Number,0123456789
+//	Post Date,Value Date,Branch,Journal No.,Description,Debit,Credit,Balance
+//	01/10/2026 08:15:22,01/10/2026,0259,938271,TRF DARI BUDI SANTOSO,0.00,150123.00,1650123.00
+type BNIParser struct{}
+
+func (BNIParser) Name() string  { return "bni" }
+func (BNIParser) Label() string { return "BNI (BNIDirect CSV)" }
+
+func (BNIParser) Detect(data []byte) bool {
+	h := head(data)
+	return strings.Contains(h, "post date") && strings.Contains(h, "journal no")
+}
+
+func (BNIParser) Parse(data []byte) (*Statement, error) {
+	st := &Statement{Bank: "BNI"}
+
+	rows, errs := readCSV(data, "post date")
+	st.Errors = append(st.Errors, errs...)
+
+	headerAt := findHeader(rows, "post date", "description")
+	if headerAt < 0 {
+		return nil, errors.New("header \"Post Date\" tidak ditemukan")
+	}
+
+	for _, row := range rows[:headerAt] {
+		label := strings.ToLower(row.Get(0))
+		if strings.HasPrefix(label, "account number") || strings.HasPrefix(label, "nomor rekening") {
+			st.Account = row.Get(1)
+		}
+	}
+
+	idx := headerIndex(rows[headerAt].Fields)
+	dateCol := column(idx, "post date")
+	descCol := column(idx, "description")
+	refCol := column(idx, "journal no.", "journal no")
+	debitCol := column(idx, "debit")
+	creditCol := column(idx, "credit")
+	amountCol := column(idx, "amount")
+	dbcrCol := column(idx, "db/cr", "d/k")
+
+	for _, row := range rows[headerAt+1:] {
+		if row.Empty() {
+			continue
+		}
+		if first := strings.ToLower(row.Get(0)); strings.HasPrefix(first, "beginning balance") || strings.HasPrefix(first, "ending balance") {
+			continue
+		}
+
+		trxTime, err := parseDate(row.Get(dateCol), "02/01/2006 15:04:05", "02/01/2006 15.04.05", "02/01/2006", "02-Jan-2006", "2006-01-02")
+		if err != nil {
+			st.addError(row.Line, row.Raw(), err)
+			continue
+		}
+
+		var amountErr error
+		var amount decimal.Decimal
+		if amountCol >= 0 && dbcrCol >= 0 {
+			amount, amountErr = ParseAmount(row.Get(amountCol))
+			switch strings.ToUpper(row.Get(dbcrCol)) {
+			case "C", "CR", "K":
+				amount = amount.Abs()
+			case "D", "DB":
+				amount = amount.Abs().Neg()
+			default:
+				if amountErr == nil {
+					amountErr = errors.New("penanda Db/Cr tidak valid")
+				}
+			}
+		} else {
+			amount, amountErr = signedAmount(row.Get(debitCol), row.Get(creditCol))
+		}
+		if amountErr != nil {
+			st.addError(row.Line, row.Raw(), amountErr)
+			continue
+		}
+
+		st.add(Transaction{
+			Line:    row.Line,
+			Amount:  amount,
+			Note:    strings.Join(strings.Fields(row.Get(descCol)), " "),
+			RefCode: row.Get(refCol),
+			TrxTime: trxTime,
+		})
+	}
+
+	return st, nil
+}
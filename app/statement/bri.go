@@ -0,0 +1,79 @@
+package statement
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// BRIParser: export mutasi BRI (Internet Banking / CMS, CSV).
+//
+//	NOREK,TGL_TRAN,JAM_TRAN,DESK_TRAN,MUTASI_DEBET,MUTASI_KREDIT,SALDO_AKHIR_MUTASI,REFF_NO
+//	012301000123456,2026-10-01,08:15:22,TRF DARI BUDI SANTOSO,0.00,150123.00,1650123.00,BRI123456
+type BRIParser struct{}
+
+func (BRIParser) Name() string  { return "bri" }
+func (BRIParser) Label() string { return "BRI (CMS / Internet Banking CSV)" }
+
+func (BRIParser) Detect(data []byte) bool {
+	h := head(data)
+	return strings.Contains(h, "tgl_tran") && strings.Contains(h, "mutasi_kredit")
+}
+
+func (BRIParser) Parse(data []byte) (*Statement, error) {
+	st := &Statement{Bank: "BRI"}
+
+	rows, errs := readCSV(data, "tgl_tran")
+	st.Errors = append(st.Errors, errs...)
+
+	headerAt := findHeader(rows, "tgl_tran", "mutasi_kredit")
+	if headerAt < 0 {
+		return nil, errors.New("header \"TGL_TRAN\" tidak ditemukan")
+	}
+
+	idx := headerIndex(rows[headerAt].Fields)
+	accountCol := column(idx, "norek", "no_rek")
+	dateCol := column(idx, "tgl_tran")
+	timeCol := column(idx, "jam_tran")
+	descCol := column(idx, "desk_tran")
+	debitCol := column(idx, "mutasi_debet")
+	creditCol := column(idx, "mutasi_kredit")
+	refCol := column(idx, "reff_no", "no_ref", "ref_no", "kode_tran")
+
+	for _, row := range rows[headerAt+1:] {
+		if row.Empty() {
+			continue
+		}
+
+		trxTime, err := parseDate(row.Get(dateCol), "2006-01-02", "02/01/2006", "02/01/06", "2006-01-02 15:04:05")
+		if err != nil {
+			st.addError(row.Line, row.Raw(), err)
+			continue
+		}
+		if clock := row.Get(timeCol); clock != "" {
+			if t, err := parseDate(clock, "15:04:05", "150405", "15:04"); err == nil {
+				trxTime = time.Date(trxTime.Year(), trxTime.Month(), trxTime.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.Local)
+			}
+		}
+
+		amount, err := signedAmount(row.Get(debitCol), row.Get(creditCol))
+		if err != nil {
+			st.addError(row.Line, row.Raw(), err)
+			continue
+		}
+
+		st.add(Transaction{
+			Line:    row.Line,
+			Account: row.Get(accountCol),
+			Amount:  amount,
+			Note:    strings.Join(strings.Fields(row.Get(descCol)), " "),
+			RefCode: row.Get(refCol),
+			TrxTime: trxTime,
+		})
+	}
+
+	if len(st.Transactions) > 0 {
+		st.Account = st.Transactions[0].Account
+	}
+	return st, nil
+}
@@ -0,0 +1,152 @@
+package statement
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// csvRow: 1 record CSV + nomor barisnya di file
+type csvRow struct {
+	Line   int
+	Fields []string
+}
+
+// Raw: isi baris untuk laporan error
+func (r csvRow) Raw() string {
+	return strings.Join(r.Fields, ",")
+}
+
+// Get: kolom ke-i (sudah di-trim), kosong kalau tidak ada
+func (r csvRow) Get(i int) string {
+	if i < 0 || i >= len(r.Fields) {
+		return ""
+	}
+	return strings.Trim(strings.TrimSpace(r.Fields[i]), "'")
+}
+
+// Empty: baris kosong / hanya berisi pemisah
+func (r csvRow) Empty() bool {
+	for _, f := range r.Fields {
+		if strings.TrimSpace(f) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// readCSV: baca semua baris; pemisah (',', ';' atau tab) ditebak dari baris header.
+// Baris yang rusak dikembalikan sebagai RowError, bukan menghentikan seluruh file.
+func readCSV(data []byte, headerHint string) ([]csvRow, []RowError) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = sniffDelimiter(data, headerHint)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	var rows []csvRow
+	var errs []RowError
+	for {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		if err != nil {
+			if pe, ok := err.(*csv.ParseError); ok {
+				line = pe.Line
+			}
+			errs = append(errs, RowError{Line: line, Err: err.Error()})
+			continue
+		}
+		rows = append(rows, csvRow{Line: line, Fields: rec})
+	}
+	return rows, errs
+}
+
+// sniffDelimiter: pemisah yang paling banyak muncul di baris header
+func sniffDelimiter(data []byte, headerHint string) rune {
+	line := ""
+	for _, l := range strings.Split(string(data), "\n") {
+		if headerHint == "" || strings.Contains(strings.ToLower(l), headerHint) {
+			line = l
+			break
+		}
+	}
+
+	best, bestCount := ',', 0
+	for _, d := range []rune{',', ';', '\t', '|'} {
+		if c := strings.Count(line, string(d)); c > bestCount {
+			best, bestCount = d, c
+		}
+	}
+	return best
+}
+
+// headerIndex: posisi kolom berdasarkan nama header (huruf kecil, spasi dirapikan).
+// Nama yang sama muncul 2x (contoh "Description" di Mandiri) disimpan dengan akhiran "#2".
+func headerIndex(fields []string) map[string]int {
+	idx := map[string]int{}
+	for i, f := range fields {
+		key := strings.Join(strings.Fields(strings.ToLower(strings.Trim(f, "'\" "))), " ")
+		if key == "" {
+			continue
+		}
+		if _, exists := idx[key]; exists {
+			key += "#2"
+		}
+		idx[key] = i
+	}
+	return idx
+}
+
+// column: indeks kolom pertama yang ada dari beberapa kemungkinan nama, -1 kalau tidak ada
+func column(idx map[string]int, names ...string) int {
+	for _, n := range names {
+		if i, ok := idx[n]; ok {
+			return i
+		}
+	}
+	return -1
+}
+
+// findHeader: posisi baris header (baris pertama yang memuat semua kata kunci)
+func findHeader(rows []csvRow, keywords ...string) int {
+	for i, row := range rows {
+		line := strings.ToLower(strings.Join(row.Fields, "|"))
+		match := true
+		for _, k := range keywords {
+			if !strings.Contains(line, k) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+	return -1
+}
+
+// signedAmount: nominal bertanda dari kolom debit & kredit (kredit positif, debit negatif)
+func signedAmount(debitRaw, creditRaw string) (decimal.Decimal, error) {
+	for _, c := range []struct {
+		raw  string
+		sign int64
+	}{{creditRaw, 1}, {debitRaw, -1}} {
+		if strings.TrimSpace(c.raw) == "" {
+			continue
+		}
+		d, err := ParseAmount(c.raw)
+		if err != nil {
+			return decimal.Zero, err
+		}
+		if !d.IsZero() {
+			return d.Abs().Mul(decimal.NewFromInt(c.sign)), nil
+		}
+	}
+	return decimal.Zero, errors.New("kolom debit & kredit kosong")
+}
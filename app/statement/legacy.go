@@ -0,0 +1,63 @@
+package statement
+
+import (
+	"errors"
+	"strings"
+)
+
+// LegacyParser: template CSV lama toko "Tanggal;Deskripsi;Debit;Kredit;Saldo".
+// File ini tidak membawa nama bank & rekening, jadi keduanya diisi dari form import.
+type LegacyParser struct{}
+
+func (LegacyParser) Name() string  { return "template" }
+func (LegacyParser) Label() string { return "Template toko (Tanggal;Deskripsi;Debit;Kredit;Saldo)" }
+
+func (LegacyParser) Detect(data []byte) bool {
+	h := head(data)
+	return strings.Contains(h, "tanggal") && strings.Contains(h, "deskripsi") && strings.Contains(h, "kredit")
+}
+
+func (LegacyParser) Parse(data []byte) (*Statement, error) {
+	st := &Statement{}
+
+	rows, errs := readCSV(data, "tanggal")
+	st.Errors = append(st.Errors, errs...)
+
+	headerAt := findHeader(rows, "tanggal", "kredit")
+	if headerAt < 0 {
+		return nil, errors.New("header \"Tanggal;Deskripsi;Debit;Kredit\" tidak ditemukan")
+	}
+
+	idx := headerIndex(rows[headerAt].Fields)
+	dateCol := column(idx, "tanggal")
+	descCol := column(idx, "deskripsi", "keterangan")
+	debitCol := column(idx, "debit", "debet")
+	creditCol := column(idx, "kredit")
+
+	for _, row := range rows[headerAt+1:] {
+		if row.Empty() {
+			continue
+		}
+
+		trxTime, err := parseDate(row.Get(dateCol), "02/01/2006", "02/01/2006 15:04", "2006-01-02")
+		if err != nil {
+			st.addError(row.Line, row.Raw(), err)
+			continue
+		}
+
+		amount, err := signedAmount(row.Get(debitCol), row.Get(creditCol))
+		if err != nil {
+			st.addError(row.Line, row.Raw(), err)
+			continue
+		}
+
+		st.add(Transaction{
+			Line:    row.Line,
+			Amount:  amount,
+			Note:    row.Get(descCol),
+			TrxTime: trxTime,
+		})
+	}
+
+	return st, nil
+}
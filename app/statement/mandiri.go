@@ -0,0 +1,74 @@
+package statement
+
+import (
+	"errors"
+	"strings"
+)
+
+// MandiriParser: export mutasi Mandiri (MCM / Mandiri Online CSV).
+//
+//	Account No,Date,Val. Date,Transaction Code,Description,Description,Reference No.,Debit,Credit,
+//	1230001234567,01/10/26,01/10/26,7000,TRANSFER DARI,BUDI SANTOSO,FT2610011234,.00,150123.00,
+type MandiriParser struct{}
+
+func (MandiriParser) Name() string  { return "mandiri" }
+func (MandiriParser) Label() string { return "Mandiri (MCM CSV)" }
+
+func (MandiriParser) Detect(data []byte) bool {
+	h := head(data)
+	return strings.Contains(h, "account no") && strings.Contains(h, "transaction code")
+}
+
+func (MandiriParser) Parse(data []byte) (*Statement, error) {
+	st := &Statement{Bank: "Mandiri"}
+
+	rows, errs := readCSV(data, "account no")
+	st.Errors = append(st.Errors, errs...)
+
+	headerAt := findHeader(rows, "account no", "debit", "credit")
+	if headerAt < 0 {
+		return nil, errors.New("header \"Account No\" tidak ditemukan")
+	}
+
+	idx := headerIndex(rows[headerAt].Fields)
+	accountCol := column(idx, "account no", "account no.")
+	dateCol := column(idx, "date", "post date")
+	descCol := column(idx, "description")
+	desc2Col := column(idx, "description#2")
+	refCol := column(idx, "reference no.", "reference no")
+	debitCol := column(idx, "debit")
+	creditCol := column(idx, "credit")
+
+	for _, row := range rows[headerAt+1:] {
+		if row.Empty() {
+			continue
+		}
+
+		trxTime, err := parseDate(row.Get(dateCol), "02/01/06", "02/01/2006", "02/01/06 15.04.05", "02/01/2006 15:04:05")
+		if err != nil {
+			st.addError(row.Line, row.Raw(), err)
+			continue
+		}
+
+		amount, err := signedAmount(row.Get(debitCol), row.Get(creditCol))
+		if err != nil {
+			st.addError(row.Line, row.Raw(), err)
+			continue
+		}
+
+		note := strings.TrimSpace(row.Get(descCol) + " " + row.Get(desc2Col))
+		st.add(Transaction{
+			Line:    row.Line,
+			Account: row.Get(accountCol),
+			Amount:  amount,
+			Note:    strings.Join(strings.Fields(note), " "),
+			RefCode: row.Get(refCol),
+			TrxTime: trxTime,
+		})
+	}
+
+	if len(st.Transactions) > 0 {
+		st.Account = st.Transactions[0].Account
+	}
+	return st, nil
+}
@@ -0,0 +1,140 @@
+package statement
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// MT940Parser: SWIFT MT940 (customer statement), dipakai fitur cash management semua bank besar.
+//
+//	:25:CENAIDJA/0123456789
+//	:61:2610011001C150123,00NTRFNONREF//FT2610011234
+//	:86:TRSF E-BANKING CR BUDI SANTOSO
+type MT940Parser struct{}
+
+var (
+	mt940Field = regexp.MustCompile(`^:(\d{2}[A-Z]?):(.*)$`)
+	// tanggal valuta, tanggal buku (opsional), D/C/RD/RC, kode dana (opsional), nominal, tipe, ref nasabah, //ref bank
+	mt940Line  = regexp.MustCompile(`^(\d{6})(\d{4})?(R?[CD])([A-Z])?(\d+(?:,\d*)?)([NSF][A-Z0-9]{3})?([^/]*)(?://(.*))?$`)
+	mt940Block = regexp.MustCompile(`\{1:F\d{2}([A-Z0-9]{8})`)
+)
+
+type mt940Tag struct {
+	Tag   string
+	Value string
+	Line  int
+}
+
+func (MT940Parser) Name() string  { return "mt940" }
+func (MT940Parser) Label() string { return "MT940 (SWIFT)" }
+
+func (MT940Parser) Detect(data []byte) bool {
+	h := head(data)
+	return strings.Contains(h, ":20:") && strings.Contains(h, ":61:")
+}
+
+func (MT940Parser) Parse(data []byte) (*Statement, error) {
+	tags := mt940Tags(string(data))
+	if len(tags) == 0 {
+		return nil, errors.New("tidak ada field MT940 di file")
+	}
+
+	st := &Statement{}
+	if m := mt940Block.FindStringSubmatch(string(data)); m != nil {
+		st.Bank = bankFromBIC(m[1])
+	}
+
+	for i, tag := range tags {
+		switch tag.Tag {
+		case "25":
+			account := tag.Value
+			if parts := strings.SplitN(account, "/", 2); len(parts) == 2 {
+				if bank := bankFromBIC(parts[0]); bank != "" {
+					st.Bank = bank
+				}
+				account = parts[1]
+			}
+			st.Account = strings.TrimSpace(account)
+
+		case "61":
+			t, err := mt940Transaction(tag)
+			if err != nil {
+				st.addError(tag.Line, ":61:"+tag.Value, err)
+				continue
+			}
+			// keterangan ada di :86: tepat setelah :61:
+			if i+1 < len(tags) && tags[i+1].Tag == "86" {
+				t.Note = strings.Join(strings.Fields(tags[i+1].Value), " ")
+			}
+			st.add(t)
+		}
+	}
+
+	if st.Bank == "" {
+		st.Bank = "MT940"
+	}
+	for i := range st.Transactions {
+		if st.Transactions[i].Bank == "" {
+			st.Transactions[i].Bank = st.Bank
+		}
+		if st.Transactions[i].Account == "" {
+			st.Transactions[i].Account = st.Account
+		}
+	}
+
+	return st, nil
+}
+
+// mt940Tags: gabungkan baris lanjutan ke field sebelumnya
+func mt940Tags(text string) []mt940Tag {
+	var tags []mt940Tag
+	for n, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, " ")
+		if m := mt940Field.FindStringSubmatch(line); m != nil {
+			tags = append(tags, mt940Tag{Tag: m[1], Value: m[2], Line: n + 1})
+			continue
+		}
+		if len(tags) == 0 || line == "" || line == "-" || strings.HasPrefix(line, "{") || strings.HasPrefix(line, "-}") {
+			continue
+		}
+		tags[len(tags)-1].Value += "\n" + line
+	}
+	return tags
+}
+
+func mt940Transaction(tag mt940Tag) (Transaction, error) {
+	first := strings.SplitN(tag.Value, "\n", 2)
+	m := mt940Line.FindStringSubmatch(strings.TrimSpace(first[0]))
+	if m == nil {
+		return Transaction{}, errors.New("format :61: tidak valid")
+	}
+
+	trxTime, err := parseDate(m[1], "060102")
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	amount, err := decimal.NewFromString(strings.TrimSuffix(strings.Replace(m[5], ",", ".", 1), "."))
+	if err != nil {
+		return Transaction{}, errors.New("nominal :61: tidak valid")
+	}
+	// C = kredit, D = debit, RC = koreksi kredit (keluar), RD = koreksi debit (masuk)
+	if m[3] == "D" || m[3] == "RC" {
+		amount = amount.Neg()
+	}
+
+	ref := strings.TrimSpace(m[8])
+	if ref == "" && !strings.EqualFold(strings.TrimSpace(m[7]), "NONREF") {
+		ref = strings.TrimSpace(m[7])
+	}
+
+	return Transaction{
+		Line:    tag.Line,
+		Amount:  amount,
+		RefCode: ref,
+		TrxTime: trxTime,
+	}, nil
+}
@@ -0,0 +1,151 @@
+package statement
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// OFXParser: Open Financial Exchange, versi SGML (1.x, tag daun tanpa penutup) maupun XML (2.x).
+type OFXParser struct{}
+
+var ofxTag = regexp.MustCompile(`<(/?)([A-Za-z0-9.]+)>([^<]*)`)
+
+func (OFXParser) Name() string  { return "ofx" }
+func (OFXParser) Label() string { return "OFX (umum)" }
+
+func (OFXParser) Detect(data []byte) bool {
+	h := head(data)
+	return strings.Contains(h, "ofxheader") || strings.Contains(h, "<ofx>")
+}
+
+func (OFXParser) Parse(data []byte) (*Statement, error) {
+	text := string(data)
+	st := &Statement{}
+
+	var org string
+	var current map[string]string
+	var currentLine int
+
+	flush := func() {
+		if current == nil {
+			return
+		}
+		if t, err := ofxTransaction(current, currentLine); err != nil {
+			st.addError(currentLine, ofxRaw(current), err)
+		} else {
+			st.add(t)
+		}
+		current = nil
+	}
+
+	for _, m := range ofxTag.FindAllStringSubmatchIndex(text, -1) {
+		closing := text[m[2]:m[3]] == "/"
+		tag := strings.ToUpper(text[m[4]:m[5]])
+		value := strings.TrimSpace(text[m[6]:m[7]])
+
+		if tag == "STMTTRN" {
+			if closing {
+				flush()
+			} else {
+				flush()
+				current = map[string]string{}
+				currentLine = strings.Count(text[:m[0]], "\n") + 1
+			}
+			continue
+		}
+		if closing || value == "" {
+			continue
+		}
+
+		if current != nil {
+			current[tag] = value
+			continue
+		}
+		switch tag {
+		case "ACCTID":
+			st.Account = value
+		case "BANKID":
+			if bank := bankFromBIC(value); bank != "" {
+				st.Bank = bank
+			}
+		case "ORG":
+			org = value
+		}
+	}
+	flush()
+
+	if st.Bank == "" {
+		st.Bank = org
+	}
+	if st.Bank == "" {
+		st.Bank = "OFX"
+	}
+	for i := range st.Transactions {
+		if st.Transactions[i].Bank == "" {
+			st.Transactions[i].Bank = st.Bank
+		}
+		if st.Transactions[i].Account == "" {
+			st.Transactions[i].Account = st.Account
+		}
+	}
+
+	if len(st.Transactions) == 0 && len(st.Errors) == 0 {
+		return nil, errors.New("tidak ada <STMTTRN> di file OFX")
+	}
+	return st, nil
+}
+
+func ofxTransaction(fields map[string]string, line int) (Transaction, error) {
+	trxTime, err := parseOFXDate(fields["DTPOSTED"])
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	amount, err := ParseAmount(fields["TRNAMT"])
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	note := strings.TrimSpace(fields["NAME"] + " " + fields["MEMO"])
+	ref := fields["FITID"]
+	if ref == "" {
+		ref = fields["REFNUM"]
+	}
+
+	return Transaction{
+		Line:    line,
+		Amount:  amount,
+		Note:    strings.Join(strings.Fields(note), " "),
+		RefCode: ref,
+		TrxTime: trxTime,
+	}, nil
+}
+
+// parseOFXDate: "20261001", "20261001081522" atau "20261001081522.000[+7:WIB]"
+func parseOFXDate(raw string) (time.Time, error) {
+	digits := raw
+	if i := strings.IndexAny(digits, ".["); i >= 0 {
+		digits = digits[:i]
+	}
+	switch len(digits) {
+	case 14:
+		return parseDate(digits, "20060102150405")
+	case 12:
+		return parseDate(digits, "200601021504")
+	case 8:
+		return parseDate(digits, "20060102")
+	}
+	return time.Time{}, errors.New("DTPOSTED tidak valid: " + raw)
+}
+
+func ofxRaw(fields map[string]string) string {
+	parts := make([]string, 0, len(fields))
+	for _, k := range []string{"TRNTYPE", "DTPOSTED", "TRNAMT", "FITID", "NAME", "MEMO"} {
+		if v, ok := fields[k]; ok {
+			parts = append(parts, k+"="+v)
+		}
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,248 @@
+// Package statement: parser file mutasi rekening (BCA, Mandiri, BRI, BNI, OFX, MT940)
+// menjadi daftar transaksi yang siap disimpan sebagai models.BankTransaction.
+package statement
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// FormatAuto: pilih parser otomatis berdasarkan isi file
+const FormatAuto = "auto"
+
+var (
+	ErrUnknownFormat = errors.New("format file mutasi tidak dikenali")
+	ErrEmptyFile     = errors.New("file mutasi kosong")
+)
+
+// Transaction: 1 baris mutasi. Amount positif = kredit (uang masuk), negatif = debit.
+type Transaction struct {
+	Line    int // nomor baris di file (untuk laporan)
+	Bank    string
+	Account string
+	Amount  decimal.Decimal
+	Note    string
+	RefCode string
+	TrxTime time.Time
+}
+
+// IsCredit: mutasi uang masuk
+func (t Transaction) IsCredit() bool {
+	return t.Amount.IsPositive()
+}
+
+// RowError: baris yang tidak bisa dibaca, ditampilkan ke admin (tidak di-skip diam-diam)
+type RowError struct {
+	Line int
+	Raw  string
+	Err  string
+}
+
+// Statement: hasil parsing 1 file
+type Statement struct {
+	Format       string
+	Bank         string
+	Account      string
+	Transactions []Transaction
+	Errors       []RowError
+}
+
+func (s *Statement) addError(line int, raw string, err error) {
+	s.Errors = append(s.Errors, RowError{Line: line, Raw: truncate(raw, 200), Err: err.Error()})
+}
+
+// add: tambah transaksi, bank & rekening diisi dari header file kalau baris tidak membawanya
+func (s *Statement) add(t Transaction) {
+	if t.Bank == "" {
+		t.Bank = s.Bank
+	}
+	if t.Account == "" {
+		t.Account = s.Account
+	}
+	s.Transactions = append(s.Transactions, t)
+}
+
+// StatementParser: kontrak parser 1 format mutasi
+type StatementParser interface {
+	// Name: kode format (dipakai di form import)
+	Name() string
+	// Label: nama yang ditampilkan ke admin
+	Label() string
+	// Detect: true kalau isi file cocok dengan format ini
+	Detect(data []byte) bool
+	// Parse: baca seluruh file; baris yang gagal masuk ke Statement.Errors
+	Parse(data []byte) (*Statement, error)
+}
+
+// urutan penting: format yang penandanya paling spesifik dicek lebih dulu
+var parsers = []StatementParser{
+	OFXParser{},
+	MT940Parser{},
+	BCAParser{},
+	MandiriParser{},
+	BRIParser{},
+	BNIParser{},
+	LegacyParser{},
+}
+
+// Parsers: semua parser yang tersedia
+func Parsers() []StatementParser {
+	return parsers
+}
+
+// ByName: parser berdasarkan kode format
+func ByName(name string) (StatementParser, bool) {
+	for _, p := range parsers {
+		if p.Name() == name {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// Detect: cari parser yang cocok dengan isi file
+func Detect(data []byte) (StatementParser, error) {
+	for _, p := range parsers {
+		if p.Detect(data) {
+			return p, nil
+		}
+	}
+	return nil, ErrUnknownFormat
+}
+
+// Parse: parsing dengan format tertentu atau FormatAuto
+func Parse(data []byte, format string) (*Statement, error) {
+	data = normalize(data)
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, ErrEmptyFile
+	}
+
+	var parser StatementParser
+	if format == "" || format == FormatAuto {
+		p, err := Detect(data)
+		if err != nil {
+			return nil, err
+		}
+		parser = p
+	} else {
+		p, ok := ByName(format)
+		if !ok {
+			return nil, fmt.Errorf("format mutasi %q tidak dikenal", format)
+		}
+		parser = p
+	}
+
+	st, err := parser.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	st.Format = parser.Name()
+	return st, nil
+}
+
+// normalize: buang BOM & samakan akhir baris
+func normalize(data []byte) []byte {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	return bytes.ReplaceAll(data, []byte("\r"), []byte("\n"))
+}
+
+// head: potongan awal file (huruf kecil) untuk deteksi format
+func head(data []byte) string {
+	if len(data) > 4096 {
+		data = data[:4096]
+	}
+	return strings.ToLower(string(normalize(data)))
+}
+
+// ParseAmount: baca nominal "1.500.000,00", "1,500,000.00", "150123.00", "Rp 150.000".
+// Pemisah desimal = tanda terakhir yang diikuti tepat 1–2 digit.
+func ParseAmount(raw string) (decimal.Decimal, error) {
+	s := strings.TrimSpace(raw)
+	s = strings.Trim(s, "'\"")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "Rp"), "IDR")
+	s = strings.ReplaceAll(s, " ", "")
+
+	negative := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		negative = true
+		s = s[1 : len(s)-1]
+	}
+	if strings.HasPrefix(s, "-") {
+		negative = true
+		s = s[1:]
+	}
+	if s == "" {
+		return decimal.Zero, errors.New("nominal kosong")
+	}
+
+	lastDot := strings.LastIndex(s, ".")
+	lastComma := strings.LastIndex(s, ",")
+	sep := lastDot
+	if lastComma > sep {
+		sep = lastComma
+	}
+
+	intPart, fracPart := s, ""
+	if sep >= 0 {
+		digitsAfter := len(s) - sep - 1
+		onlyOne := strings.Count(s, string(s[sep])) == 1
+		// "150.123" = ribuan, "150.12" / "150,1" = desimal; kalau 2 jenis tanda dipakai, yang terakhir desimal
+		if (digitsAfter > 0 && digitsAfter <= 2 && onlyOne) || (lastDot >= 0 && lastComma >= 0) {
+			intPart, fracPart = s[:sep], s[sep+1:]
+		}
+	}
+	intPart = strings.NewReplacer(".", "", ",", "").Replace(intPart)
+
+	value := intPart
+	if fracPart != "" {
+		value += "." + fracPart
+	}
+	d, err := decimal.NewFromString(value)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("nominal %q tidak valid", raw)
+	}
+	if negative {
+		d = d.Neg()
+	}
+	return d, nil
+}
+
+// parseDate: coba beberapa layout tanggal; error kalau tidak ada yang cocok (tidak fallback ke time.Now)
+func parseDate(raw string, layouts ...string) (time.Time, error) {
+	s := strings.Trim(strings.TrimSpace(raw), "'\"")
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("tanggal %q tidak valid", raw)
+}
+
+// bankFromBIC: nama bank dari kode BIC/SWIFT (dipakai OFX & MT940)
+func bankFromBIC(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	switch {
+	case strings.HasPrefix(code, "CENAID"), code == "014":
+		return "BCA"
+	case strings.HasPrefix(code, "BMRIID"), code == "008":
+		return "Mandiri"
+	case strings.HasPrefix(code, "BRINID"), code == "002":
+		return "BRI"
+	case strings.HasPrefix(code, "BNINID"), code == "009":
+		return "BNI"
+	}
+	return ""
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "…"
+}
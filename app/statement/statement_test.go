@@ -0,0 +1,424 @@
+package statement
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"1.234,56", "1234.56"},
+		{"1,234.56", "1234.56"},
+		{"1.500.000,00", "1500000"},
+		{"1,500,000.00", "1500000"},
+		{"150123.00", "150123"},
+		{"150.123", "150123"}, // 3 digit setelah tanda = ribuan
+		{"150,123", "150123"},
+		{"1.234.567", "1234567"},
+		{"150,1", "150.1"},
+		{"150.12", "150.12"},
+		{"Rp 150.000", "150000"},
+		{"Rp150.000,50", "150000.5"},
+		{"IDR 2,000", "2000"},
+		{"'1.234,56'", "1234.56"},
+		{`"75000"`, "75000"},
+		{"(1.234,56)", "-1234.56"},
+		{"-500", "-500"},
+		{"- 2.500,00", "-2500"},
+		{".00", "0"},
+	}
+	for _, tt := range tests {
+		got, err := ParseAmount(tt.raw)
+		if err != nil {
+			t.Errorf("ParseAmount(%q): %v", tt.raw, err)
+			continue
+		}
+		if !got.Equal(decimal.RequireFromString(tt.want)) {
+			t.Errorf("ParseAmount(%q) = %s, want %s", tt.raw, got, tt.want)
+		}
+	}
+
+	for _, raw := range []string{"", "  ", "Rp", "()", "abc", "12a"} {
+		if got, err := ParseAmount(raw); err == nil {
+			t.Errorf("ParseAmount(%q) = %s, want error", raw, got)
+		}
+	}
+}
+
+func TestParseBCADate(t *testing.T) {
+	periodEnd := time.Date(2026, time.January, 17, 0, 0, 0, 0, time.Local)
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"02/01", "2026-01-02"},
+		{"30/12", "2025-12-30"}, // Desember di periode Januari = tahun lalu
+		{"15/11/2025", "2025-11-15"},
+		{"15/11/25", "2025-11-15"},
+	}
+	for _, tt := range tests {
+		got, err := parseBCADate(tt.raw, periodEnd)
+		if err != nil {
+			t.Errorf("parseBCADate(%q): %v", tt.raw, err)
+			continue
+		}
+		if got.Format("2006-01-02") != tt.want {
+			t.Errorf("parseBCADate(%q) = %s, want %s", tt.raw, got.Format("2006-01-02"), tt.want)
+		}
+	}
+	if _, err := parseBCADate("32/01", periodEnd); err == nil {
+		t.Error("parseBCADate(\"32/01\") tidak error")
+	}
+}
+
+type wantTrx struct {
+	line   int
+	amount string
+	note   string
+	ref    string
+	time   string // "2006-01-02 15:04:05", waktu lokal
+}
+
+type wantRowError struct {
+	line int
+	err  string // potongan pesan error
+}
+
+var statementTests = []struct {
+	name    string
+	format  string // format hasil deteksi otomatis
+	data    string
+	bank    string
+	account string
+	trx     []wantTrx
+	errors  []wantRowError
+}{
+	{
+		name:   "bca",
+		format: "bca",
+		data: `No. rekening : ,'0123456789
+Nama : ,TOKO GOSHOP
+Periode : ,01/12/2025 - 17/01/2026
+Kode Mata Uang : ,IDR
+
+Tanggal Transaksi,Keterangan,Cabang,Jumlah,,Saldo
+'30/12,TRSF E-BANKING CR 3012/FTSCY/WS95031   150123.00 BUDI,'0000,"150,123.00",CR,"1,650,123.00"
+'02/01,BIAYA ADM,'0000,"10.000,00",DB,"1.640.123,00"
+PEND,TRSF E-BANKING CR 1701/FTSCY/WS95099 50000.00 ANI,'0000,50000.00,CR,
+'03/01,SETORAN TUNAI,'0000,75000.00,,1715123.00
+'32/01,SETORAN TUNAI,'0000,75000.00,CR,1715123.00
+'04/01,SETORAN TUNAI,'0000,abc,CR,1715123.00
+
+Saldo Awal,,,1500000.00
+Mutasi Kredit,,,150123.00,1
+Mutasi Debet,,,10000.00,1
+Saldo Akhir,,,1640123.00
+`,
+		bank:    "BCA",
+		account: "0123456789",
+		trx: []wantTrx{
+			{7, "150123", "TRSF E-BANKING CR 3012/FTSCY/WS95031 150123.00 BUDI", "3012/FTSCY/WS95031", "2025-12-30 00:00:00"},
+			{8, "-10000", "BIAYA ADM", "", "2026-01-02 00:00:00"},
+		},
+		errors: []wantRowError{
+			{9, "pending"},
+			{10, "penanda CR/DB tidak ada"},
+			{11, `tanggal "32/01" tidak valid`},
+			{12, `nominal "abc" tidak valid`},
+		},
+	},
+	{
+		name:   "mandiri",
+		format: "mandiri",
+		data: `Account No,Date,Val. Date,Transaction Code,Description,Description,Reference No.,Debit,Credit,
+1230001234567,01/10/26,01/10/26,7000,TRANSFER DARI,BUDI  SANTOSO,FT2610011234,.00,150123.00,
+1230001234567,02/10/26,02/10/26,8000,BIAYA,ADMIN,,12500.00,.00,
+1230001234567,03/10/26,03/10/26,7000,KOSONG,,,.00,.00,
+1230001234567,2026-10-04,2026-10-04,7000,SALAH TANGGAL,,,.00,100.00,
+1230001234567,05/10/2026 13:45:10,05/10/26,7000,TRANSFER DARI,ANI,FT2610051111,,"1,500,000.00",
+`,
+		bank:    "Mandiri",
+		account: "1230001234567",
+		trx: []wantTrx{
+			{2, "150123", "TRANSFER DARI BUDI SANTOSO", "FT2610011234", "2026-10-01 00:00:00"},
+			{3, "-12500", "BIAYA ADMIN", "", "2026-10-02 00:00:00"},
+			{6, "1500000", "TRANSFER DARI ANI", "FT2610051111", "2026-10-05 13:45:10"},
+		},
+		errors: []wantRowError{
+			{4, "kolom debit & kredit kosong"},
+			{5, `tanggal "2026-10-04" tidak valid`},
+		},
+	},
+	{
+		name:   "bri",
+		format: "bri",
+		data: `NOREK;TGL_TRAN;JAM_TRAN;DESK_TRAN;MUTASI_DEBET;MUTASI_KREDIT;SALDO_AKHIR_MUTASI;REFF_NO
+012301000123456;2026-10-01;08:15:22;TRF DARI BUDI SANTOSO;0,00;150.123,00;1.650.123,00;BRI123456
+012301000123456;02/10/2026;;BIAYA ADM;5.000,00;0;1.645.123,00;
+012301000123456;31/02/2026;09:00:00;SALAH TANGGAL;0;100;0;
+012301000123456;2026-10-03;09:00:00;SALAH NOMINAL;0;abc;0;
+`,
+		bank:    "BRI",
+		account: "012301000123456",
+		trx: []wantTrx{
+			{2, "150123", "TRF DARI BUDI SANTOSO", "BRI123456", "2026-10-01 08:15:22"},
+			{3, "-5000", "BIAYA ADM", "", "2026-10-02 00:00:00"},
+		},
+		errors: []wantRowError{
+			{4, `tanggal "31/02/2026" tidak valid`},
+			{5, `nominal "abc" tidak valid`},
+		},
+	},
+	{
+		name:   "bni debit/credit",
+		format: "bni",
+		data: `Account Number,0123456789
+Post Date,Value Date,Branch,Journal No.,Description,Debit,Credit,Balance
+Beginning Balance,,,,,,,1500000.00
+01/10/2026 08:15:22,01/10/2026,0259,938271,TRF DARI BUDI SANTOSO,0.00,150123.00,1650123.00
+02-Oct-2026,02/10/2026,0259,938272,BIAYA ADM,2500.00,0.00,1647623.00
+02/10/2026,02/10/2026,0259,938273,KOSONG,0.00,0.00,1647623.00
+Ending Balance,,,,,,,1647623.00
+`,
+		bank:    "BNI",
+		account: "0123456789",
+		trx: []wantTrx{
+			{4, "150123", "TRF DARI BUDI SANTOSO", "938271", "2026-10-01 08:15:22"},
+			{5, "-2500", "BIAYA ADM", "938272", "2026-10-02 00:00:00"},
+		},
+		errors: []wantRowError{
+			{6, "kolom debit & kredit kosong"},
+		},
+	},
+	{
+		name:   "bni amount + db/cr",
+		format: "bni",
+		data: `Nomor Rekening,0987654321
+Post Date,Branch,Journal No,Description,Amount,Db/Cr,Balance
+2026-10-01,0259,938271,TRF DARI BUDI,"150,123.00",C,1650123.00
+2026-10-02,0259,938272,BIAYA,"2.500,00",D,1647623.00
+2026-10-03,0259,938273,TANPA PENANDA,100.00,Z,1647623.00
+2026-10-04,0259,938274,SALAH NOMINAL,abc,C,1647623.00
+`,
+		bank:    "BNI",
+		account: "0987654321",
+		trx: []wantTrx{
+			{3, "150123", "TRF DARI BUDI", "938271", "2026-10-01 00:00:00"},
+			{4, "-2500", "BIAYA", "938272", "2026-10-02 00:00:00"},
+		},
+		errors: []wantRowError{
+			{5, "penanda Db/Cr tidak valid"},
+			{6, `nominal "abc" tidak valid`},
+		},
+	},
+	{
+		name:   "template toko",
+		format: "template",
+		data: "\xef\xbb\xbfTanggal;Deskripsi;Debit;Kredit;Saldo\r\n" +
+			"01/10/2026;TRF BUDI;;150.123,00;1.650.123,00\r\n" +
+			"02/10/2026 14:30;BIAYA ADM;2.500;;1.647.623,00\r\n" +
+			"kemarin;TRF ANI;;75.000;\r\n" +
+			"2026-10-03;TRF ANI;;75.000;\r\n",
+		trx: []wantTrx{
+			{2, "150123", "TRF BUDI", "", "2026-10-01 00:00:00"},
+			{3, "-2500", "BIAYA ADM", "", "2026-10-02 14:30:00"},
+			{5, "75000", "TRF ANI", "", "2026-10-03 00:00:00"},
+		},
+		errors: []wantRowError{
+			{4, `tanggal "kemarin" tidak valid`},
+		},
+	},
+	{
+		name:   "ofx sgml",
+		format: "ofx",
+		data: `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+
+<OFX>
+<SIGNONMSGSRSV1><SONRS><FI><ORG>Bank Contoh</ORG></FI></SONRS></SIGNONMSGSRSV1>
+<BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<BANKACCTFROM>
+<BANKID>CENAIDJA
+<ACCTID>0123456789
+</BANKACCTFROM>
+<BANKTRANLIST>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20261001081522.000[+7:WIB]
+<TRNAMT>150123.00
+<FITID>FT001
+<NAME>BUDI SANTOSO
+<MEMO>TRSF   E-BANKING
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20261002
+<TRNAMT>-2500.00
+<REFNUM>R002
+<NAME>BIAYA ADM
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>2026-10-03
+<TRNAMT>100.00
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20261004
+<TRNAMT>abc
+</STMTTRN>
+</BANKTRANLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`,
+		bank:    "BCA",
+		account: "0123456789",
+		trx: []wantTrx{
+			{13, "150123", "BUDI SANTOSO TRSF E-BANKING", "FT001", "2026-10-01 08:15:22"},
+			{21, "-2500", "BIAYA ADM", "R002", "2026-10-02 00:00:00"},
+		},
+		errors: []wantRowError{
+			{28, "DTPOSTED tidak valid"},
+			{33, `nominal "abc" tidak valid`},
+		},
+	},
+	{
+		name:   "ofx xml",
+		format: "ofx",
+		data: `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="211" SECURITY="NONE"?>
+<OFX>
+<SIGNONMSGSRSV1><SONRS><FI><ORG>Bank Lain</ORG></FI></SONRS></SIGNONMSGSRSV1>
+<BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<BANKACCTFROM><BANKID>999</BANKID><ACCTID>555</ACCTID></BANKACCTFROM>
+<BANKTRANLIST>
+<STMTTRN><TRNTYPE>CREDIT</TRNTYPE><DTPOSTED>202610011200</DTPOSTED><TRNAMT>75000</TRNAMT><FITID>X1</FITID><NAME>ANI</NAME></STMTTRN>
+</BANKTRANLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`,
+		bank:    "Bank Lain",
+		account: "555",
+		trx: []wantTrx{
+			{8, "75000", "ANI", "X1", "2026-10-01 12:00:00"},
+		},
+	},
+	{
+		name:   "mt940",
+		format: "mt940",
+		data: `{1:F01BMRIIDJAXXXX0000000000}{2:I940XXXXXXXXXXXXN}{4:
+:20:STMT261001
+:25:0123456789
+:28C:00001/001
+:60F:C261001IDR1500000,00
+:61:2610011001C150123,00NTRFNONREF//FT2610011234
+:86:TRSF DARI BUDI
+SANTOSO
+:61:2610021002D2500,NCHGREF123
+:86:BIAYA ADM
+:61:2610031003RD1000,00NTRFNONREF
+:61:26100X1003C1000,00NTRF
+:61:2610041004RC500,NTRFNONREF//FT999
+:62F:C261004IDR1647123,00
+-}
+`,
+		bank:    "Mandiri",
+		account: "0123456789",
+		trx: []wantTrx{
+			{6, "150123", "TRSF DARI BUDI SANTOSO", "FT2610011234", "2026-10-01 00:00:00"},
+			{9, "-2500", "BIAYA ADM", "REF123", "2026-10-02 00:00:00"},
+			{11, "1000", "", "", "2026-10-03 00:00:00"}, // RD = koreksi debit, uang masuk
+			{13, "-500", "", "FT999", "2026-10-04 00:00:00"},
+		},
+		errors: []wantRowError{
+			{12, "format :61: tidak valid"},
+		},
+	},
+	{
+		name:   "mt940 bank dari :25:",
+		format: "mt940",
+		data: `:20:STMT
+:25:BNINIDJA/0987654321
+:61:261001C150123,NTRFNONREF
+`,
+		bank:    "BNI",
+		account: "0987654321",
+		trx: []wantTrx{
+			{3, "150123", "", "", "2026-10-01 00:00:00"},
+		},
+	},
+}
+
+func TestParse(t *testing.T) {
+	for _, tt := range statementTests {
+		t.Run(tt.name, func(t *testing.T) {
+			st, err := Parse([]byte(tt.data), FormatAuto)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if st.Format != tt.format || st.Bank != tt.bank || st.Account != tt.account {
+				t.Errorf("format/bank/rekening = %q/%q/%q, want %q/%q/%q", st.Format, st.Bank, st.Account, tt.format, tt.bank, tt.account)
+			}
+
+			if len(st.Transactions) != len(tt.trx) {
+				t.Fatalf("%d transaksi, want %d: %+v (errors %+v)", len(st.Transactions), len(tt.trx), st.Transactions, st.Errors)
+			}
+			for i, want := range tt.trx {
+				got := st.Transactions[i]
+				wantTime, _ := time.ParseInLocation("2006-01-02 15:04:05", want.time, time.Local)
+				if got.Line != want.line || !got.Amount.Equal(decimal.RequireFromString(want.amount)) ||
+					got.Note != want.note || got.RefCode != want.ref || !got.TrxTime.Equal(wantTime) {
+					t.Errorf("transaksi %d = line %d %s %q ref %q %s\nwant line %d %s %q ref %q %s", i,
+						got.Line, got.Amount, got.Note, got.RefCode, got.TrxTime.Format("2006-01-02 15:04:05"),
+						want.line, want.amount, want.note, want.ref, want.time)
+				}
+				if got.Bank != tt.bank || got.Account != tt.account {
+					t.Errorf("transaksi %d bank/rekening = %q/%q", i, got.Bank, got.Account)
+				}
+			}
+
+			if len(st.Errors) != len(tt.errors) {
+				t.Fatalf("%d baris error, want %d: %+v", len(st.Errors), len(tt.errors), st.Errors)
+			}
+			for i, want := range tt.errors {
+				got := st.Errors[i]
+				if got.Line != want.line || !strings.Contains(got.Err, want.err) || got.Raw == "" {
+					t.Errorf("error %d = line %d %q (raw %q), want line %d %q", i, got.Line, got.Err, got.Raw, want.line, want.err)
+				}
+			}
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if _, err := Parse([]byte("\xef\xbb\xbf \r\n\r\n"), FormatAuto); !errors.Is(err, ErrEmptyFile) {
+		t.Fatalf("file kosong: err = %v", err)
+	}
+	if _, err := Parse([]byte("a,b,c\n1,2,3\n"), FormatAuto); !errors.Is(err, ErrUnknownFormat) {
+		t.Fatalf("format asing: err = %v", err)
+	}
+	if _, err := Parse([]byte("a,b,c\n"), "qris"); err == nil {
+		t.Fatal("format tidak dikenal diterima")
+	}
+	// format dipilih manual tapi isi file tidak cocok: header tidak ditemukan
+	if _, err := Parse([]byte(statementTests[0].data), "bri"); err == nil {
+		t.Fatal("file BCA diterima parser BRI")
+	}
+	if _, err := Parse([]byte("OFXHEADER:100\n<OFX></OFX>\n"), "ofx"); err == nil {
+		t.Fatal("OFX tanpa STMTTRN diterima")
+	}
+
+	// format yang dipilih manual tetap dipakai walau deteksi otomatis memilih lain
+	st, err := Parse([]byte(statementTests[0].data), "bca")
+	if err != nil || st.Format != "bca" || len(st.Transactions) != len(statementTests[0].trx) {
+		t.Fatalf("Parse bca = %+v, %v", st, err)
+	}
+}
@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakeS3: tiruan S3 (path-style, seperti MinIO) yang menyimpan file di memori, untuk development & test.
+// Tanda tangan SigV4 dicek sungguhan, jadi client S3 di package ini teruji tanpa akun cloud.
+// Pasang di router (BasePath, contoh "/storage/fake") atau lewat httptest.NewServer.
+type FakeS3 struct {
+	AccessKey string
+	SecretKey string
+	Region    string
+	BasePath  string
+
+	mu      sync.Mutex
+	objects map[string]fakeObject // "bucket/key" → isi
+}
+
+type fakeObject struct {
+	Data        []byte
+	ContentType string
+	ModTime     time.Time
+}
+
+func NewFakeS3(accessKey, secretKey, region, basePath string) *FakeS3 {
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &FakeS3{
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+		Region:    region,
+		BasePath:  strings.TrimRight(basePath, "/"),
+		objects:   map[string]fakeObject{},
+	}
+}
+
+// Len: jumlah file tersimpan (untuk test)
+func (f *FakeS3) Len() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.objects)
+}
+
+func (f *FakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	creds := credentials{AccessKey: f.AccessKey, SecretKey: f.SecretKey, Region: f.Region}
+	if err := creds.verify(r, time.Now()); err != nil {
+		fakeS3Error(w, http.StatusForbidden, "SignatureDoesNotMatch", err.Error())
+		return
+	}
+
+	name := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, f.BasePath), "/")
+	if bucket, key, ok := strings.Cut(name, "/"); !ok || bucket == "" || key == "" {
+		fakeS3Error(w, http.StatusBadRequest, "InvalidRequest", "path harus /{bucket}/{key}")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		f.put(w, r, name)
+	case http.MethodGet, http.MethodHead:
+		f.get(w, r, name)
+	case http.MethodDelete:
+		f.mu.Lock()
+		delete(f.objects, name)
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		fakeS3Error(w, http.StatusMethodNotAllowed, "MethodNotAllowed", r.Method)
+	}
+}
+
+func (f *FakeS3) put(w http.ResponseWriter, r *http.Request, name string) {
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxObjectSize+1))
+	if err != nil || len(data) > maxObjectSize {
+		fakeS3Error(w, http.StatusBadRequest, "EntityTooLarge", "file terlalu besar")
+		return
+	}
+	if hash := r.Header.Get("X-Amz-Content-Sha256"); hash != unsignedPayload && hash != hashHex(data) {
+		fakeS3Error(w, http.StatusBadRequest, "XAmzContentSHA256Mismatch", "hash isi file tidak cocok")
+		return
+	}
+
+	f.mu.Lock()
+	f.objects[name] = fakeObject{Data: data, ContentType: r.Header.Get("Content-Type"), ModTime: time.Now()}
+	f.mu.Unlock()
+	w.WriteHeader(http.StatusOK)
+}
+
+func (f *FakeS3) get(w http.ResponseWriter, r *http.Request, name string) {
+	f.mu.Lock()
+	obj, ok := f.objects[name]
+	f.mu.Unlock()
+	if !ok {
+		fakeS3Error(w, http.StatusNotFound, "NoSuchKey", "file tidak ditemukan")
+		return
+	}
+
+	header := w.Header()
+	contentType := obj.ContentType
+	if ct := r.URL.Query().Get("response-content-type"); ct != "" {
+		contentType = ct
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	header.Set("Content-Type", contentType)
+	if cd := r.URL.Query().Get("response-content-disposition"); cd != "" {
+		header.Set("Content-Disposition", cd)
+	}
+	header.Set("Content-Length", strconv.Itoa(len(obj.Data)))
+	header.Set("Last-Modified", obj.ModTime.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodGet {
+		w.Write(obj.Data)
+	}
+}
+
+// fakeS3Error: format error XML seperti S3 asli
+func fakeS3Error(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string
+		Message string
+	}{Code: code, Message: message})
+}
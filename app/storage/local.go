@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// Local: file disimpan di folder Dir dan disajikan sendiri lewat ServeHTTP (di-mount di URL).
+// Tanpa Secret folder dianggap publik (URL tetap, tanpa tanda tangan);
+// dengan Secret setiap URL harus ditandatangani & ada masa berlakunya.
+type Local struct {
+	Dir    string
+	URL    string // prefix URL tempat ServeHTTP di-mount, contoh "/uploads"
+	Secret []byte // kunci HMAC untuk SignedURL; kosong = publik
+}
+
+func NewLocal(dir, baseURL string, secret []byte) *Local {
+	return &Local{Dir: dir, URL: baseURL, Secret: secret}
+}
+
+func (l *Local) path(key string) (string, error) {
+	clean, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(l.Dir, filepath.FromSlash(clean)), nil
+}
+
+// Put: tulis ke file sementara lalu rename, supaya pembaca tidak pernah melihat file setengah jadi
+func (l *Local) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	full, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	dirPerm, filePerm := os.FileMode(0o755), os.FileMode(0o644)
+	if l.private() {
+		dirPerm, filePerm = 0o750, 0o640
+	}
+	if err := os.MkdirAll(filepath.Dir(full), dirPerm); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(full), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // tidak berefek kalau rename sudah berhasil
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(filePerm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), full)
+}
+
+func (l *Local) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	full, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(full)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// Delete: hapus file lalu folder induknya yang jadi kosong (sampai Dir)
+func (l *Local) Delete(ctx context.Context, key string) error {
+	full, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(full); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	root := filepath.Clean(l.Dir)
+	for dir := filepath.Dir(full); dir != root && len(dir) > len(root); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil { // gagal = folder belum kosong
+			break
+		}
+	}
+	return nil
+}
+
+// SignedURL: URL publik biasa, atau URL dengan expires & signature kalau Secret diisi
+func (l *Local) SignedURL(ctx context.Context, key string, opts URLOptions) (string, error) {
+	clean, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	u := l.URL + "/" + (&url.URL{Path: clean}).EscapedPath()
+	if !l.private() {
+		return u, nil
+	}
+
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(time.Now().Add(opts.Expires).Unix(), 10))
+	if opts.ContentType != "" {
+		query.Set("ct", opts.ContentType)
+	}
+	if opts.Disposition != "" {
+		query.Set("cd", opts.Disposition)
+	}
+	query.Set("sig", l.sign(clean, query))
+	return u + "?" + query.Encode(), nil
+}
+
+// ServeHTTP: sajikan file (pasang dengan http.StripPrefix(l.URL+"/", l)). Folder tidak pernah di-list.
+func (l *Local) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key, err := CleanKey(r.URL.Path)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	query := r.URL.Query()
+	if l.private() {
+		expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+		if err != nil || time.Now().Unix() > expires ||
+			!hmac.Equal([]byte(query.Get("sig")), []byte(l.sign(key, query))) {
+			http.Error(w, "link sudah kedaluwarsa atau tidak valid", http.StatusForbidden)
+			return
+		}
+	}
+
+	full, _ := l.path(key)
+	f, err := os.Open(full)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+
+	header := w.Header()
+	header.Set("X-Content-Type-Options", "nosniff")
+	if l.private() {
+		header.Set("Cache-Control", "private, max-age=600")
+		if ct := query.Get("ct"); ct != "" {
+			header.Set("Content-Type", ct)
+		}
+		if cd := query.Get("cd"); cd != "" {
+			header.Set("Content-Disposition", cd)
+		}
+	}
+	http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+}
+
+func (l *Local) private() bool {
+	return len(l.Secret) > 0
+}
+
+// sign: HMAC-SHA256 atas key + parameter yang mempengaruhi respons
+func (l *Local) sign(key string, query url.Values) string {
+	mac := hmac.New(sha256.New, l.Secret)
+	for _, part := range []string{key, query.Get("expires"), query.Get("ct"), query.Get("cd")} {
+		mac.Write([]byte(part))
+		mac.Write([]byte{0})
+	}
+	return hex.EncodeToString(mac.Sum(nil))
+}
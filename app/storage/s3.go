@@ -0,0 +1,175 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// S3: penyimpanan di layanan S3-compatible (AWS S3, MinIO, Cloudflare R2, ...) memakai path-style URL
+// ({Endpoint}/{Bucket}/{key}) dan tanda tangan SigV4, tanpa SDK.
+type S3 struct {
+	Endpoint   string // contoh https://s3.ap-southeast-1.amazonaws.com atau http://127.0.0.1:9000 (MinIO)
+	Region     string
+	Bucket     string
+	AccessKey  string
+	SecretKey  string
+	Prefix     string // awalan key di bucket, contoh "uploads"
+	PublicURL  string // kalau bucket / CDN-nya publik: SignedURL tanpa override = {PublicURL}/{key} tanpa tanda tangan
+	HTTPClient *http.Client
+}
+
+// maxObjectSize: Put membaca isi file ke memori untuk dihitung hash-nya
+const maxObjectSize = 64 << 20
+
+func (s *S3) credentials() credentials {
+	region := s.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	return credentials{AccessKey: s.AccessKey, SecretKey: s.SecretKey, Region: region}
+}
+
+func (s *S3) client() *http.Client {
+	if s.HTTPClient != nil {
+		return s.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// objectKey: key lengkap di bucket (dengan Prefix)
+func (s *S3) objectKey(key string) (string, error) {
+	clean, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	if prefix := strings.Trim(s.Prefix, "/"); prefix != "" {
+		clean = prefix + "/" + clean
+	}
+	return clean, nil
+}
+
+func (s *S3) objectURL(key string) (*url.URL, error) {
+	full, err := s.objectKey(key)
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(strings.TrimRight(s.Endpoint, "/"))
+	if err != nil {
+		return nil, err
+	}
+	u.Path += "/" + s.Bucket + "/" + full
+	u.RawPath = escapePath(u.Path) // kirim path persis seperti yang ditandatangani
+	return u, nil
+}
+
+func (s *S3) do(ctx context.Context, method, key string, body []byte, contentType string) (*http.Response, error) {
+	u, err := s.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var reader io.Reader
+	payloadHash := emptyPayloadHash
+	if body != nil {
+		reader = bytes.NewReader(body)
+		payloadHash = hashHex(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.credentials().signRequest(req, payloadHash, time.Now())
+
+	return s.client().Do(req)
+}
+
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	data, err := io.ReadAll(io.LimitReader(r, maxObjectSize+1))
+	if err != nil {
+		return err
+	}
+	if len(data) > maxObjectSize {
+		return fmt.Errorf("storage: file %s lebih dari %d MB", key, maxObjectSize>>20)
+	}
+
+	res, err := s.do(ctx, http.MethodPut, key, data, contentType)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	return s3Error(res, key)
+}
+
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	res, err := s.do(ctx, http.MethodGet, key, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := s3Error(res, key); err != nil {
+		res.Body.Close()
+		return nil, err
+	}
+	return res.Body, nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	res, err := s.do(ctx, http.MethodDelete, key, nil, "")
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if err := s3Error(res, key); err != nil && err != ErrNotFound {
+		return err
+	}
+	return nil
+}
+
+// SignedURL: presigned GET; ContentType / Disposition dikirim sebagai response-content-* (dihormati S3 & MinIO)
+func (s *S3) SignedURL(ctx context.Context, key string, opts URLOptions) (string, error) {
+	if s.PublicURL != "" && opts.ContentType == "" && opts.Disposition == "" {
+		full, err := s.objectKey(key)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(s.PublicURL, "/") + "/" + escapePath(full), nil
+	}
+
+	u, err := s.objectURL(key)
+	if err != nil {
+		return "", err
+	}
+	expires := opts.Expires
+	if expires <= 0 || expires > 7*24*time.Hour { // batas presigned URL SigV4
+		expires = 7 * 24 * time.Hour
+	}
+
+	extra := url.Values{}
+	if opts.ContentType != "" {
+		extra.Set("response-content-type", opts.ContentType)
+	}
+	if opts.Disposition != "" {
+		extra.Set("response-content-disposition", opts.Disposition)
+	}
+	return s.credentials().presign(http.MethodGet, u, extra, time.Now(), expires), nil
+}
+
+// s3Error: nil untuk status 2xx, ErrNotFound untuk 404, selain itu pesan error dari S3
+func s3Error(res *http.Response, key string) error {
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return nil
+	}
+	if res.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+	return fmt.Errorf("storage: S3 %s %s: %s", res.Request.Method, key, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,199 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestS3: client S3 yang bicara ke FakeS3 lewat HTTP sungguhan
+func newTestS3(t *testing.T) (*S3, *FakeS3) {
+	t.Helper()
+	fake := NewFakeS3("test-access-key", "test-secret-key", "ap-southeast-1", "")
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	return &S3{
+		Endpoint:   srv.URL,
+		Region:     "ap-southeast-1",
+		Bucket:     "goshop",
+		AccessKey:  "test-access-key",
+		SecretKey:  "test-secret-key",
+		Prefix:     "uploads",
+		HTTPClient: srv.Client(),
+	}, fake
+}
+
+func readBlob(t *testing.T, b Blob, key string) string {
+	t.Helper()
+	r, err := b.Get(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Get(%q): %v", key, err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestS3PutGetDelete(t *testing.T) {
+	s3, fake := newTestS3(t)
+	ctx := context.Background()
+
+	// key dengan spasi & karakter non-ASCII ikut ditandatangani dengan benar
+	keys := []string{"products/1/kaos.jpg", "products/1/kaos polos (1).jpg", "chat/ü+=&.txt"}
+	for _, key := range keys {
+		if err := PutBytes(ctx, s3, key, []byte("isi "+key), "text/plain"); err != nil {
+			t.Fatalf("Put(%q): %v", key, err)
+		}
+	}
+	if fake.Len() != len(keys) {
+		t.Fatalf("Len = %d, want %d", fake.Len(), len(keys))
+	}
+	for _, key := range keys {
+		if got := readBlob(t, s3, key); got != "isi "+key {
+			t.Fatalf("Get(%q) = %q", key, got)
+		}
+	}
+
+	// timpa isi file
+	if err := PutBytes(ctx, s3, keys[0], []byte("baru"), "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readBlob(t, s3, keys[0]); got != "baru" {
+		t.Fatalf("isi setelah ditimpa = %q", got)
+	}
+
+	if err := s3.Delete(ctx, keys[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s3.Get(ctx, keys[0]); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get setelah Delete: err = %v, want ErrNotFound", err)
+	}
+	if err := s3.Delete(ctx, keys[0]); err != nil {
+		t.Fatalf("Delete file yang tidak ada: %v", err)
+	}
+	if fake.Len() != len(keys)-1 {
+		t.Fatalf("Len = %d, want %d", fake.Len(), len(keys)-1)
+	}
+
+	// key "../" tidak bisa keluar dari Prefix
+	if err := PutBytes(ctx, s3, "../private/rahasia.txt", []byte("x"), "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readBlob(t, Prefix(s3, "private"), "rahasia.txt"); got != "x" {
+		t.Fatalf("isi = %q", got)
+	}
+	if err := PutBytes(ctx, s3, "", []byte("x"), ""); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("key kosong: err = %v, want ErrInvalidKey", err)
+	}
+}
+
+func TestS3WrongCredentials(t *testing.T) {
+	s3, fake := newTestS3(t)
+	ctx := context.Background()
+
+	wrong := *s3
+	wrong.SecretKey = "other-secret-key"
+	if err := PutBytes(ctx, &wrong, "a.txt", []byte("x"), "text/plain"); err == nil || !strings.Contains(err.Error(), "SignatureDoesNotMatch") {
+		t.Fatalf("Put dengan secret salah: err = %v", err)
+	}
+
+	wrong = *s3
+	wrong.Region = "us-east-1"
+	if err := PutBytes(ctx, &wrong, "a.txt", []byte("x"), "text/plain"); err == nil {
+		t.Fatal("Put dengan region salah diterima")
+	}
+	if fake.Len() != 0 {
+		t.Fatalf("Len = %d, want 0", fake.Len())
+	}
+}
+
+func TestS3SignedURL(t *testing.T) {
+	s3, _ := newTestS3(t)
+	ctx := context.Background()
+	if err := PutBytes(ctx, s3, "proofs/bukti transfer.jpg", []byte("jpeg"), "image/jpeg"); err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := s3.SignedURL(ctx, "proofs/bukti transfer.jpg", URLOptions{
+		Expires:     time.Minute,
+		ContentType: "image/jpeg",
+		Disposition: `inline; filename="bukti.jpg"`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := s3.HTTPClient.Get(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || string(body) != "jpeg" {
+		t.Fatalf("GET signed URL = %d %q", res.StatusCode, body)
+	}
+	if got := res.Header.Get("Content-Disposition"); got != `inline; filename="bukti.jpg"` {
+		t.Fatalf("Content-Disposition = %q", got)
+	}
+
+	// URL yang diubah (file lain / override lain) ditolak
+	u, _ := url.Parse(signed)
+	q := u.Query()
+	q.Set("response-content-type", "text/html")
+	u.RawQuery = q.Encode()
+	tampered := []string{strings.Replace(signed, "bukti%20transfer", "lain", 1), u.String()}
+	for _, target := range tampered {
+		res, err := s3.HTTPClient.Get(target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusForbidden {
+			t.Fatalf("GET %s = %d, want 403", target, res.StatusCode)
+		}
+	}
+
+	// URL yang sudah kedaluwarsa ditolak
+	obj, _ := s3.objectURL("proofs/bukti transfer.jpg")
+	expired := s3.credentials().presign(http.MethodGet, obj, url.Values{}, time.Now().Add(-time.Hour), time.Minute)
+	res, err = s3.HTTPClient.Get(expired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusForbidden {
+		t.Fatalf("GET URL kedaluwarsa = %d, want 403", res.StatusCode)
+	}
+}
+
+func TestS3SignedURLPublic(t *testing.T) {
+	s3, _ := newTestS3(t)
+	s3.PublicURL = "https://cdn.shop.test/"
+
+	got, err := s3.SignedURL(context.Background(), "products/kaos polos.jpg", URLOptions{Expires: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "https://cdn.shop.test/uploads/products/kaos%20polos.jpg" {
+		t.Fatalf("SignedURL = %q", got)
+	}
+
+	// override header tetap lewat presigned URL bucket
+	got, err = s3.SignedURL(context.Background(), "products/kaos.jpg", URLOptions{Expires: time.Hour, Disposition: "attachment"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(got, s3.Endpoint+"/goshop/uploads/products/kaos.jpg?") || !strings.Contains(got, "X-Amz-Signature=") {
+		t.Fatalf("SignedURL = %q", got)
+	}
+}
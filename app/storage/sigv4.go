@@ -0,0 +1,210 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tanda tangan AWS Signature Version 4 (service "s3"), dipakai S3 (client) dan FakeS3 (verifikasi).
+// Referensi: https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-authenticating-requests.html
+
+const (
+	sigAlgorithm    = "AWS4-HMAC-SHA256"
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+	amzDateFormat   = "20060102T150405Z"
+)
+
+var errSignature = errors.New("storage: tanda tangan S3 tidak valid")
+
+// emptyPayloadHash: sha256 dari body kosong (GET / DELETE)
+var emptyPayloadHash = hashHex(nil)
+
+type credentials struct {
+	AccessKey string
+	SecretKey string
+	Region    string
+}
+
+func (c credentials) scope(t time.Time) string {
+	return t.UTC().Format("20060102") + "/" + c.Region + "/s3/aws4_request"
+}
+
+// signRequest: isi X-Amz-Date, X-Amz-Content-Sha256 & Authorization. Semua header yang sudah di-set ikut ditandatangani.
+func (c credentials) signRequest(req *http.Request, payloadHash string, now time.Time) {
+	req.Header.Set("X-Amz-Date", now.UTC().Format(amzDateFormat))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signed := []string{"host"}
+	for name := range req.Header {
+		signed = append(signed, strings.ToLower(name))
+	}
+	sort.Strings(signed)
+
+	header := func(name string) string {
+		if name == "host" {
+			return req.URL.Host
+		}
+		return req.Header.Get(name)
+	}
+	canonical := canonicalRequest(req.Method, req.URL, req.URL.Query(), header, signed, payloadHash)
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigAlgorithm, c.AccessKey, c.scope(now), strings.Join(signed, ";"), c.signature(now, canonical)))
+}
+
+// presign: URL dengan tanda tangan di query (hanya header host yang ditandatangani)
+func (c credentials) presign(method string, u *url.URL, extra url.Values, now time.Time, expires time.Duration) string {
+	query := url.Values{}
+	for k, v := range extra {
+		query[k] = v
+	}
+	query.Set("X-Amz-Algorithm", sigAlgorithm)
+	query.Set("X-Amz-Credential", c.AccessKey+"/"+c.scope(now))
+	query.Set("X-Amz-Date", now.UTC().Format(amzDateFormat))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires/time.Second)))
+	query.Set("X-Amz-SignedHeaders", "host")
+
+	header := func(string) string { return u.Host }
+	canonical := canonicalRequest(method, u, query, header, []string{"host"}, unsignedPayload)
+	signature := c.signature(now, canonical)
+
+	return u.Scheme + "://" + u.Host + escapePath(u.Path) + "?" + canonicalQuery(query) + "&X-Amz-Signature=" + signature
+}
+
+func (c credentials) signature(t time.Time, canonical string) string {
+	stringToSign := sigAlgorithm + "\n" + t.UTC().Format(amzDateFormat) + "\n" + c.scope(t) + "\n" + hashHex([]byte(canonical))
+
+	key := hmacSHA256([]byte("AWS4"+c.SecretKey), t.UTC().Format("20060102"))
+	key = hmacSHA256(key, c.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// verify: cek tanda tangan request masuk (header Authorization atau presigned query). Dipakai FakeS3.
+func (c credentials) verify(r *http.Request, now time.Time) error {
+	header := func(name string) string {
+		if name == "host" {
+			return r.Host
+		}
+		return strings.Join(r.Header.Values(name), ",")
+	}
+
+	query := r.URL.Query()
+	if sig := query.Get("X-Amz-Signature"); sig != "" {
+		t, err := time.Parse(amzDateFormat, query.Get("X-Amz-Date"))
+		if err != nil {
+			return errSignature
+		}
+		expires, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+		if err != nil || now.After(t.Add(time.Duration(expires)*time.Second)) {
+			return errors.New("storage: URL sudah kedaluwarsa")
+		}
+		if query.Get("X-Amz-Credential") != c.AccessKey+"/"+c.scope(t) {
+			return errSignature
+		}
+		query.Del("X-Amz-Signature")
+		signed := strings.Split(query.Get("X-Amz-SignedHeaders"), ";")
+		canonical := canonicalRequest(r.Method, r.URL, query, header, signed, unsignedPayload)
+		if !hmac.Equal([]byte(sig), []byte(c.signature(t, canonical))) {
+			return errSignature
+		}
+		return nil
+	}
+
+	// Authorization: AWS4-HMAC-SHA256 Credential=AK/scope, SignedHeaders=a;b, Signature=hex
+	auth := strings.TrimPrefix(r.Header.Get("Authorization"), sigAlgorithm+" ")
+	fields := map[string]string{}
+	for _, part := range strings.Split(auth, ",") {
+		if k, v, ok := strings.Cut(strings.TrimSpace(part), "="); ok {
+			fields[k] = v
+		}
+	}
+	t, err := time.Parse(amzDateFormat, r.Header.Get("X-Amz-Date"))
+	if err != nil || fields["Credential"] != c.AccessKey+"/"+c.scope(t) {
+		return errSignature
+	}
+	if d := now.Sub(t); d > 15*time.Minute || d < -15*time.Minute {
+		return errors.New("storage: jam request terlalu jauh berbeda")
+	}
+	signed := strings.Split(fields["SignedHeaders"], ";")
+	canonical := canonicalRequest(r.Method, r.URL, query, header, signed, r.Header.Get("X-Amz-Content-Sha256"))
+	if !hmac.Equal([]byte(fields["Signature"]), []byte(c.signature(t, canonical))) {
+		return errSignature
+	}
+	return nil
+}
+
+func canonicalRequest(method string, u *url.URL, query url.Values, header func(string) string, signed []string, payloadHash string) string {
+	var headers strings.Builder
+	for _, name := range signed {
+		headers.WriteString(name + ":" + strings.Join(strings.Fields(header(name)), " ") + "\n")
+	}
+	return strings.Join([]string{
+		method,
+		escapePath(u.Path),
+		canonicalQuery(query),
+		headers.String(),
+		strings.Join(signed, ";"),
+		payloadHash,
+	}, "\n")
+}
+
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, escape(k, true)+"="+escape(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// escapePath: path URL versi AWS (semua selain A-Z a-z 0-9 - _ . ~ dan "/" di-encode)
+func escapePath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	return escape(p, false)
+}
+
+func escape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
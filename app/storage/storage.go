@@ -0,0 +1,145 @@
+// Package storage: tempat simpan file upload (gambar produk, bukti bayar, lampiran chat) di balik interface Blob.
+// Local = folder di disk, S3 = layanan S3-compatible (AWS S3, MinIO, R2, ...), FakeS3 = tiruan S3 untuk lokal/test.
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound   = errors.New("storage: file tidak ditemukan")
+	ErrInvalidKey = errors.New("storage: key tidak valid")
+)
+
+// Blob: kontrak yang harus dipenuhi setiap penyimpanan file.
+// Key memakai "/" sebagai pemisah folder, contoh "products/{id}/{file}.jpg".
+type Blob interface {
+	// Put: simpan (atau timpa) isi file
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	// Get: buka file untuk dibaca, ErrNotFound kalau tidak ada
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete: hapus file; file yang memang tidak ada bukan error
+	Delete(ctx context.Context, key string) error
+	// SignedURL: alamat yang bisa dibuka browser langsung, berlaku selama opts.Expires
+	SignedURL(ctx context.Context, key string, opts URLOptions) (string, error)
+}
+
+// URLOptions: pengaturan SignedURL
+type URLOptions struct {
+	Expires     time.Duration
+	ContentType string // Content-Type respons (kosong = sesuai file)
+	Disposition string // Content-Disposition respons (kosong = tidak di-set)
+}
+
+// CleanKey: rapikan key dan tolak key yang keluar dari root ("../") atau kosong
+func CleanKey(key string) (string, error) {
+	clean := strings.TrimPrefix(path.Clean("/"+key), "/")
+	if clean == "" || strings.Contains(key, "\x00") {
+		return "", ErrInvalidKey
+	}
+	return clean, nil
+}
+
+// PutBytes: Put dari []byte
+func PutBytes(ctx context.Context, b Blob, key string, data []byte, contentType string) error {
+	return b.Put(ctx, key, bytes.NewReader(data), contentType)
+}
+
+// Copy: salin file antar Blob (dipakai storage:migrate & saat file dipindah keperluan)
+func Copy(ctx context.Context, dst Blob, dstKey string, src Blob, srcKey, contentType string) error {
+	r, err := src.Get(ctx, srcKey)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return dst.Put(ctx, dstKey, r, contentType)
+}
+
+// Handler: sajikan file Blob publik di bawah prefix route (pakai bersama http.StripPrefix).
+// Local dilayani langsung dari disk; backend lain di-redirect ke SignedURL.
+func Handler(b Blob, expires time.Duration) http.Handler {
+	if h, ok := b.(http.Handler); ok {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		url, err := b.SignedURL(r.Context(), r.URL.Path, URLOptions{Expires: expires})
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		http.Redirect(w, r, url, http.StatusFound)
+	})
+}
+
+// Prefix: Blob yang semua key-nya diberi awalan prefix/ (satu folder / bucket dipakai beberapa keperluan)
+func Prefix(b Blob, prefix string) Blob {
+	return prefixBlob{blob: b, prefix: strings.Trim(prefix, "/")}
+}
+
+type prefixBlob struct {
+	blob   Blob
+	prefix string
+}
+
+func (p prefixBlob) key(key string) (string, error) {
+	clean, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return p.prefix + "/" + clean, nil
+}
+
+func (p prefixBlob) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	full, err := p.key(key)
+	if err != nil {
+		return err
+	}
+	return p.blob.Put(ctx, full, r, contentType)
+}
+
+func (p prefixBlob) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	full, err := p.key(key)
+	if err != nil {
+		return nil, err
+	}
+	return p.blob.Get(ctx, full)
+}
+
+func (p prefixBlob) Delete(ctx context.Context, key string) error {
+	full, err := p.key(key)
+	if err != nil {
+		return err
+	}
+	return p.blob.Delete(ctx, full)
+}
+
+func (p prefixBlob) SignedURL(ctx context.Context, key string, opts URLOptions) (string, error) {
+	full, err := p.key(key)
+	if err != nil {
+		return "", err
+	}
+	return p.blob.SignedURL(ctx, full, opts)
+}
+
+// LocalPath: lokasi file di disk kalau b (atau Blob di balik Prefix) adalah Local
+func LocalPath(b Blob, key string) (string, bool) {
+	switch v := b.(type) {
+	case *Local:
+		full, err := v.path(key)
+		return full, err == nil
+	case prefixBlob:
+		full, err := v.key(key)
+		if err != nil {
+			return "", false
+		}
+		return LocalPath(v.blob, full)
+	}
+	return "", false
+}
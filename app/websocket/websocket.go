@@ -0,0 +1,311 @@
+// Package websocket: implementasi server WebSocket (RFC 6455) minimal untuk push realtime.
+// Hanya mendukung yang dibutuhkan aplikasi: handshake, frame teks/biner, ping/pong & close, tanpa ekstensi.
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Opcode frame
+const (
+	continuationFrame = 0
+	TextMessage       = 1
+	BinaryMessage     = 2
+	CloseMessage      = 8
+	PingMessage       = 9
+	PongMessage       = 10
+)
+
+// Kode penutupan yang dipakai server
+const (
+	CloseNormal        = 1000
+	CloseGoingAway     = 1001
+	CloseProtocolError = 1002
+	CloseTooBig        = 1009
+)
+
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// batas waktu menulis 1 frame; koneksi yang macet dianggap putus
+const writeWait = 10 * time.Second
+
+var (
+	ErrBadHandshake  = errors.New("websocket: handshake tidak valid")
+	ErrProtocol      = errors.New("websocket: frame tidak valid")
+	ErrMessageTooBig = errors.New("websocket: pesan terlalu besar")
+	ErrClosed        = errors.New("websocket: koneksi sudah ditutup")
+)
+
+// Conn: koneksi WebSocket sisi server. ReadMessage hanya boleh dipanggil dari 1 goroutine,
+// WriteMessage aman dipanggil dari banyak goroutine.
+type Conn struct {
+	conn net.Conn
+	br   *bufio.Reader
+
+	// MaxMessageSize: batas ukuran pesan dari client (default 64 KB)
+	MaxMessageSize int64
+
+	writeMu sync.Mutex
+	closed  bool
+}
+
+// IsUpgrade: request meminta upgrade ke WebSocket
+func IsUpgrade(r *http.Request) bool {
+	return headerHasToken(r.Header, "Connection", "upgrade") && headerHasToken(r.Header, "Upgrade", "websocket")
+}
+
+// Upgrade: lakukan handshake & ambil alih koneksi HTTP. Kalau gagal, response error sudah ditulis.
+// Origin browser harus sama dengan host (cookie session ikut terkirim, jadi situs lain tidak boleh membuka koneksi).
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	if r.Method != http.MethodGet || !IsUpgrade(r) {
+		http.Error(w, "websocket upgrade required", http.StatusBadRequest)
+		return nil, ErrBadHandshake
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported websocket version", http.StatusUpgradeRequired)
+		return nil, ErrBadHandshake
+	}
+	key := strings.TrimSpace(r.Header.Get("Sec-WebSocket-Key"))
+	if key == "" {
+		http.Error(w, "missing Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, ErrBadHandshake
+	}
+	if !sameOrigin(r) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return nil, ErrBadHandshake
+	}
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket not supported", http.StatusInternalServerError)
+		return nil, ErrBadHandshake
+	}
+	netConn, brw, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n"
+
+	_ = netConn.SetWriteDeadline(time.Now().Add(writeWait))
+	if _, err := netConn.Write([]byte(response)); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	_ = netConn.SetDeadline(time.Time{})
+
+	return &Conn{conn: netConn, br: brw.Reader, MaxMessageSize: 64 << 10}, nil
+}
+
+// ReadMessage: baca 1 pesan utuh (frame lanjutan digabung). Ping dibalas otomatis,
+// frame close dibalas lalu mengembalikan io.EOF.
+func (c *Conn) ReadMessage() (messageType int, data []byte, err error) {
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			if errors.Is(err, ErrMessageTooBig) {
+				c.closeWith(CloseTooBig)
+			} else if errors.Is(err, ErrProtocol) {
+				c.closeWith(CloseProtocolError)
+			}
+			return 0, nil, err
+		}
+
+		switch opcode {
+		case PingMessage:
+			if err := c.WriteMessage(PongMessage, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			continue
+		case CloseMessage:
+			c.closeWith(CloseNormal)
+			return 0, nil, io.EOF
+		case continuationFrame:
+			if messageType == 0 {
+				c.closeWith(CloseProtocolError)
+				return 0, nil, ErrProtocol
+			}
+		case TextMessage, BinaryMessage:
+			if messageType != 0 {
+				c.closeWith(CloseProtocolError)
+				return 0, nil, ErrProtocol
+			}
+			messageType = opcode
+		default:
+			c.closeWith(CloseProtocolError)
+			return 0, nil, ErrProtocol
+		}
+
+		data = append(data, payload...)
+		if int64(len(data)) > c.MaxMessageSize {
+			c.closeWith(CloseTooBig)
+			return 0, nil, ErrMessageTooBig
+		}
+		if fin {
+			return messageType, data, nil
+		}
+	}
+}
+
+func (c *Conn) readFrame() (fin bool, opcode int, payload []byte, err error) {
+	var head [2]byte
+	if _, err = io.ReadFull(c.br, head[:]); err != nil {
+		return
+	}
+
+	fin = head[0]&0x80 != 0
+	if head[0]&0x70 != 0 {
+		return false, 0, nil, ErrProtocol // RSV tanpa ekstensi harus 0
+	}
+	opcode = int(head[0] & 0x0f)
+	masked := head[1]&0x80 != 0
+	if !masked {
+		return false, 0, nil, ErrProtocol // frame dari client wajib di-mask
+	}
+
+	length := int64(head[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:]))
+	}
+
+	if opcode >= CloseMessage && (length > 125 || !fin) {
+		return false, 0, nil, ErrProtocol
+	}
+	if length < 0 || length > c.MaxMessageSize {
+		return false, 0, nil, ErrMessageTooBig
+	}
+
+	var mask [4]byte
+	if _, err = io.ReadFull(c.br, mask[:]); err != nil {
+		return
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.br, payload); err != nil {
+		return
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return fin, opcode, payload, nil
+}
+
+// WriteMessage: kirim 1 frame utuh (tanpa fragmentasi)
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closed {
+		return ErrClosed
+	}
+	return c.writeFrame(messageType, data)
+}
+
+func (c *Conn) writeFrame(opcode int, data []byte) error {
+	header := make([]byte, 0, 10)
+	header = append(header, 0x80|byte(opcode))
+
+	switch n := len(data); {
+	case n <= 125:
+		header = append(header, byte(n))
+	case n <= 0xffff:
+		header = append(header, 126, byte(n>>8), byte(n))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+
+	_ = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	if _, err := c.conn.Write(append(header, data...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// WriteText: shortcut kirim pesan teks (JSON)
+func (c *Conn) WriteText(data []byte) error {
+	return c.WriteMessage(TextMessage, data)
+}
+
+// SetReadDeadline: batas waktu menunggu frame berikutnya dari client
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// Close: kirim frame close (1000) lalu tutup koneksi
+func (c *Conn) Close() error {
+	c.closeWith(CloseNormal)
+	return c.conn.Close()
+}
+
+// closeWith: kirim frame close sekali saja; koneksi TCP ditutup oleh Close
+func (c *Conn) closeWith(code int) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closed {
+		return
+	}
+	c.closed = true
+
+	payload := []byte{byte(code >> 8), byte(code)}
+	_ = c.writeFrame(CloseMessage, payload)
+}
+
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// sameOrigin: tanpa header Origin (client non-browser) diizinkan
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+func headerHasToken(h http.Header, name, token string) bool {
+	for _, value := range h.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}